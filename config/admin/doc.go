@@ -0,0 +1,47 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package admin provides an HTTP/JSON API to manage configuration values, for
+// example as backend for an administration user interface.
+//
+// The handler renders the section, group and field tree of config.Sections for
+// a requested scope, returns the effective values including the scope from
+// where the value has been retrieved and writes or deletes values. Writing
+// checks the permitted scopes of a field and the WriteScopePerm of the
+// FieldMeta data. All observers of the config.Service run as usual, so
+// validators return a 400 Bad Request.
+//
+// Each value has an ETag. Write and delete requests can provide the header
+// If-Match to avoid lost updates, a changed value triggers 412 Precondition
+// Failed. Read requests support If-None-Match.
+//
+// Endpoints, relative to the mount point of the handler:
+//		GET    /sections                   all sections for a scope
+//		GET    /sections/{section}         one section for a scope
+//		GET    /values/{section/group/field}
+//		PUT    /values/{section/group/field}   body: {"value":"..."}
+//		DELETE /values/{section/group/field}
+//
+// The scope gets selected via the query parameters `website` and `store`. No
+// parameter selects the default scope, `?website=1` the website scope with ID
+// 1 and `?website=1&store=2` the store scope with ID 2 and its parent website
+// 1. Example to mount the handler:
+//		h, err := admin.NewHandler(cfgSrv, admin.Options{Sections: sections})
+//		mux.Handle("/admin/config/", http.StripPrefix("/admin/config", h))
+package admin
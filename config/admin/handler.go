@@ -0,0 +1,538 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/corestoreio/errors"
+	"github.com/corestoreio/log"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// Service defines the functions of the configuration service used by the
+// Handler. Type *config.Service implements this interface.
+type Service interface {
+	Scoped(websiteID, storeID int64) config.Scoped
+	Set(p *config.Path, v []byte) error
+	Delete(p *config.Path) error
+}
+
+// Options applies options to the Handler.
+type Options struct {
+	// Sections defines the tree of sections, groups and fields. Only the
+	// routes of the fields can be read or written. Required.
+	Sections config.Sections
+	// MaxRequestSize limits the body of a PUT request. Default 64kb.
+	MaxRequestSize int64
+	// RequireIfMatch rejects PUT and DELETE requests without an If-Match
+	// header with status 428 Precondition Required.
+	RequireIfMatch bool
+	// ErrorHandler custom error handler. The default error handler writes the
+	// status code and a JSON object containing the status and the error
+	// message.
+	ErrorHandler func(statusCode int, err error) http.Handler
+	// Log optional logger. Errors get logged with level info.
+	Log log.Logger
+}
+
+// Handler implements the HTTP/JSON API. See the package documentation for the
+// endpoints. Safe for concurrent use.
+type Handler struct {
+	srv Service
+	o   Options
+	// muWrite serializes the ETag comparison and the following write, so two
+	// requests with the same If-Match header cannot succeed both.
+	muWrite sync.Mutex
+}
+
+// NewHandler creates a new Handler and validates the sections.
+func NewHandler(srv Service, o Options) (*Handler, error) {
+	if srv == nil {
+		return nil, errors.Empty.Newf("[config/admin] NewHandler: Service cannot be nil")
+	}
+	if len(o.Sections) == 0 {
+		return nil, errors.Empty.Newf("[config/admin] NewHandler: Options.Sections cannot be empty")
+	}
+	if err := o.Sections.Validate(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if o.MaxRequestSize == 0 {
+		o.MaxRequestSize = 1024 * 64 // 64kb
+	}
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = jsonError
+	}
+	return &Handler{srv: srv, o: o}, nil
+}
+
+// MustNewHandler same as NewHandler but panics on error.
+func MustNewHandler(srv Service, o Options) *Handler {
+	h, err := NewHandler(srv, o)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// Value represents the effective value of a route for a scope.
+type Value struct {
+	Route string `json:"route"`
+	// Scope defines the requested scope, e.g. "stores/2".
+	Scope string `json:"scope"`
+	// Value is nil if neither the requested scope nor its parent scopes nor
+	// the default value of the field provide a value.
+	Value *string `json:"value"`
+	// Origin defines the scope in which the value has been found, e.g.
+	// "websites/1" when requesting "stores/2". Empty if Value is nil.
+	Origin string `json:"origin,omitempty"`
+	// IsDefault is true if the value has been provided by the default value
+	// of the field and not by a storage.
+	IsDefault bool `json:"is_default,omitempty"`
+	// ETag gets also sent as HTTP header and must be used in the If-Match
+	// header to avoid lost updates.
+	ETag string `json:"etag"`
+}
+
+func (v Value) etag() string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(v.Origin))
+	if v.IsDefault {
+		_, _ = h.Write([]byte{'d'})
+	}
+	if v.Value != nil { // distinguishes between not found and an empty value
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(*v.Value))
+	}
+	return `"` + strconv.FormatUint(h.Sum64(), 16) + `"`
+}
+
+// Section represents a config.Section and contains only the groups visible in
+// the requested scope.
+type Section struct {
+	ID        string  `json:"id"`
+	Label     string  `json:"label,omitempty"`
+	SortOrder int     `json:"sort_order,omitempty"`
+	Groups    []Group `json:"groups,omitempty"`
+}
+
+// Group represents a config.Group and contains only the fields visible in the
+// requested scope.
+type Group struct {
+	ID        string  `json:"id"`
+	Label     string  `json:"label,omitempty"`
+	Comment   string  `json:"comment,omitempty"`
+	SortOrder int     `json:"sort_order,omitempty"`
+	Fields    []Field `json:"fields,omitempty"`
+}
+
+// Field represents a config.Field including its effective value.
+type Field struct {
+	ID         string           `json:"id"`
+	Type       config.FieldType `json:"type,omitempty"`
+	Label      string           `json:"label,omitempty"`
+	Comment    string           `json:"comment,omitempty"`
+	Tooltip    string           `json:"tooltip,omitempty"`
+	SortOrder  int              `json:"sort_order,omitempty"`
+	CanBeEmpty bool             `json:"can_be_empty,omitempty"`
+	// Scopes defines the scopes in which the field can be written.
+	Scopes scope.Perm `json:"scopes,omitempty"`
+	Value
+}
+
+// writeRequest represents the body of a PUT request.
+type writeRequest struct {
+	Value *string `json:"value"`
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resource, rest := splitResource(r.URL.Path)
+	switch resource {
+	case "sections":
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			h.methodNotAllowed(w, r, "GET, HEAD")
+			return
+		}
+		h.getSections(w, r, rest)
+	case "values":
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			h.getValue(w, r, rest)
+		case http.MethodPut:
+			h.putValue(w, r, rest)
+		case http.MethodDelete:
+			h.deleteValue(w, r, rest)
+		default:
+			h.methodNotAllowed(w, r, "GET, HEAD, PUT, DELETE")
+		}
+	default:
+		h.writeError(w, r, http.StatusNotFound, errors.NotFound.Newf("[config/admin] Resource %q not found", r.URL.Path))
+	}
+}
+
+func splitResource(urlPath string) (resource, rest string) {
+	urlPath = strings.Trim(urlPath, "/")
+	if i := strings.IndexByte(urlPath, '/'); i > 0 {
+		return urlPath[:i], urlPath[i+1:]
+	}
+	return urlPath, ""
+}
+
+// scoped creates the config.Scoped from the query parameters website and
+// store.
+func (h *Handler) scoped(r *http.Request) (config.Scoped, error) {
+	q := r.URL.Query()
+	var ids [2]int64
+	for i, key := range [...]string{"website", "store"} {
+		v := q.Get(key)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 1 {
+			return config.Scoped{}, errors.NotValid.Newf("[config/admin] Query parameter %q must be a positive integer, have %q", key, v)
+		}
+		ids[i] = id
+	}
+	ss := h.srv.Scoped(ids[0], ids[1])
+	if !ss.IsValid() {
+		return ss, errors.NotValid.Newf("[config/admin] Query parameter store requires the query parameter website")
+	}
+	return ss, nil
+}
+
+// visible reports whether a section, group or field can be used in a scope.
+// An empty permission allows all scopes.
+func visible(p scope.Perm, t scope.Type) bool {
+	return p == 0 || p.Has(t)
+}
+
+func fieldRoute(s *config.Section, g *config.Group, f *config.Field) string {
+	if f.ConfigRoute != "" {
+		return f.ConfigRoute
+	}
+	return s.ID + string(config.PathSeparator) + g.ID + string(config.PathSeparator) + f.ID
+}
+
+// field returns the field for a route if the field is visible in the scope.
+func (h *Handler) field(route string, t scope.Type) (*config.Field, error) {
+	for _, s := range h.o.Sections {
+		for _, g := range s.Groups {
+			for _, f := range g.Fields {
+				if fieldRoute(s, g, f) != route {
+					continue
+				}
+				if !visible(s.Scopes, t) || !visible(g.Scopes, t) || !visible(f.Scopes, t) {
+					return nil, errors.NotAllowed.Newf("[config/admin] Route %q is not available in scope %q", route, t.StrType())
+				}
+				return f, nil
+			}
+		}
+	}
+	return nil, errors.NotFound.Newf("[config/admin] Route %q not found", route)
+}
+
+// value returns the effective value and its origin for a route.
+func (h *Handler) value(ss config.Scoped, route string) (Value, error) {
+	v := ss.Get(scope.Absent, route)
+	str, ok, err := v.Str()
+	if err != nil {
+		return Value{}, errors.Wrapf(err, "[config/admin] Get route %q", route)
+	}
+	ret := Value{
+		Route: route,
+		Scope: fqScope(ss.ScopeID()),
+	}
+	if ok {
+		ret.Value = &str
+		ret.Origin = fqScope(v.Path.ScopeID)
+		ret.IsDefault = v.IsDefault()
+	}
+	ret.ETag = ret.etag()
+	return ret, nil
+}
+
+// fqScope returns e.g. "default/0", "websites/1" or "stores/2".
+func fqScope(id scope.TypeID) string {
+	t, i := id.Unpack()
+	if t != scope.Website && t != scope.Store {
+		t, i = scope.Default, 0
+	}
+	return t.StrType() + string(config.PathSeparator) + strconv.FormatInt(i, 10)
+}
+
+func (h *Handler) getSections(w http.ResponseWriter, r *http.Request, id string) {
+	ss, err := h.scoped(r)
+	if err != nil {
+		h.writeError(w, r, statusCode(err), err)
+		return
+	}
+	t := ss.ScopeID().Type()
+
+	ret := make([]Section, 0, len(h.o.Sections))
+	for _, s := range h.o.Sections {
+		if (id != "" && s.ID != id) || !visible(s.Scopes, t) {
+			continue
+		}
+		sec := Section{ID: s.ID, Label: s.Label, SortOrder: s.SortOrder}
+		for _, g := range s.Groups {
+			if !visible(g.Scopes, t) {
+				continue
+			}
+			grp := Group{ID: g.ID, Label: g.Label, Comment: g.Comment, SortOrder: g.SortOrder}
+			for _, f := range g.Fields {
+				if !visible(f.Scopes, t) {
+					continue
+				}
+				v, err := h.value(ss, fieldRoute(s, g, f))
+				if err != nil {
+					h.writeError(w, r, statusCode(err), err)
+					return
+				}
+				grp.Fields = append(grp.Fields, Field{
+					ID:         f.ID,
+					Type:       f.Type,
+					Label:      f.Label,
+					Comment:    f.Comment,
+					Tooltip:    f.Tooltip,
+					SortOrder:  f.SortOrder,
+					CanBeEmpty: f.CanBeEmpty,
+					Scopes:     f.Scopes,
+					Value:      v,
+				})
+			}
+			sort.SliceStable(grp.Fields, func(i, j int) bool { return grp.Fields[i].SortOrder < grp.Fields[j].SortOrder })
+			sec.Groups = append(sec.Groups, grp)
+		}
+		sort.SliceStable(sec.Groups, func(i, j int) bool { return sec.Groups[i].SortOrder < sec.Groups[j].SortOrder })
+		ret = append(ret, sec)
+	}
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].SortOrder < ret[j].SortOrder })
+
+	if id == "" {
+		h.writeJSON(w, r, http.StatusOK, "", ret)
+		return
+	}
+	if len(ret) == 0 {
+		h.writeError(w, r, http.StatusNotFound, errors.NotFound.Newf("[config/admin] Section %q not found", id))
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, "", ret[0])
+}
+
+func (h *Handler) getValue(w http.ResponseWriter, r *http.Request, route string) {
+	ss, err := h.scoped(r)
+	if err == nil {
+		_, err = h.field(route, ss.ScopeID().Type())
+	}
+	if err != nil {
+		h.writeError(w, r, statusCode(err), err)
+		return
+	}
+	v, err := h.value(ss, route)
+	if err != nil {
+		h.writeError(w, r, statusCode(err), err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, v.ETag, v)
+}
+
+// prepareWrite validates the scope and the route of a PUT or DELETE request.
+// The returned path is bound to the requested scope.
+func (h *Handler) prepareWrite(r *http.Request, route string) (config.Scoped, *config.Path, error) {
+	ss, err := h.scoped(r)
+	if err != nil {
+		return ss, nil, errors.WithStack(err)
+	}
+	if _, err := h.field(route, ss.ScopeID().Type()); err != nil {
+		return ss, nil, errors.WithStack(err)
+	}
+	p, err := config.NewPathWithScope(ss.ScopeID(), route)
+	return ss, p, errors.WithStack(err)
+}
+
+// checkIfMatch compares the If-Match header with the ETag of the current
+// value and writes the error response if the precondition fails.
+func (h *Handler) checkIfMatch(w http.ResponseWriter, r *http.Request, cur Value) bool {
+	im := r.Header.Get("If-Match")
+	switch {
+	case im == "" && h.o.RequireIfMatch:
+		h.writeError(w, r, http.StatusPreconditionRequired, errors.Empty.Newf("[config/admin] Header If-Match is required"))
+		return false
+	case im == "":
+		return true
+	case strings.TrimSpace(im) == "*" && cur.Value != nil:
+		return true
+	}
+	for _, tag := range strings.Split(im, ",") {
+		if strings.TrimSpace(tag) == cur.ETag {
+			return true
+		}
+	}
+	h.writeError(w, r, http.StatusPreconditionFailed, errors.Mismatch.Newf("[config/admin] Route %q has been modified, current ETag %s", cur.Route, cur.ETag))
+	return false
+}
+
+func (h *Handler) putValue(w http.ResponseWriter, r *http.Request, route string) {
+	defer r.Body.Close()
+
+	ss, p, err := h.prepareWrite(r, route)
+	if err != nil {
+		h.writeError(w, r, statusCode(err), err)
+		return
+	}
+
+	var wr writeRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, h.o.MaxRequestSize)).Decode(&wr); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, errors.BadEncoding.New(err, "[config/admin] Failed to decode the request body"))
+		return
+	}
+	if wr.Value == nil {
+		h.writeError(w, r, http.StatusBadRequest, errors.Empty.Newf("[config/admin] Field value is missing in the request body. Use DELETE to remove a value."))
+		return
+	}
+
+	h.muWrite.Lock()
+	defer h.muWrite.Unlock()
+
+	cur, err := h.value(ss, route)
+	if err != nil {
+		h.writeError(w, r, statusCode(err), err)
+		return
+	}
+	if !h.checkIfMatch(w, r, cur) {
+		return
+	}
+	if err := h.srv.Set(p, []byte(*wr.Value)); err != nil {
+		h.writeError(w, r, statusCode(err), errors.WithStack(err))
+		return
+	}
+	h.writeNewValue(w, r, ss, route)
+}
+
+func (h *Handler) deleteValue(w http.ResponseWriter, r *http.Request, route string) {
+	ss, p, err := h.prepareWrite(r, route)
+	if err != nil {
+		h.writeError(w, r, statusCode(err), err)
+		return
+	}
+
+	h.muWrite.Lock()
+	defer h.muWrite.Unlock()
+
+	cur, err := h.value(ss, route)
+	if err != nil {
+		h.writeError(w, r, statusCode(err), err)
+		return
+	}
+	if !h.checkIfMatch(w, r, cur) {
+		return
+	}
+	if err := h.srv.Delete(p); err != nil {
+		h.writeError(w, r, statusCode(err), errors.WithStack(err))
+		return
+	}
+	h.writeNewValue(w, r, ss, route)
+}
+
+// writeNewValue writes the effective value after a successful write or
+// delete. After a delete the value falls back to the parent scope.
+func (h *Handler) writeNewValue(w http.ResponseWriter, r *http.Request, ss config.Scoped, route string) {
+	v, err := h.value(ss, route)
+	if err != nil {
+		h.writeError(w, r, statusCode(err), err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, v.ETag, v)
+}
+
+// writeJSON writes the data and the ETag header. An empty etag gets
+// calculated from the encoded data. GET requests with a matching
+// If-None-Match header receive 304 Not Modified.
+func (h *Handler) writeJSON(w http.ResponseWriter, r *http.Request, code int, etag string, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, errors.WithStack(err))
+		return
+	}
+	if etag == "" {
+		hs := fnv.New64a()
+		_, _ = hs.Write(body)
+		etag = `"` + strconv.FormatUint(hs.Sum64(), 16) + `"`
+	}
+	hdr := w.Header()
+	hdr.Set("ETag", etag)
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	hdr.Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
+
+func (h *Handler) methodNotAllowed(w http.ResponseWriter, r *http.Request, allow string) {
+	w.Header().Set("Allow", allow)
+	h.writeError(w, r, http.StatusMethodNotAllowed, errors.NotSupported.Newf("[config/admin] Method %q not allowed for %q", r.Method, r.URL.Path))
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, code int, err error) {
+	if h.o.Log != nil && h.o.Log.IsInfo() {
+		h.o.Log.Info("config.admin.Handler.Error", log.Err(err), log.Int("status_code", code), log.String("method", r.Method), log.String("url", r.URL.String()))
+	}
+	h.o.ErrorHandler(code, err).ServeHTTP(w, r)
+}
+
+// statusCode maps the error kind to an HTTP status code.
+func statusCode(err error) int {
+	switch {
+	case errors.NotFound.Match(err):
+		return http.StatusNotFound
+	case errors.NotAllowed.Match(err), errors.Unauthorized.Match(err):
+		return http.StatusForbidden
+	case errors.NotImplemented.Match(err):
+		return http.StatusNotImplemented
+	case errors.NotValid.Match(err), errors.NotAcceptable.Match(err), errors.NotSupported.Match(err),
+		errors.OutOfRange.Match(err), errors.Empty.Match(err), errors.BadEncoding.Match(err), errors.Mismatch.Match(err):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func jsonError(code int, err error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(struct {
+			Status int    `json:"status"`
+			Error  string `json:"error"`
+		}{Status: code, Error: err.Error()})
+	})
+}
@@ -0,0 +1,307 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/admin"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+var testSections = config.MustMakeSectionsValidate(
+	&config.Section{
+		ID:        "web",
+		Label:     "Web",
+		SortOrder: 20,
+		Scopes:    scope.PermStore,
+		Groups: config.MakeGroups(
+			&config.Group{
+				ID:     "unsecure",
+				Label:  "Base URLs",
+				Scopes: scope.PermStore,
+				Fields: config.MakeFields(
+					&config.Field{
+						ID:        "base_url",
+						Type:      config.TypeText,
+						Label:     "Base URL",
+						SortOrder: 20,
+						Scopes:    scope.PermStore,
+						Default:   "http://shop.dev/",
+					},
+					&config.Field{
+						ID:        "base_static_url",
+						Type:      config.TypeText,
+						SortOrder: 30,
+						Scopes:    scope.PermStore,
+					},
+					&config.Field{
+						ID:        "base_link_url",
+						Type:      config.TypeText,
+						Label:     "Base Link URL",
+						SortOrder: 10,
+						Scopes:    scope.PermWebsite,
+					},
+				),
+			},
+		),
+	},
+	&config.Section{
+		ID:        "dev",
+		SortOrder: 10,
+		Scopes:    scope.PermDefault,
+		Groups: config.MakeGroups(
+			&config.Group{
+				ID: "js",
+				Fields: config.MakeFields(
+					&config.Field{
+						ID:      "merge_files",
+						Type:    config.TypeSelect,
+						Scopes:  scope.PermDefault,
+						Default: "0",
+					},
+				),
+			},
+		),
+	},
+)
+
+func newTestHandler(t *testing.T, o admin.Options) (*config.Service, *admin.Handler) {
+	srv := config.MustNewService(storage.NewMap(), config.Options{}, config.WithApplySections(testSections...))
+	o.Sections = testSections
+	h, err := admin.NewHandler(srv, o)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return srv, h
+}
+
+func serve(h http.Handler, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body != "" {
+		req = httptest.NewRequest(method, target, strings.NewReader(body))
+	} else {
+		req = httptest.NewRequest(method, target, nil)
+	}
+	for i := 0; i < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeValue(t *testing.T, rec *httptest.ResponseRecorder) admin.Value {
+	var v admin.Value
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("%s: %+v", rec.Body.String(), err)
+	}
+	assert.Exactly(t, rec.Header().Get("ETag"), v.ETag)
+	return v
+}
+
+func TestNewHandler(t *testing.T) {
+	_, err := admin.NewHandler(nil, admin.Options{Sections: testSections})
+	assert.True(t, errors.Empty.Match(err), "%+v", err)
+
+	_, err = admin.NewHandler(config.MustNewService(storage.NewMap(), config.Options{}), admin.Options{})
+	assert.True(t, errors.Empty.Match(err), "%+v", err)
+}
+
+func TestHandler_Sections(t *testing.T) {
+	_, h := newTestHandler(t, admin.Options{})
+
+	t.Run("default scope sorted", func(t *testing.T) {
+		rec := serve(h, "GET", "/sections", "")
+		assert.Exactly(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var secs []admin.Section
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &secs))
+		assert.Len(t, secs, 2)
+		assert.Exactly(t, "dev", secs[0].ID)
+		assert.Exactly(t, "web", secs[1].ID)
+		fields := secs[1].Groups[0].Fields
+		assert.Exactly(t, "base_link_url", fields[0].ID)
+		assert.Exactly(t, "base_url", fields[1].ID)
+		assert.Exactly(t, "http://shop.dev/", *fields[1].Value.Value)
+		assert.True(t, fields[1].IsDefault, "base_url must be a default value")
+		assert.Nil(t, fields[0].Value.Value)
+
+		etag := rec.Header().Get("ETag")
+		rec = serve(h, "GET", "/sections", "", "If-None-Match", etag)
+		assert.Exactly(t, http.StatusNotModified, rec.Code)
+	})
+
+	t.Run("store scope hides fields", func(t *testing.T) {
+		rec := serve(h, "GET", "/sections/web?website=1&store=2", "")
+		assert.Exactly(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var sec admin.Section
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sec))
+		assert.Exactly(t, "web", sec.ID)
+		assert.Len(t, sec.Groups[0].Fields, 2)
+		assert.Exactly(t, "base_url", sec.Groups[0].Fields[0].ID)
+		assert.Exactly(t, "base_static_url", sec.Groups[0].Fields[1].ID)
+		assert.Exactly(t, "stores/2", sec.Groups[0].Fields[0].Scope)
+	})
+
+	t.Run("section not visible in scope", func(t *testing.T) {
+		rec := serve(h, "GET", "/sections/dev?website=1", "")
+		assert.Exactly(t, http.StatusNotFound, rec.Code, rec.Body.String())
+	})
+
+	t.Run("store without website", func(t *testing.T) {
+		rec := serve(h, "GET", "/sections?store=2", "")
+		assert.Exactly(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := serve(h, "POST", "/sections", "")
+		assert.Exactly(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Exactly(t, "GET, HEAD", rec.Header().Get("Allow"))
+	})
+
+	t.Run("unknown resource", func(t *testing.T) {
+		rec := serve(h, "GET", "/stores", "")
+		assert.Exactly(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestHandler_Values(t *testing.T) {
+	srv, h := newTestHandler(t, admin.Options{})
+	const route = "/values/web/unsecure/base_url"
+
+	rec := serve(h, "GET", route+"?website=1", "")
+	assert.Exactly(t, http.StatusOK, rec.Code, rec.Body.String())
+	v := decodeValue(t, rec)
+	assert.Exactly(t, "websites/1", v.Scope)
+	assert.True(t, v.IsDefault)
+
+	t.Run("write with If-Match", func(t *testing.T) {
+		rec := serve(h, "PUT", route+"?website=1", `{"value":"http://ws1.dev/"}`, "If-Match", v.ETag)
+		assert.Exactly(t, http.StatusOK, rec.Code, rec.Body.String())
+		nv := decodeValue(t, rec)
+		assert.Exactly(t, "http://ws1.dev/", *nv.Value)
+		assert.Exactly(t, "websites/1", nv.Origin)
+		assert.False(t, nv.IsDefault)
+		assert.NotEqual(t, v.ETag, nv.ETag)
+
+		assert.Exactly(t, `"http://ws1.dev/"`, srv.Get(config.MustNewPath("web/unsecure/base_url").BindWebsite(1)).String())
+	})
+
+	t.Run("store inherits from website", func(t *testing.T) {
+		assert.NoError(t, srv.Set(config.MustNewPath("web/unsecure/base_static_url").BindWebsite(1), []byte(`http://static.dev/`)))
+		rec := serve(h, "GET", "/values/web/unsecure/base_static_url?website=1&store=2", "")
+		assert.Exactly(t, http.StatusOK, rec.Code, rec.Body.String())
+		sv := decodeValue(t, rec)
+		assert.Exactly(t, "stores/2", sv.Scope)
+		assert.Exactly(t, "websites/1", sv.Origin)
+		assert.Exactly(t, "http://static.dev/", *sv.Value)
+
+		rec = serve(h, "GET", "/values/web/unsecure/base_static_url", "")
+		assert.Nil(t, decodeValue(t, rec).Value)
+	})
+
+	t.Run("stale If-Match", func(t *testing.T) {
+		rec := serve(h, "PUT", route+"?website=1", `{"value":"http://lost.dev/"}`, "If-Match", v.ETag)
+		assert.Exactly(t, http.StatusPreconditionFailed, rec.Code, rec.Body.String())
+		assert.Exactly(t, `"http://ws1.dev/"`, srv.Get(config.MustNewPath("web/unsecure/base_url").BindWebsite(1)).String())
+	})
+
+	t.Run("If-None-Match", func(t *testing.T) {
+		rec := serve(h, "GET", route+"?website=1", "")
+		rec = serve(h, "GET", route+"?website=1", "", "If-None-Match", rec.Header().Get("ETag"))
+		assert.Exactly(t, http.StatusNotModified, rec.Code)
+	})
+
+	t.Run("scope not permitted", func(t *testing.T) {
+		rec := serve(h, "PUT", "/values/web/unsecure/base_link_url?website=1&store=2", `{"value":"x"}`)
+		assert.Exactly(t, http.StatusForbidden, rec.Code, rec.Body.String())
+		rec = serve(h, "DELETE", "/values/dev/js/merge_files?website=1", "")
+		assert.Exactly(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		rec := serve(h, "PUT", "/values/web/unsecure/xxx", `{"value":"x"}`)
+		assert.Exactly(t, http.StatusNotFound, rec.Code, rec.Body.String())
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		rec := serve(h, "PUT", route, `{"value":`)
+		assert.Exactly(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		rec = serve(h, "PUT", route, `{}`)
+		assert.Exactly(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	})
+
+	t.Run("validation by observer", func(t *testing.T) {
+		assert.NoError(t, srv.RegisterObserver(config.EventOnBeforeSet, "web/unsecure/base_url", observerFunc(func(p config.Path, rawData []byte, found bool) ([]byte, error) {
+			if !strings.HasPrefix(string(rawData), "https://") {
+				return nil, errors.NotValid.Newf("URL must start with https://")
+			}
+			return rawData, nil
+		})))
+		defer func() { assert.NoError(t, srv.DeregisterObserver(config.EventOnBeforeSet, "web/unsecure/base_url")) }()
+
+		rec := serve(h, "PUT", route+"?website=1&store=2", `{"value":"http://store2.dev/"}`)
+		assert.Exactly(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		assert.Contains(t, rec.Body.String(), "URL must start with https://")
+
+		rec = serve(h, "PUT", route+"?website=1&store=2", `{"value":"https://store2.dev/"}`)
+		assert.Exactly(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Exactly(t, "stores/2", decodeValue(t, rec).Origin)
+	})
+
+	t.Run("delete falls back to parent scope", func(t *testing.T) {
+		rec := serve(h, "DELETE", route+"?website=1", "", "If-Match", "*")
+		assert.Exactly(t, http.StatusOK, rec.Code, rec.Body.String())
+		dv := decodeValue(t, rec)
+		assert.Exactly(t, "http://shop.dev/", *dv.Value)
+		assert.True(t, dv.IsDefault)
+
+		rec = serve(h, "GET", route+"?website=1&store=2", "")
+		assert.Exactly(t, "https://store2.dev/", *decodeValue(t, rec).Value)
+	})
+}
+
+func TestHandler_RequireIfMatch(t *testing.T) {
+	_, h := newTestHandler(t, admin.Options{RequireIfMatch: true})
+
+	rec := serve(h, "PUT", "/values/dev/js/merge_files", `{"value":"1"}`)
+	assert.Exactly(t, http.StatusPreconditionRequired, rec.Code, rec.Body.String())
+
+	rec = serve(h, "GET", "/values/dev/js/merge_files", "")
+	rec = serve(h, "PUT", "/values/dev/js/merge_files", `{"value":"1"}`, "If-Match", rec.Header().Get("ETag"))
+	assert.Exactly(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+type observerFunc func(p config.Path, rawData []byte, found bool) ([]byte, error)
+
+func (fn observerFunc) Observe(p config.Path, rawData []byte, found bool) ([]byte, error) {
+	return fn(p, rawData, found)
+}
@@ -0,0 +1,116 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/corestoreio/errors"
+	"github.com/corestoreio/log"
+)
+
+// BridgeMessage gets sent between processes when a configuration value has
+// been written or deleted.
+type BridgeMessage struct {
+	// NodeID identifies the sending process, see Options.BridgeNodeID.
+	NodeID string `json:"node_id"`
+	// Path contains the fully qualified path including the environment
+	// suffix.
+	Path string `json:"path"`
+}
+
+// Bridge transports change notifications of configuration values between
+// processes, for example between the pods of a deployment. Package
+// config/bridge provides in-memory and socket based implementations. Other
+// implementations can use a message broker.
+type Bridge interface {
+	// Publish sends the message to all other processes.
+	Publish(BridgeMessage) error
+	// Listen calls fn for each received message until the Bridge gets
+	// closed. Listen gets called once by NewService and must not block.
+	Listen(fn func(BridgeMessage)) error
+	// Close terminates the connection.
+	Close() error
+}
+
+// setupBridge starts listening for messages of other processes.
+func (s *Service) setupBridge() error {
+	if s.config.Bridge == nil {
+		return nil
+	}
+	s.bridgeNodeID = s.config.BridgeNodeID
+	if s.bridgeNodeID == "" {
+		var id [8]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return errors.WithStack(err)
+		}
+		s.bridgeNodeID = hex.EncodeToString(id[:])
+	}
+	if err := s.config.Bridge.Listen(s.receiveBridgeMessage); err != nil {
+		return errors.WithStack(err)
+	}
+	s.RegisterCloser(s.config.Bridge)
+	return nil
+}
+
+// publish notifies the local subscribers and the other processes about a
+// changed path.
+func (s *Service) publish(p Path) {
+	if s.pubSub != nil {
+		s.pubSub.sendMsg(p)
+	}
+	if s.bridgeNodeID == "" {
+		return // no Bridge or the initial data loading by NewService
+	}
+	fq, err := p.FQ()
+	if err == nil {
+		err = s.config.Bridge.Publish(BridgeMessage{NodeID: s.bridgeNodeID, Path: fq})
+	}
+	if err != nil && s.Log != nil && s.Log.IsInfo() {
+		s.Log.Info("config.Service.publish.Bridge", log.Err(err), log.Stringer("path", &p))
+	}
+}
+
+// receiveBridgeMessage invalidates the Level1 cache and notifies the local
+// subscribers about a path changed by another process.
+func (s *Service) receiveBridgeMessage(msg BridgeMessage) {
+	if msg.NodeID == s.bridgeNodeID {
+		return
+	}
+	var p Path
+	if err := p.Parse(msg.Path); err != nil {
+		if s.Log != nil && s.Log.IsInfo() {
+			s.Log.Info("config.Service.receiveBridgeMessage.Parse", log.Err(err), log.String("path", msg.Path), log.String("node_id", msg.NodeID))
+		}
+		return
+	}
+	if s.config.Level1 != nil {
+		s.mu.RLock()
+		err := s.config.Level1.Delete(&p)
+		s.mu.RUnlock()
+		if err != nil && s.Log != nil && s.Log.IsInfo() {
+			s.Log.Info("config.Service.receiveBridgeMessage.Level1.Delete", log.Err(err), log.Stringer("path", &p))
+		}
+	}
+	if s.pubSub != nil {
+		s.pubSub.sendMsg(p)
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bridge_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/corestoreio/errors"
+	"github.com/fortytw2/leaktest"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/bridge"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+type msgReceiver func(p config.Path) error
+
+func (mr msgReceiver) MessageConfig(p config.Path) error { return mr(p) }
+
+// newNode creates a config.Service sharing the Level2 storage with the other
+// nodes but having its own Level1 cache.
+func newNode(t *testing.T, level2 config.Storager, b config.Bridge, nodeID string) (*config.Service, chan string) {
+	srv, err := config.NewService(level2, config.Options{
+		Level1:       storage.NewMap(),
+		EnablePubSub: true,
+		Bridge:       b,
+		BridgeNodeID: nodeID,
+	})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	msgC := make(chan string, 10)
+	_, err = srv.Subscribe("websites/2/aa/bb", msgReceiver(func(p config.Path) error {
+		msgC <- p.String()
+		return nil
+	}))
+	assert.NoError(t, err)
+	return srv, msgC
+}
+
+func receive(t *testing.T, msgC chan string) string {
+	select {
+	case fq := <-msgC:
+		return fq
+	case <-time.After(time.Second):
+		t.Fatal("message has not been received")
+	}
+	return ""
+}
+
+// waitConnected sets path p on node A until node B receives the message.
+func waitConnected(t *testing.T, srvA *config.Service, msgA, msgB chan string, p config.Path) {
+	deadline := time.Now().Add(time.Second)
+	connected := false
+	for !connected && time.Now().Before(deadline) {
+		assert.NoError(t, srvA.Set(p, []byte("1")))
+		assert.Exactly(t, "websites/2/aa/bb/cc", receive(t, msgA))
+		select {
+		case fq := <-msgB:
+			assert.Exactly(t, "websites/2/aa/bb/cc", fq)
+			connected = true
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	assert.True(t, connected, "Node B has not been connected")
+}
+
+// testNodes checks that a change on node A invalidates the Level1 cache of
+// node B and notifies the subscribers of B.
+func testNodes(t *testing.T, bridgeA, bridgeB config.Bridge) {
+	level2 := storage.NewMap()
+	srvA, msgA := newNode(t, level2, bridgeA, "a")
+	srvB, msgB := newNode(t, level2, bridgeB, "b")
+
+	p := config.MustNewPath("aa/bb/cc").BindWebsite(2)
+	waitConnected(t, srvA, msgA, msgB, p)
+
+	assert.Exactly(t, `"1"`, srvB.Get(p).String()) // cached in Level1 of B
+
+	assert.NoError(t, srvA.Set(p, []byte("2")))
+	assert.Exactly(t, "websites/2/aa/bb/cc", receive(t, msgA))
+	assert.Exactly(t, "websites/2/aa/bb/cc", receive(t, msgB))
+	assert.Exactly(t, `"2"`, srvB.Get(p).String())
+
+	assert.NoError(t, srvB.Delete(p))
+	assert.Exactly(t, "websites/2/aa/bb/cc", receive(t, msgB))
+	assert.Exactly(t, "websites/2/aa/bb/cc", receive(t, msgA))
+	assert.False(t, srvA.Get(p).IsValid(), "Path must be deleted")
+
+	assert.NoError(t, srvA.Close())
+	assert.NoError(t, srvB.Close())
+}
+
+func TestHub(t *testing.T) {
+	defer leaktest.CheckTimeout(t, time.Second)()
+	hub := bridge.NewHub()
+	testNodes(t, hub.Join(), hub.Join())
+}
+
+func TestRelay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config_bridge")
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dir)) }()
+
+	tests := []struct {
+		network string
+		address string
+	}{
+		{"tcp", "127.0.0.1:0"},
+		{"unix", filepath.Join(dir, "relay.sock")},
+	}
+	for _, test := range tests {
+		t.Run(test.network, func(t *testing.T) {
+			defer leaktest.CheckTimeout(t, time.Second)()
+
+			r, err := bridge.Listen(test.network, test.address)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			bA, err := bridge.Dial(test.network, r.Addr().String(), bridge.DialOptions{})
+			assert.NoError(t, err)
+			bB, err := bridge.Dial(test.network, r.Addr().String(), bridge.DialOptions{})
+			assert.NoError(t, err)
+
+			testNodes(t, bA, bB)
+			assert.NoError(t, r.Close())
+		})
+	}
+}
+
+func TestRelay_Reconnect(t *testing.T) {
+	defer leaktest.CheckTimeout(t, 2*time.Second)()
+
+	r, err := bridge.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	addr := r.Addr().String()
+
+	errC := make(chan error, 10)
+	do := bridge.DialOptions{
+		RetryInterval: 10 * time.Millisecond,
+		ErrorHandler: func(err error) {
+			select {
+			case errC <- err:
+			default:
+			}
+		},
+	}
+	bA, err := bridge.Dial("tcp", addr, do)
+	assert.NoError(t, err)
+	bB, err := bridge.Dial("tcp", addr, do)
+	assert.NoError(t, err)
+
+	level2 := storage.NewMap()
+	srvA, msgA := newNode(t, level2, bA, "a")
+	srvB, msgB := newNode(t, level2, bB, "b")
+	p := config.MustNewPath("aa/bb/cc").BindWebsite(2)
+	waitConnected(t, srvA, msgA, msgB, p)
+
+	assert.NoError(t, r.Close()) // drops the connections of both nodes
+	select {
+	case err := <-errC:
+		assert.True(t, errors.ConnectionLost.Match(err), "%+v", err)
+	case <-time.After(time.Second):
+		t.Fatal("lost connection has not been reported")
+	}
+
+	r, err = bridge.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	waitConnected(t, srvA, msgA, msgB, p)
+
+	assert.NoError(t, srvA.Close())
+	assert.NoError(t, srvB.Close())
+	assert.NoError(t, r.Close())
+}
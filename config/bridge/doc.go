@@ -0,0 +1,39 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bridge provides transports for config.Bridge to distribute change
+// notifications of configuration values between processes.
+//
+// A Hub connects several config.Service within the same process, mainly for
+// testing. A Relay accepts TCP or Unix socket connections and forwards each
+// message to all other connections. Each process connects via Dial. Messages
+// are encoded as one JSON object per line. A lost connection gets reported to
+// DialOptions.ErrorHandler and re-established. Lost messages only delay the
+// Level1 cache invalidation and the notifications, the Level2 storage stays
+// the source of truth.
+//
+// Example to run the relay in one process and connect the config.Service of
+// all processes:
+//		r, err := bridge.Listen("tcp", ":7070")
+//		b, err := bridge.Dial("tcp", "relay.internal:7070", bridge.DialOptions{})
+//		cfgSrv, err := config.NewService(level2, config.Options{
+//			EnablePubSub: true,
+//			Bridge:       b,
+//		})
+package bridge
@@ -0,0 +1,121 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bridge
+
+import (
+	"sync"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+)
+
+// Hub connects the bridges created via Join within the same process. Each
+// published message gets delivered asynchronously to all other bridges of the
+// Hub. A bridge buffers up to 64 messages, further messages get dropped until
+// the listener catches up.
+type Hub struct {
+	mu      sync.RWMutex
+	members map[*hubBridge]struct{}
+}
+
+// NewHub creates a new in-memory Hub.
+func NewHub() *Hub {
+	return &Hub{
+		members: make(map[*hubBridge]struct{}),
+	}
+}
+
+// Join creates a new config.Bridge connected to the Hub.
+func (h *Hub) Join() config.Bridge {
+	hb := &hubBridge{
+		hub:  h,
+		msgs: make(chan config.BridgeMessage, 64),
+		done: make(chan struct{}),
+	}
+	h.mu.Lock()
+	h.members[hb] = struct{}{}
+	h.mu.Unlock()
+	return hb
+}
+
+func (h *Hub) publish(from *hubBridge, msg config.BridgeMessage) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if from.closed {
+		return errors.AlreadyClosed.Newf("[config/bridge] Hub bridge already closed")
+	}
+	for hb := range h.members {
+		if hb == from {
+			continue
+		}
+		select {
+		case hb.msgs <- msg:
+		default: // slow listener, the message gets dropped
+		}
+	}
+	return nil
+}
+
+// hubBridge fields are protected by the mutex of the Hub.
+type hubBridge struct {
+	hub       *Hub
+	msgs      chan config.BridgeMessage
+	listening bool
+	closed    bool
+	done      chan struct{}
+}
+
+func (hb *hubBridge) Publish(msg config.BridgeMessage) error {
+	return hb.hub.publish(hb, msg)
+}
+
+func (hb *hubBridge) Listen(fn func(config.BridgeMessage)) error {
+	hb.hub.mu.Lock()
+	defer hb.hub.mu.Unlock()
+	if hb.listening || hb.closed {
+		return errors.NotSupported.Newf("[config/bridge] Hub bridge already listening or closed")
+	}
+	hb.listening = true
+	go func() {
+		defer close(hb.done)
+		for msg := range hb.msgs {
+			fn(msg)
+		}
+	}()
+	return nil
+}
+
+func (hb *hubBridge) Close() error {
+	hb.hub.mu.Lock()
+	if hb.closed {
+		hb.hub.mu.Unlock()
+		return errors.AlreadyClosed.Newf("[config/bridge] Hub bridge already closed")
+	}
+	hb.closed = true
+	delete(hb.hub.members, hb)
+	close(hb.msgs)
+	listening := hb.listening
+	hb.hub.mu.Unlock()
+	if listening {
+		<-hb.done
+	}
+	return nil
+}
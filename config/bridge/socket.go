@@ -0,0 +1,324 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bridge
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+)
+
+// Timeouts of the socket based transport.
+const (
+	DialTimeout  = 5 * time.Second
+	WriteTimeout = 5 * time.Second
+)
+
+// Default pauses between the attempts to reconnect to a Relay, see
+// DialOptions.
+const (
+	DefaultRetryInterval    = 100 * time.Millisecond
+	DefaultMaxRetryInterval = 30 * time.Second
+)
+
+// maxMessageSize limits the length of one encoded message.
+const maxMessageSize = 64 * 1024
+
+// Relay accepts TCP or Unix socket connections and forwards each received
+// message to all other connections. A connection which cannot receive a
+// message within WriteTimeout gets closed.
+type Relay struct {
+	ln net.Listener
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// Listen starts a new Relay on the network address. Network can be "tcp",
+// "tcp4", "tcp6" or "unix".
+func Listen(network, address string) (*Relay, error) {
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, errors.ConnectionFailed.New(err, "[config/bridge] Listen on %s %q", network, address)
+	}
+	r := &Relay{
+		ln:    ln,
+		conns: make(map[net.Conn]struct{}),
+	}
+	r.wg.Add(1)
+	go r.serve()
+	return r, nil
+}
+
+// Addr returns the address of the listener, useful when listening on port 0.
+func (r *Relay) Addr() net.Addr {
+	return r.ln.Addr()
+}
+
+func (r *Relay) serve() {
+	defer r.wg.Done()
+	for {
+		c, err := r.ln.Accept()
+		if err != nil {
+			return // listener closed
+		}
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			_ = c.Close()
+			return
+		}
+		r.conns[c] = struct{}{}
+		r.wg.Add(1)
+		r.mu.Unlock()
+		go r.handle(c)
+	}
+}
+
+func (r *Relay) handle(c net.Conn) {
+	defer r.wg.Done()
+	defer r.drop(c)
+	sc := bufio.NewScanner(c)
+	sc.Buffer(make([]byte, 0, 4096), maxMessageSize)
+	for sc.Scan() {
+		line := make([]byte, 0, len(sc.Bytes())+1)
+		line = append(line, sc.Bytes()...)
+		r.broadcast(c, append(line, '\n'))
+	}
+}
+
+func (r *Relay) broadcast(from net.Conn, line []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for c := range r.conns {
+		if c == from {
+			continue
+		}
+		_ = c.SetWriteDeadline(time.Now().Add(WriteTimeout))
+		if _, err := c.Write(line); err != nil {
+			_ = c.Close() // the reading goroutine removes it
+		}
+	}
+}
+
+func (r *Relay) drop(c net.Conn) {
+	r.mu.Lock()
+	delete(r.conns, c)
+	r.mu.Unlock()
+	_ = c.Close()
+}
+
+// Close stops accepting new connections, closes all connections and waits
+// until all goroutines have been terminated.
+func (r *Relay) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return errors.AlreadyClosed.Newf("[config/bridge] Relay already closed")
+	}
+	r.closed = true
+	err := r.ln.Close()
+	for c := range r.conns {
+		_ = c.Close()
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	return errors.WithStack(err)
+}
+
+// DialOptions configures the connection of Dial.
+type DialOptions struct {
+	// RetryInterval defines the pause before the first attempt to reconnect
+	// to the Relay. The pause gets doubled after each failed attempt up to
+	// MaxRetryInterval. Defaults to DefaultRetryInterval.
+	RetryInterval time.Duration
+	// MaxRetryInterval limits the pause between two attempts to reconnect.
+	// Defaults to DefaultMaxRetryInterval.
+	MaxRetryInterval time.Duration
+	// ErrorHandler gets called if the connection to the Relay gets lost or
+	// an attempt to reconnect fails. Optional.
+	ErrorHandler func(error)
+}
+
+// socketBridge implements config.Bridge for a connection to a Relay.
+type socketBridge struct {
+	network string
+	address string
+	o       DialOptions
+
+	mu        sync.Mutex // protects the writes and the fields below
+	conn      net.Conn
+	listening bool
+	closed    bool
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// Dial connects to a Relay and returns the config.Bridge for
+// config.Options.Bridge. Once listening, a lost connection gets reported to
+// DialOptions.ErrorHandler and re-established with an increasing pause
+// between the attempts. Messages sent while disconnected are lost.
+func Dial(network, address string, o DialOptions) (config.Bridge, error) {
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = DefaultRetryInterval
+	}
+	if o.MaxRetryInterval <= 0 {
+		o.MaxRetryInterval = DefaultMaxRetryInterval
+	}
+	if o.MaxRetryInterval < o.RetryInterval {
+		o.MaxRetryInterval = o.RetryInterval
+	}
+	c, err := net.DialTimeout(network, address, DialTimeout)
+	if err != nil {
+		return nil, errors.ConnectionFailed.New(err, "[config/bridge] Dial %s %q", network, address)
+	}
+	return &socketBridge{
+		network: network,
+		address: address,
+		o:       o,
+		conn:    c,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}, nil
+}
+
+func (sb *socketBridge) Publish(msg config.BridgeMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	data = append(data, '\n')
+
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	if sb.closed {
+		return errors.AlreadyClosed.Newf("[config/bridge] Connection already closed")
+	}
+	_ = sb.conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	if _, err := sb.conn.Write(data); err != nil {
+		return errors.ConnectionLost.New(err, "[config/bridge] Failed to publish message for path %q", msg.Path)
+	}
+	return nil
+}
+
+func (sb *socketBridge) Listen(fn func(config.BridgeMessage)) error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	if sb.listening || sb.closed {
+		return errors.NotSupported.Newf("[config/bridge] Connection already listening or closed")
+	}
+	sb.listening = true
+	go sb.read(sb.conn, fn)
+	return nil
+}
+
+// read calls fn for each message received from connection c and reconnects
+// if the connection gets lost.
+func (sb *socketBridge) read(c net.Conn, fn func(config.BridgeMessage)) {
+	defer close(sb.done)
+	for c != nil {
+		sc := bufio.NewScanner(c)
+		sc.Buffer(make([]byte, 0, 4096), maxMessageSize)
+		for sc.Scan() {
+			var msg config.BridgeMessage
+			if err := json.Unmarshal(sc.Bytes(), &msg); err == nil {
+				fn(msg)
+			}
+		}
+		if sb.isClosed() {
+			return
+		}
+		err := sc.Err()
+		if err == nil {
+			err = io.EOF
+		}
+		sb.handleError(errors.ConnectionLost.New(err, "[config/bridge] Lost connection to %s %q", sb.network, sb.address))
+		c = sb.reconnect()
+	}
+}
+
+// reconnect dials the Relay until it succeeds or the bridge gets closed. It
+// returns nil if the bridge has been closed.
+func (sb *socketBridge) reconnect() net.Conn {
+	pause := sb.o.RetryInterval
+	for {
+		select {
+		case <-sb.stop:
+			return nil
+		case <-time.After(pause):
+		}
+		c, err := net.DialTimeout(sb.network, sb.address, DialTimeout)
+		if err != nil {
+			sb.handleError(errors.ConnectionFailed.New(err, "[config/bridge] Reconnect to %s %q", sb.network, sb.address))
+			if pause *= 2; pause > sb.o.MaxRetryInterval {
+				pause = sb.o.MaxRetryInterval
+			}
+			continue
+		}
+		sb.mu.Lock()
+		if sb.closed {
+			sb.mu.Unlock()
+			_ = c.Close()
+			return nil
+		}
+		_ = sb.conn.Close()
+		sb.conn = c
+		sb.mu.Unlock()
+		return c
+	}
+}
+
+func (sb *socketBridge) isClosed() bool {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.closed
+}
+
+func (sb *socketBridge) handleError(err error) {
+	if sb.o.ErrorHandler != nil {
+		sb.o.ErrorHandler(err)
+	}
+}
+
+func (sb *socketBridge) Close() error {
+	sb.mu.Lock()
+	if sb.closed {
+		sb.mu.Unlock()
+		return errors.AlreadyClosed.Newf("[config/bridge] Connection already closed")
+	}
+	sb.closed = true
+	close(sb.stop)
+	err := sb.conn.Close()
+	listening := sb.listening
+	sb.mu.Unlock()
+	if listening {
+		<-sb.done
+	}
+	return errors.WithStack(err)
+}
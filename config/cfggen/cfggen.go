@@ -0,0 +1,406 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfggen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/strs"
+)
+
+// Supported Go types of the generated accessors. They can be used in
+// Accessors.GoTypes.
+const (
+	GoTypeString   = "string"
+	GoTypeStrings  = "[]string"
+	GoTypeBool     = "bool"
+	GoTypeInt64    = "int64"
+	GoTypeFloat64  = "float64"
+	GoTypeTime     = "time.Time"
+	GoTypeDuration = "time.Duration"
+)
+
+// goTypeMethods maps a Go type to the conversion method of *config.Value and
+// to its zero value.
+var goTypeMethods = map[string][2]string{
+	GoTypeString:   {"Str", `""`},
+	GoTypeStrings:  {"Strs", "nil"},
+	GoTypeBool:     {"Bool", "false"},
+	GoTypeInt64:    {"Int64", "0"},
+	GoTypeFloat64:  {"Float64", "0"},
+	GoTypeTime:     {"Time", "time.Time{}"},
+	GoTypeDuration: {"Duration", "0"},
+}
+
+// Accessors generates Go source code with typed accessors for the fields of
+// config.Sections. Once created with NewAccessors the exported fields can be
+// adjusted before calling WriteGo.
+type Accessors struct {
+	// Package name of the generated file.
+	Package string
+	// TypeName of the generated empty struct type which provides the
+	// accessor methods. Defaults to Accessors.
+	TypeName string
+	// VarName of the generated package variable of type TypeName. Defaults
+	// to Config.
+	VarName string
+	// GoTypes overwrites the automatically detected Go type of a field. The
+	// key is the route of the field and the value one of the GoType*
+	// constants.
+	GoTypes map[string]string
+	// DisableFileHeader does not write the package clause and the imports.
+	DisableFileHeader bool
+	sections          config.Sections
+}
+
+// NewAccessors creates a new typed accessor code generator for validated
+// sections.
+func NewAccessors(packageName string, sections config.Sections) (*Accessors, error) {
+	if packageName == "" {
+		return nil, errors.Empty.Newf("[cfggen] NewAccessors: Package name cannot be empty")
+	}
+	if err := sections.Validate(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Accessors{
+		Package:  packageName,
+		TypeName: "Accessors",
+		VarName:  "Config",
+		GoTypes:  make(map[string]string),
+		sections: sections,
+	}, nil
+}
+
+// accessor contains the data to render one field.
+type accessor struct {
+	Name       string
+	RouteConst string
+	Route      string
+	Label      string
+	GoType     string
+	Method     string
+	Zero       string
+	Default    string // Go literal, empty if there is no default value
+	Scope      string
+	// Receiver suffix of the generated type which provides the accessor:
+	// Default, Website or Store.
+	Receiver string
+	// ScopedArgs contains the arguments for config.Scoper.Scoped.
+	ScopedArgs string
+}
+
+// receivers maps the top scope to the receiver type suffix and the arguments
+// of config.Scoper.Scoped. Fields without scope restriction need a store.
+var receivers = map[string][2]string{
+	"scope.Default": {"Default", "0, 0"},
+	"scope.Website": {"Website", "a.websiteID, 0"},
+	"scope.Store":   {"Store", "a.websiteID, a.storeID"},
+	"scope.Absent":  {"Store", "a.websiteID, a.storeID"},
+}
+
+// detectGoType derives the Go type from the field type and the default value.
+// A select field with default 0 or 1 is considered a yes/no select.
+func detectGoType(f *config.Field) string {
+	switch f.Type {
+	case config.TypeMultiselect:
+		return GoTypeStrings
+	case config.TypeTime:
+		return GoTypeTime
+	case config.TypeDuration:
+		return GoTypeDuration
+	case config.TypeSelect:
+		if f.Default == "0" || f.Default == "1" {
+			return GoTypeBool
+		}
+	}
+	d := f.Default
+	switch {
+	case d == "":
+		return GoTypeString
+	case d == "true" || d == "false":
+		return GoTypeBool
+	}
+	if _, err := strconv.ParseInt(d, 10, 64); err == nil {
+		return GoTypeInt64
+	}
+	if _, err := strconv.ParseFloat(d, 64); err == nil {
+		return GoTypeFloat64
+	}
+	return GoTypeString
+}
+
+// defaultLiteral converts the default value into a Go literal of type goType.
+// Returns an empty string if there is no default.
+func defaultLiteral(goType, d string) (string, error) {
+	if d == "" {
+		return "", nil
+	}
+	switch goType {
+	case GoTypeString:
+		return strconv.Quote(d), nil
+	case GoTypeStrings:
+		parts := strings.Split(d, ",")
+		for i, p := range parts {
+			parts[i] = strconv.Quote(p)
+		}
+		return "[]string{" + strings.Join(parts, ", ") + "}", nil
+	case GoTypeBool:
+		b, err := strconv.ParseBool(d)
+		return strconv.FormatBool(b), errors.WithStack(err)
+	case GoTypeInt64:
+		i, err := strconv.ParseInt(d, 10, 64)
+		return strconv.FormatInt(i, 10), errors.WithStack(err)
+	case GoTypeFloat64:
+		f, err := strconv.ParseFloat(d, 64)
+		return strconv.FormatFloat(f, 'g', -1, 64), errors.WithStack(err)
+	case GoTypeDuration:
+		dur, err := time.ParseDuration(d)
+		return fmt.Sprintf("time.Duration(%d)", dur), errors.WithStack(err)
+	case GoTypeTime:
+		// The config.Service applies the default value of the FieldMeta.
+		return "", nil
+	}
+	return "", errors.NotSupported.Newf("[cfggen] Go type %q not supported", goType)
+}
+
+// topScope returns the scope up to which the value gets looked up. The first
+// non empty Perm of the field, group or section gets used. Custom scope types
+// registered with scope.RegisterType are not supported because they have no
+// identifier in package scope.
+func topScope(perms ...scope.Perm) (string, error) {
+	for _, p := range perms {
+		if p > 0 {
+			top := p.Top()
+			if top.IsCustom() {
+				return "", errors.NotSupported.Newf("[cfggen] Custom scope %s not supported", top)
+			}
+			return "scope." + top.String(), nil
+		}
+	}
+	return "scope.Absent", nil
+}
+
+func (ag *Accessors) accessors() ([]accessor, error) {
+	var acs []accessor
+	names := make(map[string]string)
+	for _, s := range ag.sections {
+		for _, g := range s.Groups {
+			for _, f := range g.Fields {
+				route := f.ConfigRoute
+				if route == "" {
+					route = s.ID + "/" + g.ID + "/" + f.ID
+				}
+				name := strs.ToGoCamelCase(g.ID) + strs.ToGoCamelCase(f.ID)
+				if len(ag.sections) > 1 {
+					name = strs.ToGoCamelCase(s.ID) + name
+				}
+				if other, ok := names[name]; ok {
+					return nil, errors.Duplicated.Newf("[cfggen] Method name %q for route %q already generated for route %q", name, route, other)
+				}
+				names[name] = route
+
+				goType := detectGoType(f)
+				if gt, ok := ag.GoTypes[route]; ok {
+					goType = gt
+				}
+				m, ok := goTypeMethods[goType]
+				if !ok {
+					return nil, errors.NotSupported.Newf("[cfggen] Go type %q of route %q not supported", goType, route)
+				}
+				dl, err := defaultLiteral(goType, f.Default)
+				if err != nil {
+					return nil, errors.NotValid.New(err, "[cfggen] Default value %q of route %q cannot be converted to %s", f.Default, route, goType)
+				}
+				scp, err := topScope(f.Scopes, g.Scopes, s.Scopes)
+				if err != nil {
+					return nil, errors.Wrapf(err, "[cfggen] Route %q", route)
+				}
+				acs = append(acs, accessor{
+					Name:       name,
+					RouteConst: "Route" + name,
+					Route:      route,
+					Label:      strings.TrimSpace(f.Label),
+					GoType:     goType,
+					Method:     m[0],
+					Zero:       m[1],
+					Default:    dl,
+					Scope:      scp,
+					Receiver:   receivers[scp][0],
+					ScopedArgs: receivers[scp][1],
+				})
+			}
+		}
+	}
+	return acs, nil
+}
+
+const tplAccessors = `
+// Configuration routes of the sections: {{.SectionIDs}}.
+const (
+{{- range .Accessors}}
+	// {{.RouteConst}} {{if .Label}}{{.Label}}{{else}}route of {{.Name}}{{end}}
+	{{.RouteConst}} = {{printf "%q" .Route}}
+{{- end}}
+)
+
+// {{.TypeName}} provides typed accessors for the configuration fields of the
+// sections: {{.SectionIDs}}. The accessors are restricted at compile time to
+// the scopes of their fields, hence the configuration must be bound with one
+// of the functions Default, Website or Store.
+type {{.TypeName}} struct{}
+
+// {{.VarName}} provides typed accessors for the configuration fields of the
+// sections: {{.SectionIDs}}.
+var {{.VarName}} {{.TypeName}}
+
+// {{.TypeName}}Default provides the accessors of the fields which can only be
+// set in the default scope.
+type {{.TypeName}}Default struct {
+	s config.Scoper
+}
+
+// {{.TypeName}}Website provides the accessors of the fields which can be set
+// up to the website scope.
+type {{.TypeName}}Website struct {
+	s         config.Scoper
+	websiteID int64
+}
+
+// {{.TypeName}}Store provides the accessors of the fields which can be set up
+// to the store scope and of the fields without scope restriction.
+type {{.TypeName}}Store struct {
+	s                  config.Scoper
+	websiteID, storeID int64
+}
+
+// Default binds the accessors to the default scope.
+func ({{.TypeName}}) Default(s config.Scoper) {{.TypeName}}Default {
+	return {{.TypeName}}Default{s: s}
+}
+
+// Website binds the accessors to a website.
+func ({{.TypeName}}) Website(s config.Scoper, websiteID int64) {{.TypeName}}Website {
+	return {{.TypeName}}Website{s: s, websiteID: websiteID}
+}
+
+// Store binds the accessors to a store of a website.
+func ({{.TypeName}}) Store(s config.Scoper, websiteID, storeID int64) {{.TypeName}}Store {
+	return {{.TypeName}}Store{s: s, websiteID: websiteID, storeID: storeID}
+}
+
+// Website returns the accessors bound to the website of the store.
+func (a {{.TypeName}}Store) Website() {{.TypeName}}Website {
+	return {{.TypeName}}Website{s: a.s, websiteID: a.websiteID}
+}
+
+// Default returns the accessors bound to the default scope.
+func (a {{.TypeName}}Website) Default() {{.TypeName}}Default {
+	return {{.TypeName}}Default{s: a.s}
+}
+{{range .Accessors}}
+// {{.Name}} returns the value of route {{printf "%q" .Route}} as {{.GoType}}.
+// {{if eq .Scope "scope.Absent"}}Lookup through all scopes.{{else}}Lookup restricted up to {{.Scope}}.{{end}}{{if .Default}} Default: {{.Default}}{{end}}
+func (a {{$.TypeName}}{{.Receiver}}) {{.Name}}() ({{.GoType}}, error) {
+{{- if eq .Method "Strs"}}
+	val, err := a.s.Scoped({{.ScopedArgs}}).Get({{.Scope}}, {{.RouteConst}}).Strs()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	{{- if .Default}}
+	if len(val) == 0 {
+		return {{.Default}}, nil
+	}
+	{{- end}}
+	return val, nil
+{{- else}}
+	val, ok, err := a.s.Scoped({{.ScopedArgs}}).Get({{.Scope}}, {{.RouteConst}}).{{.Method}}()
+	if err != nil {
+		return {{.Zero}}, errors.WithStack(err)
+	}
+	if !ok {
+		return {{if .Default}}{{.Default}}{{else}}{{.Zero}}{{end}}, nil
+	}
+	return val, nil
+{{- end}}
+}
+{{end}}`
+
+var tplAccessorsParsed = template.Must(template.New("accessors").Parse(tplAccessors))
+
+// WriteGo writes the formatted Go source code into `w`.
+func (ag *Accessors) WriteGo(w io.Writer) error {
+	acs, err := ag.accessors()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	ids := make([]string, len(ag.sections))
+	var usesTime bool
+	for i, s := range ag.sections {
+		ids[i] = s.ID
+	}
+	for _, a := range acs {
+		usesTime = usesTime || strings.HasPrefix(a.GoType, "time.")
+	}
+
+	buf := new(bytes.Buffer)
+	if !ag.DisableFileHeader {
+		fmt.Fprintf(buf, "// Auto generated via github.com/sniperkit/snk.fork.corestoreio-pkg/config/cfggen\n\npackage %s\n\nimport (\n", ag.Package)
+		if usesTime {
+			buf.WriteString("\t\"time\"\n\n")
+		}
+		buf.WriteString("\t\"github.com/corestoreio/errors\"\n\n")
+		buf.WriteString("\t\"github.com/sniperkit/snk.fork.corestoreio-pkg/config\"\n")
+		buf.WriteString("\t\"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope\"\n)\n")
+	}
+
+	data := struct {
+		TypeName   string
+		VarName    string
+		SectionIDs string
+		Accessors  []accessor
+	}{
+		TypeName:   ag.TypeName,
+		VarName:    ag.VarName,
+		SectionIDs: strings.Join(ids, ", "),
+		Accessors:  acs,
+	}
+	if err := tplAccessorsParsed.Execute(buf, data); err != nil {
+		return errors.WriteFailed.New(err, "[cfggen] For sections %q", data.SectionIDs)
+	}
+
+	fmted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, "[cfggen] Failed to format the generated source:\n%s", buf.String())
+	}
+	_, err = w.Write(fmted)
+	return errors.WithStack(err)
+}
@@ -0,0 +1,205 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfggen_test
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/cfggen"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+func catalogSections() config.Sections {
+	return config.MustMakeSectionsValidate(
+		&config.Section{
+			ID:     "catalog",
+			Scopes: scope.PermStore,
+			Groups: config.MakeGroups(
+				&config.Group{
+					ID:     "frontend",
+					Scopes: scope.PermWebsite,
+					Fields: config.MakeFields(
+						&config.Field{
+							ID:      "list_mode",
+							Label:   "List Mode",
+							Type:    config.TypeSelect,
+							Default: "grid-list",
+						},
+						&config.Field{
+							ID:      "grid_per_page",
+							Type:    config.TypeText,
+							Scopes:  scope.PermStore,
+							Default: "12",
+						},
+						&config.Field{
+							ID:      "flat_catalog_category",
+							Type:    config.TypeSelect,
+							Scopes:  scope.PermDefault,
+							Default: "0",
+						},
+						&config.Field{
+							ID:      "swatches",
+							Type:    config.TypeMultiselect,
+							Default: "red,green",
+						},
+						&config.Field{
+							ID:          "cache_lifetime",
+							ConfigRoute: "catalog/cache/lifetime",
+							Type:        config.TypeDuration,
+							Default:     "1h",
+						},
+					),
+				},
+			),
+		},
+	)
+}
+
+func TestAccessors_WriteGo(t *testing.T) {
+	t.Parallel()
+
+	t.Run("catalog", func(t *testing.T) {
+		ag, err := cfggen.NewAccessors("catalog", catalogSections())
+		assert.NoError(t, err)
+
+		var buf bytes.Buffer
+		assert.NoError(t, ag.WriteGo(&buf))
+		src := buf.String()
+
+		_, err = parser.ParseFile(token.NewFileSet(), "catalog.go", src, parser.AllErrors)
+		assert.NoError(t, err, "%s", src)
+
+		assert.Contains(t, src, "package catalog\n")
+		assert.Contains(t, src, "\t\"time\"\n")
+		assert.Contains(t, src, `RouteFrontendListMode = "catalog/frontend/list_mode"`)
+		assert.Contains(t, src, `RouteFrontendCacheLifetime = "catalog/cache/lifetime"`)
+		assert.Contains(t, src, "var Config Accessors\n")
+		assert.Contains(t, src, `func (Accessors) Store(s config.Scoper, websiteID, storeID int64) AccessorsStore {`)
+		assert.Contains(t, src, `func (a AccessorsStore) Website() AccessorsWebsite {`)
+		assert.Contains(t, src, `func (a AccessorsWebsite) Default() AccessorsDefault {`)
+		assert.Contains(t, src, `func (a AccessorsWebsite) FrontendListMode() (string, error) {
+	val, ok, err := a.s.Scoped(a.websiteID, 0).Get(scope.Website, RouteFrontendListMode).Str()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if !ok {
+		return "grid-list", nil
+	}
+	return val, nil
+}`)
+		assert.Contains(t, src, `func (a AccessorsStore) FrontendGridPerPage() (int64, error) {
+	val, ok, err := a.s.Scoped(a.websiteID, a.storeID).Get(scope.Store, RouteFrontendGridPerPage).Int64()`)
+		assert.Contains(t, src, "\t\treturn 12, nil\n")
+		assert.Contains(t, src, `func (a AccessorsDefault) FrontendFlatCatalogCategory() (bool, error) {
+	val, ok, err := a.s.Scoped(0, 0).Get(scope.Default, RouteFrontendFlatCatalogCategory).Bool()`)
+		assert.Contains(t, src, `func (a AccessorsWebsite) FrontendSwatches() ([]string, error) {
+	val, err := a.s.Scoped(a.websiteID, 0).Get(scope.Website, RouteFrontendSwatches).Strs()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(val) == 0 {
+		return []string{"red", "green"}, nil
+	}
+	return val, nil
+}`)
+		assert.Contains(t, src, `func (a AccessorsWebsite) FrontendCacheLifetime() (time.Duration, error) {`)
+		assert.Contains(t, src, "\t\treturn time.Duration(3600000000000), nil\n")
+	})
+
+	t.Run("custom names and types", func(t *testing.T) {
+		ag, err := cfggen.NewAccessors("catalog", catalogSections())
+		assert.NoError(t, err)
+		ag.TypeName = "CatalogConfig"
+		ag.VarName = "Catalog"
+		ag.DisableFileHeader = true
+		ag.GoTypes["catalog/frontend/grid_per_page"] = cfggen.GoTypeString
+
+		var buf bytes.Buffer
+		assert.NoError(t, ag.WriteGo(&buf))
+		src := buf.String()
+		assert.NotContains(t, src, "package catalog")
+		assert.Contains(t, src, "var Catalog CatalogConfig\n")
+		assert.Contains(t, src, "type CatalogConfigWebsite struct {\n")
+		assert.Contains(t, src, `func (a CatalogConfigStore) FrontendGridPerPage() (string, error) {`)
+		assert.Contains(t, src, "\t\treturn \"12\", nil\n")
+	})
+
+	t.Run("multiple sections get prefixed", func(t *testing.T) {
+		ss := append(catalogSections(), &config.Section{
+			ID: "web",
+			Groups: config.MakeGroups(&config.Group{
+				ID:     "unsecure",
+				Fields: config.MakeFields(&config.Field{ID: "base_url"}),
+			}),
+		})
+		ag, err := cfggen.NewAccessors("shop", ss)
+		assert.NoError(t, err)
+
+		var buf bytes.Buffer
+		assert.NoError(t, ag.WriteGo(&buf))
+		src := buf.String()
+		assert.Contains(t, src, `func (a AccessorsWebsite) CatalogFrontendListMode() (string, error) {`)
+		assert.Contains(t, src, `func (a AccessorsStore) WebUnsecureBaseURL() (string, error) {
+	val, ok, err := a.s.Scoped(a.websiteID, a.storeID).Get(scope.Absent, RouteWebUnsecureBaseURL).Str()`)
+		assert.Contains(t, src, "// Configuration routes of the sections: catalog, web.\n")
+	})
+
+	t.Run("invalid default", func(t *testing.T) {
+		ag, err := cfggen.NewAccessors("catalog", catalogSections())
+		assert.NoError(t, err)
+		ag.GoTypes["catalog/frontend/list_mode"] = cfggen.GoTypeInt64
+		err = ag.WriteGo(new(bytes.Buffer))
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+
+	t.Run("unsupported go type", func(t *testing.T) {
+		ag, err := cfggen.NewAccessors("catalog", catalogSections())
+		assert.NoError(t, err)
+		ag.GoTypes["catalog/frontend/list_mode"] = "uint8"
+		err = ag.WriteGo(new(bytes.Buffer))
+		assert.True(t, errors.NotSupported.Match(err), "%+v", err)
+	})
+
+	t.Run("duplicate method name", func(t *testing.T) {
+		ag, err := cfggen.NewAccessors("catalog", config.MustMakeSectionsValidate(&config.Section{
+			ID: "catalog",
+			Groups: config.MakeGroups(
+				&config.Group{ID: "list", Fields: config.MakeFields(&config.Field{ID: "mode_xy"})},
+				&config.Group{ID: "list_mode", Fields: config.MakeFields(&config.Field{ID: "xy"})},
+			),
+		}))
+		assert.NoError(t, err)
+		err = ag.WriteGo(new(bytes.Buffer))
+		assert.True(t, errors.Duplicated.Match(err), "%+v", err)
+	})
+
+	t.Run("empty package", func(t *testing.T) {
+		ag, err := cfggen.NewAccessors("", catalogSections())
+		assert.Nil(t, ag)
+		assert.True(t, errors.Empty.Match(err), "%+v", err)
+	})
+}
@@ -0,0 +1,59 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cfggen generates typed Go accessors for the fields of
+// config.Sections.
+//
+// Instead of using string routes and choosing the scope and the conversion
+// function at runtime
+//		s.Get(scope.Website, "catalog/frontend/list_mode").Str()
+// the generated code provides one method per field:
+//		mode, err := catalog.Config.Website(srv, websiteID).FrontendListMode()
+//
+// The Go type of a field gets derived from the Field.Type and the
+// Field.Default value. The fallback through the scope hierarchy gets
+// restricted to the top scope of Field.Scopes (or of the parent group or
+// section if the field has no scopes) and that scope gets hard coded into the
+// generated source.
+//
+// The allowed scopes get also restricted at compile time. The accessor of a
+// field gets generated for the type which matches its top scope: the
+// functions Default, Website and Store of the generated type bind the
+// config.Scoper to a scope and return the types providing the accessors. A
+// website scoped field cannot be read with a store bound value, the store must
+// be explicitly converted:
+//		sa := catalog.Config.Store(srv, websiteID, storeID)
+//		perPage, err := sa.FrontendGridPerPage()
+//		mode, err := sa.Website().FrontendListMode()
+//
+// Custom scope types registered with scope.RegisterType are not supported and
+// WriteGo returns a NotSupported error. The Field.Default value gets embedded
+// into the generated code, so a default gets also returned if the FieldMeta
+// data has not been loaded into the config.Service.
+//
+// Example usage in a go:generate file:
+//		ag, err := cfggen.NewAccessors("catalog", catalog.NewSections())
+//		if err != nil {
+//			panic(err)
+//		}
+//		ag.GoTypes["catalog/frontend/list_per_page"] = "int64"
+//		if err := ag.WriteGo(f); err != nil {
+//			panic(err)
+//		}
+package cfggen
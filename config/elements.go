@@ -532,6 +532,35 @@ type Field struct {
 	// bool. An empty string is equal to NULL. A default gets requests if the
 	// value for a path cannot be retrieved from Level1 or Level2 storage.
 	Default string `json:",omitempty"`
+	// Required rejects empty values. Only used by schema validation, see
+	// package config/observer.
+	Required bool `json:",omitempty"`
+	// Options contains the allowed values of a select or multiselect field.
+	Options []string `json:",omitempty"`
+	// SourceModel provides the allowed values of a select or multiselect
+	// field if Options is empty. It gets called for each validation, hence
+	// options can change at runtime.
+	SourceModel FieldOptioner `json:"-"`
+	// MinValue and MaxValue define the inclusive numeric range of a value. An
+	// empty string means not set.
+	MinValue string `json:",omitempty"`
+	MaxValue string `json:",omitempty"`
+	// Pattern defines a regular expression which must match the value.
+	Pattern string `json:",omitempty"`
+}
+
+// FieldOptioner returns the allowed values of a select or multiselect field,
+// similar to a source model in Magento.
+type FieldOptioner interface {
+	FieldOptions() ([]string, error)
+}
+
+// FieldOptionsFunc implements interface FieldOptioner.
+type FieldOptionsFunc func() ([]string, error)
+
+// FieldOptions calls f().
+func (f FieldOptionsFunc) FieldOptions() ([]string, error) {
+	return f()
 }
 
 // MakeFields wrapper to create a new Fields
@@ -627,5 +656,21 @@ func (f *Field) Update(new *Field) *Field {
 	if new.Default != "" {
 		f.Default = new.Default
 	}
+	f.Required = new.Required
+	if len(new.Options) > 0 {
+		f.Options = new.Options
+	}
+	if new.SourceModel != nil {
+		f.SourceModel = new.SourceModel
+	}
+	if new.MinValue != "" {
+		f.MinValue = new.MinValue
+	}
+	if new.MaxValue != "" {
+		f.MaxValue = new.MaxValue
+	}
+	if new.Pattern != "" {
+		f.Pattern = new.Pattern
+	}
 	return f
 }
@@ -0,0 +1,263 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// Explanation describes the full resolution chain of a route for a Scoped
+// configuration. It gets returned by Scoped.Explain and is meant for debugging
+// misconfigured websites and stores.
+type Explanation struct {
+	// Route the requested route.
+	Route string
+	// ScopeID the scope to which the Scoped type has been bound to.
+	ScopeID scope.TypeID
+	// RestrictUpTo the argument passed to Scoped.Explain.
+	RestrictUpTo scope.Type
+	// Steps contains for each scope, in the order of the fallback
+	// store->website->default, the lookup result.
+	Steps []*ExplainStep
+	// Value the final value, equal to the value returned by Scoped.Get.
+	Value *Value
+}
+
+// Origin returns the scope where the final value has been found. Returns zero
+// if the value has not been found.
+func (e *Explanation) Origin() scope.TypeID {
+	if e.Value == nil || e.Value.found == valFoundNo {
+		return 0
+	}
+	return e.Value.Path.ScopeID
+}
+
+// String returns a human readable representation of the resolution chain, one
+// line for each step.
+func (e *Explanation) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "route %q requested for %s", e.Route, e.ScopeID)
+	if e.RestrictUpTo > scope.Absent {
+		fmt.Fprintf(&buf, " restricted up to %s", e.RestrictUpTo)
+	}
+	buf.WriteByte('\n')
+	for i, st := range e.Steps {
+		fmt.Fprintf(&buf, "%d. %s\n", i+1, st)
+	}
+	if o := e.Origin(); o > 0 {
+		fmt.Fprintf(&buf, "=> %s from %s", e.Value, o)
+	} else {
+		fmt.Fprintf(&buf, "=> %s", e.Value)
+	}
+	return buf.String()
+}
+
+// ExplainStep describes the resolution of a value for one fully qualified
+// path. It gets returned by Service.Explain.
+type ExplainStep struct {
+	// Path the fully qualified path including the optional environment suffix.
+	Path Path
+	// Skipped reports that this scope has not been queried due to the
+	// restriction of the scope hierarchy. All other fields are empty.
+	Skipped bool
+	// Lookups lists each queried storage level in the order of the queries.
+	Lookups []ExplainLookup
+	// Observers lists each observer applied on this path, in the order of the
+	// dispatching.
+	Observers []ExplainObserver
+	// DefaultApplied reports that the default value of the FieldMeta has been
+	// used, because no storage level contained the path.
+	DefaultApplied bool
+	// DefaultRoute the route of the trie node which provided the default value.
+	DefaultRoute string
+	// Value the returned value for this path.
+	Value *Value
+}
+
+// ExplainLookup describes a query to a storage level.
+type ExplainLookup struct {
+	// Level either "Level1" or "Level2".
+	Level string
+	Found bool
+	Err   error
+}
+
+// ExplainObserver describes a dispatched Observer.
+type ExplainObserver struct {
+	Event uint8
+	// Route of the trie node where the observer has been registered. Can be
+	// a section, group or field route.
+	Route string
+	// Index of the observer within the list of observers of the node and event.
+	Index int
+	// Type contains the Go type of the observer.
+	Type string
+	// Data the raw data returned by the observer.
+	Data []byte
+	Err  error
+}
+
+func (st *ExplainStep) addLookup(level uint8, found bool, err error) {
+	st.Lookups = append(st.Lookups, ExplainLookup{
+		Level: valFoundStringer(level),
+		Found: found,
+		Err:   err,
+	})
+}
+
+func (st *ExplainStep) addObserver(event uint8, route string, idx int, o Observer, data []byte, err error) {
+	st.Observers = append(st.Observers, ExplainObserver{
+		Event: event,
+		Route: route,
+		Index: idx,
+		Type:  fmt.Sprintf("%T", o),
+		Data:  data,
+		Err:   err,
+	})
+}
+
+// Found returns the origin of the value: "NO", "Level1", "Level2" or
+// "Defaults".
+func (st *ExplainStep) Found() string {
+	if st.Value == nil {
+		return valFoundStringer(valFoundNo)
+	}
+	return valFoundStringer(st.Value.found)
+}
+
+// String returns a one line summary of the step.
+func (st *ExplainStep) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(st.Path.String())
+	if st.Skipped {
+		buf.WriteString(": skipped")
+		return buf.String()
+	}
+	buf.WriteString(":")
+	for _, l := range st.Lookups {
+		fmt.Fprintf(&buf, " %s found=%t", l.Level, l.Found)
+		if l.Err != nil {
+			fmt.Fprintf(&buf, " err=%q", l.Err)
+		}
+		buf.WriteByte(';')
+	}
+	for _, o := range st.Observers {
+		fmt.Fprintf(&buf, " observer %s %s[%d] %s", eventName(o.Event), o.Route, o.Index, o.Type)
+		if o.Err != nil {
+			fmt.Fprintf(&buf, " err=%q", o.Err)
+		}
+		buf.WriteByte(';')
+	}
+	if st.DefaultApplied {
+		fmt.Fprintf(&buf, " default from %s;", st.DefaultRoute)
+	}
+	fmt.Fprintf(&buf, " result %s=%s", st.Found(), st.Value)
+	return buf.String()
+}
+
+// eventName reverses MakeEvent.
+func eventName(event uint8) string {
+	switch event {
+	case EventOnBeforeSet:
+		return "before_set"
+	case EventOnAfterSet:
+		return "after_set"
+	case EventOnBeforeGet:
+		return "before_get"
+	case EventOnAfterGet:
+		return "after_get"
+	case EventOnBeforeDelete:
+		return "before_delete"
+	case EventOnAfterDelete:
+		return "after_delete"
+	}
+	return fmt.Sprintf("event_%d", event)
+}
+
+// Explain works like Get but records each step of the value resolution: the
+// environment suffix, the queried storage levels, the applied observers and
+// the default value fallback. Explain has the same side effects as Get, for
+// example a value found in Level2 gets written into Level1.
+func (s *Service) Explain(p *Path) *ExplainStep {
+	st := &ExplainStep{}
+	st.Value = s.get(p, st)
+	st.Path = *p
+	return st
+}
+
+type explainer interface {
+	Explain(p *Path) *ExplainStep
+}
+
+// Explain traverses like Get through the scopes store->website->default,
+// including the custom scopes in between, and returns the full resolution
+// chain of the route. The Value field of the returned Explanation is equal to
+// the value returned by Get. Returns a NotSupported error if the underlying
+// service cannot explain.
+func (ss Scoped) Explain(restrictUpTo scope.Type, route string) (*Explanation, error) {
+	ex, ok := ss.rootSrv.(explainer)
+	if !ok {
+		return nil, errors.NotSupported.Newf("[config] Scoped.Explain: Type %T does not support explaining", ss.rootSrv)
+	}
+
+	e := &Explanation{
+		Route:        route,
+		ScopeID:      ss.ScopeID(),
+		RestrictUpTo: restrictUpTo,
+	}
+	skipped := func(scp scope.TypeID) {
+		e.Steps = append(e.Steps, &ExplainStep{
+			Path:    Path{route: Route(route), ScopeID: scp},
+			Skipped: true,
+		})
+	}
+	// pending contains the scopes with an ID in hierarchical order. Before a
+	// scope gets queried all previous not allowed scopes get marked as
+	// skipped.
+	var pending []scope.TypeID
+	scp := ss.restrict(restrictUpTo)
+	for _, typ := range ss.ScopeID().Type().Chain() {
+		if id := ss.id(typ); typ != scope.Default && typ.IsConfigScope() && id > 0 {
+			pending = append(pending, typ.WithID(id))
+		}
+	}
+
+	e.Value = ss.get(restrictUpTo, route, func(p *Path) *Value {
+		for len(pending) > 0 {
+			id := pending[0]
+			pending = pending[1:]
+			if id == p.ScopeID {
+				break
+			}
+			if !ss.isAllowed(id.Type(), scp) {
+				skipped(id)
+			}
+		}
+		st := ex.Explain(p)
+		e.Steps = append(e.Steps, st)
+		return st.Value
+	})
+	return e, nil
+}
@@ -0,0 +1,159 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+func TestScoped_Explain(t *testing.T) {
+	t.Parallel()
+
+	srv := config.MustNewService(storage.NewMap(), config.Options{
+		Level1: storage.NewMap(),
+	}, config.WithFieldMeta(
+		&config.FieldMeta{
+			Route:   "carrier/dhl/timeout",
+			Default: "3600s",
+		},
+	))
+	defer func() { assert.NoError(t, srv.Close()) }()
+
+	pUser := config.MustNewPath("carrier/dhl/username")
+	assert.NoError(t, srv.Set(pUser, []byte(`user0`)))
+	assert.NoError(t, srv.Set(pUser.BindWebsite(2), []byte(`user2`)))
+	assert.NoError(t, srv.RegisterObserver(config.EventOnAfterGet, "carrier/dhl", testObserver{
+		observe: func(p config.Path, rawData []byte, found bool) ([]byte, error) {
+			if !found {
+				return rawData, nil
+			}
+			return bytes.ToUpper(rawData), nil
+		},
+	}))
+
+	t.Run("website value with observer", func(t *testing.T) {
+		e, err := srv.Scoped(2, 5).Explain(scope.Absent, "carrier/dhl/username")
+		assert.NoError(t, err)
+		assert.Exactly(t, `"USER2"`, e.Value.String())
+		assert.Exactly(t, scope.Website.WithID(2), e.Origin())
+		assert.Exactly(t, scope.Store.WithID(5), e.ScopeID)
+		assert.Len(t, e.Steps, 2)
+
+		st := e.Steps[0]
+		assert.Exactly(t, "stores/5/carrier/dhl/username", st.Path.String())
+		assert.Exactly(t, "NO", st.Found())
+		assert.Exactly(t, []config.ExplainLookup{{Level: "Level1"}, {Level: "Level2"}}, st.Lookups)
+		assert.Len(t, st.Observers, 1)
+		assert.False(t, st.DefaultApplied)
+
+		st = e.Steps[1]
+		assert.Exactly(t, "websites/2/carrier/dhl/username", st.Path.String())
+		assert.Exactly(t, "Level2", st.Found())
+		assert.Exactly(t, []config.ExplainLookup{{Level: "Level1"}, {Level: "Level2", Found: true}}, st.Lookups)
+		assert.Len(t, st.Observers, 1)
+		assert.Exactly(t, config.EventOnAfterGet, st.Observers[0].Event)
+		assert.Exactly(t, "carrier/dhl", st.Observers[0].Route)
+		assert.Exactly(t, "config_test.testObserver", st.Observers[0].Type)
+		assert.Exactly(t, []byte(`USER2`), st.Observers[0].Data)
+
+		assert.Contains(t, e.String(), `observer after_get carrier/dhl[0] config_test.testObserver;`)
+		assert.Contains(t, e.String(), `=> "USER2" from Type(Website) ID(2)`)
+	})
+
+	t.Run("website value from level1", func(t *testing.T) {
+		e, err := srv.Scoped(2, 5).Explain(scope.Absent, "carrier/dhl/username")
+		assert.NoError(t, err)
+		assert.Exactly(t, `"USER2"`, e.Value.String())
+		assert.Exactly(t, "Level1", e.Steps[1].Found())
+		assert.Exactly(t, []config.ExplainLookup{{Level: "Level1", Found: true}}, e.Steps[1].Lookups)
+	})
+
+	t.Run("restricted to website skips store", func(t *testing.T) {
+		e, err := srv.Scoped(1, 5).Explain(scope.Website, "carrier/dhl/username")
+		assert.NoError(t, err)
+		assert.Exactly(t, `"USER0"`, e.Value.String())
+		assert.Exactly(t, scope.DefaultTypeID, e.Origin())
+		assert.Len(t, e.Steps, 3)
+		assert.True(t, e.Steps[0].Skipped)
+		assert.Exactly(t, "stores/5/carrier/dhl/username", e.Steps[0].Path.String())
+		assert.False(t, e.Steps[1].Skipped)
+		assert.Exactly(t, "websites/1/carrier/dhl/username", e.Steps[1].Path.String())
+		assert.Exactly(t, "default/0/carrier/dhl/username", e.Steps[2].Path.String())
+		assert.Contains(t, e.String(), "stores/5/carrier/dhl/username: skipped")
+	})
+
+	t.Run("default of FieldMeta", func(t *testing.T) {
+		e, err := srv.Scoped(2, 5).Explain(scope.Absent, "carrier/dhl/timeout")
+		assert.NoError(t, err)
+		assert.Exactly(t, `"3600s"`, e.Value.String())
+		assert.Len(t, e.Steps, 1)
+		assert.True(t, e.Steps[0].DefaultApplied)
+		assert.Exactly(t, "carrier/dhl/timeout", e.Steps[0].DefaultRoute)
+		assert.Exactly(t, "Defaults", e.Steps[0].Found())
+		assert.Contains(t, e.String(), "default from carrier/dhl/timeout;")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		e, err := srv.Scoped(2, 0).Explain(scope.Absent, "carrier/ups/username")
+		assert.NoError(t, err)
+		assert.False(t, e.Value.IsValid())
+		assert.Exactly(t, scope.TypeID(0), e.Origin())
+		assert.Len(t, e.Steps, 2)
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		e, err := config.NewFakeService(storage.NewMap()).Scoped(1, 2).Explain(scope.Absent, "carrier/dhl/username")
+		assert.Nil(t, e)
+		assert.True(t, errors.NotSupported.Match(err), "%+v", err)
+	})
+}
+
+func TestService_Explain(t *testing.T) {
+	t.Parallel()
+
+	srv := config.MustNewService(storage.NewMap(), config.Options{
+		EnvName: "STAGING",
+	})
+	defer func() { assert.NoError(t, srv.Close()) }()
+
+	p := config.MustNewPath("payment/stripe/key").BindStore(3)
+	p.UseEnvSuffix = true
+	assert.NoError(t, srv.Set(p, []byte(`sk_test`)))
+	assert.NoError(t, srv.RegisterObserver(config.EventOnBeforeGet, "payment", testObserver{
+		err: errors.NotAllowed.Newf("Access denied"),
+	}))
+
+	st := srv.Explain(config.MustNewPath("payment/stripe/key").BindStore(3).WithEnvSuffix())
+	assert.Exactly(t, "stores/3/payment/stripe/key/STAGING", st.Path.String())
+	assert.False(t, st.Value.IsValid())
+	assert.Contains(t, st.Value.Error(), "Access denied")
+	assert.Len(t, st.Observers, 1)
+	assert.Exactly(t, config.EventOnBeforeGet, st.Observers[0].Event)
+	assert.True(t, errors.NotAllowed.Match(st.Observers[0].Err), "%+v", st.Observers[0].Err)
+	assert.Len(t, st.Lookups, 0)
+}
@@ -0,0 +1,114 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package customscope_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/cfggen"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+var customerGroup scope.Type
+
+// TestMain registers the custom scope before any test runs, because the scope
+// hierarchy is global: Default -> Website -> CustomerGroup -> Group -> Store
+func TestMain(m *testing.M) {
+	var err error
+	if customerGroup, err = scope.RegisterType("CustomerGroup", "customer_groups", scope.Website); err != nil {
+		fmt.Fprintf(os.Stderr, "%+v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+func TestScoped_CustomScopes(t *testing.T) {
+	srv := config.NewFakeService(storage.NewMap(
+		"default/0/aa/bb/cc", "d",
+		"websites/1/aa/bb/cc", "w",
+		"customer_groups/3/aa/bb/cc", "cg",
+		"default/0/aa/bb/dd", "d",
+		"customer_groups/3/aa/bb/ee", "cg",
+		"stores/2/aa/bb/ee", "s",
+	))
+
+	sc := srv.Scoped(1, 2).WithScopeID(customerGroup.WithID(3), scope.Store.WithID(5), customerGroup.WithID(0))
+	assert.Exactly(t, scope.Store.WithID(2), sc.ScopeID())
+	assert.Exactly(t, customerGroup.WithID(3), sc.ParentID())
+
+	tests := []struct {
+		sc           config.Scoped
+		restrictUpTo scope.Type
+		route        string
+		want         string
+	}{
+		{sc, scope.Absent, "aa/bb/cc", "cg"},
+		{sc, scope.Website, "aa/bb/cc", "w"},
+		{sc, customerGroup, "aa/bb/cc", "cg"},
+		{sc, scope.Group, "aa/bb/cc", "d"},
+		{sc, scope.Absent, "aa/bb/dd", "d"},
+		{sc, scope.Absent, "aa/bb/ee", "s"},
+		{sc, customerGroup, "aa/bb/ee", "cg"},
+		{srv.Scoped(1, 2), scope.Absent, "aa/bb/cc", "w"},
+		{srv.Scoped(1, 0).WithScopeID(customerGroup.WithID(3)), scope.Absent, "aa/bb/cc", "cg"},
+		{srv.Scoped(1, 0).WithScopeID(customerGroup.WithID(3)), scope.Store, "aa/bb/ee", "cg"},
+		{sc.WithScopeID(customerGroup.WithID(4)), scope.Absent, "aa/bb/cc", "w"},
+	}
+	for i, test := range tests {
+		have, ok, err := test.sc.Get(test.restrictUpTo, test.route).Str()
+		assert.NoError(t, err, "Index %d", i)
+		assert.True(t, ok, "Index %d", i)
+		assert.Exactly(t, test.want, have, "Index %d", i)
+	}
+
+	wsc := srv.Scoped(1, 0).WithScopeID(customerGroup.WithID(3))
+	assert.Exactly(t, customerGroup.WithID(3), wsc.ScopeID())
+	assert.Exactly(t, scope.Website.WithID(1), wsc.ParentID())
+
+	p, err := config.NewPathWithScope(customerGroup.WithID(3), "aa/bb/cc")
+	assert.NoError(t, err)
+	assert.Exactly(t, "customer_groups/3/aa/bb/cc", p.String())
+}
+
+func TestAccessors_WriteGo_CustomScope(t *testing.T) {
+	ag, err := cfggen.NewAccessors("customer", config.MustMakeSectionsValidate(&config.Section{
+		ID:     "customer",
+		Scopes: scope.PermStore,
+		Groups: config.MakeGroups(&config.Group{
+			ID: "account",
+			Fields: config.MakeFields(&config.Field{
+				ID:     "discount",
+				Scopes: scope.PermDefault.Set(scope.Website, customerGroup),
+			}),
+		}),
+	}))
+	assert.NoError(t, err)
+
+	err = ag.WriteGo(new(bytes.Buffer))
+	assert.True(t, errors.NotSupported.Match(err), "%+v", err)
+}
@@ -27,6 +27,10 @@ Sniperkit-Bot
 // notemptytrimspace, not_empty_trim_space, hexadecimal, hexcolor and any custom
 // validator added via function RegisterValidator.
 //
+// A SchemaValidator derives the validation rules from the config.Sections,
+// e.g. allowed options, numeric ranges, patterns, required fields and scope
+// restrictions of a config.Field.
+//
 // Installed modifiers: upper, lower, trim, title, base64_encode, base64_decode,
 // hex_encode, hex_decode, sha256, gzip, gunzip, AES-GCM encrypt/decrypt and any
 // custom modifier added via function RegisterModifier.
@@ -36,6 +40,10 @@ Sniperkit-Bot
 //
 // Other encryption algorithms are getting later added.
 //
+// A Keyring encrypts values with multiple AES-GCM keys. The key ID gets stored
+// within the encrypted value, so keys can be rotated via Keyring.Rotate. It can
+// also decrypt values encrypted by Magento 2 with crypt/key.
+//
 // Note: When using sha256 the fully qualified path gets prefixed to the value.
 //
 // To enabled HTTP handler or protobuf you must set build tags on the CLI.
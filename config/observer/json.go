@@ -72,8 +72,8 @@ type Configuration struct {
 	// observer for prefix route `general` gets dispatched every time a route
 	// with that prefix gets called.
 	Route string `json:"route,omitempty"`
-	// Event can be before_set, after_set, before_get or after_get. See
-	// config.MakeEvent.
+	// Event can be before_set, after_set, before_get, after_get, before_delete
+	// or after_delete. See config.MakeEvent.
 	Event string `json:"event,omitempty"`
 	// Type specifies the kind of the observer which should be created. Case
 	// sensitive. Supported names are: "ValidateMinMaxInt", "validator",
@@ -0,0 +1,451 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observer
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/corestoreio/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+)
+
+// keyringPrefix starts each value encrypted by the Keyring with AES-GCM. The
+// format is: cs:<key ID>:<base64(nonce|ciphertext)>
+const keyringPrefix = "cs:"
+
+// magentoCipherChaCha20 defines the crypt version of Magento 2 for values
+// encrypted with ChaCha20-Poly1305 IETF via libsodium.
+const magentoCipherChaCha20 = 3
+
+// EncryptionKey defines an AES key of a Keyring. The key gets loaded from the
+// first non empty source: environment variable, file and Key field. The key
+// must be either 16, 24, or 32 bytes long to select AES-128, AES-192, or
+// AES-256. A key with the prefix "hex:" or "base64:" gets decoded from hex or
+// standard base64, any other key gets used as it is.
+type EncryptionKey struct {
+	// ID gets embedded in each encrypted value to find the key for
+	// decryption. Cannot be empty and cannot contain a colon or white space.
+	ID                      string
+	Key                     string
+	File                    string
+	EnvironmentVariableName string
+}
+
+// KeyringOptions configures a new Keyring.
+type KeyringOptions struct {
+	// Keys contains all known keys. Old keys are required to decrypt values
+	// which have not yet been rotated.
+	Keys []EncryptionKey
+	// ActiveKeyID defines the key used for encryption. Defaults to the ID of
+	// the last key in Keys.
+	ActiveKeyID string
+	// MagentoCryptKey contains the value of crypt/key from Magento's
+	// app/etc/env.php, loaded like EncryptionKey from an environment
+	// variable, a file or the Key field. Multiple keys are separated by new
+	// lines, the line number defines the key version. Values in the format
+	// of Magento's Encryptor `<key version>:3:<base64>` (ChaCha20-Poly1305)
+	// can then be decrypted. Older Magento crypt versions (mcrypt) are not
+	// supported.
+	MagentoCryptKey EncryptionKey
+	// MagentoCompatible encrypts new values in Magento's format with the
+	// last Magento key, so a PHP installation sharing the database can read
+	// them. Requires MagentoCryptKey.
+	MagentoCompatible bool
+}
+
+// Keyring encrypts and decrypts configuration values with multiple keys. The
+// ID of the key gets embedded into the encrypted value, so keys can be rotated
+// without downtime: add a new key, make it active and call Rotate. Keyring is
+// safe for concurrent use.
+type Keyring struct {
+	mu     sync.RWMutex
+	keys   map[string]cipher.AEAD
+	active string
+	// magentoKeys index is the key version.
+	magentoKeys       []cipher.AEAD
+	magentoCompatible bool
+}
+
+// load reads the key from the first non empty source.
+func (ek EncryptionKey) load() ([]byte, error) {
+	if v, ok := os.LookupEnv(ek.EnvironmentVariableName); ok && ek.EnvironmentVariableName != "" && v != "" {
+		return []byte(strings.TrimSpace(v)), nil
+	}
+	if ek.File != "" {
+		data, err := ioutil.ReadFile(ek.File)
+		if err != nil {
+			return nil, errors.NotFound.New(err, "[config/observer] Key file %q of key ID %q cannot be read", ek.File, ek.ID)
+		}
+		return bytes.TrimSpace(data), nil
+	}
+	return []byte(ek.Key), nil
+}
+
+// Prefixes of an encoded EncryptionKey.
+const (
+	keyPrefixHex    = "hex:"
+	keyPrefixBase64 = "base64:"
+)
+
+// decodeAESKey decodes the key if it has the prefix of an encoding, otherwise
+// it returns the raw key.
+func decodeAESKey(key []byte) ([]byte, error) {
+	switch s := string(key); {
+	case strings.HasPrefix(s, keyPrefixHex):
+		return hex.DecodeString(s[len(keyPrefixHex):])
+	case strings.HasPrefix(s, keyPrefixBase64):
+		return base64.StdEncoding.DecodeString(s[len(keyPrefixBase64):])
+	}
+	return key, nil
+}
+
+// NewKeyring creates a new Keyring from the options.
+func NewKeyring(o KeyringOptions) (*Keyring, error) {
+	kr := &Keyring{
+		keys:              make(map[string]cipher.AEAD, len(o.Keys)),
+		magentoCompatible: o.MagentoCompatible,
+	}
+	for _, ek := range o.Keys {
+		if err := kr.AddKey(ek); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	if mk := o.MagentoCryptKey; mk.Key != "" || mk.File != "" || mk.EnvironmentVariableName != "" {
+		data, err := mk.load()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for version, key := range strings.Fields(string(data)) {
+			aead, err := chacha20poly1305.New([]byte(key))
+			if err != nil {
+				return nil, errors.NotValid.New(err, "[config/observer] Magento crypt key version %d has a wrong format", version)
+			}
+			kr.magentoKeys = append(kr.magentoKeys, aead)
+		}
+	}
+	if o.MagentoCompatible && len(kr.magentoKeys) == 0 {
+		return nil, errors.Empty.Newf("[config/observer] MagentoCompatible requires a MagentoCryptKey")
+	}
+
+	switch {
+	case o.ActiveKeyID != "":
+		if err := kr.SetActiveKey(o.ActiveKeyID); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case len(o.Keys) > 0:
+		kr.active = o.Keys[len(o.Keys)-1].ID
+	case !o.MagentoCompatible:
+		return nil, errors.Empty.Newf("[config/observer] A Keyring requires at least one key")
+	}
+	return kr, nil
+}
+
+// MustNewKeyring same as NewKeyring but panics on error.
+func MustNewKeyring(o KeyringOptions) *Keyring {
+	kr, err := NewKeyring(o)
+	if err != nil {
+		panic(err)
+	}
+	return kr
+}
+
+// AddKey adds a new key or replaces an existing key with the same ID.
+func (kr *Keyring) AddKey(ek EncryptionKey) error {
+	if ek.ID == "" || strings.ContainsAny(ek.ID, ": \t\r\n") {
+		return errors.NotValid.Newf("[config/observer] Invalid key ID %q", ek.ID)
+	}
+	key, err := ek.load()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if key, err = decodeAESKey(key); err != nil {
+		return errors.NotValid.New(err, "[config/observer] The encryption key %q cannot be decoded", ek.ID)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return errors.NotValid.New(err, "[config/observer] The encryption key %q has a wrong format", ek.ID)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return errors.Fatal.New(err, "[config/observer] cipher GCM failed")
+	}
+	kr.mu.Lock()
+	kr.keys[ek.ID] = aead
+	kr.mu.Unlock()
+	return nil
+}
+
+// SetActiveKey sets the key used for encryption. The key must have been
+// added before.
+func (kr *Keyring) SetActiveKey(id string) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if _, ok := kr.keys[id]; !ok {
+		return errors.NotFound.Newf("[config/observer] Key ID %q not found", id)
+	}
+	kr.active = id
+	return nil
+}
+
+// ActiveKeyID returns the ID of the key used for encryption.
+func (kr *Keyring) ActiveKeyID() string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.active
+}
+
+func randomNonce(size int) ([]byte, error) {
+	nonce := make([]byte, size)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.ReadFailed.New(err, "[config/observer] ReadFull failed")
+	}
+	return nonce, nil
+}
+
+// Encrypt encrypts the plaintext with the active key and a random nonce. In
+// Magento compatible mode the last Magento key gets used.
+func (kr *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	if kr.magentoCompatible {
+		version := len(kr.magentoKeys) - 1
+		aead := kr.magentoKeys[version]
+		nonce, err := randomNonce(aead.NonceSize())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		sealed := aead.Seal(nonce, nonce, plaintext, nonce) // nonce|ciphertext, nonce is also the additional data
+		var buf bytes.Buffer
+		buf.WriteString(strconv.Itoa(version))
+		buf.WriteString(":" + strconv.Itoa(magentoCipherChaCha20) + ":")
+		buf.WriteString(base64.StdEncoding.EncodeToString(sealed))
+		return buf.Bytes(), nil
+	}
+
+	aead := kr.keys[kr.active]
+	nonce, err := randomNonce(aead.NonceSize())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(kr.active))
+	var buf bytes.Buffer
+	buf.WriteString(keyringPrefix)
+	buf.WriteString(kr.active)
+	buf.WriteByte(':')
+	buf.WriteString(base64.StdEncoding.EncodeToString(sealed))
+	return buf.Bytes(), nil
+}
+
+// keyringValue describes the format of an encrypted value.
+type keyringValue struct {
+	keyID          string // empty for Magento values
+	magentoVersion int
+	sealed         []byte // nonce|ciphertext
+}
+
+func (kr *Keyring) parse(data []byte) (kv keyringValue, err error) {
+	s := string(data)
+	var b64 string
+	if strings.HasPrefix(s, keyringPrefix) {
+		parts := strings.SplitN(s[len(keyringPrefix):], ":", 2)
+		if len(parts) != 2 {
+			return kv, errors.CorruptData.Newf("[config/observer] Invalid encrypted value format")
+		}
+		kv.keyID, b64 = parts[0], parts[1]
+	} else {
+		// Magento: <key version>:<crypt version>:<base64>
+		parts := strings.SplitN(s, ":", 3)
+		if len(parts) != 3 {
+			return kv, errors.CorruptData.Newf("[config/observer] Value is not encrypted or has an unknown format")
+		}
+		if kv.magentoVersion, err = strconv.Atoi(parts[0]); err != nil {
+			return kv, errors.CorruptData.New(err, "[config/observer] Invalid Magento key version")
+		}
+		cryptVersion, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return kv, errors.CorruptData.New(err, "[config/observer] Invalid Magento crypt version")
+		}
+		if cryptVersion != magentoCipherChaCha20 {
+			return kv, errors.NotSupported.Newf("[config/observer] Magento crypt version %d not supported", cryptVersion)
+		}
+		b64 = parts[2]
+	}
+	if kv.sealed, err = base64.StdEncoding.DecodeString(b64); err != nil {
+		return kv, errors.CorruptData.New(err, "[config/observer] Invalid base64 encoding of an encrypted value")
+	}
+	return kv, nil
+}
+
+// Decrypt decrypts a value encrypted by Encrypt or by Magento.
+func (kr *Keyring) Decrypt(data []byte) ([]byte, error) {
+	kv, err := kr.parse(data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	var aead cipher.AEAD
+	if kv.keyID != "" {
+		var ok bool
+		if aead, ok = kr.keys[kv.keyID]; !ok {
+			return nil, errors.NotFound.Newf("[config/observer] Key ID %q not found", kv.keyID)
+		}
+	} else {
+		if kv.magentoVersion < 0 || kv.magentoVersion >= len(kr.magentoKeys) {
+			return nil, errors.NotFound.Newf("[config/observer] Magento key version %d not found", kv.magentoVersion)
+		}
+		aead = kr.magentoKeys[kv.magentoVersion]
+	}
+
+	ns := aead.NonceSize()
+	if len(kv.sealed) < ns {
+		return nil, errors.CorruptData.Newf("[config/observer] Encrypted value too short")
+	}
+	nonce, ciphertext := kv.sealed[:ns], kv.sealed[ns:]
+	ad := nonce
+	if kv.keyID != "" {
+		ad = []byte(kv.keyID)
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, errors.NotValid.New(err, "[config/observer] Decryption failed")
+	}
+	return plaintext, nil
+}
+
+// needsRotation reports whether an encrypted value has not been encrypted
+// with the current key.
+func (kr *Keyring) needsRotation(data []byte) (bool, error) {
+	kv, err := kr.parse(data)
+	if err != nil {
+		return false, err
+	}
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	if kr.magentoCompatible {
+		return kv.keyID != "" || kv.magentoVersion != len(kr.magentoKeys)-1, nil
+	}
+	return kv.keyID != kr.active, nil
+}
+
+// Rotate re-encrypts all encrypted values stored in `st` whose route equals or
+// starts with the route prefix and which have not been encrypted with the
+// active key. Values not encrypted are skipped. Rotate writes directly into
+// the storage, usually the Level2 storage of the config.Service, to bypass the
+// encryption observer. A Level1 cache may contain the old encrypted values
+// which can still be decrypted as long as the old key stays in the Keyring.
+// Returns the number of rotated values.
+func (kr *Keyring) Rotate(st config.Storager, routePrefix string) (rotated int, err error) {
+	type pathValue struct {
+		p config.Path
+		v []byte
+	}
+	var pvs []pathValue
+	err = st.Iterate(0, routePrefix, func(p config.Path, v []byte) error {
+		if ok, err := kr.needsRotation(v); err == nil && ok {
+			pvs = append(pvs, pathValue{p: p, v: v})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "[config/observer] Keyring.Rotate.Iterate with route prefix %q", routePrefix)
+	}
+
+	for _, pv := range pvs {
+		plaintext, err := kr.Decrypt(pv.v)
+		if err != nil {
+			return rotated, errors.Wrapf(err, "[config/observer] Keyring.Rotate for path %q", pv.p.String())
+		}
+		enc, err := kr.Encrypt(plaintext)
+		if err != nil {
+			return rotated, errors.WithStack(err)
+		}
+		if err := st.Set(&pv.p, enc); err != nil {
+			return rotated, errors.Wrapf(err, "[config/observer] Keyring.Rotate for path %q", pv.p.String())
+		}
+		rotated++
+	}
+	return rotated, nil
+}
+
+type keyringObserver struct {
+	kr        *Keyring
+	eventType uint8
+}
+
+// NewObserver creates a new observer which encrypts the value for event
+// config.EventOnBeforeSet and decrypts the value for event
+// config.EventOnAfterGet. Other events are not supported. For security
+// reasons this function cannot be accessed via JSON or protocol buffers.
+func (kr *Keyring) NewObserver(eventType uint8) (config.Observer, error) {
+	if eventType != config.EventOnBeforeSet && eventType != config.EventOnAfterGet {
+		return nil, errors.NotValid.Newf("[config/observer] Event type can only be: EventOnBeforeSet (encryption) or EventOnAfterGet (decryption)")
+	}
+	return keyringObserver{kr: kr, eventType: eventType}, nil
+}
+
+// RegisterObservers registers the encryption and decryption observers for
+// each route.
+func (kr *Keyring) RegisterObservers(or config.ObserverRegisterer, routes ...string) error {
+	for _, route := range routes {
+		if err := or.RegisterObserver(config.EventOnBeforeSet, route, keyringObserver{kr: kr, eventType: config.EventOnBeforeSet}); err != nil {
+			return errors.Wrapf(err, "[config/observer] Keyring.RegisterObservers for route %q", route)
+		}
+		if err := or.RegisterObserver(config.EventOnAfterGet, route, keyringObserver{kr: kr, eventType: config.EventOnAfterGet}); err != nil {
+			return errors.Wrapf(err, "[config/observer] Keyring.RegisterObservers for route %q", route)
+		}
+	}
+	return nil
+}
+
+func (ko keyringObserver) Observe(p config.Path, rawData []byte, found bool) ([]byte, error) {
+	switch ko.eventType {
+	case config.EventOnBeforeSet:
+		enc, err := ko.kr.Encrypt(rawData)
+		return enc, errors.Wrapf(err, "[config/observer] For Path %q", p.String())
+	case config.EventOnAfterGet:
+		if !found || rawData == nil {
+			return rawData, nil
+		}
+		dec, err := ko.kr.Decrypt(rawData)
+		if err != nil {
+			return nil, errors.Wrapf(err, "[config/observer] For Path %q", p.String())
+		}
+		return dec, nil
+	}
+	return nil, errors.Fatal.Newf("[config/observer] A programmer made an error")
+}
@@ -0,0 +1,281 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observer_test
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/corestoreio/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/observer"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+const (
+	testKey1 = "0123456789abcdef0123456789abcdef"
+	testKey2 = "fedcba9876543210fedcba9876543210"
+)
+
+func TestKeyring(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no keys", func(t *testing.T) {
+		kr, err := observer.NewKeyring(observer.KeyringOptions{})
+		assert.Nil(t, kr)
+		assert.True(t, errors.Empty.Match(err), "%+v", err)
+	})
+	t.Run("invalid key ID", func(t *testing.T) {
+		_, err := observer.NewKeyring(observer.KeyringOptions{
+			Keys: []observer.EncryptionKey{{ID: "a:b", Key: testKey1}},
+		})
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+	t.Run("invalid key length", func(t *testing.T) {
+		_, err := observer.NewKeyring(observer.KeyringOptions{
+			Keys: []observer.EncryptionKey{{ID: "k1", Key: "short"}},
+		})
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+	t.Run("encoded keys", func(t *testing.T) {
+		const rawKey = "0123456789abcdef" // AES-128
+		tests := []struct {
+			key     string
+			wantRaw bool
+		}{
+			{"hex:30313233343536373839616263646566", true},
+			{"base64:MDEyMzQ1Njc4OWFiY2RlZg==", true},
+			{"30313233343536373839616263646566", false}, // used as AES-256 key
+		}
+		for _, test := range tests {
+			kr := observer.MustNewKeyring(observer.KeyringOptions{
+				Keys: []observer.EncryptionKey{{ID: "k1", Key: test.key}},
+			})
+			enc, err := kr.Encrypt([]byte("secret"))
+			assert.NoError(t, err)
+			assert.NoError(t, kr.AddKey(observer.EncryptionKey{ID: "k1", Key: rawKey}))
+			_, err = kr.Decrypt(enc)
+			assert.Exactly(t, test.wantRaw, err == nil, "Key %q: %+v", test.key, err)
+		}
+
+		_, err := observer.NewKeyring(observer.KeyringOptions{
+			Keys: []observer.EncryptionKey{{ID: "k1", Key: "hex:30313233343536373839616263646X"}},
+		})
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+	t.Run("active key not found", func(t *testing.T) {
+		_, err := observer.NewKeyring(observer.KeyringOptions{
+			Keys:        []observer.EncryptionKey{{ID: "k1", Key: testKey1}},
+			ActiveKeyID: "k2",
+		})
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+	})
+
+	t.Run("encrypt decrypt with old and new keys", func(t *testing.T) {
+		kr := observer.MustNewKeyring(observer.KeyringOptions{
+			Keys: []observer.EncryptionKey{{ID: "k1", Key: testKey1}},
+		})
+		enc1, err := kr.Encrypt([]byte("secret"))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(enc1), "cs:k1:"), "%q", enc1)
+
+		assert.NoError(t, kr.AddKey(observer.EncryptionKey{ID: "k2", Key: "base64:ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="}))
+		assert.NoError(t, kr.SetActiveKey("k2"))
+		assert.Exactly(t, "k2", kr.ActiveKeyID())
+
+		enc2, err := kr.Encrypt([]byte("secret"))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(enc2), "cs:k2:"), "%q", enc2)
+
+		for _, enc := range [][]byte{enc1, enc2} {
+			dec, err := kr.Decrypt(enc)
+			assert.NoError(t, err)
+			assert.Exactly(t, "secret", string(dec))
+		}
+
+		// tampered key ID must fail because the ID is the additional data.
+		_, err = kr.Decrypt([]byte(strings.Replace(string(enc1), "cs:k1:", "cs:k2:", 1)))
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+
+		_, err = kr.Decrypt([]byte("cs:k3:AAAA"))
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+		_, err = kr.Decrypt([]byte("plain text"))
+		assert.True(t, errors.CorruptData.Match(err), "%+v", err)
+	})
+
+	t.Run("key from env and file", func(t *testing.T) {
+		const envName = "CS_KEYRING_TEST_KEY"
+		assert.NoError(t, os.Setenv(envName, "  "+testKey1+"\n"))
+		defer os.Unsetenv(envName)
+
+		f, err := ioutil.TempFile("", "keyring")
+		assert.NoError(t, err)
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(testKey2 + "\n")
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+
+		kr := observer.MustNewKeyring(observer.KeyringOptions{
+			Keys: []observer.EncryptionKey{
+				{ID: "env", EnvironmentVariableName: envName},
+				{ID: "file", File: f.Name()},
+			},
+		})
+		assert.Exactly(t, "file", kr.ActiveKeyID())
+
+		krEnv := observer.MustNewKeyring(observer.KeyringOptions{
+			Keys: []observer.EncryptionKey{{ID: "env", Key: testKey1}},
+		})
+		enc, err := krEnv.Encrypt([]byte("from env"))
+		assert.NoError(t, err)
+		dec, err := kr.Decrypt(enc)
+		assert.NoError(t, err)
+		assert.Exactly(t, "from env", string(dec))
+
+		_, err = observer.NewKeyring(observer.KeyringOptions{
+			Keys: []observer.EncryptionKey{{ID: "file", File: f.Name() + "404"}},
+		})
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+	})
+}
+
+// magentoEncrypt mimics Magento\Framework\Encryption\Encryptor::encrypt with
+// libsodium ChaCha20-Poly1305 IETF.
+func magentoEncrypt(t *testing.T, keyVersion, key, plain string) string {
+	aead, err := chacha20poly1305.New([]byte(key))
+	assert.NoError(t, err)
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, "magentononce")
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nonce)
+	return keyVersion + ":3:" + base64.StdEncoding.EncodeToString(sealed)
+}
+
+func TestKeyring_Magento(t *testing.T) {
+	t.Parallel()
+
+	kr := observer.MustNewKeyring(observer.KeyringOptions{
+		Keys:            []observer.EncryptionKey{{ID: "k1", Key: testKey1}},
+		MagentoCryptKey: observer.EncryptionKey{Key: testKey1 + "\n" + testKey2},
+	})
+
+	dec, err := kr.Decrypt([]byte(magentoEncrypt(t, "1", testKey2, "magento secret")))
+	assert.NoError(t, err)
+	assert.Exactly(t, "magento secret", string(dec))
+
+	dec, err = kr.Decrypt([]byte(magentoEncrypt(t, "0", testKey1, "older secret")))
+	assert.NoError(t, err)
+	assert.Exactly(t, "older secret", string(dec))
+
+	_, err = kr.Decrypt([]byte(magentoEncrypt(t, "2", testKey2, "x")))
+	assert.True(t, errors.NotFound.Match(err), "%+v", err)
+
+	_, err = kr.Decrypt([]byte("0:2:AAAA")) // mcrypt
+	assert.True(t, errors.NotSupported.Match(err), "%+v", err)
+
+	t.Run("compatible mode", func(t *testing.T) {
+		krm := observer.MustNewKeyring(observer.KeyringOptions{
+			MagentoCryptKey:   observer.EncryptionKey{Key: testKey1 + "\n" + testKey2},
+			MagentoCompatible: true,
+		})
+		enc, err := krm.Encrypt([]byte("shared"))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(enc), "1:3:"), "%q", enc)
+		dec, err := kr.Decrypt(enc)
+		assert.NoError(t, err)
+		assert.Exactly(t, "shared", string(dec))
+	})
+}
+
+func TestKeyring_Rotate(t *testing.T) {
+	t.Parallel()
+
+	kr := observer.MustNewKeyring(observer.KeyringOptions{
+		Keys: []observer.EncryptionKey{{ID: "k1", Key: testKey1}},
+	})
+	st := config.NewSnapshot()
+
+	pUser := config.MustNewPath("carrier/dhl/username").BindWebsite(2)
+	pPass := config.MustNewPath("carrier/dhl/password")
+	pPlain := config.MustNewPath("carrier/dhl/title")
+	pOther := config.MustNewPath("payment/paypal/secret")
+
+	for _, p := range []*config.Path{pUser, pPass, pOther} {
+		enc, err := kr.Encrypt([]byte(p.String()))
+		assert.NoError(t, err)
+		assert.NoError(t, st.Set(p, enc))
+	}
+	assert.NoError(t, st.Set(pPlain, []byte("DHL")))
+
+	assert.NoError(t, kr.AddKey(observer.EncryptionKey{ID: "k2", Key: testKey2}))
+	assert.NoError(t, kr.SetActiveKey("k2"))
+
+	rotated, err := kr.Rotate(st, "carrier")
+	assert.NoError(t, err)
+	assert.Exactly(t, 2, rotated)
+
+	for _, p := range []*config.Path{pUser, pPass} {
+		v, ok, err := st.Get(p)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, strings.HasPrefix(string(v), "cs:k2:"), "%q", v)
+		dec, err := kr.Decrypt(v)
+		assert.NoError(t, err)
+		assert.Exactly(t, p.String(), string(dec))
+	}
+	v, _, _ := st.Get(pPlain)
+	assert.Exactly(t, "DHL", string(v))
+	v, _, _ = st.Get(pOther)
+	assert.True(t, strings.HasPrefix(string(v), "cs:k1:"), "%q", v)
+
+	rotated, err = kr.Rotate(st, "carrier")
+	assert.NoError(t, err)
+	assert.Exactly(t, 0, rotated)
+}
+
+func TestKeyring_NewObserver(t *testing.T) {
+	t.Parallel()
+
+	kr := observer.MustNewKeyring(observer.KeyringOptions{
+		Keys: []observer.EncryptionKey{{ID: "k1", Key: testKey1}},
+	})
+	_, err := kr.NewObserver(config.EventOnBeforeGet)
+	assert.True(t, errors.NotValid.Match(err), "%+v", err)
+
+	obEnc, err := kr.NewObserver(config.EventOnBeforeSet)
+	assert.NoError(t, err)
+	obDec, err := kr.NewObserver(config.EventOnAfterGet)
+	assert.NoError(t, err)
+
+	p := *config.MustNewPath("aa/bb/cc")
+	enc, err := obEnc.Observe(p, []byte("plain"), true)
+	assert.NoError(t, err)
+	dec, err := obDec.Observe(p, enc, true)
+	assert.NoError(t, err)
+	assert.Exactly(t, "plain", string(dec))
+
+	dec, err = obDec.Observe(p, nil, false)
+	assert.NoError(t, err)
+	assert.Nil(t, dec)
+}
@@ -0,0 +1,296 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observer
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// SchemaValidator validates values against the meta data of the config.Field
+// types, which removes the need to duplicate validation rules. Supported are:
+// allowed options of select and multiselect fields from Field.Options or
+// Field.SourceModel, numeric ranges, regular expressions, required fields,
+// scope restrictions and the format of time and duration fields. All rule
+// violations of a value get aggregated into one error. SchemaValidator is safe
+// for concurrent use.
+type SchemaValidator struct {
+	// Insecure enables printing in case of errors the values. This might and
+	// will leak sensitive information.
+	Insecure bool
+	fields   map[string]*schemaField // key: route
+	routes   []string
+}
+
+type schemaField struct {
+	*config.Field
+	route    string
+	scopes   scope.Perm // inherited from group or section if empty
+	pattern  *regexp.Regexp
+	min, max float64
+	hasMin   bool
+	hasMax   bool
+}
+
+// NewSchemaValidator creates a new validator from the sections. Returns a
+// NotValid error if a regular expression or a numeric range cannot be parsed.
+func NewSchemaValidator(sections config.Sections) (*SchemaValidator, error) {
+	sv := &SchemaValidator{
+		fields: make(map[string]*schemaField, sections.TotalFields()),
+	}
+	for _, s := range sections {
+		for _, g := range s.Groups {
+			for _, f := range g.Fields {
+				sf := &schemaField{
+					Field:  f,
+					route:  f.ConfigRoute,
+					scopes: f.Scopes,
+				}
+				if sf.route == "" {
+					sf.route = s.ID + "/" + g.ID + "/" + f.ID
+				}
+				if sf.scopes == 0 {
+					sf.scopes = g.Scopes
+				}
+				if sf.scopes == 0 {
+					sf.scopes = s.Scopes
+				}
+				if err := sf.compile(); err != nil {
+					return nil, errors.WithStack(err)
+				}
+				if _, ok := sv.fields[sf.route]; !ok {
+					sv.routes = append(sv.routes, sf.route)
+				}
+				sv.fields[sf.route] = sf
+			}
+		}
+	}
+	sort.Strings(sv.routes)
+	return sv, nil
+}
+
+// MustNewSchemaValidator same as NewSchemaValidator but panics on error.
+func MustNewSchemaValidator(sections config.Sections) *SchemaValidator {
+	sv, err := NewSchemaValidator(sections)
+	if err != nil {
+		panic(err)
+	}
+	return sv
+}
+
+func (sf *schemaField) compile() (err error) {
+	if sf.Pattern != "" {
+		if sf.pattern, err = regexp.Compile(sf.Pattern); err != nil {
+			return errors.NotValid.New(err, "[config/observer] Invalid pattern %q for route %q", sf.Pattern, sf.route)
+		}
+	}
+	if sf.MinValue != "" {
+		if sf.min, err = strconv.ParseFloat(sf.MinValue, 64); err != nil {
+			return errors.NotValid.New(err, "[config/observer] Invalid MinValue %q for route %q", sf.MinValue, sf.route)
+		}
+		sf.hasMin = true
+	}
+	if sf.MaxValue != "" {
+		if sf.max, err = strconv.ParseFloat(sf.MaxValue, 64); err != nil {
+			return errors.NotValid.New(err, "[config/observer] Invalid MaxValue %q for route %q", sf.MaxValue, sf.route)
+		}
+		sf.hasMax = true
+	}
+	if sf.hasMin && sf.hasMax && sf.min > sf.max {
+		return errors.NotValid.Newf("[config/observer] MinValue %q is greater than MaxValue %q for route %q", sf.MinValue, sf.MaxValue, sf.route)
+	}
+	return nil
+}
+
+// Routes returns all routes known to the validator in sorted order.
+func (sv *SchemaValidator) Routes() []string {
+	return append([]string(nil), sv.routes...)
+}
+
+// RegisterObservers registers the validator for event config.EventOnBeforeSet
+// for all routes.
+func (sv *SchemaValidator) RegisterObservers(or config.ObserverRegisterer) error {
+	for _, route := range sv.routes {
+		if err := or.RegisterObserver(config.EventOnBeforeSet, route, sv); err != nil {
+			return errors.Wrapf(err, "[config/observer] SchemaValidator.RegisterObservers for route %q", route)
+		}
+	}
+	return nil
+}
+
+// Observe implements config.Observer and validates the value.
+func (sv *SchemaValidator) Observe(p config.Path, rawData []byte, found bool) ([]byte, error) {
+	if err := sv.Validate(p, rawData); err != nil {
+		return nil, err
+	}
+	return rawData, nil
+}
+
+func (sv *SchemaValidator) findField(p *config.Path) *schemaField {
+	_, route := p.ScopeRoute()
+	if sf, ok := sv.fields[route]; ok {
+		return sf
+	}
+	if i := strings.LastIndexByte(route, config.PathSeparator); p.UseEnvSuffix && i > 0 {
+		return sv.fields[route[:i]] // without environment suffix
+	}
+	return nil
+}
+
+// Validate checks the value for path p. Returns nil if the path has no
+// field definition. Returns a NotValid error containing all violations.
+func (sv *SchemaValidator) Validate(p config.Path, v []byte) error {
+	sf := sv.findField(&p)
+	if sf == nil {
+		return nil
+	}
+	if msgs := sf.violations(&p, v); len(msgs) > 0 {
+		return errors.NotValid.Newf("[config/observer] Path %q: %s", p.String(), sv.format(v, msgs))
+	}
+	return nil
+}
+
+// ValidateStorage validates all values stored in st and aggregates the
+// violations of all paths into one NotValid error. Useful to check
+// configuration imports before activating them, for example a
+// config.Snapshot.
+func (sv *SchemaValidator) ValidateStorage(st config.Storager) error {
+	var buf bytes.Buffer
+	var count int
+	err := st.Iterate(0, "", func(p config.Path, v []byte) error {
+		sf := sv.findField(&p)
+		if sf == nil {
+			return nil
+		}
+		if msgs := sf.violations(&p, v); len(msgs) > 0 {
+			count++
+			fmt.Fprintf(&buf, "\n%s: %s", p.String(), sv.format(v, msgs))
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if count > 0 {
+		return errors.NotValid.Newf("[config/observer] %d invalid values:%s", count, buf.String())
+	}
+	return nil
+}
+
+func (sv *SchemaValidator) format(v []byte, msgs []string) string {
+	s := strings.Join(msgs, "; ")
+	if sv.Insecure {
+		s = fmt.Sprintf("value %q: %s", v, s)
+	}
+	return s
+}
+
+// violations returns a human readable message for each violated rule.
+func (sf *schemaField) violations(p *config.Path, v []byte) (msgs []string) {
+	if sf.scopes > 0 {
+		if st := p.ScopeID.Type(); !sf.scopes.Has(st) {
+			msgs = append(msgs, fmt.Sprintf("scope %s not allowed, allowed scopes: %s", st, strings.Join(sf.scopes.Human(), ", ")))
+		}
+	}
+
+	val := string(v)
+	if strings.TrimSpace(val) == "" {
+		if sf.Required {
+			msgs = append(msgs, "value is required")
+		}
+		return msgs
+	}
+
+	switch sf.Type {
+	case config.TypeSelect, config.TypeMultiselect:
+		msgs = sf.checkOptions(val, msgs)
+	case config.TypeTime:
+		if _, ok, err := config.NewValue(v).Time(); !ok || err != nil {
+			msgs = append(msgs, "value is not a valid date time")
+		}
+	case config.TypeDuration:
+		if _, ok, err := config.NewValue(v).Duration(); !ok || err != nil {
+			msgs = append(msgs, "value is not a valid duration")
+		}
+	}
+
+	if sf.hasMin || sf.hasMax {
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		switch {
+		case err != nil:
+			msgs = append(msgs, "value is not a number")
+		case sf.hasMin && f < sf.min:
+			msgs = append(msgs, fmt.Sprintf("value must be greater than or equal to %s", sf.MinValue))
+		case sf.hasMax && f > sf.max:
+			msgs = append(msgs, fmt.Sprintf("value must be less than or equal to %s", sf.MaxValue))
+		}
+	}
+	if sf.pattern != nil && !sf.pattern.MatchString(val) {
+		msgs = append(msgs, fmt.Sprintf("value does not match pattern %q", sf.Pattern))
+	}
+	return msgs
+}
+
+func (sf *schemaField) checkOptions(val string, msgs []string) []string {
+	opts := sf.Options
+	if len(opts) == 0 && sf.SourceModel != nil {
+		var err error
+		if opts, err = sf.SourceModel.FieldOptions(); err != nil {
+			return append(msgs, fmt.Sprintf("source model failed: %s", err))
+		}
+	}
+	if len(opts) == 0 {
+		return msgs
+	}
+	values := []string{val}
+	if sf.Type == config.TypeMultiselect {
+		values = strings.Split(val, string(config.CSVColumnSeparator))
+	}
+	// The rejected option does not get printed because the value might be
+	// sensitive, see SchemaValidator.Insecure.
+	for i, v := range values {
+		switch {
+		case containsString(opts, v):
+		case len(values) > 1:
+			msgs = append(msgs, fmt.Sprintf("option %d not allowed, allowed options: %s", i+1, strings.Join(opts, ", ")))
+		default:
+			msgs = append(msgs, fmt.Sprintf("option not allowed, allowed options: %s", strings.Join(opts, ", ")))
+		}
+	}
+	return msgs
+}
+
+func containsString(sl []string, s string) bool {
+	for _, v := range sl {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/observer"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+func schemaSections() config.Sections {
+	return config.MustMakeSectionsValidate(&config.Section{
+		ID: "carrier",
+		Groups: config.MakeGroups(&config.Group{
+			ID:     "dhl",
+			Scopes: scope.PermWebsite,
+			Fields: config.MakeFields(
+				&config.Field{
+					ID:      "mode",
+					Type:    config.TypeSelect,
+					Options: []string{"live", "sandbox"},
+				},
+				&config.Field{
+					ID:   "methods",
+					Type: config.TypeMultiselect,
+					SourceModel: config.FieldOptionsFunc(func() ([]string, error) {
+						return []string{"express", "economy", "same_day"}, nil
+					}),
+				},
+				&config.Field{
+					ID:       "max_weight",
+					Type:     config.TypeText,
+					MinValue: "0.5",
+					MaxValue: "70",
+				},
+				&config.Field{
+					ID:       "account",
+					Type:     config.TypeText,
+					Required: true,
+					Pattern:  `^[A-Z]{2}\d{4}$`,
+					Scopes:   scope.PermDefault,
+				},
+				&config.Field{
+					ID:   "timeout",
+					Type: config.TypeDuration,
+				},
+			),
+		}),
+	})
+}
+
+func TestNewSchemaValidator(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		sv, err := observer.NewSchemaValidator(config.MakeSections(&config.Section{
+			ID: "a", Groups: config.MakeGroups(&config.Group{
+				ID: "b", Fields: config.MakeFields(&config.Field{ID: "c", Pattern: "[a-"}),
+			}),
+		}))
+		assert.Nil(t, sv)
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+	t.Run("min greater max", func(t *testing.T) {
+		_, err := observer.NewSchemaValidator(config.MakeSections(&config.Section{
+			ID: "a", Groups: config.MakeGroups(&config.Group{
+				ID: "b", Fields: config.MakeFields(&config.Field{ID: "c", MinValue: "5", MaxValue: "1"}),
+			}),
+		}))
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+	t.Run("routes", func(t *testing.T) {
+		sv := observer.MustNewSchemaValidator(schemaSections())
+		assert.Exactly(t, []string{
+			"carrier/dhl/account", "carrier/dhl/max_weight", "carrier/dhl/methods",
+			"carrier/dhl/mode", "carrier/dhl/timeout",
+		}, sv.Routes())
+	})
+}
+
+func TestSchemaValidator_Validate(t *testing.T) {
+	t.Parallel()
+
+	sv := observer.MustNewSchemaValidator(schemaSections())
+
+	tests := []struct {
+		p       *config.Path
+		val     string
+		wantErr string
+	}{
+		{config.MustNewPath("carrier/dhl/mode"), "live", ""},
+		{config.MustNewPath("carrier/dhl/mode").BindWebsite(1), "sandbox", ""},
+		{config.MustNewPath("carrier/dhl/mode"), "test", "option not allowed, allowed options: live, sandbox"},
+		{config.MustNewPath("carrier/dhl/mode").BindStore(1), "live", "scope Store not allowed"},
+		{config.MustNewPath("carrier/dhl/methods"), "express,same_day", ""},
+		{config.MustNewPath("carrier/dhl/methods"), "express,drone", "option 2 not allowed"},
+		{config.MustNewPath("carrier/dhl/max_weight"), "70", ""},
+		{config.MustNewPath("carrier/dhl/max_weight"), "0.1", "greater than or equal to 0.5"},
+		{config.MustNewPath("carrier/dhl/max_weight"), "71", "less than or equal to 70"},
+		{config.MustNewPath("carrier/dhl/max_weight"), "heavy", "value is not a number"},
+		{config.MustNewPath("carrier/dhl/account"), "DE1234", ""},
+		{config.MustNewPath("carrier/dhl/account"), " ", "value is required"},
+		{config.MustNewPath("carrier/dhl/account"), "de12", "does not match pattern"},
+		{config.MustNewPath("carrier/dhl/timeout"), "3s", ""},
+		{config.MustNewPath("carrier/dhl/timeout"), "3 seconds", "not a valid duration"},
+		{config.MustNewPath("carrier/ups/unknown"), "anything", ""},
+	}
+	for i, test := range tests {
+		err := sv.Validate(*test.p, []byte(test.val))
+		if test.wantErr == "" {
+			assert.NoError(t, err, "Index %d", i)
+			continue
+		}
+		assert.True(t, errors.NotValid.Match(err), "Index %d: %+v", i, err)
+		assert.Contains(t, err.Error(), test.wantErr, "Index %d", i)
+	}
+
+	t.Run("aggregated violations", func(t *testing.T) {
+		err := sv.Validate(*config.MustNewPath("carrier/dhl/account").BindWebsite(2), []byte("xx"))
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+		assert.Contains(t, err.Error(), "scope Website not allowed; value does not match pattern")
+		assert.False(t, strings.Contains(err.Error(), `"xx"`), "Value must not be printed")
+	})
+
+	t.Run("rejected option", func(t *testing.T) {
+		p := *config.MustNewPath("carrier/dhl/methods")
+		err := sv.Validate(p, []byte("express,s3cr3t"))
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+		assert.False(t, strings.Contains(err.Error(), "s3cr3t"), "Value must not be printed: %s", err)
+
+		svInsecure := observer.MustNewSchemaValidator(schemaSections())
+		svInsecure.Insecure = true
+		err = svInsecure.Validate(p, []byte("express,s3cr3t"))
+		assert.Contains(t, err.Error(), `value "express,s3cr3t": option 2 not allowed`)
+	})
+}
+
+func TestSchemaValidator_RegisterObservers(t *testing.T) {
+	t.Parallel()
+
+	srv := config.MustNewService(nil, config.Options{})
+	sv := observer.MustNewSchemaValidator(schemaSections())
+	assert.NoError(t, sv.RegisterObservers(srv))
+
+	p := config.MustNewPath("carrier/dhl/mode")
+	assert.NoError(t, srv.Set(p, []byte("live")))
+	err := srv.Set(p, []byte("test"))
+	assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	assert.Exactly(t, `"live"`, srv.Get(p).String())
+}
+
+func TestSchemaValidator_ValidateStorage(t *testing.T) {
+	t.Parallel()
+
+	sv := observer.MustNewSchemaValidator(schemaSections())
+	sn := config.NewSnapshot()
+	assert.NoError(t, sn.Set(config.MustNewPath("carrier/dhl/mode"), []byte("live")))
+	assert.NoError(t, sn.Set(config.MustNewPath("carrier/dhl/max_weight"), []byte("100")))
+	assert.NoError(t, sn.Set(config.MustNewPath("carrier/dhl/account"), []byte("")))
+	assert.NoError(t, sv.ValidateStorage(config.NewSnapshot()))
+
+	err := sv.ValidateStorage(sn)
+	assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	assert.Contains(t, err.Error(), "2 invalid values")
+	assert.Contains(t, err.Error(), "value is required")
+	assert.Contains(t, err.Error(), "less than or equal to 70")
+}
@@ -67,6 +67,26 @@ type Options struct {
 	// HotReloadSignals specifies custom signals to listen to. Defaults to
 	// syscall.SIGUSR2
 	HotReloadSignals []os.Signal
+
+	// SnapshotStorage persists the snapshots created with
+	// Service.TakeSnapshot. Persisted snapshots get loaded when creating a new
+	// Service. Should not be the Level2 storage. Optional, without it the
+	// snapshots are kept only in memory.
+	SnapshotStorage Storager
+	// SnapshotMaxVersions defines the maximum number of snapshots to keep. The
+	// oldest snapshot gets removed once the limit has been reached. Zero means
+	// unlimited.
+	SnapshotMaxVersions int
+
+	// Bridge publishes change notifications of Set and Delete operations to
+	// other processes and replays received notifications into the local
+	// pubsub and the Level1 cache invalidation. All processes must share the
+	// same Level2 storage. The Bridge gets closed with Service.Close.
+	// Optional.
+	Bridge Bridge
+	// BridgeNodeID identifies this process within the Bridge to ignore its
+	// own messages. Defaults to a random ID.
+	BridgeNodeID string
 }
 
 // LoadDataOption allows other storage backends to pump their data into the
@@ -331,7 +331,7 @@ func (p *Path) AppendFQ(buf *bytes.Buffer) error {
 	}
 
 	scp, id := p.ScopeID.Unpack()
-	if scp != scope.Website && scp != scope.Store {
+	if !scp.IsWebSiteOrStore() && !scp.IsCustom() {
 		scp = scope.Default
 		id = 0
 	}
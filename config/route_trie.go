@@ -57,11 +57,14 @@ const (
 	EventOnAfterSet
 	EventOnBeforeGet
 	EventOnAfterGet
+	EventOnBeforeDelete
+	EventOnAfterDelete
 	eventMaxCount
 )
 
-// MakeEvent creates a new validated event from one of the four possible event
-// names: before_set, before_get, after_set and after_get.
+// MakeEvent creates a new validated event from one of the six possible event
+// names: before_set, before_get, before_delete, after_set, after_get and
+// after_delete.
 func MakeEvent(name string) (event uint8, err error) {
 	switch name {
 	case "before_set":
@@ -72,8 +75,12 @@ func MakeEvent(name string) (event uint8, err error) {
 		event = EventOnAfterSet
 	case "after_get":
 		event = EventOnAfterGet
+	case "before_delete":
+		event = EventOnBeforeDelete
+	case "after_delete":
+		event = EventOnAfterDelete
 	default:
-		err = errors.NotFound.Newf("[config] Unknown event name: %q. Available: before_set, before_get, after_set and after_get", name)
+		err = errors.NotFound.Newf("[config] Unknown event name: %q. Available: before_set, before_get, before_delete, after_set, after_get and after_delete", name)
 	}
 	return
 }
@@ -90,13 +97,17 @@ type Observer interface {
 
 type observers []Observer
 
-func (fns observers) dispatch(p *Path, v []byte, found bool) (_ []byte, err error) {
+func (fns observers) dispatch(p *Path, v []byte, found bool, tr *ExplainStep, event uint8, route string) (_ []byte, err error) {
 	if len(fns) == 0 {
 		return v, nil
 	}
 	p2 := *p
 	for idx, fn := range fns {
-		if v, err = fn.Observe(p2, v, found); err != nil {
+		v, err = fn.Observe(p2, v, found)
+		if tr != nil {
+			tr.addObserver(event, route, idx, fn, v, err)
+		}
+		if err != nil {
 			return nil, errors.Wrapf(err, "[config] At index %d", idx)
 		}
 	}
@@ -144,7 +155,7 @@ func newTrieRoute() *trieRoute {
 func buildTrieKey(key string, scp scope.TypeID) string {
 	// This code provides less allocs and fastest execution.
 	hasPS := strings.HasPrefix(key, sPathSeparator)
-	if !scp.Type().IsWebSiteOrStore() {
+	if t := scp.Type(); !t.IsWebSiteOrStore() && !t.IsCustom() {
 		if hasPS {
 			return key
 		}
@@ -190,8 +201,9 @@ func (trie *trieRoute) Get(key string) FieldMeta {
 }
 
 // process runs on each tree level and dispatches the events and checks for
-// scope permission and default value.
-func (trie *trieRoute) process(key string, event uint8, p *Path, v []byte, found bool) (v2 []byte, found2 bool, err error) {
+// scope permission and default value. The optional argument tr records each
+// applied observer and the default value fallback.
+func (trie *trieRoute) process(key string, event uint8, p *Path, v []byte, found bool, tr *ExplainStep) (v2 []byte, found2 bool, err error) {
 	if trie == nil {
 		return v, found, nil
 	}
@@ -203,11 +215,20 @@ func (trie *trieRoute) process(key string, event uint8, p *Path, v []byte, found
 		if node == nil {
 			return v, found, nil
 		}
-		if node.fm.valid && event == EventOnBeforeSet && node.fm.WriteScopePerm > 0 && p.ScopeID > 0 && !node.fm.WriteScopePerm.Has(p.ScopeID.Type()) {
+		if node.fm.valid && (event == EventOnBeforeSet || event == EventOnBeforeDelete) && node.fm.WriteScopePerm > 0 && p.ScopeID > 0 && !node.fm.WriteScopePerm.Has(p.ScopeID.Type()) {
 			return nil, false, errors.NotAllowed.Newf("[config] The path %q is not allowed to access this scope %s", p.String(), node.fm.WriteScopePerm.String())
 		}
 
-		if v, err = node.fm.Events[event].dispatch(p, v, found); err != nil {
+		var nodeRoute string
+		if tr != nil {
+			nodeRoute = key
+			if i > 0 {
+				nodeRoute = key[:i]
+			}
+			nodeRoute = strings.TrimPrefix(nodeRoute, sPathSeparator)
+		}
+
+		if v, err = node.fm.Events[event].dispatch(p, v, found, tr, event, nodeRoute); err != nil {
 			return nil, false, errors.WithStack(err)
 		}
 
@@ -215,6 +236,10 @@ func (trie *trieRoute) process(key string, event uint8, p *Path, v []byte, found
 			event == EventOnAfterGet && !found && v == nil && node.fm.DefaultValid {
 			v = []byte(node.fm.Default)
 			found = true
+			if tr != nil {
+				tr.DefaultApplied = true
+				tr.DefaultRoute = nodeRoute
+			}
 		}
 
 		if i == -1 {
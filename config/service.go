@@ -20,6 +20,7 @@ Sniperkit-Bot
 package config
 
 import (
+	"io"
 	"os"
 	"os/signal"
 	"sort"
@@ -60,7 +61,17 @@ type Storager interface {
 	// desired path, return value `found` must be false. A nil value `v`
 	// indicates also a value and hence `found` is true, if found.
 	Get(p *Path) (v []byte, found bool, err error)
-	// Delete(p *Path) error TODO
+	// Delete removes the value for the path. Deleting a non-existent path
+	// returns nil.
+	Delete(p *Path) error
+	// Iterate calls fn for each stored path which matches the scope and
+	// whose route equals or starts with the route prefix. The prefix must
+	// match whole route segments, e.g. prefix "web/unsecure" matches
+	// "web/unsecure/base_url" but not "web/unsecure_foo/bar". A zero scope
+	// matches all scopes and an empty prefix matches all routes. Returning
+	// an error from fn stops the iteration and Iterate returns that error.
+	// The order of the paths is defined by the implementation.
+	Iterate(scp scope.TypeID, routePrefix string, fn func(p Path, v []byte) error) error
 }
 
 // ObserverRegisterer adds or removes observers for different events and theirs
@@ -96,6 +107,16 @@ type Service struct {
 	// routeConfig contains essential information about a route like scope for
 	// permission, default value or events.
 	routeConfig *trieRoute
+
+	// muClosers protects the field closers.
+	muClosers sync.Mutex
+	closers   []io.Closer
+	// bridgeNodeID identifies this Service within Options.Bridge.
+	bridgeNodeID string
+	// muSnapshot protects the field snapshots.
+	muSnapshot sync.Mutex
+	// snapshots sorted by version in ascending order.
+	snapshots []*Snapshot
 }
 
 // NewService creates the main new configuration for all scopes: default,
@@ -114,6 +135,9 @@ func NewService(level2 Storager, o Options, fns ...LoadDataOption) (s *Service,
 	if err := s.setupEnv(); err != nil {
 		return nil, errors.WithStack(err)
 	}
+	if err := s.loadSnapshots(); err != nil {
+		return nil, errors.WithStack(err)
+	}
 
 	if o.EnablePubSub {
 		var l log.Logger
@@ -137,6 +161,12 @@ func NewService(level2 Storager, o Options, fns ...LoadDataOption) (s *Service,
 	if err := s.shouldEnableHotReload(); err != nil {
 		return nil, errors.WithStack(err)
 	}
+	if err := s.setupBridge(); err != nil {
+		if err2 := s.Close(); err2 != nil {
+			return nil, errors.WithStack(err2)
+		}
+		return nil, errors.WithStack(err)
+	}
 
 	return s, nil
 }
@@ -236,8 +266,30 @@ func (s *Service) ReplaceEnvName(str string) string {
 	return s.envReplacer.Replace(str)
 }
 
+// RegisterCloser adds a Closer which gets closed when calling Close, before
+// the internal goroutines get terminated. For example a LoadDataOption can
+// register its background goroutine.
+func (s *Service) RegisterCloser(c io.Closer) {
+	s.muClosers.Lock()
+	s.closers = append(s.closers, c)
+	s.muClosers.Unlock()
+}
+
 // Close closes and terminates the internal goroutines and connections.
 func (s *Service) Close() error {
+	s.muClosers.Lock()
+	closers := s.closers
+	s.closers = nil
+	s.muClosers.Unlock()
+
+	// All closers and goroutines must be terminated even if one closer fails,
+	// hence only the first error gets returned.
+	var firstErr error
+	for _, c := range closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = errors.WithStack(err)
+		}
+	}
 
 	if s.config.EnableHotReload {
 		signal.Stop(s.hotReloadSignal)
@@ -245,11 +297,11 @@ func (s *Service) Close() error {
 	}
 
 	if s.config.EnablePubSub {
-		if err := s.pubSub.Close(); err != nil {
-			return errors.WithStack(err)
+		if err := s.pubSub.Close(); err != nil && firstErr == nil {
+			firstErr = errors.WithStack(err)
 		}
 	}
-	return nil
+	return firstErr
 }
 
 // Flush flushes the internal caches. Write operation also flushes the entry for
@@ -338,30 +390,106 @@ func (s *Service) Set(p *Path, v []byte) (err error) { // TODO v should be an im
 	}
 
 	s.mu.RLock()
+	err = s.set(p, v)
+	s.mu.RUnlock()
+	if err == nil {
+		s.publish(*p)
+	}
+	return
+}
+
+// set writes the value into the Level2 storage and dispatches the observers.
+// The caller must hold a lock of s.mu and must send the pubsub message.
+func (s *Service) set(p *Path, v []byte) (err error) {
 	key := p.separatorSuffixRoute() // this can be optimized to move it into the process signature
 	key = buildTrieKey(key, p.ScopeID)
-	if v, _, err = s.routeConfig.process(key, EventOnBeforeSet, p, v, true); err != nil {
-		s.mu.RUnlock()
+	if v, _, err = s.routeConfig.process(key, EventOnBeforeSet, p, v, true, nil); err != nil {
 		return errors.WithStack(err)
 	}
 	defer func() {
 		var err2 error
-		if v, _, err2 = s.routeConfig.process(key, EventOnAfterSet, p, v, err == nil); err == nil && err2 != nil {
+		if v, _, err2 = s.routeConfig.process(key, EventOnAfterSet, p, v, err == nil, nil); err == nil && err2 != nil {
 			err = errors.WithStack(err2)
 		}
-		s.mu.RUnlock()
 	}()
 
 	if err := s.level2.Set(p, v); err != nil {
 		return errors.Wrap(err, "[config] Service.level2.Set")
 	}
-	if s.pubSub != nil {
-		s.pubSub.sendMsg(*p)
+	return
+}
+
+// Delete removes a value from the Service, from the Level1 cache and from the
+// Level2 storage. Safe for concurrent use. Deleting a website or store scoped
+// value lets the scope hierarchy fall back to the parent scope or to the
+// default value. The observers for the events EventOnBeforeDelete and
+// EventOnAfterDelete get called and the subscribers receive a message for the
+// path. Example usage:
+//		// remove the website override, store 6 falls back to the default scope
+//		err := s.Delete(config.MustNewPath("currency/option/base").BindWebsite(3))
+func (s *Service) Delete(p *Path) (err error) {
+	if p.UseEnvSuffix && p.envSuffix != s.envName {
+		p.envSuffix = s.envName
+	}
+	if s.config.Log != nil && s.config.Log.IsDebug() {
+		defer log.WhenDone(s.config.Log).Debug("config.Service.Delete", log.Stringer("path", p), log.Err(err))
+	}
+	if err = p.IsValid(); err != nil {
+		err = errors.WithStack(err)
+		return
+	}
+
+	s.mu.RLock()
+	err = s.delete(p)
+	s.mu.RUnlock()
+	if err == nil {
+		s.publish(*p)
+	}
+	return
+}
+
+// delete removes the value from both storage levels and dispatches the
+// observers. The caller must hold a lock of s.mu and must send the pubsub
+// message.
+func (s *Service) delete(p *Path) (err error) {
+	key := p.separatorSuffixRoute()
+	key = buildTrieKey(key, p.ScopeID)
+	if _, _, err = s.routeConfig.process(key, EventOnBeforeDelete, p, nil, true, nil); err != nil {
+		return errors.WithStack(err)
 	}
+	defer func() {
+		if _, _, err2 := s.routeConfig.process(key, EventOnAfterDelete, p, nil, err == nil, nil); err == nil && err2 != nil {
+			err = errors.WithStack(err2)
+		}
+	}()
 
+	if s.config.Level1 != nil {
+		if err := s.config.Level1.Delete(p); err != nil {
+			return errors.Wrap(err, "[config] Service.Level1.Delete")
+		}
+	}
+	if err := s.level2.Delete(p); err != nil {
+		return errors.Wrap(err, "[config] Service.level2.Delete")
+	}
 	return
 }
 
+// Iterate walks over all values stored in the Level2 storage which match the
+// scope and the route prefix. A zero scope matches all scopes and an empty
+// prefix matches all routes. Default values of the FieldMeta data and
+// observers are not considered. See Storager.Iterate for more details. Example
+// usage to list all website scoped overrides of section "web":
+//		err := s.Iterate(scope.Website.WithID(1), "web", func(p config.Path, v []byte) error {
+//			fmt.Printf("%s => %q\n", p.String(), v)
+//			return nil
+//		})
+func (s *Service) Iterate(scp scope.TypeID, routePrefix string, fn func(p Path, v []byte) error) error {
+	if err := s.level2.Iterate(scp, routePrefix, fn); err != nil {
+		return errors.Wrapf(err, "[config] Service.level2.Iterate with scope %q and route prefix %q", scp.String(), routePrefix)
+	}
+	return nil
+}
+
 // Get returns a configuration value from the Service, ignoring the scope
 // hierarchy/fallback logic using a direct match. Safe for concurrent use.
 // Example usage:
@@ -384,6 +512,12 @@ func (s *Service) Set(p *Path, v []byte) (err error) { // TODO v should be an im
 //
 // Returns a guaranteed non-nil value.
 func (s *Service) Get(p *Path) (v *Value) {
+	return s.get(p, nil)
+}
+
+// get implements Get. The optional argument tr records each step of the
+// value resolution.
+func (s *Service) get(p *Path, tr *ExplainStep) (v *Value) {
 	if p.UseEnvSuffix && p.envSuffix != s.envName {
 		p.envSuffix = s.envName
 	}
@@ -404,7 +538,7 @@ func (s *Service) Get(p *Path) (v *Value) {
 	s.mu.RLock()
 	key := p.separatorSuffixRoute() // this can be optimized to move it into the process signature
 	key = buildTrieKey(key, p.ScopeID)
-	if _, _, err := s.routeConfig.process(key, EventOnBeforeGet, p, nil, false); err != nil {
+	if _, _, err := s.routeConfig.process(key, EventOnBeforeGet, p, nil, false, tr); err != nil {
 		s.mu.RUnlock()
 		v.lastErr = errors.WithStack(err)
 		return
@@ -412,7 +546,7 @@ func (s *Service) Get(p *Path) (v *Value) {
 	defer func() {
 		var err2 error
 		var ok2 bool
-		if v.data, ok2, err2 = s.routeConfig.process(key, EventOnAfterGet, p, v.data, v.found > valFoundNo); v.lastErr == nil && err2 != nil {
+		if v.data, ok2, err2 = s.routeConfig.process(key, EventOnAfterGet, p, v.data, v.found > valFoundNo, tr); v.lastErr == nil && err2 != nil {
 			v.lastErr = errors.WithStack(err2)
 		}
 
@@ -424,6 +558,9 @@ func (s *Service) Get(p *Path) (v *Value) {
 
 	if s.config.Level1 != nil {
 		v.data, ok, v.lastErr = s.config.Level1.Get(p)
+		if tr != nil {
+			tr.addLookup(valFoundL1, ok, v.lastErr)
+		}
 		if v.lastErr != nil {
 			return
 		}
@@ -434,6 +571,9 @@ func (s *Service) Get(p *Path) (v *Value) {
 	}
 
 	v.data, ok, v.lastErr = s.level2.Get(p)
+	if tr != nil {
+		tr.addLookup(valFoundL2, ok, v.lastErr)
+	}
 	switch {
 	case v.lastErr != nil:
 		v.lastErr = errors.Wrapf(v.lastErr, "[config] Service.Value with path %q", p)
@@ -493,12 +633,18 @@ func (s *Service) Unsubscribe(subscriptionID int) error {
 // WebsiteID and StoreID must be in a relation like enforced in the database
 // tables via foreign keys. Empty storeID triggers the website scope. Empty
 // websiteID and empty storeID are triggering the default scope.
+//
+// Custom scopes registered with scope.RegisterType, for example a customer
+// group between website and store, take part in the bubbling if their IDs
+// have been set with WithScopeID.
 type Scoped struct {
 	// Root holds the main functions for retrieving values by paths from the
 	// storage or a fake service.
 	rootSrv   getter
 	websiteID int64
 	storeID   int64
+	// customIDs contains the IDs of the custom scopes.
+	customIDs scope.TypeIDs
 }
 
 // TODO: Scoped should support websites/0/ and stores/0/ to provide a top level websites or stores specific configuration.
@@ -524,11 +670,57 @@ func (ss Scoped) IsValid() bool {
 		(ss.websiteID > 0 && ss.storeID > 0))
 }
 
+// WithScopeID returns a copy of Scoped which additionally considers the
+// custom scopes and their IDs. A custom scope must have been registered with
+// scope.RegisterType. Other types and IDs smaller than one get ignored. An
+// already set custom scope gets replaced.
+func (ss Scoped) WithScopeID(ids ...scope.TypeID) Scoped {
+	custom := make(scope.TypeIDs, 0, len(ss.customIDs)+len(ids))
+	for _, cid := range ss.customIDs {
+		replaced := false
+		for _, id := range ids {
+			replaced = replaced || id.Type() == cid.Type()
+		}
+		if !replaced {
+			custom = append(custom, cid)
+		}
+	}
+	for _, id := range ids {
+		if id.Type().IsCustom() && id.ID() > 0 {
+			custom = append(custom, id)
+		}
+	}
+	ss.customIDs = custom
+	return ss
+}
+
+// id returns the ID for a scope type or zero if the type is not set.
+func (ss Scoped) id(typ scope.Type) int64 {
+	switch typ {
+	case scope.Website:
+		return ss.websiteID
+	case scope.Store:
+		return ss.storeID
+	}
+	for _, cid := range ss.customIDs {
+		if cid.Type() == typ {
+			return cid.ID()
+		}
+	}
+	return 0
+}
+
 // ParentID tells you the parent underlying scope and its ID. Store falls back
-// to website and website falls back to default.
+// to website and website falls back to default. Custom scopes get considered
+// according to their position in the hierarchy.
 func (ss Scoped) ParentID() scope.TypeID {
-	if ss.storeID > 0 {
-		return scope.Website.WithID(ss.websiteID)
+	chain := ss.ScopeID().Type().Chain()
+	for i := 1; i < len(chain); i++ {
+		if typ := chain[i]; typ != scope.Default && typ.IsConfigScope() {
+			if id := ss.id(typ); id > 0 {
+				return typ.WithID(id)
+			}
+		}
 	}
 	return scope.DefaultTypeID
 }
@@ -536,13 +728,19 @@ func (ss Scoped) ParentID() scope.TypeID {
 // ScopeID tells you the current underlying scope and its ID to which this
 // configuration has been bound to.
 func (ss Scoped) ScopeID() scope.TypeID {
+	id := scope.DefaultTypeID
+	if ss.websiteID > 0 {
+		id = scope.Website.WithID(ss.websiteID)
+	}
 	if ss.storeID > 0 {
-		return scope.Store.WithID(ss.storeID)
+		id = scope.Store.WithID(ss.storeID)
 	}
-	if ss.websiteID > 0 {
-		return scope.Website.WithID(ss.websiteID)
+	for _, cid := range ss.customIDs {
+		if id.Type().IsParentOf(cid.Type()) {
+			id = cid
+		}
 	}
-	return scope.DefaultTypeID
+	return id
 }
 
 // ScopeIDs returns the hierarchical order of the scopes containing ScopeID() on
@@ -556,49 +754,50 @@ func (ss Scoped) ScopeIDs() scope.TypeIDs {
 	return ids[:]
 }
 
-func (ss Scoped) isAllowedStore(restrictUpTo scope.Type) bool {
-	scp := ss.ScopeID().Type()
+// restrict returns the scope type up to which values can be queried.
+func (ss Scoped) restrict(restrictUpTo scope.Type) scope.Type {
 	if restrictUpTo > scope.Absent {
-		scp = restrictUpTo
+		return restrictUpTo
 	}
-	return ss.storeID > 0 && scope.PermStoreReverse.Has(scp)
+	return ss.ScopeID().Type()
 }
 
-func (ss Scoped) isAllowedWebsite(restrictUpTo scope.Type) bool {
-	scp := ss.ScopeID().Type()
-	if restrictUpTo > scope.Absent {
-		scp = restrictUpTo
-	}
-	return ss.websiteID > 0 && scope.PermWebsiteReverse.Has(scp)
+// isAllowed reports whether scope typ can be queried. typ must be the
+// restricted scope scp or one of its parents and both types must be
+// configuration scopes.
+func (ss Scoped) isAllowed(typ, scp scope.Type) bool {
+	return ss.id(typ) > 0 && typ.IsConfigScope() && scp.IsConfigScope() &&
+		(typ == scp || typ.IsParentOf(scp))
 }
 
-// Get traverses through the scopes store->website->default to find a matching
-// byte slice value. The argument `restrictUpTo` scope.Type restricts the
-// bubbling. For example a path gets stored in all three scopes but argument
-// `restrictUpTo` specifies only website scope, then the store scope will be
-// ignored for querying. If argument `restrictUpTo` has been set to zero aka.
-// scope.Absent, then all three scopes are considered for querying.
+// Get traverses through the scopes store->website->default, including the
+// custom scopes in between, to find a matching byte slice value. The argument
+// `restrictUpTo` scope.Type restricts the bubbling. For example a path gets
+// stored in all three scopes but argument `restrictUpTo` specifies only
+// website scope, then the store scope will be ignored for querying. If
+// argument `restrictUpTo` has been set to zero aka. scope.Absent, then all
+// three scopes are considered for querying.
 // Returns a guaranteed non-nil Value.
 func (ss Scoped) Get(restrictUpTo scope.Type, route string) (v *Value) {
+	return ss.get(restrictUpTo, route, ss.rootSrv.Get)
+}
+
+// get implements the scope fallback of Get and queries each scope with
+// function getFn.
+func (ss Scoped) get(restrictUpTo scope.Type, route string, getFn func(p *Path) *Value) (v *Value) {
 	// fallback to next parent scope if value does not exists
 	p := Path{
 		route: Route(route),
 	}
-	if ss.isAllowedStore(restrictUpTo) {
-		p.ScopeID = scope.Store.WithID(ss.storeID)
-		v := ss.rootSrv.Get(&p)
-		if v.found > valFoundNo || v.lastErr != nil {
-			// value found or err is not a NotFound error
-			if v.lastErr != nil {
-				v.lastErr = errors.WithStack(v.lastErr) // hmm, maybe can be removed if no one gets confused
-			}
-			return v
+	scp := ss.restrict(restrictUpTo)
+	for _, typ := range ss.ScopeID().Type().Chain() {
+		if typ == scope.Default || !ss.isAllowed(typ, scp) {
+			continue
 		}
-	}
-	if ss.isAllowedWebsite(restrictUpTo) {
-		p.ScopeID = scope.Website.WithID(ss.websiteID)
-		v := ss.rootSrv.Get(&p)
+		p.ScopeID = typ.WithID(ss.id(typ))
+		v := getFn(&p)
 		if v.found > valFoundNo || v.lastErr != nil {
+			// value found or err is not a NotFound error
 			if v.lastErr != nil {
 				v.lastErr = errors.WithStack(v.lastErr) // hmm, maybe can be removed if no one gets confused
 			}
@@ -606,5 +805,5 @@ func (ss Scoped) Get(restrictUpTo scope.Type, route string) (v *Value) {
 		}
 	}
 	p.ScopeID = scope.DefaultTypeID
-	return ss.rootSrv.Get(&p)
+	return getFn(&p)
 }
@@ -548,6 +548,38 @@ func TestHotReload(t *testing.T) {
 	assert.Exactly(t, `"3601s"`, srv.Get(pTimeout).String())
 }
 
+type closerFunc func() error
+
+func (cf closerFunc) Close() error { return cf() }
+
+func TestService_Close_CloserErrors(t *testing.T) {
+	defer leaktest.Check(t)()
+
+	srv := config.MustNewService(storage.NewMap(), config.Options{
+		EnablePubSub:     true,
+		EnableHotReload:  true,
+		HotReloadSignals: []os.Signal{syscall.SIGUSR2},
+	})
+
+	var closed []string
+	srv.RegisterCloser(closerFunc(func() error {
+		closed = append(closed, "first")
+		return errors.ConnectionLost.Newf("first closer failed")
+	}))
+	srv.RegisterCloser(closerFunc(func() error {
+		closed = append(closed, "second")
+		return errors.NotValid.Newf("second closer failed")
+	}))
+	srv.RegisterCloser(closerFunc(func() error {
+		closed = append(closed, "third")
+		return nil
+	}))
+
+	err := srv.Close()
+	assert.True(t, errors.ConnectionLost.Match(err), "%+v", err)
+	assert.Exactly(t, []string{"first", "second", "third"}, closed)
+}
+
 type keyer interface {
 	Keys(ret ...string) []string
 }
@@ -706,6 +738,114 @@ func TestService_Observer(t *testing.T) {
 	})
 }
 
+func TestService_Delete(t *testing.T) {
+	t.Parallel()
+
+	lvl1 := storage.NewMap()
+	srv := config.MustNewService(storage.NewMap(), config.Options{
+		Level1:       lvl1,
+		EnablePubSub: true,
+	}, config.WithFieldMeta(
+		&config.FieldMeta{
+			Route:          "carrier/dhl/timeout",
+			WriteScopePerm: scope.PermDefault,
+			Default:        "3600s",
+		},
+	))
+	defer func() { assert.NoError(t, srv.Close()) }()
+
+	msgC := make(chan string, 2)
+	_, err := srv.Subscribe("websites/2/carrier/dhl", &testSubscriber{
+		t: t,
+		f: func(p config.Path) error {
+			msgC <- p.String()
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	pDefault := config.MustNewPath("carrier/dhl/username")
+	pWebsite := pDefault.BindWebsite(2)
+	assert.NoError(t, srv.Set(pDefault, []byte(`prdUser0`)))
+	assert.NoError(t, srv.Set(pWebsite, []byte(`prdUser2`)))
+	assert.Exactly(t, `"prdUser2"`, srv.Get(pWebsite).String()) // fills level 1
+
+	var beforeCalled, afterCalled int32
+	assert.NoError(t, srv.RegisterObserver(config.EventOnBeforeDelete, "carrier/dhl", testObserver{
+		observe: func(p config.Path, rawData []byte, found bool) ([]byte, error) {
+			assert.Exactly(t, `websites/2/carrier/dhl/username`, p.String())
+			atomic.AddInt32(&beforeCalled, 1)
+			return rawData, nil
+		},
+	}))
+	assert.NoError(t, srv.RegisterObserver(config.EventOnAfterDelete, "carrier/dhl/username", testObserver{
+		observe: func(p config.Path, rawData []byte, found bool) ([]byte, error) {
+			assert.True(t, found, "found indicates a successful delete")
+			atomic.AddInt32(&afterCalled, 1)
+			return rawData, nil
+		},
+	}))
+
+	assert.NoError(t, srv.Delete(pWebsite))
+	assert.Exactly(t, int32(1), atomic.LoadInt32(&beforeCalled))
+	assert.Exactly(t, int32(1), atomic.LoadInt32(&afterCalled))
+	assert.NoError(t, srv.DeregisterObserver(config.EventOnBeforeDelete, "carrier/dhl"))
+	assert.NoError(t, srv.DeregisterObserver(config.EventOnAfterDelete, "carrier/dhl/username"))
+	for i := 0; i < 2; i++ { // one message from Set and one from Delete
+		select {
+		case fq := <-msgC:
+			assert.Exactly(t, `websites/2/carrier/dhl/username`, fq)
+		case <-time.After(time.Second):
+			t.Fatalf("pubsub message %d has not been received", i)
+		}
+	}
+
+	_, ok, _ := lvl1.Get(pWebsite)
+	assert.False(t, ok, "Level1 must not contain the deleted path")
+	assert.Exactly(t, `"prdUser0"`, srv.Scoped(2, 0).Get(scope.Website, "carrier/dhl/username").String(), "must fall back to the default scope")
+
+	t.Run("observer returns error", func(t *testing.T) {
+		assert.NoError(t, srv.RegisterObserver(config.EventOnBeforeDelete, "aa/bb/cc", testObserver{
+			err: errors.AlreadyInUse.Newf("Ups"),
+		}))
+		err := srv.Delete(config.MustNewPath("aa/bb/cc"))
+		assert.True(t, errors.AlreadyInUse.Match(err), "%+v", err)
+		assert.NoError(t, srv.DeregisterObserver(config.EventOnBeforeDelete, "aa/bb/cc"))
+	})
+
+	t.Run("WriteScopePerm denies", func(t *testing.T) {
+		err := srv.Delete(config.MustNewPath("carrier/dhl/timeout").BindStore(3))
+		assert.True(t, errors.NotAllowed.Match(err), "%+v", err)
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		err := srv.Delete(new(config.Path))
+		assert.Error(t, err)
+	})
+}
+
+func TestService_Iterate(t *testing.T) {
+	t.Parallel()
+
+	srv := config.MustNewService(storage.NewMap(), config.Options{})
+	assert.NoError(t, srv.Set(config.MustNewPath("carrier/dhl/username"), []byte(`a`)))
+	assert.NoError(t, srv.Set(config.MustNewPath("carrier/dhl/username").BindWebsite(1), []byte(`b`)))
+	assert.NoError(t, srv.Set(config.MustNewPath("carrier/dhl/password").BindWebsite(1), []byte(`c`)))
+	assert.NoError(t, srv.Set(config.MustNewPath("carrier/ups/username").BindWebsite(1), []byte(`d`)))
+
+	var have []string
+	assert.NoError(t, srv.Iterate(scope.Website.WithID(1), "carrier/dhl", func(p config.Path, v []byte) error {
+		have = append(have, p.String()+"="+string(v))
+		return nil
+	}))
+	assert.Exactly(t, []string{"websites/1/carrier/dhl/password=c", "websites/1/carrier/dhl/username=b"}, have)
+
+	err := srv.Iterate(0, "", func(p config.Path, v []byte) error {
+		return errors.Aborted.Newf("stop")
+	})
+	assert.True(t, errors.Aborted.Match(err), "%+v", err)
+}
+
 func genFieldMetaChan(fms ...*config.FieldMeta) func(*config.Service) (<-chan *config.FieldMeta, <-chan error) {
 	return func(s *config.Service) (<-chan *config.FieldMeta, <-chan error) {
 		fmc := make(chan *config.FieldMeta)
@@ -0,0 +1,600 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// snapshotRoute defines the route prefix under which the snapshots get
+// persisted in Options.SnapshotStorage. Paths with this route prefix are
+// excluded from a snapshot.
+const snapshotRoute = "config/snapshot/version"
+
+// Change kinds returned by Snapshot.Diff.
+const (
+	ChangeAdded uint8 = iota + 1
+	ChangeModified
+	ChangeDeleted
+)
+
+// Change describes the difference of one path between two snapshots.
+type Change struct {
+	// Kind one of the constants ChangeAdded, ChangeModified or ChangeDeleted.
+	Kind uint8
+	Path Path
+	// Old value, nil for ChangeAdded.
+	Old []byte
+	// New value, nil for ChangeDeleted.
+	New []byte
+}
+
+// String returns a unified diff like line.
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s %q", c.Path.String(), c.New)
+	case ChangeModified:
+		return fmt.Sprintf("~ %s %q => %q", c.Path.String(), c.Old, c.New)
+	case ChangeDeleted:
+		return fmt.Sprintf("- %s %q", c.Path.String(), c.Old)
+	}
+	return fmt.Sprintf("? %s", c.Path.String())
+}
+
+// Changes a list of changes sorted by the fully qualified path.
+type Changes []Change
+
+// String returns one Change per line.
+func (cs Changes) String() string {
+	var buf bytes.Buffer
+	for i, c := range cs {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(c.String())
+	}
+	return buf.String()
+}
+
+// invert returns the changes which undo cs, in reversed order.
+func (cs Changes) invert() Changes {
+	inv := make(Changes, 0, len(cs))
+	for i := len(cs) - 1; i >= 0; i-- {
+		c := cs[i]
+		switch c.Kind {
+		case ChangeAdded:
+			c.Kind = ChangeDeleted
+		case ChangeDeleted:
+			c.Kind = ChangeAdded
+		}
+		c.Old, c.New = c.New, c.Old
+		inv = append(inv, c)
+	}
+	return inv
+}
+
+// Snapshot contains an immutable copy of all values of the Level2 storage of
+// a Service at a specific point in time. A Snapshot gets created with
+// Service.TakeSnapshot or with NewSnapshot to import data. Snapshot implements
+// the Storager interface, so the loaders of package config/storage can fill a
+// Snapshot. A Snapshot created by the Service must not be modified.
+type Snapshot struct {
+	// Version starts at one and gets incremented with each
+	// Service.TakeSnapshot call. Zero for an imported snapshot.
+	Version uint64
+	Created time.Time
+	Comment string
+	// EnvName the name of the environment of the Service, e.g. STAGING or
+	// PRODUCTION. Paths with an environment suffix get compared without the
+	// suffix in Diff.
+	EnvName string
+	values  map[string]snapshotValue // key is the fully qualified path
+}
+
+type snapshotValue struct {
+	p Path
+	v []byte
+}
+
+// NewSnapshot creates a new empty Snapshot, mainly for importing data.
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		values: make(map[string]snapshotValue),
+	}
+}
+
+// normalizeSnapshotPath merges the environment suffix into the route, the same
+// as the Storager receives it, and removes the ID of the default scope.
+func normalizeSnapshotPath(p *Path) Path {
+	np := Path{
+		route:   p.route,
+		ScopeID: p.ScopeID,
+	}
+	if p.UseEnvSuffix && p.envSuffix != "" {
+		np.route = Route(string(p.route) + sPathSeparator + p.envSuffix)
+	}
+	if t := np.ScopeID.Type(); !t.IsWebSiteOrStore() && !t.IsCustom() {
+		np.ScopeID = scope.DefaultTypeID
+	}
+	return np
+}
+
+// Set adds or replaces a value. Implements interface Storager.
+func (sn *Snapshot) Set(p *Path, v []byte) error {
+	if err := p.IsValid(); err != nil {
+		return errors.WithStack(err)
+	}
+	np := normalizeSnapshotPath(p)
+	fq, err := np.FQ()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if sn.values == nil {
+		sn.values = make(map[string]snapshotValue)
+	}
+	sn.values[fq] = snapshotValue{p: np, v: append([]byte(nil), v...)}
+	return nil
+}
+
+// Get returns a value. Implements interface Storager.
+func (sn *Snapshot) Get(p *Path) (v []byte, found bool, err error) {
+	np := normalizeSnapshotPath(p)
+	fq, err := np.FQ()
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	sv, found := sn.values[fq]
+	return sv.v, found, nil
+}
+
+// Delete removes a value. Implements interface Storager.
+func (sn *Snapshot) Delete(p *Path) error {
+	np := normalizeSnapshotPath(p)
+	fq, err := np.FQ()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	delete(sn.values, fq)
+	return nil
+}
+
+// Iterate calls fn for each matching value sorted by the fully qualified path.
+// Implements interface Storager.
+func (sn *Snapshot) Iterate(scp scope.TypeID, routePrefix string, fn func(p Path, v []byte) error) error {
+	for _, fq := range sn.sortedKeys() {
+		sv := sn.values[fq]
+		if scp > 0 && sv.p.ScopeID != scp {
+			continue
+		}
+		if r := string(sv.p.route); routePrefix != "" && r != routePrefix && !strings.HasPrefix(r, routePrefix+sPathSeparator) {
+			continue
+		}
+		if err := fn(sv.p, sv.v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Len returns the number of values.
+func (sn *Snapshot) Len() int {
+	return len(sn.values)
+}
+
+func (sn *Snapshot) sortedKeys() []string {
+	keys := make([]string, 0, len(sn.values))
+	for fq := range sn.values {
+		keys = append(keys, fq)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Tree returns the values in the structure route => scope => scope ID =>
+// value. The same structure gets used by the YAML and JSON loaders in package
+// config/storage.
+func (sn *Snapshot) Tree() map[string]map[string]map[string]string {
+	t := make(map[string]map[string]map[string]string, len(sn.values))
+	for _, sv := range sn.values {
+		route := string(sv.p.route)
+		scp, id := sv.p.ScopeID.Unpack()
+		if t[route] == nil {
+			t[route] = make(map[string]map[string]string, 3)
+		}
+		strScp := scp.StrType()
+		if t[route][strScp] == nil {
+			t[route][strScp] = make(map[string]string, 1)
+		}
+		t[route][strScp][strconv.FormatInt(id, 10)] = string(sv.v)
+	}
+	return t
+}
+
+// compareKey strips the environment suffix from the fully qualified path.
+func (sn *Snapshot) compareKey(fq string) string {
+	if sn.EnvName == "" {
+		return fq
+	}
+	return strings.TrimSuffix(fq, sPathSeparator+sn.EnvName)
+}
+
+func (sn *Snapshot) byCompareKey() map[string]snapshotValue {
+	m := make(map[string]snapshotValue, len(sn.values))
+	for fq, sv := range sn.values {
+		m[sn.compareKey(fq)] = sv
+	}
+	return m
+}
+
+// Diff computes the changes which transform the current snapshot into the
+// snapshot `to`. Diff can compare two versions of the same Service or two
+// Services of different environments, for example STAGING with PRODUCTION,
+// because paths with an environment suffix get compared without the suffix.
+func (sn *Snapshot) Diff(to *Snapshot) Changes {
+	from, toM := sn.byCompareKey(), to.byCompareKey()
+	keys := make([]string, 0, len(toM))
+	for k := range toM {
+		keys = append(keys, k)
+	}
+	for k := range from {
+		if _, ok := toM[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var cs Changes
+	for _, k := range keys {
+		fv, inFrom := from[k]
+		tv, inTo := toM[k]
+		switch {
+		case inFrom && !inTo:
+			cs = append(cs, Change{Kind: ChangeDeleted, Path: fv.p, Old: fv.v})
+		case !inFrom && inTo:
+			cs = append(cs, Change{Kind: ChangeAdded, Path: tv.p, New: tv.v})
+		case !bytes.Equal(fv.v, tv.v):
+			cs = append(cs, Change{Kind: ChangeModified, Path: tv.p, Old: fv.v, New: tv.v})
+		}
+	}
+	return cs
+}
+
+type snapshotJSON struct {
+	Version uint64                                  `json:"version"`
+	Created time.Time                               `json:"created"`
+	Comment string                                  `json:"comment,omitempty"`
+	EnvName string                                  `json:"env_name,omitempty"`
+	Values  map[string]map[string]map[string]string `json:"values"`
+}
+
+// MarshalJSON encodes the Snapshot including its meta data. The values get
+// encoded as strings.
+func (sn *Snapshot) MarshalJSON() ([]byte, error) {
+	return json.Marshal(snapshotJSON{
+		Version: sn.Version,
+		Created: sn.Created,
+		Comment: sn.Comment,
+		EnvName: sn.EnvName,
+		Values:  sn.Tree(),
+	})
+}
+
+// UnmarshalJSON decodes data created with MarshalJSON.
+func (sn *Snapshot) UnmarshalJSON(data []byte) error {
+	var sj snapshotJSON
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return errors.BadEncoding.New(err, "[config] Snapshot.UnmarshalJSON")
+	}
+	sn.Version, sn.Created, sn.Comment, sn.EnvName = sj.Version, sj.Created, sj.Comment, sj.EnvName
+	sn.values = make(map[string]snapshotValue, len(sj.Values))
+	var p Path
+	for route, scps := range sj.Values {
+		for scp, ids := range scps {
+			for id, v := range ids {
+				if err := p.ParseStrings(scp, id, route); err != nil {
+					return errors.CorruptData.New(err, "[config] Snapshot.UnmarshalJSON with path %q %q %q", scp, id, route)
+				}
+				if err := sn.Set(&p, []byte(v)); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// loadSnapshots loads the persisted snapshots from Options.SnapshotStorage.
+func (s *Service) loadSnapshots() error {
+	if s.config.SnapshotStorage == nil {
+		return nil
+	}
+	err := s.config.SnapshotStorage.Iterate(scope.DefaultTypeID, snapshotRoute, func(p Path, v []byte) error {
+		sn := NewSnapshot()
+		if err := json.Unmarshal(v, sn); err != nil {
+			return errors.CorruptData.New(err, "[config] Failed to decode snapshot %q", p.String())
+		}
+		s.snapshots = append(s.snapshots, sn)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "[config] Service.loadSnapshots")
+	}
+	sort.Slice(s.snapshots, func(i, j int) bool {
+		return s.snapshots[i].Version < s.snapshots[j].Version
+	})
+	return nil
+}
+
+func snapshotStoragePath(version uint64) (*Path, error) {
+	return NewPath(snapshotRoute + sPathSeparator + strconv.FormatUint(version, 10))
+}
+
+// snapshot copies all values of the Level2 storage into sn. The caller must
+// hold the write lock of s.mu to get a consistent view.
+func (s *Service) snapshot(sn *Snapshot) error {
+	err := s.level2.Iterate(0, "", func(p Path, v []byte) error {
+		if strings.HasPrefix(string(p.route), snapshotRoute+sPathSeparator) {
+			return nil
+		}
+		return sn.Set(&p, v)
+	})
+	return errors.Wrap(err, "[config] Service.level2.Iterate")
+}
+
+// TakeSnapshot creates a new version of all values stored in the Level2
+// storage. Writes to the Service are blocked while copying the values. The
+// snapshot gets kept in memory and gets persisted if Options.SnapshotStorage
+// has been set. The oldest snapshot gets removed if the number of snapshots
+// exceeds Options.SnapshotMaxVersions.
+func (s *Service) TakeSnapshot(comment string) (*Snapshot, error) {
+	sn := NewSnapshot()
+	sn.Created = time.Now()
+	sn.Comment = comment
+	sn.EnvName = s.envName
+
+	s.mu.Lock()
+	err := s.snapshot(sn)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	s.muSnapshot.Lock()
+	defer s.muSnapshot.Unlock()
+
+	sn.Version = 1
+	if l := len(s.snapshots); l > 0 {
+		sn.Version = s.snapshots[l-1].Version + 1
+	}
+
+	if st := s.config.SnapshotStorage; st != nil {
+		data, err := json.Marshal(sn)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		p, err := snapshotStoragePath(sn.Version)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := st.Set(p, data); err != nil {
+			return nil, errors.Wrapf(err, "[config] Service.TakeSnapshot failed to persist version %d", sn.Version)
+		}
+	}
+	s.snapshots = append(s.snapshots, sn)
+
+	for maxV := s.config.SnapshotMaxVersions; maxV > 0 && len(s.snapshots) > maxV; {
+		if st := s.config.SnapshotStorage; st != nil {
+			p, err := snapshotStoragePath(s.snapshots[0].Version)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if err := st.Delete(p); err != nil {
+				return nil, errors.Wrapf(err, "[config] Service.TakeSnapshot failed to remove version %d", s.snapshots[0].Version)
+			}
+		}
+		s.snapshots[0] = nil
+		s.snapshots = s.snapshots[1:]
+	}
+	return sn, nil
+}
+
+// Snapshots returns all available snapshots sorted by version in ascending
+// order.
+func (s *Service) Snapshots() []*Snapshot {
+	s.muSnapshot.Lock()
+	defer s.muSnapshot.Unlock()
+	return append([]*Snapshot(nil), s.snapshots...)
+}
+
+// Snapshot returns the snapshot for a version. Returns a NotFound error if
+// the version does not exist.
+func (s *Service) Snapshot(version uint64) (*Snapshot, error) {
+	s.muSnapshot.Lock()
+	defer s.muSnapshot.Unlock()
+	for _, sn := range s.snapshots {
+		if sn.Version == version {
+			return sn, nil
+		}
+	}
+	return nil, errors.NotFound.Newf("[config] Snapshot version %d not found", version)
+}
+
+// Rollback restores the values of a previous snapshot version. See Restore.
+func (s *Service) Rollback(version uint64) (Changes, error) {
+	sn, err := s.Snapshot(version)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return s.Restore(sn)
+}
+
+// Restore replaces all values of the Level2 storage with the values of the
+// snapshot and returns the applied changes. Restore runs atomically like
+// ApplyChanges: readers see either none or all changes and if one change
+// fails, for example due to an observer, all already applied changes get
+// reverted and an Aborted error gets returned. The observers for the set and
+// delete events get called. The subscribers receive a message for each
+// changed path after all changes have been applied. A snapshot of another
+// environment cannot be restored and returns a Mismatch error.
+func (s *Service) Restore(sn *Snapshot) (Changes, error) {
+	if sn.EnvName != "" && sn.EnvName != s.envName {
+		return nil, errors.Mismatch.Newf("[config] Snapshot environment %q does not match the environment %q of the Service", sn.EnvName, s.envName)
+	}
+
+	current := NewSnapshot()
+	current.EnvName = s.envName
+	s.mu.Lock()
+	err := s.snapshot(current)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	cs := current.Diff(sn)
+	if err := s.applyChangesAtomic(cs); err != nil {
+		return nil, errors.Wrap(err, "[config] Service.Restore")
+	}
+	s.notifyChanges(cs)
+	return cs, nil
+}
+
+// ApplyChanges writes the changes atomically into the Service, for example the
+// changes between two versions of configuration files. Readers see either
+// none or all changes and if one change fails, for example due to the
+// validation of an observer, all already applied changes get reverted and an
+// Aborted error gets returned. The observers run without the write lock, so
+// they can read other values from the Service, like with Set. The
+// EventOnBefore* observers see the values before any change has been applied.
+// The subscribers receive a message for each changed path after all changes
+// have been applied.
+func (s *Service) ApplyChanges(cs Changes) error {
+	if len(cs) == 0 {
+		return nil
+	}
+	if err := s.applyChangesAtomic(cs); err != nil {
+		return errors.WithStack(err)
+	}
+	s.notifyChanges(cs)
+	return nil
+}
+
+// applyChangesAtomic applies all changes or none. The caller must not hold a
+// lock of s.mu. Only the storages get written while holding the write lock,
+// the observers run while holding the read lock, same as in Set and Delete.
+// First the EventOnBefore* observers validate and might modify the new values,
+// a failure aborts without writing anything. Then all changes get written to
+// the storages. At last the EventOnAfter* observers get called, a failure
+// reverts the written changes.
+func (s *Service) applyChangesAtomic(cs Changes) error {
+	s.mu.RLock()
+	cs, err := s.observeChanges(cs, EventOnBeforeSet, EventOnBeforeDelete)
+	s.mu.RUnlock()
+	if err != nil {
+		return errors.Aborted.New(err, "[config] Changes have been aborted")
+	}
+
+	s.mu.Lock()
+	applied, err := s.writeChanges(cs)
+	if err != nil {
+		err = s.revertChanges(applied, err)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	_, err = s.observeChanges(cs, EventOnAfterSet, EventOnAfterDelete)
+	s.mu.RUnlock()
+	if err != nil {
+		s.mu.Lock()
+		err = s.revertChanges(cs, err)
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// revertChanges undoes the applied changes after err occurred. The caller must
+// hold the write lock of s.mu.
+func (s *Service) revertChanges(applied Changes, err error) error {
+	if _, err2 := s.writeChanges(applied.invert()); err2 != nil {
+		return errors.Fatal.New(err2, "[config] Failed to revert the changes after error: %+v", err)
+	}
+	return errors.Aborted.New(err, "[config] Changes have been aborted and reverted")
+}
+
+func (s *Service) notifyChanges(cs Changes) {
+	for _, c := range cs {
+		s.publish(c.Path)
+	}
+}
+
+// observeChanges dispatches the observers of the set or delete event for each
+// change and returns the changes with the possibly modified new values. The
+// caller must hold the read lock of s.mu.
+func (s *Service) observeChanges(cs Changes, eventSet, eventDelete uint8) (Changes, error) {
+	observed := make(Changes, len(cs))
+	for i, c := range cs {
+		key := buildTrieKey(c.Path.separatorSuffixRoute(), c.Path.ScopeID)
+		var err error
+		if c.Kind == ChangeDeleted {
+			_, _, err = s.routeConfig.process(key, eventDelete, &c.Path, nil, true, nil)
+		} else {
+			c.New, _, err = s.routeConfig.process(key, eventSet, &c.Path, c.New, true, nil)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "[config] Failed to apply change: %s", c.String())
+		}
+		observed[i] = c
+	}
+	return observed, nil
+}
+
+// writeChanges writes the changes into the storages without dispatching the
+// observers and returns the successfully applied changes. The caller must hold
+// the write lock of s.mu.
+func (s *Service) writeChanges(cs Changes) (applied Changes, err error) {
+	for _, c := range cs {
+		p := c.Path
+		if s.config.Level1 != nil {
+			err = s.config.Level1.Delete(&p) // invalidate the cache
+		}
+		if err == nil && c.Kind == ChangeDeleted {
+			err = s.level2.Delete(&p)
+		} else if err == nil {
+			err = s.level2.Set(&p, c.New)
+		}
+		if err != nil {
+			return applied, errors.Wrapf(err, "[config] Failed to apply change: %s", c.String())
+		}
+		applied = append(applied, c)
+	}
+	return applied, nil
+}
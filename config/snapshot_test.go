@@ -0,0 +1,254 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+var _ config.Storager = (*config.Snapshot)(nil)
+
+func snapshotVersions(sns []*config.Snapshot) []uint64 {
+	vs := make([]uint64, len(sns))
+	for i, sn := range sns {
+		vs[i] = sn.Version
+	}
+	return vs
+}
+
+func TestService_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	snapStorage := storage.NewMap()
+	opts := config.Options{
+		Level1:              storage.NewMap(),
+		EnablePubSub:        true,
+		SnapshotStorage:     snapStorage,
+		SnapshotMaxVersions: 2,
+	}
+	srv := config.MustNewService(storage.NewMap(), opts)
+	defer func() { assert.NoError(t, srv.Close()) }()
+
+	pDefault := config.MustNewPath("aa/bb/cc")
+	pWebsite := pDefault.BindWebsite(1)
+	pNew := config.MustNewPath("dd/ee/ff")
+
+	assert.NoError(t, srv.Set(pDefault, []byte(`1`)))
+	assert.NoError(t, srv.Set(pWebsite, []byte(`2`)))
+
+	sn1, err := srv.TakeSnapshot("initial")
+	assert.NoError(t, err)
+	assert.Exactly(t, uint64(1), sn1.Version)
+	assert.Exactly(t, "initial", sn1.Comment)
+	assert.Exactly(t, 2, sn1.Len())
+
+	assert.Exactly(t, `"2"`, srv.Get(pWebsite).String()) // fills level 1
+	assert.NoError(t, srv.Set(pWebsite, []byte(`3`)))
+	assert.NoError(t, srv.Set(pNew, []byte(`x`)))
+	assert.NoError(t, srv.Delete(pDefault))
+
+	sn2, err := srv.TakeSnapshot("second")
+	assert.NoError(t, err)
+	assert.Exactly(t, uint64(2), sn2.Version)
+	assert.Exactly(t, "- default/0/aa/bb/cc \"1\"\n+ default/0/dd/ee/ff \"x\"\n~ websites/1/aa/bb/cc \"2\" => \"3\"", sn1.Diff(sn2).String())
+
+	msgC := make(chan string, 1)
+	_, err = srv.Subscribe("websites/1/aa/bb", &testSubscriber{
+		t: t,
+		f: func(p config.Path) error {
+			msgC <- p.String()
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	t.Run("rollback", func(t *testing.T) {
+		cs, err := srv.Rollback(1)
+		assert.NoError(t, err)
+		assert.Len(t, cs, 3)
+		assert.Exactly(t, `"1"`, srv.Get(pDefault).String())
+		assert.Exactly(t, `"2"`, srv.Get(pWebsite).String(), "Level1 must have been invalidated")
+		assert.False(t, srv.Get(pNew).IsValid())
+		select {
+		case fq := <-msgC:
+			assert.Exactly(t, `websites/1/aa/bb/cc`, fq)
+		case <-time.After(time.Second):
+			t.Fatal("pubsub message has not been received")
+		}
+	})
+
+	t.Run("max versions", func(t *testing.T) {
+		sn3, err := srv.TakeSnapshot("after rollback")
+		assert.NoError(t, err)
+		assert.Exactly(t, uint64(3), sn3.Version)
+		assert.Len(t, sn1.Diff(sn3), 0)
+		assert.Exactly(t, []uint64{2, 3}, snapshotVersions(srv.Snapshots()))
+		_, err = srv.Snapshot(1)
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+	})
+
+	t.Run("persisted snapshots", func(t *testing.T) {
+		srv2 := config.MustNewService(storage.NewMap(), opts)
+		defer func() { assert.NoError(t, srv2.Close()) }()
+		assert.Exactly(t, []uint64{2, 3}, snapshotVersions(srv2.Snapshots()))
+		sn2b, err := srv2.Snapshot(2)
+		assert.NoError(t, err)
+		assert.Exactly(t, "second", sn2b.Comment)
+		assert.Len(t, sn2.Diff(sn2b), 0)
+	})
+
+	t.Run("failed rollback gets reverted", func(t *testing.T) {
+		assert.NoError(t, srv.RegisterObserver(config.EventOnBeforeSet, "dd/ee/ff", testObserver{
+			err: errors.NotValid.Newf("Invalid value"),
+		}))
+		defer func() { assert.NoError(t, srv.DeregisterObserver(config.EventOnBeforeSet, "dd/ee/ff")) }()
+
+		cs, err := srv.Rollback(2)
+		assert.Nil(t, cs)
+		assert.True(t, errors.Aborted.Match(err), "%+v", err)
+		assert.Exactly(t, `"1"`, srv.Get(pDefault).String())
+		assert.Exactly(t, `"2"`, srv.Get(pWebsite).String())
+		assert.False(t, srv.Get(pNew).IsValid())
+	})
+
+	t.Run("environment mismatch", func(t *testing.T) {
+		sn := config.NewSnapshot()
+		sn.EnvName = "STAGING"
+		cs, err := srv.Restore(sn)
+		assert.Nil(t, cs)
+		assert.True(t, errors.Mismatch.Match(err), "%+v", err)
+	})
+}
+
+func TestSnapshot_Diff_Environments(t *testing.T) {
+	t.Parallel()
+
+	takeSnapshot := func(envName, key string) *config.Snapshot {
+		srv := config.MustNewService(storage.NewMap(), config.Options{EnvName: envName})
+		defer func() { assert.NoError(t, srv.Close()) }()
+		assert.NoError(t, srv.Set(config.MustNewPath("payment/stripe/key").WithEnvSuffix(), []byte(key)))
+		assert.NoError(t, srv.Set(config.MustNewPath("payment/stripe/active"), []byte(`1`)))
+		if envName == "STAGING" {
+			assert.NoError(t, srv.Set(config.MustNewPath("dev/debug/active"), []byte(`1`)))
+		}
+		sn, err := srv.TakeSnapshot(envName)
+		assert.NoError(t, err)
+		assert.Exactly(t, envName, sn.EnvName)
+		return sn
+	}
+
+	snStaging := takeSnapshot("STAGING", "sk_test")
+	snProduction := takeSnapshot("PRODUCTION", "sk_live")
+
+	assert.Exactly(t,
+		"- default/0/dev/debug/active \"1\"\n~ default/0/payment/stripe/key/PRODUCTION \"sk_test\" => \"sk_live\"",
+		snStaging.Diff(snProduction).String())
+}
+
+func TestService_ApplyChanges_ObserverReadsService(t *testing.T) {
+	t.Parallel()
+
+	srv := config.MustNewService(storage.NewMap(), config.Options{})
+	defer func() { assert.NoError(t, srv.Close()) }()
+
+	pMode := config.MustNewPath("aa/bb/mode")
+	pURL := config.MustNewPath("aa/bb/url")
+	assert.NoError(t, srv.Set(pURL, []byte(`https://corestore.io`)))
+
+	// validates the dependent route, as an observer of a config field would do.
+	assert.NoError(t, srv.RegisterObserver(config.EventOnBeforeSet, "aa/bb/mode", testObserver{
+		observe: func(p config.Path, rawData []byte, found bool) ([]byte, error) {
+			if string(rawData) == "secure" && !strings.HasPrefix(srv.Get(pURL).UnsafeStr(), "https://") {
+				return nil, errors.NotValid.Newf("URL must be secure")
+			}
+			return rawData, nil
+		},
+	}))
+	var afterValue string
+	assert.NoError(t, srv.RegisterObserver(config.EventOnAfterSet, "aa/bb/mode", testObserver{
+		observe: func(p config.Path, rawData []byte, found bool) ([]byte, error) {
+			afterValue = srv.Get(pMode).String()
+			return rawData, nil
+		},
+	}))
+
+	applyChanges := func(cs config.Changes) error {
+		errC := make(chan error, 1)
+		go func() { errC <- srv.ApplyChanges(cs) }()
+		select {
+		case err := <-errC:
+			return err
+		case <-time.After(time.Second):
+			t.Fatal("ApplyChanges deadlocks when an observer reads from the Service")
+		}
+		return nil
+	}
+
+	t.Run("observers read the Service", func(t *testing.T) {
+		assert.NoError(t, applyChanges(config.Changes{
+			{Kind: config.ChangeAdded, Path: *pMode, New: []byte(`secure`)},
+		}))
+		assert.Exactly(t, `"secure"`, srv.Get(pMode).String())
+		assert.Exactly(t, `"secure"`, afterValue, "after observer must see the applied value")
+	})
+
+	t.Run("observer rejects", func(t *testing.T) {
+		pOther := config.MustNewPath("aa/bb/other")
+		assert.NoError(t, srv.Set(pMode, []byte(`plain`)))
+		assert.NoError(t, srv.Set(pURL, []byte(`http://corestore.io`)))
+		defer func() { assert.NoError(t, srv.Set(pURL, []byte(`https://corestore.io`))) }()
+
+		err := applyChanges(config.Changes{
+			{Kind: config.ChangeAdded, Path: *pOther, New: []byte(`1`)},
+			{Kind: config.ChangeModified, Path: *pMode, Old: []byte(`plain`), New: []byte(`secure`)},
+		})
+		assert.True(t, errors.Aborted.Match(err), "%+v", err)
+		assert.Exactly(t, `"plain"`, srv.Get(pMode).String())
+		assert.False(t, srv.Get(pOther).IsValid())
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		assert.NoError(t, srv.Set(pMode, []byte(`secure`)))
+		sn, err := srv.TakeSnapshot("secure")
+		assert.NoError(t, err)
+		assert.NoError(t, srv.Set(pMode, []byte(`plain`)))
+
+		errC := make(chan error, 1)
+		go func() {
+			_, err := srv.Restore(sn)
+			errC <- err
+		}()
+		select {
+		case err := <-errC:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("Restore deadlocks when an observer reads from the Service")
+		}
+		assert.Exactly(t, `"secure"`, srv.Get(pMode).String())
+	})
+}
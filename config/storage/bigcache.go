@@ -26,6 +26,7 @@ import (
 	"github.com/corestoreio/errors"
 
 	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
 )
 
 type bcStorage struct {
@@ -71,3 +72,33 @@ func (s *bcStorage) Get(p *config.Path) (v []byte, found bool, err error) {
 
 	return val, true, nil
 }
+
+// Delete removes a value from the cache.
+func (s *bcStorage) Delete(p *config.Path) error {
+	err := s.bc.Delete(p.String())
+	if _, isNotFound := err.(*bigcache.EntryNotFoundError); err != nil && !isNotFound {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Iterate walks over all entries in the cache. Each key gets parsed back into
+// a config.Path. The paths are sorted by scope and route.
+func (s *bcStorage) Iterate(scp scope.TypeID, routePrefix string, fn func(p config.Path, v []byte) error) error {
+	var pvs pathValues
+	it := s.bc.Iterator()
+	for it.SetNext() {
+		ei, err := it.Value()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		var p config.Path
+		if err := p.Parse(ei.Key()); err != nil {
+			return errors.Wrapf(err, "[config/storage] bcStorage.Iterate with key %q", ei.Key())
+		}
+		if pScp, route := p.ScopeRoute(); matchScopeRoute(scp, routePrefix, pScp, route) {
+			pvs = append(pvs, pathValue{p: p, v: ei.Value()})
+		}
+	}
+	return pvs.iterate(fn)
+}
@@ -93,3 +93,13 @@ func TestCacheError(t *testing.T) {
 	assert.True(t, errors.Fatal.Match(err), "Error: %s", err)
 	assert.Empty(t, sc)
 }
+
+func TestCache_DeleteIterate(t *testing.T) {
+	bgc, err := storage.NewBigCache(bigcache.Config{
+		Shards: 64,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateDeleteIterate(t, bgc)
+}
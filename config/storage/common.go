@@ -20,6 +20,9 @@ Sniperkit-Bot
 package storage
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/corestoreio/errors"
 
 	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
@@ -35,6 +38,52 @@ func makeCacheKey(s scope.TypeID, r string) cacheKey {
 	return cacheKey{scp: s, route: r}
 }
 
+// matchScopeRoute reports whether the stored scope and route match the
+// arguments of config.Storager.Iterate. A zero wantScp matches all scopes and
+// an empty prefix matches all routes. The prefix must match whole route
+// segments.
+func matchScopeRoute(wantScp scope.TypeID, prefix string, scp scope.TypeID, route string) bool {
+	if wantScp > 0 && wantScp != scp {
+		return false
+	}
+	prefix = strings.TrimSuffix(prefix, string(config.PathSeparator))
+	if prefix == "" || route == prefix {
+		return true
+	}
+	return len(route) > len(prefix) && route[len(prefix)] == config.PathSeparator && strings.HasPrefix(route, prefix)
+}
+
+// pathValue gets used to collect the matching items while holding a lock and
+// to call the iteration callback after releasing the lock, so the callback can
+// use the storage again.
+type pathValue struct {
+	p config.Path
+	v []byte
+}
+
+type pathValues []pathValue
+
+func (pvs pathValues) Len() int      { return len(pvs) }
+func (pvs pathValues) Swap(i, j int) { pvs[i], pvs[j] = pvs[j], pvs[i] }
+func (pvs pathValues) Less(i, j int) bool {
+	si, ri := pvs[i].p.ScopeRoute()
+	sj, rj := pvs[j].p.ScopeRoute()
+	if si != sj {
+		return si < sj
+	}
+	return ri < rj
+}
+
+func (pvs pathValues) iterate(fn func(p config.Path, v []byte) error) error {
+	sort.Sort(pvs)
+	for _, pv := range pvs {
+		if err := fn(pv.p, pv.v); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
 // WithLoadStrings loads a balanced fully qualified path and its stringified
 // value pair into the config.Service. It does not panic when the fqPathValue
 // slice argument isn't balanced, but returns an error. This functional option
@@ -104,3 +104,65 @@ func TestWithLoadStrings(t *testing.T) {
 		assert.Exactly(t, "\"alph\\uf8ffX\"", cfgSrv.Get(pUserName).String())
 	})
 }
+
+// validateDeleteIterate runs a storage independent test for the functions
+// Delete and Iterate.
+func validateDeleteIterate(t *testing.T, s config.Storager) {
+	for _, p := range []*config.Path{
+		config.MustNewPath("web/unsecure/base_url"),
+		config.MustNewPath("web/unsecure/base_url").BindWebsite(1),
+		config.MustNewPath("web/unsecure/base_link_url").BindStore(2),
+		config.MustNewPath("web/unsecure_x/base_url").BindWebsite(1),
+		config.MustNewPath("web/secure/base_url").BindWebsite(1),
+		config.MustNewPath("dev/js/merge_files"),
+	} {
+		assert.NoError(t, s.Set(p, []byte(p.String())))
+	}
+
+	collect := func(scp scope.TypeID, prefix string) []string {
+		var have []string
+		assert.NoError(t, s.Iterate(scp, prefix, func(p config.Path, v []byte) error {
+			assert.Exactly(t, p.String(), string(v))
+			have = append(have, p.String())
+			return nil
+		}))
+		return have
+	}
+
+	assert.Exactly(t, []string{
+		"default/0/dev/js/merge_files",
+		"default/0/web/unsecure/base_url",
+		"websites/1/web/secure/base_url",
+		"websites/1/web/unsecure/base_url",
+		"websites/1/web/unsecure_x/base_url",
+		"stores/2/web/unsecure/base_link_url",
+	}, collect(0, ""))
+	assert.Exactly(t, []string{
+		"default/0/web/unsecure/base_url",
+		"websites/1/web/unsecure/base_url",
+		"stores/2/web/unsecure/base_link_url",
+	}, collect(0, "web/unsecure"))
+	assert.Exactly(t, []string{
+		"websites/1/web/unsecure/base_url",
+	}, collect(scope.Website.WithID(1), "web/unsecure/"))
+	assert.Exactly(t, []string(nil), collect(scope.Store.WithID(3), "web"))
+
+	errStop := errors.Aborted.Newf("stop")
+	var calls int
+	err := s.Iterate(0, "web", func(p config.Path, v []byte) error {
+		calls++
+		return errStop
+	})
+	assert.True(t, errors.Aborted.Match(err), "%+v", err)
+	assert.Exactly(t, 1, calls)
+
+	pWebsite := config.MustNewPath("web/unsecure/base_url").BindWebsite(1)
+	assert.NoError(t, s.Delete(pWebsite))
+	assert.NoError(t, s.Delete(pWebsite), "deleting a non-existent path must not fail")
+	validateNotFoundGet(t, s, scope.Website.WithID(1), "web/unsecure/base_url")
+	validateFoundGet(t, s, scope.DefaultTypeID, "web/unsecure/base_url", "default/0/web/unsecure/base_url")
+	assert.Exactly(t, []string{
+		"default/0/web/unsecure/base_url",
+		"stores/2/web/unsecure/base_link_url",
+	}, collect(0, "web/unsecure"))
+}
@@ -124,8 +124,11 @@ type dbStats struct {
 type DB struct {
 	cfg DBOptions
 
-	sqlRead  *dml.Select
-	sqlWrite *dml.Insert
+	sqlRead   *dml.Select
+	sqlWrite  *dml.Insert
+	sqlDelete *dml.Delete
+	// tbl gets used to build the SELECT statement for Iterate.
+	tbl *ddl.Table
 
 	tickerDaemonStop chan struct{}
 	tickerRead       *time.Ticker
@@ -194,11 +197,20 @@ func NewDB(tbls *ddl.Tables, o DBOptions) (*DB, error) {
 	qryWrite.OnDuplicateKeys = dml.Conditions{dml.Column("value")}
 	qryWrite.Log = o.Log
 
+	qryDelete := dml.NewDelete(tbl.Name).Where(
+		dml.Column("scope").PlaceHolder(),
+		dml.Column("scope_id").PlaceHolder(),
+		dml.Column("path").PlaceHolder(),
+	).WithDB(tbl.DB)
+	qryDelete.Log = o.Log
+
 	dbs := &DB{
 		cfg:              o,
 		tickerDaemonStop: make(chan struct{}),
 		sqlRead:          qryRead,
 		sqlWrite:         qryWrite,
+		sqlDelete:        qryDelete,
+		tbl:              tbl,
 	}
 	if dbs.cfg.IdleRead == 0 {
 		dbs.cfg.IdleRead = time.Second * 20 // just a guess
@@ -375,6 +387,74 @@ func (dbs *DB) Get(p *config.Path) (v []byte, ok bool, err error) {
 	return ret, true, nil
 }
 
+// Delete removes a path from the database table. The delete statement does
+// not get prepared because deleting happens rarely. Enabled debug level logs
+// the rows affected.
+func (dbs *DB) Delete(p *config.Path) error {
+	dbs.muWrite.Lock()
+	defer dbs.muWrite.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbs.cfg.ContextTimeoutWrite)
+	defer cancel()
+	scp, path := p.ScopeRoute()
+	s, id := scp.Unpack()
+	res, err := dbs.sqlDelete.WithArgs().String(s.StrType()).Int64(id).String(path).ExecContext(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "[config/storage] DB.Delete Scope %q Path %q", scp.String(), path)
+	}
+	if dbs.cfg.Log != nil && dbs.cfg.Log.IsDebug() {
+		ra, err2 := res.RowsAffected()
+		dbs.cfg.Log.Debug(
+			"config.storage.DB.Delete.Result",
+			log.Int64("rowsAffected", ra),
+			log.ErrWithKey("rowsAffectedErr", err2),
+			log.String("path", p.String()),
+		)
+	}
+	return nil
+}
+
+// Iterate queries all rows matching the scope and the route prefix, ordered
+// by scope, scope_id and path. The route prefix gets translated into a LIKE
+// condition and the result gets additionally filtered to match only whole
+// route segments. The iteration callback runs while the rows are open, so it
+// must not block for a long time.
+func (dbs *DB) Iterate(scp scope.TypeID, routePrefix string, fn func(p config.Path, v []byte) error) error {
+	var wheres dml.Conditions
+	if scp > 0 {
+		s, id := scp.Unpack()
+		wheres = append(wheres, dml.Column("scope").Str(s.StrType()), dml.Column("scope_id").Int64(id))
+	}
+	if routePrefix != "" {
+		wheres = append(wheres, dml.Column("path").Like().Str(routePrefix+"%"))
+	}
+	qry := dbs.tbl.Select("scope", "scope_id", "path", "value").Where(wheres...).OrderBy("scope", "scope_id", "path")
+	qry.Log = dbs.cfg.Log
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbs.cfg.ContextTimeoutRead)
+	defer cancel()
+
+	return qry.WithArgs().IterateSerial(ctx, func(cm *dml.ColumnMap) error {
+		var ccd TableCoreConfigData
+		if err := ccd.MapColumns(cm); err != nil {
+			return errors.Wrapf(err, "[config/storage] DB.Iterate at row %d", cm.Count)
+		}
+		rowScp := scope.FromString(ccd.Scope).WithID(ccd.ScopeID)
+		if !matchScopeRoute(scp, routePrefix, rowScp, ccd.Path) {
+			return nil
+		}
+		p, err := config.NewPathWithScope(rowScp, ccd.Path)
+		if err != nil {
+			return errors.Wrapf(err, "[config/storage] DB.Iterate.config.NewPathWithScope Path %q Scope: %q", ccd.Path, rowScp)
+		}
+		var v []byte
+		if ccd.Value.Valid {
+			v = []byte(ccd.Value.String)
+		}
+		return fn(*p, v)
+	})
+}
+
 // Statistics returns live statistics about opening and closing prepared statements.
 func (dbs *DB) Statistics() (value dbStats, set dbStats) {
 	dbs.muRead.Lock()
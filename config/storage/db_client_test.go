@@ -312,3 +312,103 @@ func Test_WithCoreConfigData(t *testing.T) {
 	assert.True(t, ok)
 	assert.Exactly(t, "{{unsecure_base_url}}skin/", v)
 }
+
+func TestDB_Delete(t *testing.T) {
+	defer leaktest.CheckTimeout(t, time.Second)()
+
+	dbc, dbMock := dmltest.MockDB(t)
+	defer dmltest.MockClose(t, dbc, dbMock)
+
+	dbs, err := storage.NewDB(storage.NewTableCollection(dbc.DB), storage.DBOptions{
+		SkipSchemaValidation: true,
+	})
+	assert.NoError(t, err)
+	defer dmltest.Close(t, dbs)
+
+	t.Run("success", func(t *testing.T) {
+		dbMock.ExpectExec(dmltest.SQLMockQuoteMeta("DELETE FROM `core_config_data` WHERE (`scope` = ?) AND (`scope_id` = ?) AND (`path` = ?)")).
+			WithArgs("websites", int64(1), "web/unsecure/base_url").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, dbs.Delete(config.MustNewPath("web/unsecure/base_url").BindWebsite(1)))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		dbMock.ExpectExec(dmltest.SQLMockQuoteMeta("DELETE FROM `core_config_data` WHERE (`scope` = ?) AND (`scope_id` = ?) AND (`path` = ?)")).
+			WithArgs("default", int64(0), "web/unsecure/base_url").
+			WillReturnError(errors.ConnectionFailed.Newf("Upsss"))
+
+		err := dbs.Delete(config.MustNewPath("web/unsecure/base_url"))
+		assert.True(t, errors.ConnectionFailed.Match(err), "%+v", err)
+	})
+}
+
+func TestDB_Iterate(t *testing.T) {
+	defer leaktest.CheckTimeout(t, time.Second)()
+
+	dbc, dbMock := dmltest.MockDB(t)
+	defer dmltest.MockClose(t, dbc, dbMock)
+
+	dbs, err := storage.NewDB(storage.NewTableCollection(dbc.DB), storage.DBOptions{
+		SkipSchemaValidation: true,
+	})
+	assert.NoError(t, err)
+	defer dmltest.Close(t, dbs)
+
+	collect := func(scp scope.TypeID, prefix string) []string {
+		var have []string
+		assert.NoError(t, dbs.Iterate(scp, prefix, func(p config.Path, v []byte) error {
+			have = append(have, fmt.Sprintf("%s=%s", p.String(), v))
+			return nil
+		}))
+		return have
+	}
+
+	t.Run("all rows", func(t *testing.T) {
+		dbMock.ExpectQuery(dmltest.SQLMockQuoteMeta("SELECT `scope`, `scope_id`, `path`, `value` FROM `core_config_data` AS `main_table` ORDER BY `scope`, `scope_id`, `path`")).
+			WillReturnRows(sqlmock.NewRows([]string{"scope", "scope_id", "path", "value"}).
+				AddRow("default", 0, "dev/js/merge_files", "1").
+				AddRow("stores", 2, "web/unsecure/base_link_url", nil).
+				AddRow("websites", 1, "web/unsecure/base_url", "http://corestore.io"))
+
+		assert.Exactly(t, []string{
+			"default/0/dev/js/merge_files=1",
+			"stores/2/web/unsecure/base_link_url=",
+			"websites/1/web/unsecure/base_url=http://corestore.io",
+		}, collect(0, ""))
+	})
+
+	t.Run("scope and route segment filter", func(t *testing.T) {
+		dbMock.ExpectQuery(dmltest.SQLMockQuoteMeta("SELECT `scope`, `scope_id`, `path`, `value` FROM `core_config_data` AS `main_table` WHERE (`scope` = 'websites') AND (`scope_id` = 1) AND (`path` LIKE 'web/unsecure%') ORDER BY `scope`, `scope_id`, `path`")).
+			WillReturnRows(sqlmock.NewRows([]string{"scope", "scope_id", "path", "value"}).
+				AddRow("websites", 1, "web/unsecure/base_url", "http://corestore.io").
+				AddRow("websites", 1, "web/unsecure_x/base_url", "http://x.corestore.io"))
+
+		assert.Exactly(t, []string{
+			"websites/1/web/unsecure/base_url=http://corestore.io",
+		}, collect(scope.Website.WithID(1), "web/unsecure"))
+	})
+
+	t.Run("callback error", func(t *testing.T) {
+		dbMock.ExpectQuery(dmltest.SQLMockQuoteMeta("SELECT `scope`, `scope_id`, `path`, `value` FROM `core_config_data` AS `main_table` WHERE (`path` LIKE 'web%') ORDER BY `scope`, `scope_id`, `path`")).
+			WillReturnRows(sqlmock.NewRows([]string{"scope", "scope_id", "path", "value"}).
+				AddRow("default", 0, "web/secure/base_url", "a").
+				AddRow("default", 0, "web/unsecure/base_url", "b"))
+
+		var calls int
+		err := dbs.Iterate(0, "web", func(p config.Path, v []byte) error {
+			calls++
+			return errors.Aborted.Newf("stop")
+		})
+		assert.True(t, errors.Aborted.Match(err), "%+v", err)
+		assert.Exactly(t, 1, calls)
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		dbMock.ExpectQuery(dmltest.SQLMockQuoteMeta("SELECT `scope`, `scope_id`, `path`, `value` FROM `core_config_data` AS `main_table` ORDER BY `scope`, `scope_id`, `path`")).
+			WillReturnError(errors.ConnectionFailed.Newf("Upsss"))
+
+		err := dbs.Iterate(0, "", func(p config.Path, v []byte) error { return nil })
+		assert.True(t, errors.ConnectionFailed.Match(err), "%+v", err)
+	})
+}
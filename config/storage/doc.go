@@ -24,4 +24,7 @@ Sniperkit-Bot
 // functions. Supported tags are: bigcache (store in big cache), db (store in
 // MySQL/MariaDB), etcdv3 (store in etcd cluster/server), load from json and
 // yaml.
+//
+// The remote backend, WithRemote and NewRemoteHandler, lets many stateless
+// workers follow a central config.Service over HTTP with long-polling.
 package storage
@@ -39,6 +39,7 @@ import (
 	"go.etcd.io/etcd/mvcc/mvccpb"
 
 	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
 	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/bufferpool"
 )
 
@@ -137,6 +138,54 @@ func (s *etcdv3Client) Get(p *config.Path) (v []byte, found bool, err error) {
 	return nil, false, nil
 }
 
+// Delete removes a key from the etcd service.
+func (s *etcdv3Client) Delete(p *config.Path) error {
+	ctx := context.Background()
+	if s.options.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), s.options.RequestTimeout)
+		defer cancel()
+	}
+
+	key, err := s.toKey(p)
+	if err != nil {
+		return errors.Wrapf(err, "[storage/etcdv3] toKey with key %q", key)
+	}
+	if _, err = s.client.Delete(ctx, key); err != nil {
+		return errors.Wrapf(err, "[storage/etcdv3] Delete failed with key %q", key)
+	}
+	return nil
+}
+
+// Iterate loads all keys with the configured key prefix from the etcd service
+// and calls fn for each matching path. The paths are sorted by scope and route.
+func (s *etcdv3Client) Iterate(scp scope.TypeID, routePrefix string, fn func(p config.Path, v []byte) error) error {
+	ctx := context.Background()
+	if s.options.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), s.options.RequestTimeout)
+		defer cancel()
+	}
+
+	resp, err := s.client.Get(ctx, s.options.KeyPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return errors.Wrapf(err, "[storage/etcdv3] Client Get with key prefix %q", s.options.KeyPrefix)
+	}
+
+	var pvs pathValues
+	for _, ev := range resp.Kvs {
+		var p config.Path
+		key := strings.TrimPrefix(string(ev.Key), s.options.KeyPrefix)
+		if err := p.Parse(key); err != nil {
+			return errors.Wrapf(err, "[storage/etcdv3] Iterate with key %q", ev.Key)
+		}
+		if pScp, route := p.ScopeRoute(); matchScopeRoute(scp, routePrefix, pScp, route) {
+			pvs = append(pvs, pathValue{p: p, v: ev.Value})
+		}
+	}
+	return pvs.iterate(fn)
+}
+
 // Etcdv3FakeClient implementation for testing purposes.
 type Etcdv3FakeClient struct {
 	PutFn    func(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error)
@@ -145,6 +194,9 @@ type Etcdv3FakeClient struct {
 	GetFn    func(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
 	GetKey   []byte
 	GetValue []byte
+	// DeleteFn if nil, Delete returns DeleteError.
+	DeleteFn    func(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error)
+	DeleteError error
 }
 
 func (cm Etcdv3FakeClient) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
@@ -172,7 +224,13 @@ func (cm Etcdv3FakeClient) Get(ctx context.Context, key string, opts ...clientv3
 }
 
 func (cm Etcdv3FakeClient) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
-	return nil, errors.NotImplemented.Newf("[storage/etcdv3] Delete Not Implemented")
+	if cm.DeleteFn != nil {
+		return cm.DeleteFn(ctx, key, opts...)
+	}
+	if cm.DeleteError != nil {
+		return nil, cm.DeleteError
+	}
+	return &clientv3.DeleteResponse{}, nil
 }
 
 func (cm Etcdv3FakeClient) Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
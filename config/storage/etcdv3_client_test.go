@@ -224,3 +224,91 @@ func TestWithLoadData_Success(t *testing.T) {
 	assert.Exactly(t, `"e30d8df9810bc36105c96ad3ae76ffd3"`, cfgSrv.Get(p.BindDefault()).String())
 
 }
+
+func TestStorage_DeleteIterate(t *testing.T) {
+
+	t.Run("Delete key", func(t *testing.T) {
+		var haveKey string
+		mo := Etcdv3FakeClient{
+			DeleteFn: func(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+				haveKey = key
+				return &clientv3.DeleteResponse{Deleted: 1}, nil
+			},
+		}
+
+		s, err := NewEtcdv3Client(mo, Etcdv3Options{})
+		assert.NoError(t, err)
+
+		assert.NoError(t, s.Delete(config.MustNewPath("path/to/orion").BindWebsite(3)))
+		assert.Exactly(t, Etcdv3DefaultKeyPrefix+"websites/3/path/to/orion", haveKey)
+	})
+
+	t.Run("Delete error", func(t *testing.T) {
+		mo := Etcdv3FakeClient{
+			DeleteError: errors.ConnectionLost.Newf("Ups"),
+		}
+
+		s, err := NewEtcdv3Client(mo, Etcdv3Options{})
+		assert.NoError(t, err)
+
+		err = s.Delete(config.MustNewPath("path/to/orion"))
+		assert.True(t, errors.ConnectionLost.Match(err), "Should have error kind connection lost")
+	})
+
+	newIterateClient := func(t *testing.T) config.Storager {
+		mo := Etcdv3FakeClient{
+			GetFn: func(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+				assert.Exactly(t, Etcdv3DefaultKeyPrefix, key)
+				return &clientv3.GetResponse{
+					Kvs: []*mvccpb.KeyValue{
+						{Key: []byte(Etcdv3DefaultKeyPrefix + `websites/1/web/unsecure_x/base_url`), Value: []byte(`w1x`)},
+						{Key: []byte(Etcdv3DefaultKeyPrefix + `stores/2/web/unsecure/base_link_url`), Value: []byte(`s2`)},
+						{Key: []byte(Etcdv3DefaultKeyPrefix + `websites/1/web/unsecure/base_url`), Value: []byte(`w1`)},
+						{Key: []byte(Etcdv3DefaultKeyPrefix + `default/0/web/unsecure/base_url`), Value: []byte(`d0`)},
+						{Key: []byte(Etcdv3DefaultKeyPrefix + `default/0/dev/js/merge_files`), Value: []byte(`d0js`)},
+					},
+				}, nil
+			},
+		}
+		s, err := NewEtcdv3Client(mo, Etcdv3Options{})
+		assert.NoError(t, err)
+		return s
+	}
+
+	collect := func(t *testing.T, s config.Storager, scp scope.TypeID, prefix string) []string {
+		var have []string
+		assert.NoError(t, s.Iterate(scp, prefix, func(p config.Path, v []byte) error {
+			have = append(have, p.String()+"="+string(v))
+			return nil
+		}))
+		return have
+	}
+
+	t.Run("Iterate all sorted", func(t *testing.T) {
+		assert.Exactly(t, []string{
+			"default/0/dev/js/merge_files=d0js",
+			"default/0/web/unsecure/base_url=d0",
+			"websites/1/web/unsecure/base_url=w1",
+			"websites/1/web/unsecure_x/base_url=w1x",
+			"stores/2/web/unsecure/base_link_url=s2",
+		}, collect(t, newIterateClient(t), 0, ""))
+	})
+
+	t.Run("Iterate scope and route segment", func(t *testing.T) {
+		assert.Exactly(t, []string{
+			"websites/1/web/unsecure/base_url=w1",
+		}, collect(t, newIterateClient(t), scope.Website.WithID(1), "web/unsecure"))
+	})
+
+	t.Run("Iterate Get error", func(t *testing.T) {
+		mo := Etcdv3FakeClient{
+			GetError: errors.ConnectionLost.Newf("Ups"),
+		}
+
+		s, err := NewEtcdv3Client(mo, Etcdv3Options{})
+		assert.NoError(t, err)
+
+		err = s.Iterate(0, "", func(p config.Path, v []byte) error { return nil })
+		assert.True(t, errors.ConnectionLost.Match(err), "Should have error kind connection lost")
+	})
+}
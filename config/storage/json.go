@@ -34,7 +34,8 @@ import (
 // WithLoadJSON reads the configuration values from a JSON file and applies it
 // to the config.service. "testdata/example.json" provides an example JSON file.
 // Loads all data into RAM before processing it. Can be refactored internally
-// for stream based processing.
+// for stream based processing. A null value deletes the path from the
+// config.Service.
 func WithLoadJSON(opts ...option) config.LoadDataOption {
 	return config.MakeLoadDataOption(func(s *config.Service) (err error) {
 		for i := 0; i < len(opts) && err == nil; i++ {
@@ -44,6 +45,13 @@ func WithLoadJSON(opts ...option) config.LoadDataOption {
 	}).WithUseStorageLevel(1)
 }
 
+// WithWatchJSON loads the configuration values from JSON files like
+// WithLoadJSON and reloads them once their content changes. See WithWatchYAML
+// for the details.
+func WithWatchJSON(wo WatchOptions, opts ...option) config.LoadDataOption {
+	return withWatch(wo, loadJSON, opts)
+}
+
 func loadJSON(s config.Setter, r io.Reader) error {
 	jd := make(map[string]interface{})
 
@@ -66,6 +74,16 @@ func loadJSON(s config.Setter, r io.Reader) error {
 			case map[string]interface{}:
 				for scpID, dataIF := range v2t {
 
+					if dataIF == nil { // JSON null
+						if err := p.ParseStrings(scp, scpID, route); err != nil {
+							return errors.CorruptData.New(err, "[cfgfile] WithLoadJSON failed to create path: %q %q %q", route, scp, scpID)
+						}
+						if err := deletePath(s, p); err != nil {
+							return errors.Fatal.New(err, "[cfgfile] WithLoadJSON.Service.Delete failed with %q", p.String())
+						}
+						continue
+					}
+
 					data, err := conv.ToByteE(dataIF)
 					if err != nil {
 						return errors.CorruptData.New(err, "[cfgfile] WithLoadJSON failed to convert %v into a byte slice for path: %q %q %q", dataIF, route, scp, scpID)
@@ -94,6 +112,14 @@ func loadJSON(s config.Setter, r io.Reader) error {
 					return errors.Fatal.New(err, "[cfgfile] WithLoadJSON.Service.Set failed with %q", p.String())
 				}
 
+			case nil: // JSON null
+				if err := p.ParseStrings(scp, "0", route); err != nil {
+					return errors.CorruptData.New(err, "[cfgfile] WithLoadJSON failed to create path: %q %q", scp, route)
+				}
+				if err := deletePath(s, p); err != nil {
+					return errors.Fatal.New(err, "[cfgfile] WithLoadJSON.Service.Delete failed with %q", p.String())
+				}
+
 			default:
 				return errors.CorruptData.Newf("[cfgfile] WithLoadJSON unexpected data in %#v", v2)
 			}
@@ -101,3 +127,22 @@ func loadJSON(s config.Setter, r io.Reader) error {
 	}
 	return nil
 }
+
+// WriteSnapshotJSON writes the values of the snapshot as indented JSON in the
+// format of WithLoadJSON. The meta data of the snapshot gets not exported.
+func WriteSnapshotJSON(w io.Writer, sn *config.Snapshot) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return errors.WithStack(e.Encode(sn.Tree()))
+}
+
+// ReadSnapshotJSON creates a new snapshot from JSON data in the format of
+// WithLoadJSON. The returned snapshot can be applied with
+// config.Service.Restore.
+func ReadSnapshotJSON(r io.Reader) (*config.Snapshot, error) {
+	sn := config.NewSnapshot()
+	if err := loadJSON(sn, r); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return sn, nil
+}
@@ -22,6 +22,8 @@ Sniperkit-Bot
 package storage_test
 
 import (
+	"bytes"
+	"os"
 	"testing"
 
 	"github.com/corestoreio/errors"
@@ -32,6 +34,20 @@ import (
 	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
 )
 
+func init() {
+	watchFormats = append(watchFormats, watchFormat{
+		name:     "JSON",
+		fileName: "config.json",
+		watch: func(wo storage.WatchOptions, file string) config.LoadDataOption {
+			return storage.WithWatchJSON(wo, storage.WithFile(file))
+		},
+		initial:   `{"aa/bb/cc":{"default":"1","websites":{"1":"2"}},"xx/yy/zz":{"default":"keep"}}`,
+		changed:   `{"aa/bb/cc":{"default":"3","websites":{"1":"2"}}}`,
+		rejected:  `{"aa/bb/cc":{"default":"invalid","websites":{"1":"4"}}}`,
+		malformed: `{"aa/bb/cc":`,
+	})
+}
+
 func TestWithLoadJSON(t *testing.T) {
 	pUserName := config.MustNewPath("payment/stripe/user_name")
 
@@ -57,6 +73,26 @@ func TestWithLoadJSON(t *testing.T) {
 		assert.Exactly(t, `"true"`, cfgSrv.Get(config.MustNewPathWithScope(scope.Website.WithID(0), "payment/stripe/enable")).String())
 	})
 
+	t.Run("null deletes", func(t *testing.T) {
+
+		cfgSrv, err := config.NewService(
+			storage.NewMap(), config.Options{},
+			storage.WithLoadJSON(
+				storage.WithFile("testdata", "example.json"),
+				storage.WithFile("testdata", "example_delete.json"),
+			),
+		)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+
+		assert.Exactly(t, `"WS1Username"`, cfgSrv.Get(pUserName.BindWebsite(1)).String())
+		assert.False(t, cfgSrv.Get(pUserName.BindWebsite(2)).IsValid(), "website 2 must be deleted")
+		assert.Exactly(t, `"SO5Username"`, cfgSrv.Get(pUserName.BindStore(5)).String())
+		assert.False(t, cfgSrv.Get(pUserName.BindStore(11)).IsValid(), "store 11 must be deleted")
+		assert.Exactly(t, `"AUserName"`, cfgSrv.Scoped(2, 11).Get(scope.Store, "payment/stripe/user_name").String())
+	})
+
 	runner := func(file string, errKind errors.Kind, errTxt string) func(*testing.T) {
 		return func(t *testing.T) {
 			cfgSrv, err := config.NewService(
@@ -82,3 +118,32 @@ func TestWithLoadJSON(t *testing.T) {
 		`WithLoadJSON unexpected data in []interface {}{}`))
 
 }
+
+func TestSnapshotJSON(t *testing.T) {
+	f, err := os.Open("testdata/example.json")
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, f.Close()) }()
+
+	sn, err := storage.ReadSnapshotJSON(f)
+	assert.NoError(t, err)
+	v, ok, err := sn.Get(config.MustNewPath("payment/stripe/user_name").BindStore(11))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Exactly(t, "SO11Username", string(v))
+
+	var buf bytes.Buffer
+	assert.NoError(t, storage.WriteSnapshotJSON(&buf, sn))
+
+	sn2, err := storage.ReadSnapshotJSON(&buf)
+	assert.NoError(t, err)
+	assert.Exactly(t, sn.Len(), sn2.Len())
+	assert.Len(t, sn.Diff(sn2), 0)
+
+	srv := config.MustNewService(storage.NewMap(), config.Options{})
+	cs, err := srv.Restore(sn2)
+	assert.NoError(t, err)
+	assert.Len(t, cs, sn.Len())
+	after, err := srv.TakeSnapshot("imported")
+	assert.NoError(t, err)
+	assert.Len(t, sn.Diff(after), 0)
+}
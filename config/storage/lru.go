@@ -26,6 +26,7 @@ import (
 	"sync"
 
 	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
 )
 
 type liElem struct {
@@ -98,6 +99,32 @@ func (c *lruCache) Get(p *config.Path) (v []byte, found bool, err error) {
 	return
 }
 
+// Delete removes a key from the cache.
+func (c *lruCache) Delete(p *config.Path) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ele, hit := c.cache[makeCacheKey(p.ScopeRoute())]; hit {
+		c.removeElement(ele)
+	}
+	return nil
+}
+
+// Iterate walks over all cached keys without changing their recently used
+// order. The paths are sorted by scope and route.
+func (c *lruCache) Iterate(scp scope.TypeID, routePrefix string, fn func(p config.Path, v []byte) error) error {
+	c.mu.Lock()
+	var pvs pathValues
+	for k, ele := range c.cache {
+		if matchScopeRoute(scp, routePrefix, k.scp, k.route) {
+			le := ele.Value.(liElem)
+			pvs = append(pvs, pathValue{p: le.Path, v: le.bVal})
+		}
+	}
+	c.mu.Unlock()
+	return pvs.iterate(fn)
+}
+
 func (c *lruCache) removeOldest() {
 	ele := c.ll.Back()
 	if ele == nil {
@@ -149,3 +149,7 @@ func TestLRUNew_Parallel(t *testing.T) {
 	})
 
 }
+
+func TestLRU_DeleteIterate(t *testing.T) {
+	validateDeleteIterate(t, storage.NewLRU(0))
+}
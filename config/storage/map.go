@@ -25,6 +25,7 @@ import (
 	"github.com/corestoreio/errors"
 
 	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
 )
 
 type kvmap struct {
@@ -76,6 +77,34 @@ func (sp *kvmap) Get(p *config.Path) (v []byte, found bool, err error) {
 	return nil, false, nil
 }
 
+// Delete implements Storager interface.
+func (sp *kvmap) Delete(p *config.Path) error {
+	sp.Lock()
+	delete(sp.kv, makeCacheKey(p.ScopeRoute()))
+	sp.Unlock()
+	return nil
+}
+
+// Iterate implements Storager interface. The paths are sorted by scope and
+// route.
+func (sp *kvmap) Iterate(scp scope.TypeID, routePrefix string, fn func(p config.Path, v []byte) error) error {
+	sp.RLock()
+	var pvs pathValues
+	for k, v := range sp.kv {
+		if !matchScopeRoute(scp, routePrefix, k.scp, k.route) {
+			continue
+		}
+		p, err := config.NewPathWithScope(k.scp, k.route)
+		if err != nil {
+			sp.RUnlock()
+			return errors.Wrapf(err, "[config/storage] kvmap.Iterate with route %q", k.route)
+		}
+		pvs = append(pvs, pathValue{p: *p, v: []byte(v)})
+	}
+	sp.RUnlock()
+	return pvs.iterate(fn)
+}
+
 // Flush purges all stored items from the cache.
 func (sp *kvmap) Flush() error {
 	sp.Lock()
@@ -50,3 +50,8 @@ func TestNewMap_OneKey(t *testing.T) {
 	validateNotFoundGet(t, sp, scope.Store.WithID(55), "aa/bb/cc")
 
 }
+
+func TestNewMap_DeleteIterate(t *testing.T) {
+	t.Parallel()
+	validateDeleteIterate(t, storage.NewMap())
+}
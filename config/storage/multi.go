@@ -27,6 +27,7 @@ import (
 	"github.com/golang/sync/errgroup"
 
 	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
 )
 
 // MultiOptions provides options for function MakeMulti.
@@ -119,3 +120,36 @@ func (ms *multi) Get(p *config.Path) (v []byte, found bool, err error) {
 	}
 	return nil, false, nil
 }
+
+// Delete removes the path from all backends in serial order. It returns the
+// first error.
+func (ms *multi) Delete(p *config.Path) error {
+	for idx, s := range ms.backends {
+		if err := s.Delete(p); err != nil {
+			return errors.Wrapf(err, "[config] Multi.Delete failed at backend index %d with path %q", idx, p.String())
+		}
+	}
+	return nil
+}
+
+// Iterate merges the paths of all backends. If a path is stored in more than
+// one backend, the value of the first backend wins, just like in Get. The paths
+// are sorted by scope and route.
+func (ms *multi) Iterate(scp scope.TypeID, routePrefix string, fn func(p config.Path, v []byte) error) error {
+	seen := make(map[cacheKey]struct{})
+	var pvs pathValues
+	for idx, s := range ms.backends {
+		err := s.Iterate(scp, routePrefix, func(p config.Path, v []byte) error {
+			key := makeCacheKey(p.ScopeRoute())
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				pvs = append(pvs, pathValue{p: p, v: v})
+			}
+			return nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "[config] Multi.Iterate failed at backend index %d", idx)
+		}
+	}
+	return pvs.iterate(fn)
+}
@@ -101,6 +101,38 @@ func TestMakeMulti(t *testing.T) {
 
 		validateNotFoundGet(t, m, scope.Website.WithID(44), "aa/bb/cc")
 	})
+
+	t.Run("delete and iterate", func(t *testing.T) {
+		validateDeleteIterate(t, storage.MakeMulti(storage.MultiOptions{}, storage.NewMap(), storage.NewLRU(0)))
+	})
+
+	t.Run("iterate first backend wins", func(t *testing.T) {
+		inMem1 := storage.NewMap()
+		inMem2 := storage.NewMap()
+		m := storage.MakeMulti(storage.MultiOptions{}, inMem1, inMem2)
+
+		p2 := config.MustNewPathWithScope(scope.Store.WithID(44), "aa/bb/dd")
+		assert.NoError(t, inMem1.Set(p, []byte(`first`)))
+		assert.NoError(t, inMem2.Set(p, []byte(`second`)))
+		assert.NoError(t, inMem2.Set(p2, []byte(`only second`)))
+
+		var have []string
+		assert.NoError(t, m.Iterate(0, "aa/bb", func(p config.Path, v []byte) error {
+			have = append(have, p.String()+"="+string(v))
+			return nil
+		}))
+		assert.Exactly(t, []string{"stores/44/aa/bb/cc=first", "stores/44/aa/bb/dd=only second"}, have)
+	})
+
+	t.Run("delete error", func(t *testing.T) {
+		inMem1 := storage.NewMap()
+		m := storage.MakeMulti(storage.MultiOptions{}, inMem1, sleepWriter{setErr: errors.AlreadyInUse.Newf("resource in use")})
+		assert.NoError(t, inMem1.Set(p, testVal))
+
+		err := m.Delete(p)
+		assert.True(t, errors.AlreadyInUse.Match(err), "%+v", err)
+		validateNotFoundGet(t, inMem1, scope.Store.WithID(44), "aa/bb/cc")
+	})
 }
 
 type sleepWriter struct {
@@ -118,3 +150,11 @@ func (sw sleepWriter) Set(_ *config.Path, _ []byte) error {
 func (sw sleepWriter) Get(_ *config.Path) (v []byte, found bool, err error) {
 	return
 }
+
+func (sw sleepWriter) Delete(_ *config.Path) error {
+	return sw.setErr
+}
+
+func (sw sleepWriter) Iterate(_ scope.TypeID, _ string, _ func(config.Path, []byte) error) error {
+	return nil
+}
@@ -34,6 +34,22 @@ import (
 
 type option func(*config.Service, func(config.Setter, io.Reader) error) error
 
+// deleter gets implemented by *config.Service and all config.Storager.
+type deleter interface {
+	Delete(p *config.Path) error
+}
+
+// deletePath removes the path if s supports deleting. A null value in a YAML or
+// JSON file removes the path from the configuration, for example to drop a
+// website scoped override which has been loaded by a previous file.
+func deletePath(s config.Setter, p *config.Path) error {
+	d, ok := s.(deleter)
+	if !ok {
+		return errors.NotSupported.Newf("[config/storage] Type %T does not support deleting the path %q", s, p.String())
+	}
+	return errors.WithStack(d.Delete(p))
+}
+
 func processFile(file string, s *config.Service, cb func(config.Setter, io.Reader) error) (err error) {
 	var f io.ReadCloser
 	f, err = os.Open(file)
@@ -0,0 +1,450 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/corestoreio/errors"
+	"github.com/corestoreio/log"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// Remote* defines the default timings of the remote configuration backend.
+const (
+	RemoteDefaultWait          = 30 * time.Second
+	RemoteDefaultMaxWait       = 60 * time.Second
+	RemoteDefaultPollInterval  = time.Second
+	RemoteDefaultRetryInterval = 5 * time.Second
+)
+
+// RemoteSource defines the data source of a RemoteHandler. Type
+// *config.Service implements this interface.
+type RemoteSource interface {
+	Iterate(scp scope.TypeID, routePrefix string, fn func(p config.Path, v []byte) error) error
+}
+
+// RemoteHandlerOptions configures the server side of the remote configuration
+// backend.
+type RemoteHandlerOptions struct {
+	// RoutePrefix restricts the served values to routes with this prefix.
+	// Empty serves all values.
+	RoutePrefix string
+	// MaxWait limits the long-polling duration requested by a client via
+	// query parameter `wait`. Defaults to RemoteDefaultMaxWait.
+	MaxWait time.Duration
+	// PollInterval defines how long the loaded values get cached and hence
+	// how often waiting requests detect changes of the source. The source
+	// gets loaded at most once per interval, regardless of the number of
+	// waiting requests. Changes signaled via Notify get detected immediately.
+	// Defaults to RemoteDefaultPollInterval.
+	PollInterval time.Duration
+}
+
+// RemoteHandler serves all values of a RemoteSource as a JSON encoded
+// config.Snapshot. The ETag header contains a checksum of the values. A client
+// sending the ETag in the If-None-Match header together with query parameter
+// `wait` (a duration, e.g. `wait=30s`) gets blocked until the values change
+// or the duration elapses, which results in status 304 Not Modified
+// (long-polling). The handler can be mounted at any path.
+//
+// RemoteHandler implements config.MessageReceiver, so when subscribed to a
+// config.Service with enabled pubsub, waiting clients get notified
+// immediately about changes.
+type RemoteHandler struct {
+	src RemoteSource
+	o   RemoteHandlerOptions
+
+	mu      sync.Mutex
+	changed chan struct{}
+
+	// muSnap protects snap and serializes the loading of the source.
+	muSnap sync.Mutex
+	snap   *remoteSnapshot
+}
+
+// remoteSnapshot contains the encoded values of the source.
+type remoteSnapshot struct {
+	data    []byte // JSON encoded config.Snapshot
+	etag    string
+	created time.Time
+}
+
+// NewRemoteHandler creates a new HTTP handler serving the values of src.
+func NewRemoteHandler(src RemoteSource, o RemoteHandlerOptions) *RemoteHandler {
+	if o.MaxWait <= 0 {
+		o.MaxWait = RemoteDefaultMaxWait
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = RemoteDefaultPollInterval
+	}
+	return &RemoteHandler{
+		src:     src,
+		o:       o,
+		changed: make(chan struct{}),
+	}
+}
+
+// Notify discards the cached values and wakes up all waiting requests to
+// check for changed values.
+func (h *RemoteHandler) Notify() {
+	h.muSnap.Lock()
+	h.snap = nil
+	h.muSnap.Unlock()
+	h.broadcast()
+}
+
+// MessageConfig implements config.MessageReceiver and calls Notify.
+func (h *RemoteHandler) MessageConfig(config.Path) error {
+	h.Notify()
+	return nil
+}
+
+func (h *RemoteHandler) broadcast() {
+	h.mu.Lock()
+	close(h.changed)
+	h.changed = make(chan struct{})
+	h.mu.Unlock()
+}
+
+func (h *RemoteHandler) changedChan() <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.changed
+}
+
+// current returns the cached values. They get loaded again from the source if
+// Notify has been called or if they are older than PollInterval. If the
+// values have changed, all waiting requests get woken up.
+func (h *RemoteHandler) current() (*remoteSnapshot, error) {
+	h.muSnap.Lock()
+	defer h.muSnap.Unlock()
+	if h.snap != nil && time.Since(h.snap.created) < h.o.PollInterval {
+		return h.snap, nil
+	}
+	rs, err := h.load()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if h.snap != nil && h.snap.etag != rs.etag {
+		defer h.broadcast()
+	}
+	h.snap = rs
+	return rs, nil
+}
+
+// load loads and encodes all values and sets the checksum as version.
+func (h *RemoteHandler) load() (*remoteSnapshot, error) {
+	sn := config.NewSnapshot()
+	if err := h.src.Iterate(0, h.o.RoutePrefix, sn.Set); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	data, err := json.Marshal(sn.Tree()) // map keys get sorted
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	hsh := fnv.New64a()
+	_, _ = hsh.Write(data)
+	sn.Version = hsh.Sum64()
+	if data, err = json.Marshal(sn); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &remoteSnapshot{
+		data:    data,
+		etag:    `"` + strconv.FormatUint(sn.Version, 16) + `"`,
+		created: time.Now(),
+	}, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *RemoteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var wait time.Duration
+	if ws := r.URL.Query().Get("wait"); ws != "" {
+		var err error
+		if wait, err = time.ParseDuration(ws); err != nil || wait < 0 {
+			http.Error(w, "invalid query parameter wait", http.StatusBadRequest)
+			return
+		}
+		if wait > h.o.MaxWait {
+			wait = h.o.MaxWait
+		}
+	}
+
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	poll := time.NewTicker(h.o.PollInterval)
+	defer poll.Stop()
+
+	for {
+		changed := h.changedChan() // before loading the data to not miss a change
+		rs, err := h.current()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("ETag", rs.etag)
+		w.Header().Set("Cache-Control", "no-cache")
+
+		if inm := r.Header.Get("If-None-Match"); inm == "" || inm != rs.etag {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			if r.Method != http.MethodHead {
+				_, _ = w.Write(rs.data)
+			}
+			return
+		}
+		if wait <= 0 {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		// The poll ticker only triggers a check of the cached values, the
+		// source gets loaded at most once per PollInterval for all requests.
+		select {
+		case <-changed:
+		case <-poll.C:
+		case <-timeout.C:
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// RemoteOptions configures the client side of the remote configuration
+// backend.
+type RemoteOptions struct {
+	// URL of the RemoteHandler. Required.
+	URL string
+	// Client used for the requests. Defaults to a client without timeout
+	// because the duration of a request gets limited by Wait.
+	Client *http.Client
+	// Header gets added to each request, for example for authentication.
+	Header http.Header
+	// Wait defines the long-polling duration. Must be lower than the
+	// RemoteHandlerOptions.MaxWait of the server. Defaults to
+	// RemoteDefaultWait.
+	Wait time.Duration
+	// RetryInterval defines the pause after a failed request. Defaults to
+	// RemoteDefaultRetryInterval.
+	RetryInterval time.Duration
+	// ErrorHandler gets called if a request fails or the changes cannot be
+	// applied. The previous configuration stays active. Optional, defaults to
+	// logging the error with the info level of config.Service.Log.
+	ErrorHandler func(error)
+}
+
+// RemoteClient fetches the values from a RemoteHandler.
+type RemoteClient struct {
+	o   RemoteOptions
+	url *url.URL
+}
+
+// NewRemoteClient creates a new client for a RemoteHandler.
+func NewRemoteClient(o RemoteOptions) (*RemoteClient, error) {
+	if o.URL == "" {
+		return nil, errors.Empty.Newf("[config/storage] RemoteOptions.URL cannot be empty")
+	}
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return nil, errors.NotValid.New(err, "[config/storage] RemoteOptions.URL %q", o.URL)
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{}
+	}
+	if o.Wait <= 0 {
+		o.Wait = RemoteDefaultWait
+	}
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = RemoteDefaultRetryInterval
+	}
+	return &RemoteClient{o: o, url: u}, nil
+}
+
+// Fetch requests the values from the server. If etag is not empty and the
+// values have not been changed, the server blocks for the duration `wait` and
+// Fetch returns a nil Snapshot, if nothing has changed. The returned etag
+// must be passed to the next call.
+func (rc *RemoteClient) Fetch(ctx context.Context, etag string, wait time.Duration) (_ *config.Snapshot, newETag string, err error) {
+	u := *rc.url
+	if wait > 0 {
+		q := u.Query()
+		q.Set("wait", wait.String())
+		u.RawQuery = q.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	req = req.WithContext(ctx)
+	for k, v := range rc.o.Header {
+		req.Header[k] = v
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := rc.o.Client.Do(req)
+	if err != nil {
+		return nil, "", errors.ReadFailed.New(err, "[config/storage] RemoteClient.Fetch %q", rc.o.URL)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, etag, nil
+	case http.StatusOK:
+	default:
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, "", errors.ReadFailed.Newf("[config/storage] RemoteClient.Fetch %q: unexpected status %d: %s", rc.o.URL, resp.StatusCode, body)
+	}
+
+	sn := config.NewSnapshot()
+	if err := json.NewDecoder(resp.Body).Decode(sn); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	return sn, resp.Header.Get("ETag"), nil
+}
+
+// remoteFollower long-polls a RemoteHandler and applies the changed values to
+// the config.Service.
+type remoteFollower struct {
+	rc *RemoteClient
+
+	mu      sync.Mutex
+	etag    string
+	current *config.Snapshot
+
+	startOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// WithRemote loads the values from a RemoteHandler into the config.Service and
+// starts a goroutine which follows the changes via long-polling. Changed
+// values get applied atomically via config.Service.ApplyChanges, hence the
+// Level1 cache gets invalidated, observers run and subscribers get notified.
+// The goroutine terminates when the config.Service gets closed. An error gets
+// returned if the initial request fails.
+func WithRemote(o RemoteOptions) config.LoadDataOption {
+	rc, err := NewRemoteClient(o)
+	ctx, cancel := context.WithCancel(context.Background())
+	rf := &remoteFollower{
+		rc:      rc,
+		current: config.NewSnapshot(),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	return config.MakeLoadDataOption(func(s *config.Service) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := rf.sync(s, 0); err != nil {
+			return errors.WithStack(err)
+		}
+		rf.startOnce.Do(func() {
+			s.RegisterCloser(rf)
+			go rf.follow(s)
+		})
+		return nil
+	})
+}
+
+// sync fetches the values and applies the changes.
+func (rf *remoteFollower) sync(s *config.Service, wait time.Duration) error {
+	rf.mu.Lock()
+	etag := rf.etag
+	rf.mu.Unlock()
+
+	// limits the request duration in case the server does not respond.
+	ctx, cancel := context.WithTimeout(rf.ctx, wait+RemoteDefaultWait)
+	defer cancel()
+	sn, newETag, err := rf.rc.Fetch(ctx, etag, wait)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if sn == nil {
+		return nil
+	}
+
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	sn.Version = 0
+	if err := s.ApplyChanges(rf.current.Diff(sn)); err != nil {
+		return errors.WithStack(err)
+	}
+	rf.current = sn
+	rf.etag = newETag
+	return nil
+}
+
+func (rf *remoteFollower) follow(s *config.Service) {
+	defer close(rf.done)
+	for {
+		err := rf.sync(s, rf.rc.o.Wait)
+		if rf.ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			continue
+		}
+		rf.handleError(s, err)
+		select {
+		case <-rf.ctx.Done():
+			return
+		case <-time.After(rf.rc.o.RetryInterval):
+		}
+	}
+}
+
+func (rf *remoteFollower) handleError(s *config.Service, err error) {
+	switch {
+	case rf.rc.o.ErrorHandler != nil:
+		rf.rc.o.ErrorHandler(err)
+	case s.Log != nil && s.Log.IsInfo():
+		s.Log.Info("config.storage.remoteFollower.sync", log.Err(err))
+	}
+}
+
+// Close terminates the long-polling goroutine. Gets called by
+// config.Service.Close.
+func (rf *remoteFollower) Close() error {
+	rf.cancel()
+	<-rf.done
+	return nil
+}
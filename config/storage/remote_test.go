@@ -0,0 +1,246 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/corestoreio/errors"
+	"github.com/fortytw2/leaktest"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+func TestRemoteHandler(t *testing.T) {
+	src := config.MustNewService(storage.NewMap(
+		"default/0/aa/bb/cc", "1",
+		"websites/1/aa/bb/cc", "2",
+		"default/0/xx/yy/zz", "hidden",
+	), config.Options{})
+	defer func() { assert.NoError(t, src.Close()) }()
+
+	h := storage.NewRemoteHandler(src, storage.RemoteHandlerOptions{
+		RoutePrefix:  "aa",
+		PollInterval: 10 * time.Millisecond,
+	})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	rc, err := storage.NewRemoteClient(storage.RemoteOptions{URL: ts.URL})
+	assert.NoError(t, err)
+
+	sn, etag, err := rc.Fetch(context.Background(), "", 0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, etag)
+	assert.Exactly(t, map[string]map[string]map[string]string{
+		"aa/bb/cc": {"default": {"0": "1"}, "websites": {"1": "2"}},
+	}, sn.Tree())
+
+	t.Run("not modified", func(t *testing.T) {
+		sn, etag2, err := rc.Fetch(context.Background(), etag, 0)
+		assert.NoError(t, err)
+		assert.Nil(t, sn)
+		assert.Exactly(t, etag, etag2)
+
+		now := time.Now()
+		sn, _, err = rc.Fetch(context.Background(), etag, 50*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Nil(t, sn)
+		assert.True(t, time.Since(now) >= 50*time.Millisecond, "Long-polling must wait")
+	})
+
+	t.Run("long-polling returns changes", func(t *testing.T) {
+		go func() {
+			time.Sleep(30 * time.Millisecond)
+			assert.NoError(t, src.Set(config.MustNewPath("aa/bb/cc"), []byte("3")))
+		}()
+		sn, etag2, err := rc.Fetch(context.Background(), etag, 5*time.Second)
+		assert.NoError(t, err)
+		assert.NotEqual(t, etag, etag2)
+		v, ok, err := sn.Get(config.MustNewPath("aa/bb/cc"))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Exactly(t, "3", string(v))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		resp, err := http.Post(ts.URL, "text/plain", nil)
+		assert.NoError(t, err)
+		assert.Exactly(t, http.StatusMethodNotAllowed, resp.StatusCode)
+		assert.NoError(t, resp.Body.Close())
+
+		resp, err = http.Get(ts.URL + "?wait=never")
+		assert.NoError(t, err)
+		assert.Exactly(t, http.StatusBadRequest, resp.StatusCode)
+		assert.NoError(t, resp.Body.Close())
+	})
+}
+
+type countingSource struct {
+	storage.RemoteSource
+	calls int64
+}
+
+func (cs *countingSource) Iterate(scp scope.TypeID, routePrefix string, fn func(p config.Path, v []byte) error) error {
+	atomic.AddInt64(&cs.calls, 1)
+	return cs.RemoteSource.Iterate(scp, routePrefix, fn)
+}
+
+func TestRemoteHandler_SharedSnapshot(t *testing.T) {
+	src := config.MustNewService(storage.NewMap("default/0/aa/bb/cc", "1"), config.Options{})
+	defer func() { assert.NoError(t, src.Close()) }()
+	cs := &countingSource{RemoteSource: src}
+
+	h := storage.NewRemoteHandler(cs, storage.RemoteHandlerOptions{
+		PollInterval: 20 * time.Millisecond,
+	})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	rc, err := storage.NewRemoteClient(storage.RemoteOptions{URL: ts.URL})
+	assert.NoError(t, err)
+	_, etag, err := rc.Fetch(context.Background(), "", 0)
+	assert.NoError(t, err)
+
+	t.Run("waiting requests share the loaded values", func(t *testing.T) {
+		atomic.StoreInt64(&cs.calls, 0)
+		const clients = 20
+		var wg sync.WaitGroup
+		wg.Add(clients)
+		for i := 0; i < clients; i++ {
+			go func() {
+				defer wg.Done()
+				sn, _, err := rc.Fetch(context.Background(), etag, 100*time.Millisecond)
+				assert.NoError(t, err)
+				assert.Nil(t, sn)
+			}()
+		}
+		wg.Wait()
+		// 100ms/20ms results in about 5 loads, one load per request and tick
+		// would result in about 100 loads.
+		calls := atomic.LoadInt64(&cs.calls)
+		assert.True(t, calls < clients, "Source loaded %d times", calls)
+	})
+
+	t.Run("Notify discards the cache", func(t *testing.T) {
+		h2 := storage.NewRemoteHandler(cs, storage.RemoteHandlerOptions{
+			PollInterval: time.Minute,
+		})
+		ts2 := httptest.NewServer(h2)
+		defer ts2.Close()
+		rc2, err := storage.NewRemoteClient(storage.RemoteOptions{URL: ts2.URL})
+		assert.NoError(t, err)
+
+		_, etag, err := rc2.Fetch(context.Background(), "", 0)
+		assert.NoError(t, err)
+		assert.NoError(t, src.Set(config.MustNewPath("aa/bb/cc"), []byte("2")))
+
+		sn, _, err := rc2.Fetch(context.Background(), etag, 0)
+		assert.NoError(t, err)
+		assert.Nil(t, sn, "values must be cached for the PollInterval")
+
+		h2.Notify()
+		sn, etag2, err := rc2.Fetch(context.Background(), etag, 0)
+		assert.NoError(t, err)
+		assert.NotEqual(t, etag, etag2)
+		v, _, err := sn.Get(config.MustNewPath("aa/bb/cc"))
+		assert.NoError(t, err)
+		assert.Exactly(t, "2", string(v))
+	})
+}
+
+func TestNewRemoteClient(t *testing.T) {
+	_, err := storage.NewRemoteClient(storage.RemoteOptions{})
+	assert.True(t, errors.Empty.Match(err), "%+v", err)
+
+	rc, err := storage.NewRemoteClient(storage.RemoteOptions{URL: "http://127.0.0.1:1/"})
+	assert.NoError(t, err)
+	_, _, err = rc.Fetch(context.Background(), "", 0)
+	assert.True(t, errors.ReadFailed.Match(err), "%+v", err)
+}
+
+func TestWithRemote(t *testing.T) {
+	defer leaktest.CheckTimeout(t, time.Second)()
+
+	src := config.MustNewService(storage.NewMap(
+		"default/0/aa/bb/cc", "1",
+		"default/0/xx/yy/zz", "keep",
+	), config.Options{EnablePubSub: true})
+	defer func() { assert.NoError(t, src.Close()) }()
+
+	h := storage.NewRemoteHandler(src, storage.RemoteHandlerOptions{
+		PollInterval: time.Minute, // changes get signaled via pubsub
+	})
+	_, err := src.Subscribe("aa", h)
+	assert.NoError(t, err)
+	_, err = src.Subscribe("xx", h)
+	assert.NoError(t, err)
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	errC := make(chan error, 5)
+	srv, err := config.NewService(storage.NewMap(), config.Options{},
+		storage.WithRemote(storage.RemoteOptions{
+			URL:           ts.URL,
+			Wait:          time.Second,
+			RetryInterval: 10 * time.Millisecond,
+			ErrorHandler: func(err error) {
+				select {
+				case errC <- err:
+				default:
+				}
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	pDefault := config.MustNewPath("aa/bb/cc")
+	pKeep := config.MustNewPath("xx/yy/zz")
+	assert.Exactly(t, `"1"`, srv.Get(pDefault).String())
+	assert.Exactly(t, `"keep"`, srv.Get(pKeep).String())
+
+	assert.NoError(t, src.Set(pDefault, []byte("2")))
+	assert.NoError(t, src.Delete(pKeep))
+
+	deadline := time.Now().Add(time.Second)
+	for srv.Get(pKeep).IsValid() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	select {
+	case err := <-errC:
+		t.Fatalf("%+v", err)
+	default:
+	}
+	assert.Exactly(t, `"2"`, srv.Get(pDefault).String())
+	assert.False(t, srv.Get(pKeep).IsValid(), "deleted path must be removed")
+
+	assert.NoError(t, srv.Close()) // terminates the long-polling request
+}
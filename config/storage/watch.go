@@ -0,0 +1,175 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build csall json yaml
+
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/corestoreio/errors"
+	"github.com/corestoreio/log"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+)
+
+// DefaultWatchInterval defines the default polling interval of the file
+// watchers.
+const DefaultWatchInterval = 5 * time.Second
+
+// WatchOptions configures the file watchers WithWatchYAML and WithWatchJSON.
+type WatchOptions struct {
+	// Interval between two checks of the files. Defaults to
+	// DefaultWatchInterval.
+	Interval time.Duration
+	// ErrorHandler gets called if reloading the files fails, for example due
+	// to a syntax error or a rejected value. The previous configuration stays
+	// active until the content of the files changes again. Optional, defaults
+	// to logging the error with the info level of config.Service.Log.
+	ErrorHandler func(error)
+}
+
+// fileWatcher polls the files of the options, computes a checksum of their
+// content and applies the changed values to the config.Service.
+type fileWatcher struct {
+	wo   WatchOptions
+	opts []option
+	load func(config.Setter, io.Reader) error
+
+	mu       sync.Mutex
+	loaded   bool
+	checksum uint64
+	// failedChecksum prevents reporting the same error on each tick.
+	failedChecksum uint64
+	// current contains the values of the last successful load.
+	current *config.Snapshot
+
+	startOnce sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func withWatch(wo WatchOptions, load func(config.Setter, io.Reader) error, opts []option) config.LoadDataOption {
+	if wo.Interval <= 0 {
+		wo.Interval = DefaultWatchInterval
+	}
+	fw := &fileWatcher{
+		wo:      wo,
+		opts:    opts,
+		load:    load,
+		current: config.NewSnapshot(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	return config.MakeLoadDataOption(func(s *config.Service) error {
+		if err := fw.reload(s); err != nil {
+			return errors.WithStack(err)
+		}
+		fw.startOnce.Do(func() {
+			s.RegisterCloser(fw)
+			go fw.watch(s)
+		})
+		return nil
+	})
+}
+
+// reload reads all files and applies the changes, if the checksum of the files
+// has changed, atomically to the Service.
+func (fw *fileWatcher) reload(s *config.Service) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	h := fnv.New64a()
+	var contents [][]byte
+	readFile := func(_ config.Setter, r io.Reader) error {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		_, _ = fmt.Fprintf(h, "%d:", len(data))
+		_, _ = h.Write(data)
+		contents = append(contents, data)
+		return nil
+	}
+	for _, opt := range fw.opts {
+		if err := opt(s, readFile); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	sum := h.Sum64()
+	if fw.loaded && (sum == fw.checksum || sum == fw.failedChecksum) {
+		return nil
+	}
+
+	sn := config.NewSnapshot()
+	for _, data := range contents {
+		if err := fw.load(sn, bytes.NewReader(data)); err != nil {
+			fw.failedChecksum = sum
+			return errors.WithStack(err)
+		}
+	}
+	if err := s.ApplyChanges(fw.current.Diff(sn)); err != nil {
+		fw.failedChecksum = sum
+		return errors.WithStack(err)
+	}
+	fw.current = sn
+	fw.checksum = sum
+	fw.loaded = true
+	return nil
+}
+
+func (fw *fileWatcher) watch(s *config.Service) {
+	defer close(fw.done)
+	t := time.NewTicker(fw.wo.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-fw.stop:
+			return
+		case <-t.C:
+			if err := fw.reload(s); err != nil {
+				fw.handleError(s, err)
+			}
+		}
+	}
+}
+
+func (fw *fileWatcher) handleError(s *config.Service, err error) {
+	switch {
+	case fw.wo.ErrorHandler != nil:
+		fw.wo.ErrorHandler(err)
+	case s.Log != nil && s.Log.IsInfo():
+		s.Log.Info("config.storage.fileWatcher.reload", log.Err(err))
+	}
+}
+
+// Close terminates the watching goroutine. Gets called by config.Service.Close.
+func (fw *fileWatcher) Close() error {
+	close(fw.stop)
+	<-fw.done
+	return nil
+}
@@ -0,0 +1,160 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build csall json yaml
+
+package storage_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/corestoreio/errors"
+	"github.com/fortytw2/leaktest"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+type testSubscriber func(p config.Path) error
+
+func (ts testSubscriber) MessageConfig(p config.Path) error { return ts(p) }
+
+type observerFunc func(p config.Path, rawData []byte, found bool) ([]byte, error)
+
+func (fn observerFunc) Observe(p config.Path, rawData []byte, found bool) ([]byte, error) {
+	return fn(p, rawData, found)
+}
+
+// watchFormat defines the content of the files for a file watcher.
+// yaml_test.go and json_test.go register their format depending on the build
+// tags.
+type watchFormat struct {
+	name      string
+	fileName  string
+	watch     func(wo storage.WatchOptions, file string) config.LoadDataOption
+	initial   string // aa/bb/cc default 1 and website 1 2, xx/yy/zz default keep
+	changed   string // aa/bb/cc default 3 and website 1 2
+	rejected  string // aa/bb/cc default invalid and website 1 4
+	malformed string
+}
+
+var watchFormats []watchFormat
+
+func TestWithWatch(t *testing.T) {
+	for _, wf := range watchFormats {
+		t.Run(wf.name, func(t *testing.T) {
+			testWithWatch(t, wf)
+		})
+	}
+}
+
+func testWithWatch(t *testing.T, wf watchFormat) {
+	defer leaktest.CheckTimeout(t, time.Second)()
+
+	dir, err := ioutil.TempDir("", "config_storage_watch")
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dir)) }()
+	file := filepath.Join(dir, wf.fileName)
+
+	writeFile := func(data string) { // atomic like a Kubernetes ConfigMap update
+		tmp := file + ".tmp"
+		assert.NoError(t, ioutil.WriteFile(tmp, []byte(data), 0644))
+		assert.NoError(t, os.Rename(tmp, file))
+	}
+	writeFile(wf.initial)
+
+	errC := make(chan error, 5)
+	srv, err := config.NewService(storage.NewMap(), config.Options{EnablePubSub: true},
+		wf.watch(storage.WatchOptions{
+			Interval: 10 * time.Millisecond,
+			ErrorHandler: func(err error) {
+				select {
+				case errC <- err:
+				default:
+				}
+			},
+		}, file),
+	)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer func() { assert.NoError(t, srv.Close()) }()
+
+	pDefault := config.MustNewPath("aa/bb/cc")
+	pKeep := config.MustNewPath("xx/yy/zz")
+	assert.Exactly(t, `"1"`, srv.Get(pDefault).String())
+	assert.Exactly(t, `"2"`, srv.Get(pDefault.BindWebsite(1)).String())
+	assert.Exactly(t, `"keep"`, srv.Get(pKeep).String())
+
+	msgC := make(chan string, 5)
+	_, err = srv.Subscribe("default/0/aa/bb", testSubscriber(func(p config.Path) error {
+		msgC <- p.String()
+		return nil
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, srv.RegisterObserver(config.EventOnBeforeSet, "aa/bb/cc", observerFunc(func(p config.Path, rawData []byte, found bool) ([]byte, error) {
+		if string(rawData) == "invalid" {
+			return nil, errors.NotValid.Newf("Invalid value")
+		}
+		return rawData, nil
+	})))
+
+	t.Run("changed file gets applied", func(t *testing.T) {
+		writeFile(wf.changed)
+		select {
+		case fq := <-msgC:
+			assert.Exactly(t, "default/0/aa/bb/cc", fq)
+		case err := <-errC:
+			t.Fatalf("%+v", err)
+		case <-time.After(time.Second):
+			t.Fatal("pubsub message has not been received")
+		}
+		assert.Exactly(t, `"3"`, srv.Get(pDefault).String())
+		assert.Exactly(t, `"2"`, srv.Get(pDefault.BindWebsite(1)).String())
+		assert.False(t, srv.Get(pKeep).IsValid(), "removed path must be deleted")
+	})
+
+	t.Run("rejected value aborts reload", func(t *testing.T) {
+		writeFile(wf.rejected)
+		select {
+		case err := <-errC:
+			assert.True(t, errors.Aborted.Match(err), "%+v", err)
+		case <-time.After(time.Second):
+			t.Fatal("error has not been received")
+		}
+		assert.Exactly(t, `"3"`, srv.Get(pDefault).String())
+		assert.Exactly(t, `"2"`, srv.Get(pDefault.BindWebsite(1)).String())
+	})
+
+	t.Run("malformed file keeps configuration", func(t *testing.T) {
+		writeFile(wf.malformed)
+		select {
+		case err := <-errC:
+			assert.Error(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("error has not been received")
+		}
+		assert.Exactly(t, `"3"`, srv.Get(pDefault).String())
+	})
+}
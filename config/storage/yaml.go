@@ -33,7 +33,8 @@ import (
 
 // WithLoadYAML reads the configuration values from a YAML file and applies it
 // to the config.service. "testdata/example.yaml" provides an example YAML file.
-// This function processes the YAML file stream based.
+// This function processes the YAML file stream based. A null value deletes the
+// path from the config.Service.
 func WithLoadYAML(opts ...option) config.LoadDataOption {
 	return config.MakeLoadDataOption(func(s *config.Service) (err error) {
 		for i := 0; i < len(opts) && err == nil; i++ {
@@ -43,6 +44,20 @@ func WithLoadYAML(opts ...option) config.LoadDataOption {
 	}).WithUseStorageLevel(1)
 }
 
+// WithWatchYAML loads the configuration values from YAML files like
+// WithLoadYAML and polls the files in the interval of the WatchOptions. The
+// files get reloaded once the checksum of their content changes, for example
+// when Kubernetes updates a mounted ConfigMap. All changed values get applied
+// atomically via config.Service.ApplyChanges: registered observers validate
+// the values before they are applied, a rejected value aborts the whole
+// reload and the subscribers receive a message for each changed path. A path
+// removed from the files gets deleted from the config.Service. A null value
+// removes a path which has been defined in a previously processed file. The
+// watching goroutine gets terminated by config.Service.Close.
+func WithWatchYAML(wo WatchOptions, opts ...option) config.LoadDataOption {
+	return withWatch(wo, loadYAML, opts)
+}
+
 func loadYAML(s config.Setter, r io.Reader) error {
 
 	d := yaml.NewDecoder(r)
@@ -50,7 +65,7 @@ func loadYAML(s config.Setter, r io.Reader) error {
 	p := new(config.Path)
 
 	for {
-		var yd map[string]map[string]map[string]string
+		var yd map[string]map[string]map[string]*string
 		if err := d.Decode(&yd); err == io.EOF {
 			break
 		} else if err != nil {
@@ -62,7 +77,13 @@ func loadYAML(s config.Setter, r io.Reader) error {
 					if err := p.ParseStrings(scp, scpID, route); err != nil {
 						return errors.WithStack(err)
 					}
-					if err := s.Set(p, []byte(data)); err != nil {
+					if data == nil { // YAML null or ~
+						if err := deletePath(s, p); err != nil {
+							return errors.WithStack(err)
+						}
+						continue
+					}
+					if err := s.Set(p, []byte(*data)); err != nil {
 						return errors.WithStack(err)
 					}
 				}
@@ -72,6 +93,27 @@ func loadYAML(s config.Setter, r io.Reader) error {
 	return nil
 }
 
+// WriteSnapshotYAML writes the values of the snapshot as YAML in the format of
+// WithLoadYAML. The meta data of the snapshot gets not exported.
+func WriteSnapshotYAML(w io.Writer, sn *config.Snapshot) error {
+	e := yaml.NewEncoder(w)
+	if err := e.Encode(sn.Tree()); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(e.Close())
+}
+
+// ReadSnapshotYAML creates a new snapshot from a YAML stream in the format of
+// WithLoadYAML. The returned snapshot can be applied with
+// config.Service.Restore.
+func ReadSnapshotYAML(r io.Reader) (*config.Snapshot, error) {
+	sn := config.NewSnapshot()
+	if err := loadYAML(sn, r); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return sn, nil
+}
+
 // WithLoadFieldMetaYAML reads the immutable default values and permissions from
 // a YAML file and applies it to the config.Service. The data gets loaded only
 // once. "testdata/example_field_meta.yaml" provides an example YAML file.
@@ -22,6 +22,8 @@ Sniperkit-Bot
 package storage_test
 
 import (
+	"bytes"
+	"os"
 	"testing"
 
 	"github.com/corestoreio/errors"
@@ -33,6 +35,20 @@ import (
 	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
 )
 
+func init() {
+	watchFormats = append(watchFormats, watchFormat{
+		name:     "YAML",
+		fileName: "config.yaml",
+		watch: func(wo storage.WatchOptions, file string) config.LoadDataOption {
+			return storage.WithWatchYAML(wo, storage.WithFile(file))
+		},
+		initial:   "aa/bb/cc:\n  default:\n    0: 1\n  websites:\n    1: 2\nxx/yy/zz:\n  default:\n    0: keep\n",
+		changed:   "aa/bb/cc:\n  default:\n    0: 3\n  websites:\n    1: 2\n",
+		rejected:  "aa/bb/cc:\n  default:\n    0: invalid\n  websites:\n    1: 4\n",
+		malformed: "aa/bb/cc: [",
+	})
+}
+
 func TestWithLoadYAML(t *testing.T) {
 
 	t.Run("success", func(t *testing.T) {
@@ -50,6 +66,25 @@ func TestWithLoadYAML(t *testing.T) {
 		assert.Exactly(t, `"http://eshop.dev/"`, cfgSrv.Get(config.MustNewPath("web/unsecure/base_url")).String())
 	})
 
+	t.Run("null deletes", func(t *testing.T) {
+
+		cfgSrv, err := config.NewService(
+			storage.NewMap(), config.Options{},
+			storage.WithLoadYAML(storage.WithFiles(
+				[]string{"testdata", "example.yaml"},
+				[]string{"testdata", "example_delete.yaml"},
+			)),
+		)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+
+		scpd := cfgSrv.Scoped(1, 2)
+		assert.Exactly(t, `"http://eshop.dev/"`, scpd.Get(scope.Store, "web/unsecure/base_url").String())
+		assert.False(t, cfgSrv.Get(config.MustNewPathWithScope(scope.Store.WithID(6), "web/unsecure/base_url")).IsValid())
+		assert.Exactly(t, `"http://eshop.dev/ch-fr/"`, cfgSrv.Get(config.MustNewPathWithScope(scope.Store.WithID(7), "web/unsecure/base_url")).String())
+	})
+
 	t.Run("malformed path", func(t *testing.T) {
 
 		cfgSrv, err := config.NewService(
@@ -68,7 +103,7 @@ func TestWithLoadYAML(t *testing.T) {
 			storage.WithLoadYAML(storage.WithFiles([]string{"testdata", "malformed_yaml.yaml"})),
 		)
 		assert.Nil(t, cfgSrv)
-		assert.EqualError(t, err, "yaml: unmarshal errors:\n  line 2: cannot unmarshal !!str `192.168...` into map[string]string")
+		assert.EqualError(t, err, "yaml: unmarshal errors:\n  line 2: cannot unmarshal !!str `192.168...` into map[string]*string")
 	})
 }
 
@@ -126,3 +161,32 @@ func TestWithLoadFieldMetaYAML(t *testing.T) {
 		assert.True(t, errors.NotFound.Match(err), "%+v", err)
 	})
 }
+
+func TestSnapshotYAML(t *testing.T) {
+	f, err := os.Open("testdata/example.yaml")
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, f.Close()) }()
+
+	sn, err := storage.ReadSnapshotYAML(f)
+	assert.NoError(t, err)
+	v, ok, err := sn.Get(config.MustNewPath("web/unsecure/base_url").BindStore(6))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Exactly(t, "http://eshop.dev/ch-de/", string(v))
+
+	var buf bytes.Buffer
+	assert.NoError(t, storage.WriteSnapshotYAML(&buf, sn))
+
+	sn2, err := storage.ReadSnapshotYAML(&buf)
+	assert.NoError(t, err)
+	assert.Exactly(t, sn.Len(), sn2.Len())
+	assert.Len(t, sn.Diff(sn2), 0)
+
+	srv := config.MustNewService(storage.NewMap(), config.Options{})
+	cs, err := srv.Restore(sn2)
+	assert.NoError(t, err)
+	assert.Len(t, cs, sn.Len())
+	after, err := srv.TakeSnapshot("imported")
+	assert.NoError(t, err)
+	assert.Len(t, sn.Diff(after), 0)
+}
@@ -53,6 +53,8 @@ func valFoundStringer(found uint8) string {
 		return "Level2"
 	case valFoundL1:
 		return "Level1"
+	case valFoundDefaults:
+		return "Defaults"
 	}
 	return "CONFIG:FOUND_UNDEFINED"
 }
@@ -559,6 +561,13 @@ func (v *Value) IsValid() bool {
 	return v.lastErr == nil && v.found > valFoundNo
 }
 
+// IsDefault returns true if the value could not be found in the Level1 or
+// Level2 storage and has been provided by the default value of the FieldMeta
+// data.
+func (v *Value) IsDefault() bool {
+	return v.lastErr == nil && v.found == valFoundDefaults
+}
+
 // Equal compares if current object is fully equal to v2 object. Path and data
 // must be equal. Nil safe.
 func (v *Value) Equal(v2 *Value) bool {
@@ -76,6 +76,7 @@ const (
 const (
 	AcceptEncoding     = "Accept-Encoding"
 	Authorization      = "Authorization"
+	CacheControl       = "Cache-Control"
 	ClientIP           = "Client-Ip"
 	ContentDisposition = "Content-Disposition"
 	ContentEncoding    = "Content-Encoding"
@@ -85,6 +86,7 @@ const (
 	Forwarded          = "Forwarded"
 	ForwardedFor       = "Forwarded-For"
 	Location           = "Location"
+	RetryAfter         = "Retry-After"
 	Trailer            = "Trailer"
 	Upgrade            = "Upgrade"
 	Vary               = "Vary"
@@ -92,6 +94,7 @@ const (
 	XClusterClientIP   = "X-Cluster-Client-Ip"
 	XForwarded         = "X-Forwarded"
 	XForwardedFor      = "X-Forwarded-For"
+	XForwardedProto    = "X-Forwarded-Proto"
 	XRealIP            = "X-Real-Ip"
 )
 
@@ -0,0 +1,159 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backendcsrf
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/csrf"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// Routes to the configuration values.
+const (
+	PathDisabled      = `net/csrf/disabled`
+	PathMode          = `net/csrf/mode`
+	PathHeaderName    = `net/csrf/header_name`
+	PathFormFieldName = `net/csrf/form_field_name`
+	PathCookieName    = `net/csrf/cookie_name`
+	PathCookieDomain  = `net/csrf/cookie_domain`
+	PathCookiePath    = `net/csrf/cookie_path`
+	PathCookieSecure  = `net/csrf/cookie_secure`
+	PathCookieMaxAge  = `net/csrf/cookie_max_age`
+	PathExemptPaths   = `net/csrf/exempt_paths`
+)
+
+var allPaths = [...]string{
+	PathDisabled, PathMode, PathHeaderName, PathFormFieldName, PathCookieName,
+	PathCookieDomain, PathCookiePath, PathCookieSecure, PathCookieMaxAge,
+	PathExemptPaths,
+}
+
+// Configuration just exported for the sake of documentation. See fields for
+// more information. The Configuration handles the reading of configuration
+// values within this package.
+type Configuration struct {
+	*csrf.OptionFactories
+
+	// TokenStorer gets applied when a scope has been configured with the
+	// mode synchronizer. If nil, the mode synchronizer is invalid.
+	TokenStorer csrf.TokenStorer
+
+	// defaults contains the default value of each field, used when no value
+	// can be found in the configuration service.
+	defaults map[string]string
+}
+
+// New creates a new backend configuration. The sections must contain all
+// fields defined in NewConfigStructure, see the Path* constants.
+func New(sections config.Sections) (*Configuration, error) {
+	be := &Configuration{
+		OptionFactories: csrf.NewOptionFactories(),
+		defaults:        make(map[string]string, len(allPaths)),
+	}
+	for _, route := range allPaths {
+		f, idx := sections.FindField(route)
+		if idx < 0 {
+			return nil, errors.NotFound.Newf("[backendcsrf] Field %q not found in sections", route)
+		}
+		be.defaults[route] = f.Default
+	}
+	return be, nil
+}
+
+// MustNew same as New but panics on error.
+func MustNew(sections config.Sections) *Configuration {
+	be, err := New(sections)
+	if err != nil {
+		panic(err)
+	}
+	return be
+}
+
+func (be *Configuration) str(sg config.Scoped, route string) (string, error) {
+	v, ok, err := sg.Get(scope.Store, route).Str()
+	if err != nil {
+		return "", errors.Wrapf(err, "[backendcsrf] Get %q", route)
+	}
+	if !ok {
+		return be.defaults[route], nil
+	}
+	return v, nil
+}
+
+func (be *Configuration) bool(sg config.Scoped, route string) (bool, error) {
+	v, err := be.str(sg, route)
+	if err != nil || v == "" {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.NotValid.New(err, "[backendcsrf] Invalid bool %q in %q", v, route)
+	}
+	return b, nil
+}
+
+func (be *Configuration) duration(sg config.Scoped, route string) (time.Duration, error) {
+	v, err := be.str(sg, route)
+	if err != nil || v == "" {
+		return 0, err
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, errors.NotValid.New(err, "[backendcsrf] Invalid duration %q in %q", v, route)
+	}
+	return d, nil
+}
+
+func (be *Configuration) strs(sg config.Scoped, route string) ([]string, error) {
+	v, err := be.str(sg, route)
+	if err != nil || v == "" {
+		return nil, err
+	}
+	var ret []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			ret = append(ret, s)
+		}
+	}
+	return ret, nil
+}
+
+func (be *Configuration) mode(sg config.Scoped) (csrf.Mode, error) {
+	v, err := be.str(sg, PathMode)
+	if err != nil {
+		return 0, err
+	}
+	switch v {
+	case ModeDoubleSubmit, "":
+		return csrf.ModeDoubleSubmitCookie, nil
+	case ModeSynchronizer:
+		if be.TokenStorer == nil {
+			return 0, errors.NotValid.Newf("[backendcsrf] Mode %q requires a TokenStorer in scope %s", v, sg.ScopeID())
+		}
+		return csrf.ModeSynchronizerToken, nil
+	}
+	return 0, errors.NotValid.Newf("[backendcsrf] Unknown mode %q in scope %s", v, sg.ScopeID())
+}
@@ -0,0 +1,116 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backendcsrf_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/corestoreio/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/csrf"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/csrf/backendcsrf"
+)
+
+type sessionStore struct{}
+
+func (sessionStore) GetToken(_ *http.Request) ([]byte, error)                         { return nil, nil }
+func (sessionStore) SaveToken(_ http.ResponseWriter, _ *http.Request, _ []byte) error { return nil }
+
+func newBackend(t *testing.T) *backendcsrf.Configuration {
+	cfgStruct, err := backendcsrf.NewConfigStructure()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return backendcsrf.MustNew(cfgStruct)
+}
+
+func configByScope(t *testing.T, be *backendcsrf.Configuration, kv ...string) (csrf.ScopedConfig, error) {
+	srv, err := csrf.New(config.NewFakeService(storage.NewMap(kv...)), csrf.WithOptionFactory(be.PrepareOptionFactory()))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return srv.ConfigByScope(1, 2)
+}
+
+func TestNew_FieldNotFound(t *testing.T) {
+	be, err := backendcsrf.New(config.MustMakeSectionsValidate())
+	assert.Nil(t, be)
+	assert.True(t, errors.NotFound.Match(err), "%+v", err)
+}
+
+func TestConfiguration_PrepareOptionFactory(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		sc, err := configByScope(t, newBackend(t))
+		assert.NoError(t, err, "%+v", err)
+		assert.False(t, sc.Disabled)
+		assert.Exactly(t, csrf.ModeDoubleSubmitCookie, sc.Mode)
+		assert.Exactly(t, csrf.DefaultHeaderName, sc.HeaderName)
+		assert.Exactly(t, csrf.DefaultFormFieldName, sc.FormFieldName)
+		assert.Exactly(t, csrf.DefaultCookieName, sc.CookieStore.Cookie.Name)
+		assert.Exactly(t, "/", sc.CookieStore.Cookie.Path)
+		assert.False(t, sc.CookieStore.Cookie.Secure)
+		assert.Exactly(t, time.Duration(0), sc.CookieStore.MaxAge)
+		assert.Empty(t, sc.ExemptPaths)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		sc, err := configByScope(t, newBackend(t), "websites/1/"+backendcsrf.PathDisabled, "1")
+		assert.NoError(t, err, "%+v", err)
+		assert.True(t, sc.Disabled)
+	})
+	t.Run("store scope", func(t *testing.T) {
+		be := newBackend(t)
+		be.TokenStorer = sessionStore{}
+		sc, err := configByScope(t, be,
+			"stores/2/"+backendcsrf.PathMode, backendcsrf.ModeSynchronizer,
+			"stores/2/"+backendcsrf.PathHeaderName, "X-XSRF-TOKEN",
+			"websites/1/"+backendcsrf.PathCookieName, "XSRF-TOKEN",
+			"websites/1/"+backendcsrf.PathCookieDomain, "corestore.io",
+			"stores/2/"+backendcsrf.PathCookieSecure, "1",
+			"stores/2/"+backendcsrf.PathCookieMaxAge, "24h",
+			"default/0/"+backendcsrf.PathExemptPaths, "/payment/notify, /api/hook,",
+		)
+		assert.NoError(t, err, "%+v", err)
+		assert.Exactly(t, csrf.ModeSynchronizerToken, sc.Mode)
+		assert.Exactly(t, sessionStore{}, sc.TokenStorer)
+		assert.Exactly(t, "X-XSRF-TOKEN", sc.HeaderName)
+		assert.Exactly(t, "XSRF-TOKEN", sc.CookieStore.Cookie.Name)
+		assert.Exactly(t, "corestore.io", sc.CookieStore.Cookie.Domain)
+		assert.True(t, sc.CookieStore.Cookie.Secure)
+		assert.Exactly(t, 24*time.Hour, sc.CookieStore.MaxAge)
+		assert.Exactly(t, []string{"/payment/notify", "/api/hook"}, sc.ExemptPaths)
+	})
+	t.Run("synchronizer without TokenStorer", func(t *testing.T) {
+		_, err := configByScope(t, newBackend(t), "stores/2/"+backendcsrf.PathMode, backendcsrf.ModeSynchronizer)
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+	t.Run("unknown mode", func(t *testing.T) {
+		_, err := configByScope(t, newBackend(t), "stores/2/"+backendcsrf.PathMode, "cookie")
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+	t.Run("invalid duration", func(t *testing.T) {
+		_, err := configByScope(t, newBackend(t), "stores/2/"+backendcsrf.PathCookieMaxAge, "1 day")
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+}
@@ -0,0 +1,28 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package backendcsrf defines the backend configuration options and element
+// slices for the CSRF protection.
+//
+// The configuration gets loaded per scope from the config.Service via the
+// OptionFactoryFunc returned by Configuration.PrepareOptionFactory. The
+// ModeSynchronizerToken can only be enabled when a TokenStorer has been
+// assigned to the Configuration, because a session storage cannot be
+// defined in the configuration.
+package backendcsrf
@@ -0,0 +1,106 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backendcsrf
+
+import (
+	"net/http"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/csrf"
+)
+
+// PrepareOptionFactory creates a closure around the type Configuration. The
+// closure will be used during a scoped request to figure out the
+// configuration depending on the incoming scope. An option array will be
+// returned by the closure.
+func (be *Configuration) PrepareOptionFactory() csrf.OptionFactoryFunc {
+	return func(sg config.Scoped) []csrf.Option {
+		var (
+			opts [7]csrf.Option
+			i    int // used as index in opts
+		)
+
+		disabled, err := be.bool(sg, PathDisabled)
+		if err != nil {
+			return csrf.OptionsError(errors.Wrap(err, "[backendcsrf] Disabled.Get"))
+		}
+		opts[i] = csrf.WithDisable(disabled, sg.ScopeIDs()...)
+		i++
+		if disabled {
+			return opts[:i]
+		}
+
+		mode, err := be.mode(sg)
+		if err != nil {
+			return csrf.OptionsError(errors.Wrap(err, "[backendcsrf] Mode.Get"))
+		}
+		opts[i] = csrf.WithMode(mode, sg.ScopeIDs()...)
+		i++
+		if mode == csrf.ModeSynchronizerToken {
+			opts[i] = csrf.WithTokenStorer(be.TokenStorer, sg.ScopeIDs()...)
+			i++
+		}
+
+		header, err := be.str(sg, PathHeaderName)
+		if err != nil {
+			return csrf.OptionsError(errors.Wrap(err, "[backendcsrf] HeaderName.Get"))
+		}
+		opts[i] = csrf.WithHeaderName(header, sg.ScopeIDs()...)
+		i++
+
+		field, err := be.str(sg, PathFormFieldName)
+		if err != nil {
+			return csrf.OptionsError(errors.Wrap(err, "[backendcsrf] FormFieldName.Get"))
+		}
+		opts[i] = csrf.WithFormFieldName(field, sg.ScopeIDs()...)
+		i++
+
+		var c http.Cookie
+		if c.Name, err = be.str(sg, PathCookieName); err != nil {
+			return csrf.OptionsError(errors.Wrap(err, "[backendcsrf] CookieName.Get"))
+		}
+		if c.Domain, err = be.str(sg, PathCookieDomain); err != nil {
+			return csrf.OptionsError(errors.Wrap(err, "[backendcsrf] CookieDomain.Get"))
+		}
+		if c.Path, err = be.str(sg, PathCookiePath); err != nil {
+			return csrf.OptionsError(errors.Wrap(err, "[backendcsrf] CookiePath.Get"))
+		}
+		if c.Secure, err = be.bool(sg, PathCookieSecure); err != nil {
+			return csrf.OptionsError(errors.Wrap(err, "[backendcsrf] CookieSecure.Get"))
+		}
+		maxAge, err := be.duration(sg, PathCookieMaxAge)
+		if err != nil {
+			return csrf.OptionsError(errors.Wrap(err, "[backendcsrf] CookieMaxAge.Get"))
+		}
+		opts[i] = csrf.WithCookie(c, maxAge, sg.ScopeIDs()...)
+		i++
+
+		paths, err := be.strs(sg, PathExemptPaths)
+		if err != nil {
+			return csrf.OptionsError(errors.Wrap(err, "[backendcsrf] ExemptPaths.Get"))
+		}
+		opts[i] = csrf.WithExemptPaths(paths, sg.ScopeIDs()...)
+		i++
+
+		return opts[:i]
+	}
+}
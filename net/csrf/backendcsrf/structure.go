@@ -0,0 +1,165 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backendcsrf
+
+import (
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/csrf"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// Values of the field mode.
+const (
+	ModeDoubleSubmit = "double_submit"
+	ModeSynchronizer = "synchronizer"
+)
+
+// NewConfigStructure global configuration structure for this package. Used in
+// frontend (to display the user all the settings) and in backend (scope checks
+// and default values). See the source code of this function for the overall
+// available sections, groups and fields.
+func NewConfigStructure() (config.Sections, error) {
+	sortIdx := 10
+	var iter = func() int {
+		sortIdx += 10
+		return sortIdx
+	}
+	return config.MakeSectionsValidated(
+		&config.Section{
+			ID: "net",
+			Groups: config.MakeGroups(
+				&config.Group{
+					ID:        "csrf",
+					Label:     `Cross-Site Request Forgery`,
+					SortOrder: 140,
+					Scopes:    scope.PermStore,
+					Fields: config.MakeFields(
+						&config.Field{
+							// Path: net/csrf/disabled
+							ID:        "disabled",
+							Label:     `Disabled`,
+							Comment:   `Set to true to disable the CSRF protection.`,
+							Type:      config.TypeSelect,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   `0`,
+						},
+						&config.Field{
+							// Path: net/csrf/mode
+							ID:        "mode",
+							Label:     `Mode`,
+							Comment:   `Defines where the token gets stored: double_submit stores the token in a cookie, synchronizer stores the token in the session.`,
+							Type:      config.TypeSelect,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   ModeDoubleSubmit,
+							Options:   []string{ModeDoubleSubmit, ModeSynchronizer},
+						},
+						&config.Field{
+							// Path: net/csrf/header_name
+							ID:        "header_name",
+							Label:     `HTTP Header Name`,
+							Comment:   `Name of the request header which contains the token. AngularJS uses X-XSRF-TOKEN.`,
+							Type:      config.TypeText,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   csrf.DefaultHeaderName,
+						},
+						&config.Field{
+							// Path: net/csrf/form_field_name
+							ID:        "form_field_name",
+							Label:     `Form Field Name`,
+							Comment:   `Name of the form field which contains the token. Empty disables the form field lookup.`,
+							Type:      config.TypeText,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   csrf.DefaultFormFieldName,
+						},
+						&config.Field{
+							// Path: net/csrf/cookie_name
+							ID:        "cookie_name",
+							Label:     `Cookie Name`,
+							Comment:   `Name of the cookie in mode double_submit. AngularJS uses XSRF-TOKEN.`,
+							Type:      config.TypeText,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   csrf.DefaultCookieName,
+						},
+						&config.Field{
+							// Path: net/csrf/cookie_domain
+							ID:        "cookie_domain",
+							Label:     `Cookie Domain`,
+							Type:      config.TypeText,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+						},
+						&config.Field{
+							// Path: net/csrf/cookie_path
+							ID:        "cookie_path",
+							Label:     `Cookie Path`,
+							Type:      config.TypeText,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   `/`,
+						},
+						&config.Field{
+							// Path: net/csrf/cookie_secure
+							ID:        "cookie_secure",
+							Label:     `Cookie Secure`,
+							Comment:   `Set to true to send the cookie only via HTTPS.`,
+							Type:      config.TypeSelect,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   `0`,
+						},
+						&config.Field{
+							// Path: net/csrf/cookie_max_age
+							ID:        "cookie_max_age",
+							Label:     `Cookie Max Age`,
+							Comment:   `Duration like 720h after which the cookie expires. Empty creates a session cookie.`,
+							Type:      config.TypeText,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+						},
+						&config.Field{
+							// Path: net/csrf/exempt_paths
+							ID:        "exempt_paths",
+							Label:     `Exempt Paths`,
+							Comment:   `Comma separated list of URL paths which are not protected including all paths below them, e.g. web hooks of payment providers.`,
+							Type:      config.TypeTextarea,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+						},
+					),
+				},
+			),
+		},
+	)
+}
@@ -19,6 +19,23 @@ Sniperkit-Bot
 
 // Package csrf implements scope based Cross-Site Request Forgery protection.
 //
+// The middleware Service.WithCSRF creates for each client a random token and
+// stores it depending on the Mode. ModeDoubleSubmitCookie stores the token in
+// a cookie, ModeSynchronizerToken stores it on the server side via a
+// TokenStorer, e.g. in the session. Requests with unsafe methods like POST
+// must send the token back in the configured HTTP header or in the form
+// field, otherwise the FailureHandler returns status 403 Forbidden. The header
+// name can be changed for SPA frameworks, AngularJS for example reads the
+// cookie XSRF-TOKEN and sends the header X-XSRF-TOKEN.
+//
+// Each request receives via FromContextToken a new masked version of the
+// token to be rendered into HTML forms. Masking with a one-time pad prevents
+// BREACH attacks on compressed responses. Unmasked tokens as copied from the
+// cookie are accepted as well.
+//
+// Exempt paths, like web hooks of payment providers, and the disabling of the
+// protection can be configured per scope.
+//
 // Sub-package `backendcsrf` implements the external configuration loading.
 //
 // http://stackoverflow.com/questions/20504846/why-is-it-common-to-put-csrf-prevention-tokens-in-cookies/20518324#20518324
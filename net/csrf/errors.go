@@ -0,0 +1,28 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csrf
+
+const (
+	errScopedConfigNotValid     = `[csrf] ScopedConfig %s invalid. Mode %s requires a TokenStorer`
+	errScopedConfigModeNotValid = `[csrf] ScopedConfig %s has an unknown mode: %d`
+	errTokenNotFound            = `[csrf] Token not found in header %q or form field %q`
+	errTokenNotValid            = `[csrf] Token not valid`
+	errTokenMissingInStorage    = `[csrf] Token missing in storage. Mode %s, scope %s`
+)
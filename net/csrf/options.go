@@ -0,0 +1,111 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csrf
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/mw"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// WithDefaultConfig applies the default configuration settings for a specific
+// scope.
+//
+// Default values are:
+//		- ModeDoubleSubmitCookie with cookie csrf_token
+//		- header X-CSRF-Token and form field csrf_token
+//		- no exempt paths
+//		- failure handler returns status 403 Forbidden
+func WithDefaultConfig(scopeIDs ...scope.TypeID) Option {
+	return withDefaultConfig(scopeIDs...)
+}
+
+// WithMode sets the mode how the token gets stored. ModeSynchronizerToken
+// requires additionally the option WithTokenStorer.
+func WithMode(m Mode, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.Mode = m
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithTokenStorer sets the storage of the token for ModeSynchronizerToken,
+// for example a session.
+func WithTokenStorer(ts TokenStorer, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.TokenStorer = ts
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithCookie sets the cookie template and the max age of the cookie for
+// ModeDoubleSubmitCookie. An empty cookie name falls back to
+// DefaultCookieName. A maxAge of zero creates a session cookie.
+func WithCookie(tpl http.Cookie, maxAge time.Duration, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.CookieStore = CookieStore{Cookie: tpl, MaxAge: maxAge}
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithHeaderName sets the name of the request header which contains the
+// token. For example AngularJS uses X-XSRF-TOKEN.
+func WithHeaderName(name string, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.HeaderName = name
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithFormFieldName sets the name of the form field which contains the
+// token. An empty name disables the form field lookup.
+func WithFormFieldName(name string, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.FormFieldName = name
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithExemptPaths sets URL paths which are not protected including all paths
+// below them. Applying this option replaces the previously set paths.
+func WithExemptPaths(paths []string, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.ExemptPaths = append([]string(nil), paths...)
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithFailureHandler sets the handler which gets called when the token is
+// missing or invalid.
+func WithFailureHandler(fh mw.ErrorHandler, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.FailureHandler = fh
+		return s.updateScopedConfig(sc)
+	}
+}
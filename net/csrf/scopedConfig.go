@@ -0,0 +1,195 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csrf
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/mw"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// Default names of the cookie, the HTTP header and the form field which
+// transport the token.
+const (
+	DefaultCookieName    = "csrf_token"
+	DefaultHeaderName    = "X-CSRF-Token"
+	DefaultFormFieldName = "csrf_token"
+)
+
+// Mode defines how the token of a client gets stored.
+type Mode uint8
+
+// Available modes.
+const (
+	// ModeDoubleSubmitCookie stores the token in a cookie. The client must
+	// send the token additionally in the header or in the form field.
+	ModeDoubleSubmitCookie Mode = iota
+	// ModeSynchronizerToken stores the token on the server side, e.g. in the
+	// session, via a TokenStorer.
+	ModeSynchronizerToken
+	modeMax
+)
+
+var modeNames = [...]string{"DoubleSubmitCookie", "SynchronizerToken"}
+
+func (m Mode) String() string {
+	if m >= modeMax {
+		return "Mode(?)"
+	}
+	return modeNames[m]
+}
+
+var defaultFailureHandler = mw.ErrorWithStatusCode(http.StatusForbidden)
+
+// ScopedConfig contains the configuration for a specific scope.
+type ScopedConfig struct {
+	scopedConfigGeneric
+	// Mode defines where the token gets stored.
+	Mode Mode
+	// TokenStorer gets used in ModeSynchronizerToken. In
+	// ModeDoubleSubmitCookie CookieStore gets used.
+	TokenStorer TokenStorer
+	// CookieStore stores the token in ModeDoubleSubmitCookie.
+	CookieStore CookieStore
+	// HeaderName name of the HTTP request header which contains the token.
+	// Configurable for SPA frameworks, e.g. AngularJS uses X-XSRF-TOKEN.
+	HeaderName string
+	// FormFieldName name of the form field which contains the token.
+	FormFieldName string
+	// ExemptPaths URL paths which are not protected including all paths below
+	// them, e.g. web hooks of payment providers.
+	ExemptPaths []string
+	// FailureHandler gets called when the token is missing or invalid.
+	// Defaults to status 403 Forbidden.
+	FailureHandler mw.ErrorHandler
+}
+
+// isValid a configuration for a scope is only then valid when the mode is
+// known and a TokenStorer has been set in ModeSynchronizerToken.
+func (sc *ScopedConfig) isValid() error {
+	if err := sc.isValidPreCheck(); err != nil {
+		return errors.Wrap(err, "[csrf] ScopedConfig.isValid as an lastErr")
+	}
+	if sc.Disabled {
+		return nil
+	}
+	switch {
+	case sc.Mode >= modeMax:
+		return errors.NotValid.Newf(errScopedConfigModeNotValid, sc.ScopeID, sc.Mode)
+	case sc.Mode == ModeSynchronizerToken && sc.TokenStorer == nil:
+		return errors.NotValid.Newf(errScopedConfigNotValid, sc.ScopeID, sc.Mode)
+	}
+	return nil
+}
+
+func newScopedConfig(target, parent scope.TypeID) *ScopedConfig {
+	return &ScopedConfig{
+		scopedConfigGeneric: newScopedConfigGeneric(target, parent),
+		Mode:                ModeDoubleSubmitCookie,
+		HeaderName:          DefaultHeaderName,
+		FormFieldName:       DefaultFormFieldName,
+		FailureHandler:      defaultFailureHandler,
+	}
+}
+
+func (sc ScopedConfig) storer() TokenStorer {
+	if sc.Mode == ModeSynchronizerToken {
+		return sc.TokenStorer
+	}
+	return sc.CookieStore
+}
+
+// isExempt checks if the path of the request equals one of the exempt paths
+// or is below of it. The comparison matches whole path segments, so
+// "/api/hook" does not exempt "/api/hooks".
+func (sc ScopedConfig) isExempt(r *http.Request) bool {
+	for _, p := range sc.ExemptPaths {
+		if p == "" {
+			continue
+		}
+		p = strings.TrimSuffix(p, "/")
+		if r.URL.Path == p || strings.HasPrefix(r.URL.Path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isSafeMethod reports whether the HTTP method does not change state as
+// defined in RFC 7231.
+func isSafeMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		return true
+	}
+	return false
+}
+
+// token returns the raw token of the client. If the client has no token yet,
+// a new one gets created and saved. Argument isNew reports whether the token
+// has been created.
+func (sc ScopedConfig) token(w http.ResponseWriter, r *http.Request) (token []byte, isNew bool, _ error) {
+	ts := sc.storer()
+	token, err := ts.GetToken(r)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "[csrf] TokenStorer.GetToken")
+	}
+	if len(token) == TokenLength {
+		return token, false, nil
+	}
+	if token, err = newToken(); err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	if err := ts.SaveToken(w, r, token); err != nil {
+		return nil, false, errors.Wrap(err, "[csrf] TokenStorer.SaveToken")
+	}
+	return token, true, nil
+}
+
+// requestToken extracts the token sent by the client from the header or the
+// form field.
+func (sc ScopedConfig) requestToken(r *http.Request) string {
+	if t := r.Header.Get(sc.HeaderName); t != "" {
+		return t
+	}
+	if sc.FormFieldName != "" {
+		return r.PostFormValue(sc.FormFieldName)
+	}
+	return ""
+}
+
+// validate compares the token sent by the client with the stored raw token.
+func (sc ScopedConfig) validate(r *http.Request, token []byte, isNew bool) error {
+	if isNew {
+		return errors.NotFound.Newf(errTokenMissingInStorage, sc.Mode, sc.ScopeID)
+	}
+	sent := sc.requestToken(r)
+	if sent == "" {
+		return errors.NotFound.Newf(errTokenNotFound, sc.HeaderName, sc.FormFieldName)
+	}
+	if !tokensEqual(unmaskToken(sent), token) {
+		return errors.NotValid.Newf(errTokenNotValid)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csrf
+
+import (
+	"net/http"
+
+	"github.com/corestoreio/errors"
+	"github.com/corestoreio/log"
+	loghttp "github.com/corestoreio/log/http"
+)
+
+// WithCSRF to be used as a middleware. This middleware expects to find a
+// scope.FromContext(). Each request receives a masked token in its context,
+// see FromContextToken. Requests with unsafe methods like POST must send
+// the token in the header or in the form field, otherwise the
+// FailureHandler gets called.
+func (s *Service) WithCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scpCfg, err := s.configByContext(r.Context())
+		if err != nil {
+			if s.Log.IsDebug() {
+				s.Log.Debug("csrf.Service.WithCSRF.configByContext", log.Err(err), loghttp.Request("request", r))
+			}
+			s.ErrorHandler(errors.Wrap(err, "[csrf] Service.WithCSRF.configByContext")).ServeHTTP(w, r)
+			return
+		}
+		if scpCfg.Disabled || scpCfg.isExempt(r) {
+			if s.Log.IsDebug() {
+				s.Log.Debug("csrf.Service.WithCSRF.DisabledOrExempt", log.Stringer("scope", scpCfg.ScopeID),
+					log.Bool("disabled", scpCfg.Disabled), loghttp.Request("request", r))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, isNew, err := scpCfg.token(w, r)
+		if err != nil {
+			if s.Log.IsDebug() {
+				s.Log.Debug("csrf.Service.WithCSRF.token", log.Err(err), log.Stringer("scope", scpCfg.ScopeID), loghttp.Request("request", r))
+			}
+			scpCfg.ErrorHandler(errors.Wrap(err, "[csrf] Service.WithCSRF.token")).ServeHTTP(w, r)
+			return
+		}
+		masked, err := maskToken(token)
+		if err != nil {
+			scpCfg.ErrorHandler(errors.Wrap(err, "[csrf] Service.WithCSRF.maskToken")).ServeHTTP(w, r)
+			return
+		}
+		r = r.WithContext(withContextToken(r.Context(), masked))
+		// the response depends on the cookie, so caches must not share it.
+		w.Header().Add("Vary", "Cookie")
+
+		if !isSafeMethod(r.Method) {
+			if err := scpCfg.validate(r, token, isNew); err != nil {
+				if s.Log.IsDebug() {
+					s.Log.Debug("csrf.Service.WithCSRF.validate", log.Err(err), log.Stringer("scope", scpCfg.ScopeID), loghttp.Request("request", r))
+				}
+				scpCfg.FailureHandler(errors.Wrap(err, "[csrf] Request forbidden")).ServeHTTP(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
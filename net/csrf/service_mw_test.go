@@ -0,0 +1,243 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csrf_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/csrf"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/mw"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+var finalHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	tok, _ := csrf.FromContextToken(r.Context())
+	w.Header().Set("X-Test-Token", tok)
+	w.WriteHeader(http.StatusTeapot)
+})
+
+// sessionStore mocks a server side session storage.
+type sessionStore struct {
+	token []byte
+}
+
+func (ss *sessionStore) GetToken(_ *http.Request) ([]byte, error) { return ss.token, nil }
+func (ss *sessionStore) SaveToken(_ http.ResponseWriter, _ *http.Request, token []byte) error {
+	ss.token = token
+	return nil
+}
+
+func newRequest(method, path string, cookies ...*http.Cookie) *http.Request {
+	r := httptest.NewRequest(method, "http://corestore.io"+path, nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	return r.WithContext(scope.WithContext(r.Context(), 1, 2))
+}
+
+func newService(t *testing.T, opts ...csrf.Option) *csrf.Service {
+	srv, err := csrf.New(config.NewFakeService(storage.NewMap()), opts...)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return srv
+}
+
+// firstVisit executes a GET request and returns the cookie and the masked
+// token of the response.
+func firstVisit(t *testing.T, h http.Handler) (*http.Cookie, string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest("GET", "/checkout"))
+	assert.Exactly(t, http.StatusTeapot, rec.Code)
+	resp := http.Response{Header: rec.Header()}
+	cookies := resp.Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expecting one cookie, got %#v", cookies)
+	}
+	assert.Exactly(t, csrf.DefaultCookieName, cookies[0].Name)
+	assert.Exactly(t, "/", cookies[0].Path)
+	assert.Exactly(t, "Cookie", rec.Header().Get("Vary"))
+	masked := rec.Header().Get("X-Test-Token")
+	assert.NotEmpty(t, masked)
+	return cookies[0], masked
+}
+
+func TestService_WithCSRF_MWAdapter(t *testing.T) {
+	// checks if the middleware conforms to the mw.Middleware definition
+	srv := csrf.MustNew(config.NewFakeService(storage.NewMap()))
+	_ = mw.Chain(finalHandler, srv.WithCSRF)
+}
+
+func TestService_WithCSRF_DoubleSubmitCookie(t *testing.T) {
+	h := newService(t).WithCSRF(finalHandler)
+	cookie, masked := firstVisit(t, h)
+
+	t.Run("masked token changes per request", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRequest("GET", "/checkout", cookie))
+		assert.Exactly(t, http.StatusTeapot, rec.Code)
+		assert.Empty(t, rec.Header().Get("Set-Cookie"), "Cookie must not be renewed")
+		assert.NotEqual(t, masked, rec.Header().Get("X-Test-Token"))
+	})
+	t.Run("POST masked header token", func(t *testing.T) {
+		req := newRequest("POST", "/checkout", cookie)
+		req.Header.Set(csrf.DefaultHeaderName, masked)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Exactly(t, http.StatusTeapot, rec.Code)
+	})
+	t.Run("POST raw cookie value in header", func(t *testing.T) {
+		req := newRequest("POST", "/checkout", cookie)
+		req.Header.Set(csrf.DefaultHeaderName, cookie.Value)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Exactly(t, http.StatusTeapot, rec.Code)
+	})
+	t.Run("POST form field", func(t *testing.T) {
+		req := newRequest("POST", "/checkout", cookie)
+		req.Body = ioutil.NopCloser(strings.NewReader(url.Values{csrf.DefaultFormFieldName: {masked}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Exactly(t, http.StatusTeapot, rec.Code)
+	})
+	t.Run("POST token missing", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRequest("POST", "/checkout", cookie))
+		assert.Exactly(t, http.StatusForbidden, rec.Code)
+	})
+	t.Run("POST token invalid", func(t *testing.T) {
+		req := newRequest("POST", "/checkout", cookie)
+		req.Header.Set(csrf.DefaultHeaderName, "aW52YWxpZA")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Exactly(t, http.StatusForbidden, rec.Code)
+	})
+	t.Run("POST cookie missing", func(t *testing.T) {
+		req := newRequest("POST", "/checkout")
+		req.Header.Set(csrf.DefaultHeaderName, masked)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Exactly(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestService_WithCSRF_Scoped(t *testing.T) {
+	s2 := scope.MakeTypeID(scope.Store, 2)
+	h := newService(t,
+		csrf.WithHeaderName("X-XSRF-TOKEN", s2),
+		csrf.WithCookie(http.Cookie{Name: "XSRF-TOKEN", Path: "/shop"}, 0, s2),
+		csrf.WithExemptPaths([]string{"/payment/notify"}, s2),
+		csrf.WithFailureHandler(mw.ErrorWithStatusCode(http.StatusBadRequest), s2),
+	).WithCSRF(finalHandler)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest("GET", "/checkout"))
+	resp := http.Response{Header: rec.Header()}
+	cookies := resp.Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expecting one cookie, got %#v", cookies)
+	}
+	assert.Exactly(t, "XSRF-TOKEN", cookies[0].Name)
+	assert.Exactly(t, "/shop", cookies[0].Path)
+
+	req := newRequest("POST", "/checkout", cookies[0])
+	req.Header.Set("X-XSRF-TOKEN", cookies[0].Value)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Exactly(t, http.StatusTeapot, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest("POST", "/checkout", cookies[0]))
+	assert.Exactly(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest("POST", "/payment/notify"))
+	assert.Exactly(t, http.StatusTeapot, rec.Code)
+	assert.Empty(t, rec.Header().Get("Set-Cookie"))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest("POST", "/payment/notify/paypal"))
+	assert.Exactly(t, http.StatusTeapot, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest("POST", "/payment/notify-admin"))
+	assert.Exactly(t, http.StatusBadRequest, rec.Code, "exempt path must match whole path segments")
+}
+
+func TestService_WithCSRF_Disabled(t *testing.T) {
+	h := newService(t, csrf.WithDisable(true, scope.MakeTypeID(scope.Website, 1))).WithCSRF(finalHandler)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest("POST", "/checkout"))
+	assert.Exactly(t, http.StatusTeapot, rec.Code)
+	assert.Empty(t, rec.Header().Get("Set-Cookie"))
+	assert.Empty(t, rec.Header().Get("X-Test-Token"))
+}
+
+func TestService_WithCSRF_SynchronizerToken(t *testing.T) {
+	t.Run("without TokenStorer", func(t *testing.T) {
+		h := newService(t, csrf.WithMode(csrf.ModeSynchronizerToken)).WithCSRF(finalHandler)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRequest("GET", "/checkout"))
+		assert.Exactly(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	ss := new(sessionStore)
+	h := newService(t,
+		csrf.WithMode(csrf.ModeSynchronizerToken),
+		csrf.WithTokenStorer(ss),
+	).WithCSRF(finalHandler)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest("GET", "/checkout"))
+	assert.Exactly(t, http.StatusTeapot, rec.Code)
+	assert.Empty(t, rec.Header().Get("Set-Cookie"))
+	assert.Len(t, ss.token, csrf.TokenLength)
+	masked := rec.Header().Get("X-Test-Token")
+
+	req := newRequest("POST", "/checkout")
+	req.Header.Set(csrf.DefaultHeaderName, masked)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Exactly(t, http.StatusTeapot, rec.Code)
+
+	ss.token = nil // session expired
+	req = newRequest("POST", "/checkout")
+	req.Header.Set(csrf.DefaultHeaderName, masked)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Exactly(t, http.StatusForbidden, rec.Code)
+}
+
+func TestService_WithCSRF_NoScope(t *testing.T) {
+	h := newService(t).WithCSRF(finalHandler)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "http://corestore.io/checkout", nil))
+	assert.Exactly(t, http.StatusServiceUnavailable, rec.Code)
+}
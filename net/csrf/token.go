@@ -0,0 +1,161 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csrf
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/corestoreio/errors"
+)
+
+// TokenLength defines the length of the raw token in bytes.
+const TokenLength = 32
+
+// TokenStorer persists the raw unmasked token of a client. In mode
+// ModeDoubleSubmitCookie the token gets stored in a cookie, in mode
+// ModeSynchronizerToken you must provide a TokenStorer which stores the token
+// in the session of the client.
+type TokenStorer interface {
+	// GetToken returns the raw token of the client. A nil token without an
+	// error means that the client has no token yet.
+	GetToken(r *http.Request) ([]byte, error)
+	// SaveToken stores a new raw token.
+	SaveToken(w http.ResponseWriter, r *http.Request, token []byte) error
+}
+
+// CookieStore stores the raw token in a cookie. The cookie is readable by
+// JavaScript per default, so that SPA frameworks can copy its value into the
+// request header.
+type CookieStore struct {
+	// Cookie template, the fields Value and Expires get overwritten. Name
+	// defaults to DefaultCookieName and Path to "/".
+	Cookie http.Cookie
+	// MaxAge defines the validity of the cookie. Zero creates a session
+	// cookie.
+	MaxAge time.Duration
+}
+
+// GetToken reads the token from the cookie. Invalid cookie values get
+// treated as a missing token.
+func (cs CookieStore) GetToken(r *http.Request) ([]byte, error) {
+	c, err := r.Cookie(cs.name())
+	if err != nil {
+		return nil, nil // http.ErrNoCookie
+	}
+	token, err := base64.RawURLEncoding.DecodeString(c.Value)
+	if err != nil || len(token) != TokenLength {
+		return nil, nil
+	}
+	return token, nil
+}
+
+// SaveToken writes the token as a cookie into the response.
+func (cs CookieStore) SaveToken(w http.ResponseWriter, _ *http.Request, token []byte) error {
+	c := cs.Cookie
+	c.Name = cs.name()
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	c.Value = base64.RawURLEncoding.EncodeToString(token)
+	if cs.MaxAge > 0 {
+		c.Expires = time.Now().Add(cs.MaxAge)
+		c.MaxAge = int(cs.MaxAge.Seconds())
+	}
+	http.SetCookie(w, &c)
+	return nil
+}
+
+func (cs CookieStore) name() string {
+	if cs.Cookie.Name != "" {
+		return cs.Cookie.Name
+	}
+	return DefaultCookieName
+}
+
+// newToken creates a new random raw token.
+func newToken() ([]byte, error) {
+	token := make([]byte, TokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, errors.ReadFailed.New(err, "[csrf] Failed to read from crypto/rand")
+	}
+	return token, nil
+}
+
+// maskToken returns a new encoded one-time-pad masked token for each call to
+// prevent BREACH attacks. The result consists of the pad followed by the
+// token XORed with the pad.
+func maskToken(token []byte) (string, error) {
+	masked := make([]byte, 2*TokenLength)
+	if _, err := rand.Read(masked[:TokenLength]); err != nil {
+		return "", errors.ReadFailed.New(err, "[csrf] Failed to read from crypto/rand")
+	}
+	xorBytes(masked[TokenLength:], token, masked[:TokenLength])
+	return base64.RawURLEncoding.EncodeToString(masked), nil
+}
+
+// unmaskToken decodes a masked or an unmasked token. Unmasked tokens are
+// sent by clients which copy the value of the cookie into the header. Returns
+// nil on invalid input.
+func unmaskToken(encoded string) []byte {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil
+	}
+	switch len(data) {
+	case TokenLength:
+		return data
+	case 2 * TokenLength:
+		token := make([]byte, TokenLength)
+		xorBytes(token, data[TokenLength:], data[:TokenLength])
+		return token
+	}
+	return nil
+}
+
+func xorBytes(dst, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
+
+// tokensEqual compares in constant time.
+func tokensEqual(a, b []byte) bool {
+	return len(a) == TokenLength && subtle.ConstantTimeCompare(a, b) == 1
+}
+
+type ctxTokenKey struct{}
+
+func withContextToken(ctx context.Context, maskedToken string) context.Context {
+	return context.WithValue(ctx, ctxTokenKey{}, maskedToken)
+}
+
+// FromContextToken returns the masked token of the current request. The
+// token must be rendered into HTML forms or sent in the configured header.
+// The token changes with every request but all tokens stay valid as long as
+// the raw token of the client does not change.
+func FromContextToken(ctx context.Context) (string, bool) {
+	t, ok := ctx.Value(ctxTokenKey{}).(string)
+	return t, ok && t != ""
+}
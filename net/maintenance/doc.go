@@ -0,0 +1,39 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package maintenance provides a middleware which puts single store views or
+// whole websites into maintenance mode while the other scopes keep serving.
+//
+// The settings get read from a config.Scoped with the scope of the current
+// request, see scope.FromContext. Setting web/maintenance/enabled in the
+// default scope switches off the whole shop, on a website all its store views
+// and on a store view only that one. Client IP addresses listed in
+// web/maintenance/allowed_ips can still access the store. The IP address gets
+// extracted with request.RealIP.
+//
+// Browsers receive the rendered HTML template of web/maintenance/template or
+// DefaultTemplate. Clients which request JSON receive a problem.Detail. Both
+// responses carry the status code 503 and, if configured, the Retry-After
+// header.
+//
+// The parsed settings get cached per website and store ID. Call
+// Service.Subscribe to flush the cache whenever config.Service.Set writes a
+// route below web/maintenance, which allows to toggle the maintenance mode
+// without a restart.
+package maintenance
@@ -0,0 +1,230 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maintenance
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/corestoreio/errors"
+	"github.com/corestoreio/log"
+	loghttp "github.com/corestoreio/log/http"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	csnet "github.com/sniperkit/snk.fork.corestoreio-pkg/net"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/mw"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/problem"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/request"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// Options additional customizations for the maintenance middleware.
+type Options struct {
+	// ErrorHandler optional custom error handler for invalid configuration
+	// values or a missing scope in the request context. Defaults to sending
+	// an HTTP status code 500 and exposing the real error including full
+	// paths.
+	mw.ErrorHandler
+	// Log can be nil, defaults to black hole.
+	Log log.Logger
+	// TrustForwardedHeaders uses the forwarded headers to find the client IP
+	// address. Enable it only behind a trusted proxy, see request.RealIP.
+	TrustForwardedHeaders bool
+}
+
+type scopeKey struct {
+	websiteID int64
+	storeID   int64
+}
+
+// Service reads the maintenance settings per scope and provides the
+// middleware. Safe for concurrent use.
+type Service struct {
+	cfg   config.Scoper
+	opts  Options
+	ipOpt int
+
+	mu    sync.RWMutex
+	cache map[scopeKey]*Settings
+	// generation gets incremented by Flush. Settings loaded before a Flush
+	// are outdated and must not be cached.
+	generation uint64
+}
+
+// NewService creates a new maintenance service which reads the settings from
+// cfg.
+func NewService(cfg config.Scoper, o Options) *Service {
+	if o.Log == nil {
+		o.Log = log.BlackHole{} // disabled debug and info logging
+	}
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = mw.ErrorWithStatusCode(http.StatusInternalServerError)
+	}
+	s := &Service{
+		cfg:   cfg,
+		opts:  o,
+		ipOpt: request.IPForwardedIgnore,
+		cache: make(map[scopeKey]*Settings),
+	}
+	if o.TrustForwardedHeaders {
+		s.ipOpt = request.IPForwardedTrust
+	}
+	return s
+}
+
+// Settings returns the cached maintenance settings for a website and store
+// ID. On a cache miss the settings get loaded from the configuration.
+func (s *Service) Settings(websiteID, storeID int64) (*Settings, error) {
+	k := scopeKey{websiteID: websiteID, storeID: storeID}
+	s.mu.RLock()
+	st, ok := s.cache[k]
+	gen := s.generation
+	s.mu.RUnlock()
+	if ok {
+		return st, nil
+	}
+
+	st, err := loadSettings(s.cfg, websiteID, storeID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	s.mu.Lock()
+	if s.generation == gen {
+		s.cache[k] = st
+	}
+	s.mu.Unlock()
+	return st, nil
+}
+
+// Flush clears the cached settings of all scopes. Settings which are getting
+// loaded concurrently won't be cached.
+func (s *Service) Flush() {
+	s.mu.Lock()
+	s.cache = make(map[scopeKey]*Settings)
+	s.generation++
+	s.mu.Unlock()
+}
+
+// MessageConfig implements config.MessageReceiver and flushes the cache when
+// a route below web/maintenance changes. A change in the default or website
+// scope affects all its store views, so the whole cache gets cleared.
+func (s *Service) MessageConfig(p config.Path) error {
+	if p.RouteHasPrefix(PathPrefix) {
+		s.Flush()
+		if s.opts.Log.IsDebug() {
+			s.opts.Log.Debug("maintenance.Service.MessageConfig.Flush", log.Stringer("path", &p))
+		}
+	}
+	return nil
+}
+
+// Subscribe registers the Service at the pubsub system of the configuration
+// service for the default, website and store scope.
+func (s *Service) Subscribe(sub config.Subscriber) error {
+	for _, scp := range [...]scope.TypeStr{scope.StrDefault, scope.StrWebsites, scope.StrStores} {
+		if _, err := sub.Subscribe(scp.String(), s); err != nil {
+			return errors.Wrapf(err, "[maintenance] Service.Subscribe %q", scp)
+		}
+	}
+	return nil
+}
+
+// WithMaintenance to be used as a middleware. This middleware expects to find
+// a scope.FromContext(). Requests to a scope in maintenance mode get answered
+// with status 503 unless the client IP address is part of the allow list.
+func (s *Service) WithMaintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		websiteID, storeID, ok := scope.FromContext(r.Context())
+		if !ok {
+			s.opts.ErrorHandler(errors.NotFound.Newf("[maintenance] Scope not found in request context")).ServeHTTP(w, r)
+			return
+		}
+
+		st, err := s.Settings(websiteID, storeID)
+		if err != nil {
+			if s.opts.Log.IsDebug() {
+				s.opts.Log.Debug("maintenance.Service.WithMaintenance.Settings", log.Err(err),
+					log.Int64("website_id", websiteID), log.Int64("store_id", storeID), loghttp.Request("request", r))
+			}
+			s.opts.ErrorHandler(errors.Wrap(err, "[maintenance] WithMaintenance.Settings")).ServeHTTP(w, r)
+			return
+		}
+		if !st.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ip := request.RealIP(r, s.ipOpt); st.AllowedIPs.Contains(ip) {
+			if s.opts.Log.IsDebug() {
+				s.opts.Log.Debug("maintenance.Service.WithMaintenance.Allowed", log.Stringer("ip", ip),
+					log.Int64("website_id", websiteID), log.Int64("store_id", storeID))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := s.serveUnavailable(st, w, r); err != nil {
+			s.opts.ErrorHandler(errors.Wrap(err, "[maintenance] WithMaintenance.serveUnavailable")).ServeHTTP(w, r)
+		}
+	})
+}
+
+// acceptsProblem returns true if the client prefers a JSON response, like API
+// clients do. Browsers sending */* together with text/html receive HTML.
+func acceptsProblem(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "json") && !strings.Contains(accept, "text/html")
+}
+
+func (s *Service) serveUnavailable(st *Settings, w http.ResponseWriter, r *http.Request) error {
+	var body []byte
+	var contentType string
+	var err error
+	if acceptsProblem(r) {
+		d := &problem.Detail{
+			Type:   problem.DefaultURL,
+			Title:  http.StatusText(http.StatusServiceUnavailable),
+			Status: http.StatusServiceUnavailable,
+			Detail: st.Message,
+		}
+		if st.RetryAfter > 0 {
+			d.Extension = []string{"retry_after", strconv.Itoa(st.RetryAfter)}
+		}
+		body, err = d.MarshalJSON()
+		contentType = problem.MediaType
+	} else {
+		body, err = st.render()
+		contentType = csnet.TextHTMLCharsetUTF8
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if st.RetryAfter > 0 {
+		w.Header().Set(csnet.RetryAfter, strconv.Itoa(st.RetryAfter))
+	}
+	w.Header().Set(csnet.ContentType, contentType)
+	w.Header().Set(csnet.CacheControl, "no-store")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_, err = w.Write(body)
+	return errors.WithStack(err)
+}
@@ -0,0 +1,216 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maintenance_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/corestoreio/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	csnet "github.com/sniperkit/snk.fork.corestoreio-pkg/net"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/maintenance"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/problem"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+var finalHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusTeapot)
+})
+
+func newRequest(websiteID, storeID int64, remoteAddr, accept string) *http.Request {
+	r := httptest.NewRequest("GET", "http://corestore.io/catalog", nil)
+	r.RemoteAddr = remoteAddr
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	return r.WithContext(scope.WithContext(r.Context(), websiteID, storeID))
+}
+
+func TestParseAllowList(t *testing.T) {
+	al, err := maintenance.ParseAllowList("192.168.1.0/24", " 10.0.0.7 ", "", "2001:db8::/32", "::1")
+	assert.NoError(t, err)
+	assert.Len(t, al, 4)
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"192.168.1.44", true},
+		{"192.168.2.1", false},
+		{"10.0.0.7", true},
+		{"10.0.0.8", false},
+		{"2001:db8::1", true},
+		{"::1", true},
+		{"::2", false},
+	}
+	for _, test := range tests {
+		assert.Exactly(t, test.want, al.Contains(net.ParseIP(test.ip)), "IP %q", test.ip)
+	}
+	assert.False(t, al.Contains(nil))
+
+	_, err = maintenance.ParseAllowList("10.0.0.300")
+	assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	_, err = maintenance.ParseAllowList("10.0.0.0/33")
+	assert.True(t, errors.NotValid.Match(err), "%+v", err)
+}
+
+func TestService_WithMaintenance(t *testing.T) {
+	cfg := config.NewFakeService(storage.NewMap(
+		"stores/2/"+maintenance.PathEnabled, "1",
+		"stores/2/"+maintenance.PathRetryAfter, "3600",
+		"websites/1/"+maintenance.PathAllowedIPs, "192.168.1.0/24,10.0.0.7",
+		"websites/1/"+maintenance.PathMessage, "Back in an hour",
+		"websites/3/"+maintenance.PathEnabled, "1",
+		"websites/3/"+maintenance.PathTemplate, `<p>{{.Message}} {{.StoreID}}</p>`,
+	))
+	mw := maintenance.NewService(cfg, maintenance.Options{}).WithMaintenance(finalHandler)
+
+	t.Run("not in maintenance", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, newRequest(1, 1, "8.8.8.8:1234", ""))
+		assert.Exactly(t, http.StatusTeapot, rec.Code)
+	})
+	t.Run("store in maintenance HTML", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, newRequest(1, 2, "8.8.8.8:1234", "text/html,application/xhtml+xml,*/*;q=0.8"))
+		assert.Exactly(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Exactly(t, "3600", rec.Header().Get(csnet.RetryAfter))
+		assert.Exactly(t, csnet.TextHTMLCharsetUTF8, rec.Header().Get(csnet.ContentType))
+		assert.Contains(t, rec.Body.String(), "<p>Back in an hour</p>")
+	})
+	t.Run("store in maintenance JSON", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, newRequest(1, 2, "8.8.8.8:1234", "application/json"))
+		assert.Exactly(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Exactly(t, problem.MediaType, rec.Header().Get(csnet.ContentType))
+		var d problem.Detail
+		assert.NoError(t, d.UnmarshalJSON(rec.Body.Bytes()))
+		assert.Exactly(t, http.StatusServiceUnavailable, d.Status)
+		assert.Exactly(t, "Back in an hour", d.Detail)
+	})
+	t.Run("allowed IP", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, newRequest(1, 2, "192.168.1.33:1234", ""))
+		assert.Exactly(t, http.StatusTeapot, rec.Code)
+	})
+	t.Run("website in maintenance custom template", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, newRequest(3, 5, "192.168.1.33:1234", "text/html"))
+		assert.Exactly(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Exactly(t, "", rec.Header().Get(csnet.RetryAfter))
+		assert.Exactly(t, "<p>"+maintenance.DefaultMessage+" 5</p>", rec.Body.String())
+	})
+	t.Run("missing scope", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, httptest.NewRequest("GET", "http://corestore.io", nil))
+		assert.Exactly(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestService_InvalidConfig(t *testing.T) {
+	cfg := config.NewFakeService(storage.NewMap(
+		"default/0/"+maintenance.PathEnabled, "1",
+		"default/0/"+maintenance.PathAllowedIPs, "localhost",
+	))
+	srv := maintenance.NewService(cfg, maintenance.Options{})
+	_, err := srv.Settings(1, 1)
+	assert.True(t, errors.NotValid.Match(err), "%+v", err)
+
+	rec := httptest.NewRecorder()
+	srv.WithMaintenance(finalHandler).ServeHTTP(rec, newRequest(1, 1, "8.8.8.8:1234", ""))
+	assert.Exactly(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "localhost")
+}
+
+func TestService_MessageConfig(t *testing.T) {
+	stor := storage.NewMap()
+	cfg := config.NewFakeService(stor)
+	var subscribed []string
+	cfg.SubscribeFn = func(path string, mr config.MessageReceiver) (int, error) {
+		subscribed = append(subscribed, path)
+		return len(subscribed), nil
+	}
+	srv := maintenance.NewService(cfg, maintenance.Options{})
+	assert.NoError(t, srv.Subscribe(cfg))
+	assert.Exactly(t, []string{"default", "websites", "stores"}, subscribed)
+
+	st, err := srv.Settings(1, 2)
+	assert.NoError(t, err)
+	assert.False(t, st.Enabled)
+	invokes := cfg.AllInvocations().Sum()
+	_, err = srv.Settings(1, 2)
+	assert.NoError(t, err)
+	assert.Exactly(t, invokes, cfg.AllInvocations().Sum(), "second call must hit the cache")
+
+	p := config.MustNewPath(maintenance.PathEnabled).BindWebsite(1)
+	assert.NoError(t, stor.Set(p, []byte("1")))
+
+	assert.NoError(t, srv.MessageConfig(*config.MustNewPath("web/unsecure/base_url").BindWebsite(1)))
+	st, err = srv.Settings(1, 2)
+	assert.NoError(t, err)
+	assert.False(t, st.Enabled, "unrelated route must not flush the cache")
+
+	assert.NoError(t, srv.MessageConfig(*p))
+	st, err = srv.Settings(1, 2)
+	assert.NoError(t, err)
+	assert.True(t, st.Enabled)
+	assert.Exactly(t, maintenance.DefaultMessage, st.Message)
+}
+
+// flushingStorage simulates a configuration change and a Flush while the
+// Service loads the settings.
+type flushingStorage struct {
+	config.Storager
+	once  sync.Once
+	flush func()
+}
+
+func (fs *flushingStorage) Get(p *config.Path) ([]byte, bool, error) {
+	v, ok, err := fs.Storager.Get(p)
+	// the default scope gets queried last by the scope fallback
+	if p.String() == "default/0/"+maintenance.PathEnabled {
+		fs.once.Do(func() {
+			_ = fs.Storager.Set(config.MustNewPath(maintenance.PathEnabled), []byte("1"))
+			fs.flush()
+		})
+	}
+	return v, ok, err
+}
+
+func TestService_Settings_FlushWhileLoading(t *testing.T) {
+	fs := &flushingStorage{Storager: storage.NewMap()}
+	srv := maintenance.NewService(config.NewFakeService(fs), maintenance.Options{})
+	fs.flush = srv.Flush
+
+	st, err := srv.Settings(1, 2)
+	assert.NoError(t, err)
+	assert.False(t, st.Enabled, "settings loaded before the change")
+
+	st, err = srv.Settings(1, 2)
+	assert.NoError(t, err)
+	assert.True(t, st.Enabled, "outdated settings must not be cached")
+}
@@ -0,0 +1,198 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maintenance
+
+import (
+	"bytes"
+	"html/template"
+	"net"
+	"strings"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// Configuration routes read by the Service. All routes can be set in the
+// default, website and store scope.
+const (
+	// PathPrefix gets used to filter the pubsub messages.
+	PathPrefix = "web/maintenance"
+	// PathEnabled enables the maintenance mode.
+	PathEnabled = "web/maintenance/enabled"
+	// PathMessage plain text message shown to the client. Defaults to
+	// DefaultMessage.
+	PathMessage = "web/maintenance/message"
+	// PathTemplate custom HTML template, see TemplateData for the available
+	// fields. Defaults to DefaultTemplate.
+	PathTemplate = "web/maintenance/template"
+	// PathRetryAfter seconds sent in the Retry-After header. Zero omits the
+	// header.
+	PathRetryAfter = "web/maintenance/retry_after"
+	// PathAllowedIPs comma separated list of IP addresses or CIDR notations
+	// which bypass the maintenance mode.
+	PathAllowedIPs = "web/maintenance/allowed_ips"
+)
+
+// DefaultMessage gets shown if route web/maintenance/message is empty.
+const DefaultMessage = "The store is down for maintenance. Please try again later."
+
+// DefaultTemplate renders the maintenance page if route
+// web/maintenance/template is empty.
+var DefaultTemplate = template.Must(template.New("maintenance").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Maintenance</title></head>
+<body><h1>Maintenance</h1><p>{{.Message}}</p></body>
+</html>
+`))
+
+// TemplateData gets passed to the HTML template.
+type TemplateData struct {
+	Message    string
+	RetryAfter int
+	WebsiteID  int64
+	StoreID    int64
+}
+
+// AllowList contains the networks which bypass the maintenance mode.
+type AllowList []*net.IPNet
+
+// ParseAllowList parses IP addresses and CIDR notations like 192.168.1.0/24
+// or 2001:db8::/32. Empty entries and white spaces get ignored.
+func ParseAllowList(entries ...string) (AllowList, error) {
+	al := make(AllowList, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if strings.IndexByte(e, '/') > 0 {
+			_, ipn, err := net.ParseCIDR(e)
+			if err != nil {
+				return nil, errors.NotValid.New(err, "[maintenance] ParseAllowList CIDR %q", e)
+			}
+			al = append(al, ipn)
+			continue
+		}
+		ip := net.ParseIP(e)
+		if ip == nil {
+			return nil, errors.NotValid.Newf("[maintenance] ParseAllowList invalid IP address %q", e)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		al = append(al, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return al, nil
+}
+
+// Contains returns true if ip is part of one of the networks. A nil ip
+// returns false.
+func (al AllowList) Contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, ipn := range al {
+		if ipn.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Settings contains the parsed maintenance configuration of a scope.
+type Settings struct {
+	WebsiteID int64
+	StoreID   int64
+	// Enabled true if the scope is in maintenance mode.
+	Enabled    bool
+	Message    string
+	Template   *template.Template
+	RetryAfter int
+	AllowedIPs AllowList
+}
+
+// loadSettings reads all maintenance routes from the store scope of the
+// website and store ID.
+func loadSettings(cfg config.Scoper, websiteID, storeID int64) (*Settings, error) {
+	sg := cfg.Scoped(websiteID, storeID)
+	s := &Settings{
+		WebsiteID: websiteID,
+		StoreID:   storeID,
+		Message:   DefaultMessage,
+		Template:  DefaultTemplate,
+	}
+
+	var err error
+	if s.Enabled, _, err = sg.Get(scope.Store, PathEnabled).Bool(); err != nil {
+		return nil, errors.Wrapf(err, "[maintenance] Route %q", PathEnabled)
+	}
+	if !s.Enabled {
+		return s, nil
+	}
+
+	msg, ok, err := sg.Get(scope.Store, PathMessage).Str()
+	if err != nil {
+		return nil, errors.Wrapf(err, "[maintenance] Route %q", PathMessage)
+	}
+	if ok && msg != "" {
+		s.Message = msg
+	}
+
+	tpl, ok, err := sg.Get(scope.Store, PathTemplate).Str()
+	if err != nil {
+		return nil, errors.Wrapf(err, "[maintenance] Route %q", PathTemplate)
+	}
+	if ok && tpl != "" {
+		if s.Template, err = template.New("maintenance").Parse(tpl); err != nil {
+			return nil, errors.NotValid.New(err, "[maintenance] Route %q", PathTemplate)
+		}
+	}
+
+	if s.RetryAfter, _, err = sg.Get(scope.Store, PathRetryAfter).Int(); err != nil {
+		return nil, errors.Wrapf(err, "[maintenance] Route %q", PathRetryAfter)
+	}
+	if s.RetryAfter < 0 {
+		return nil, errors.NotValid.Newf("[maintenance] Route %q cannot be negative: %d", PathRetryAfter, s.RetryAfter)
+	}
+
+	ips, err := sg.Get(scope.Store, PathAllowedIPs).Strs()
+	if err != nil {
+		return nil, errors.Wrapf(err, "[maintenance] Route %q", PathAllowedIPs)
+	}
+	if s.AllowedIPs, err = ParseAllowList(ips...); err != nil {
+		return nil, errors.Wrapf(err, "[maintenance] Route %q", PathAllowedIPs)
+	}
+	return s, nil
+}
+
+// render executes the template into a buffer to avoid writing a partial page.
+func (s *Settings) render() ([]byte, error) {
+	var buf bytes.Buffer
+	err := s.Template.Execute(&buf, TemplateData{
+		Message:    s.Message,
+		RetryAfter: s.RetryAfter,
+		WebsiteID:  s.WebsiteID,
+		StoreID:    s.StoreID,
+	})
+	return buf.Bytes(), errors.WithStack(err)
+}
@@ -0,0 +1,366 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runmode
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// Configuration routes read by the CodeProcessors in this file. A mapping
+// contains a comma separated list of key=store_code pairs, for example:
+// "www.example.de=de,*.example.fr=fr".
+const (
+	// ConfigPathHostMapping maps host names to store codes. A leading
+	// wildcard "*." matches all sub domains.
+	ConfigPathHostMapping = "web/runmode/host_mapping"
+	// ConfigPathPathPrefixMapping maps the first URL path segment to store
+	// codes.
+	ConfigPathPathPrefixMapping = "web/runmode/path_prefix_mapping"
+	// ConfigPathUseStoreCode uses the first URL path segment as store code,
+	// if no prefix mapping has been found. Same route as in Magento.
+	ConfigPathUseStoreCode = "web/url/use_store"
+	// ConfigPathAcceptLanguageMapping maps language tags, like de-CH or de,
+	// to store codes.
+	ConfigPathAcceptLanguageMapping = "web/runmode/accept_language_mapping"
+)
+
+// ConfigSource reads the per scope configuration of a CodeProcessor.
+type ConfigSource struct {
+	// Config optional, if nil the static configuration of the CodeProcessor
+	// gets used.
+	Config config.Scoper
+	// Finder optional, resolves the website and the default store of the run
+	// mode to bind the configuration to the store scope. If nil, website run
+	// modes use the website scope and all other run modes the default scope.
+	Finder store.Finder
+
+	// parsed caches the parsed mappings by their raw value.
+	parsed sync.Map
+}
+
+func (cs *ConfigSource) scoped(runMode scope.TypeID) (config.Scoped, bool) {
+	if cs == nil || cs.Config == nil {
+		return config.Scoped{}, false
+	}
+	if cs.Finder != nil {
+		if storeID, websiteID, err := cs.Finder.DefaultStoreID(runMode); err == nil {
+			return cs.Config.Scoped(websiteID, storeID), true
+		}
+	}
+	if typ, id := runMode.Unpack(); typ == scope.Website {
+		return cs.Config.Scoped(id, 0), true
+	}
+	return cs.Config.Scoped(0, 0), true
+}
+
+// mapping returns the mapping from the configuration or the static mapping if
+// the route has no value.
+func (cs *ConfigSource) mapping(runMode scope.TypeID, route string, static map[string]string) map[string]string {
+	sc, ok := cs.scoped(runMode)
+	if !ok {
+		return static
+	}
+	raw, ok, err := sc.Get(scope.Store, route).Str()
+	if err != nil || !ok || raw == "" {
+		return static
+	}
+	if m, ok := cs.parsed.Load(raw); ok {
+		return m.(map[string]string)
+	}
+	m := parseMapping(raw)
+	cs.parsed.Store(raw, m)
+	return m
+}
+
+func (cs *ConfigSource) bool(runMode scope.TypeID, route string, static bool) bool {
+	sc, ok := cs.scoped(runMode)
+	if !ok {
+		return static
+	}
+	b, ok, err := sc.Get(scope.Store, route).Bool()
+	if err != nil || !ok {
+		return static
+	}
+	return b
+}
+
+// parseMapping parses "key1=code1,key2=code2". Keys are case insensitive.
+// Entries with an invalid store code get ignored.
+func parseMapping(raw string) map[string]string {
+	m := make(map[string]string)
+	for _, kv := range strings.Split(raw, string(config.CSVColumnSeparator)) {
+		i := strings.IndexByte(kv, '=')
+		if i < 1 {
+			continue
+		}
+		k, code := strings.ToLower(strings.TrimSpace(kv[:i])), strings.TrimSpace(kv[i+1:])
+		if err := store.CodeIsValid(code); err == nil {
+			m[k] = code
+		}
+	}
+	return m
+}
+
+// ProcessStoreCodeHost extracts the store code from the Host header. The
+// mapping supports exact host names and wildcards for sub domains, e.g.
+// "*.example.com". An exact match wins over a wildcard and the more specific
+// wildcard wins over the less specific one. Implements interface
+// store.CodeProcessor.
+type ProcessStoreCodeHost struct {
+	ConfigSource
+	// Mapping static host name to store code mapping, used if the route
+	// ConfigPathHostMapping has no value. Keys must be lower case.
+	Mapping map[string]string
+}
+
+// FromRequest returns the store code for the host of the request.
+func (p *ProcessStoreCodeHost) FromRequest(runMode scope.TypeID, r *http.Request) string {
+	host := r.Host
+	if host == "" && r.URL != nil {
+		host = r.URL.Host
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return ""
+	}
+
+	m := p.mapping(runMode, ConfigPathHostMapping, p.Mapping)
+	if code, ok := m[host]; ok {
+		return code
+	}
+	for i := strings.IndexByte(host, '.'); i >= 0; {
+		if code, ok := m["*"+host[i:]]; ok {
+			return code
+		}
+		j := strings.IndexByte(host[i+1:], '.')
+		if j < 0 {
+			break
+		}
+		i += j + 1
+	}
+	return ""
+}
+
+// ProcessDenied does nothing.
+func (p *ProcessStoreCodeHost) ProcessDenied(_ scope.TypeID, _, _ int64, _ http.ResponseWriter, _ *http.Request) {
+}
+
+// ProcessAllowed does nothing.
+func (p *ProcessStoreCodeHost) ProcessAllowed(_ scope.TypeID, _, _ int64, _ string, _ http.ResponseWriter, _ *http.Request) {
+}
+
+// ProcessStoreCodePath extracts the store code from the first segment of the
+// URL path, e.g. /de/checkout. The segment gets stripped from the URL path
+// before the next handler gets called. Implements interface
+// store.CodeProcessor.
+type ProcessStoreCodePath struct {
+	ConfigSource
+	// Mapping static path segment to store code mapping, used if the route
+	// ConfigPathPathPrefixMapping has no value. Keys must be lower case.
+	Mapping map[string]string
+	// UseStoreCode uses the segment itself as store code, if the segment
+	// cannot be found in the mapping. Requires ConfigSource.Finder to check
+	// that the store code exists. Can be overwritten by the route
+	// ConfigPathUseStoreCode.
+	UseStoreCode bool
+}
+
+// firstSegment returns the first path segment without slashes.
+func firstSegment(path string) string {
+	path = strings.TrimPrefix(path, "/")
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
+
+// FromRequest returns the store code for the first path segment.
+func (p *ProcessStoreCodePath) FromRequest(runMode scope.TypeID, r *http.Request) string {
+	seg := firstSegment(r.URL.Path)
+	if seg == "" {
+		return ""
+	}
+	m := p.mapping(runMode, ConfigPathPathPrefixMapping, p.Mapping)
+	if code, ok := m[strings.ToLower(seg)]; ok {
+		return code
+	}
+	if p.Finder == nil || !p.bool(runMode, ConfigPathUseStoreCode, p.UseStoreCode) {
+		return ""
+	}
+	if err := store.CodeIsValid(seg); err != nil {
+		return ""
+	}
+	if _, _, err := p.Finder.StoreIDbyCode(runMode, seg); err != nil {
+		return ""
+	}
+	return seg
+}
+
+// ProcessDenied does nothing.
+func (p *ProcessStoreCodePath) ProcessDenied(_ scope.TypeID, _, _ int64, _ http.ResponseWriter, _ *http.Request) {
+}
+
+// ProcessAllowed strips the path segment, which selected the new store, from
+// the URL path.
+func (p *ProcessStoreCodePath) ProcessAllowed(runMode scope.TypeID, _, _ int64, newStoreCode string, _ http.ResponseWriter, r *http.Request) {
+	if newStoreCode == "" || p.FromRequest(runMode, r) != newStoreCode {
+		return
+	}
+	seg := firstSegment(r.URL.Path)
+	u := *r.URL // the URL gets shared with the parent request
+	u.Path = strings.TrimPrefix(strings.TrimPrefix(u.Path, "/"), seg)
+	if !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+	u.RawPath = ""
+	r.URL = &u
+}
+
+// ProcessStoreCodeAcceptLanguage extracts the store code from the
+// Accept-Language header. The language tags get tried in the order of their
+// quality value. For a tag like de-CH the mapping for de-ch and afterwards for
+// de gets checked. Implements interface store.CodeProcessor.
+type ProcessStoreCodeAcceptLanguage struct {
+	ConfigSource
+	// Mapping static language tag to store code mapping, used if the route
+	// ConfigPathAcceptLanguageMapping has no value. Keys must be lower case.
+	Mapping map[string]string
+}
+
+// FromRequest returns the store code of the best matching language.
+func (p *ProcessStoreCodeAcceptLanguage) FromRequest(runMode scope.TypeID, r *http.Request) string {
+	al := r.Header.Get("Accept-Language")
+	if al == "" {
+		return ""
+	}
+	m := p.mapping(runMode, ConfigPathAcceptLanguageMapping, p.Mapping)
+	if len(m) == 0 {
+		return ""
+	}
+	for _, tag := range parseAcceptLanguage(al) {
+		if code, ok := m[tag]; ok {
+			return code
+		}
+		if i := strings.IndexByte(tag, '-'); i > 0 {
+			if code, ok := m[tag[:i]]; ok {
+				return code
+			}
+		}
+	}
+	return ""
+}
+
+// ProcessDenied does nothing.
+func (p *ProcessStoreCodeAcceptLanguage) ProcessDenied(_ scope.TypeID, _, _ int64, _ http.ResponseWriter, _ *http.Request) {
+}
+
+// ProcessAllowed does nothing.
+func (p *ProcessStoreCodeAcceptLanguage) ProcessAllowed(_ scope.TypeID, _, _ int64, _ string, _ http.ResponseWriter, _ *http.Request) {
+}
+
+// parseAcceptLanguage returns the lower case language tags sorted by their
+// quality value. Tags with q=0 and the wildcard get dropped.
+func parseAcceptLanguage(header string) []string {
+	type langQ struct {
+		tag string
+		q   float64
+	}
+	var langs []langQ
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lq := langQ{tag: part, q: 1}
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			lq.tag = strings.TrimSpace(part[:i])
+			if qs := strings.TrimSpace(part[i+1:]); strings.HasPrefix(qs, "q=") {
+				q, err := strconv.ParseFloat(qs[2:], 64)
+				if err != nil {
+					continue
+				}
+				lq.q = q
+			}
+		}
+		if lq.tag == "*" || lq.q <= 0 {
+			continue
+		}
+		lq.tag = strings.ToLower(lq.tag)
+		langs = append(langs, lq)
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.tag
+	}
+	return tags
+}
+
+// CodeProcessors chains multiple CodeProcessors. The first processor which
+// returns a store code wins. All processors which return that store code
+// receive the call to ProcessAllowed, all processors receive the call to
+// ProcessDenied. Implements interface store.CodeProcessor.
+type CodeProcessors []store.CodeProcessor
+
+// FromRequest returns the first non-empty store code.
+func (cps CodeProcessors) FromRequest(runMode scope.TypeID, r *http.Request) string {
+	for _, cp := range cps {
+		if code := cp.FromRequest(runMode, r); code != "" {
+			return code
+		}
+	}
+	return ""
+}
+
+// ProcessDenied calls ProcessDenied of all processors.
+func (cps CodeProcessors) ProcessDenied(runMode scope.TypeID, oldStoreID, newStoreID int64, w http.ResponseWriter, r *http.Request) {
+	for _, cp := range cps {
+		cp.ProcessDenied(runMode, oldStoreID, newStoreID, w, r)
+	}
+}
+
+// ProcessAllowed calls ProcessAllowed of all processors which return the new
+// store code, for example a host and a path processor resolving the same
+// store. The matching processors get determined before the first call, because
+// ProcessAllowed might modify the request. An empty store code gets forwarded
+// to all processors.
+func (cps CodeProcessors) ProcessAllowed(runMode scope.TypeID, oldStoreID, newStoreID int64, newStoreCode string, w http.ResponseWriter, r *http.Request) {
+	matched := make([]bool, len(cps))
+	for i, cp := range cps {
+		matched[i] = newStoreCode == "" || cp.FromRequest(runMode, r) == newStoreCode
+	}
+	for i, cp := range cps {
+		if matched[i] {
+			cp.ProcessAllowed(runMode, oldStoreID, newStoreID, newStoreCode, w, r)
+		}
+	}
+}
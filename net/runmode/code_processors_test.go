@@ -0,0 +1,219 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runmode_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/corestoreio/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/runmode"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/storemock"
+)
+
+var (
+	_ store.CodeProcessor = (*runmode.ProcessStoreCodeHost)(nil)
+	_ store.CodeProcessor = (*runmode.ProcessStoreCodePath)(nil)
+	_ store.CodeProcessor = (*runmode.ProcessStoreCodeAcceptLanguage)(nil)
+	_ store.CodeProcessor = (runmode.CodeProcessors)(nil)
+)
+
+func TestProcessStoreCodeHost_FromRequest(t *testing.T) {
+	p := &runmode.ProcessStoreCodeHost{
+		Mapping: map[string]string{
+			"www.example.de":    "de",
+			"*.example.de":      "de_sub",
+			"*.shop.example.de": "de_shop",
+			"example.fr":        "fr",
+		},
+	}
+	tests := []struct {
+		host     string
+		wantCode string
+	}{
+		{"www.example.de", "de"},
+		{"WWW.Example.DE:8080", "de"},
+		{"blog.example.de", "de_sub"},
+		{"a.shop.example.de", "de_shop"},
+		{"b.a.shop.example.de", "de_shop"},
+		{"example.de", ""},
+		{"example.fr", "fr"},
+		{"www.example.fr", ""},
+		{"localhost", ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "http://"+test.host+"/", nil)
+		assert.Exactly(t, test.wantCode, p.FromRequest(runmode.Default, req), "Host %q", test.host)
+	}
+}
+
+func TestProcessStoreCodeHost_Config(t *testing.T) {
+	cfg := config.NewFakeService(storage.NewMap(
+		"default/0/"+runmode.ConfigPathHostMapping, "www.example.ch=ch, *.example.ch=ch_sub,x.example.ch=1nvalid",
+		"websites/2/"+runmode.ConfigPathHostMapping, "www.example.at=at",
+	))
+	p := &runmode.ProcessStoreCodeHost{
+		ConfigSource: runmode.ConfigSource{Config: cfg},
+		Mapping:      map[string]string{"www.example.de": "de"},
+	}
+	get := func(host string, runMode scope.TypeID) string {
+		return p.FromRequest(runMode, httptest.NewRequest("GET", "http://"+host+"/", nil))
+	}
+	assert.Exactly(t, "ch", get("www.example.ch", runmode.Default))
+	assert.Exactly(t, "ch_sub", get("shop.example.ch", runmode.Default))
+	assert.Exactly(t, "ch_sub", get("x.example.ch", runmode.Default), "invalid code must be ignored")
+	assert.Exactly(t, "", get("www.example.de", runmode.Default), "config value overwrites static mapping")
+	assert.Exactly(t, "at", get("www.example.at", scope.Website.WithID(2)))
+	assert.Exactly(t, "", get("www.example.ch", scope.Website.WithID(2)))
+}
+
+func TestProcessStoreCodePath(t *testing.T) {
+	t.Run("mapping", func(t *testing.T) {
+		p := &runmode.ProcessStoreCodePath{
+			Mapping: map[string]string{"de": "de_de", "fr": "fr_fr"},
+		}
+		req := httptest.NewRequest("GET", "http://cs.io/de/checkout/cart?a=b", nil)
+		assert.Exactly(t, "de_de", p.FromRequest(runmode.Default, req))
+
+		origURL := req.URL
+		rec := httptest.NewRecorder()
+		p.ProcessAllowed(runmode.Default, 1, 2, "de_de", rec, req)
+		assert.Exactly(t, "/checkout/cart", req.URL.Path)
+		assert.Exactly(t, "a=b", req.URL.RawQuery)
+		assert.Exactly(t, "/de/checkout/cart", origURL.Path, "original URL must not be modified")
+
+		req = httptest.NewRequest("GET", "http://cs.io/fr", nil)
+		p.ProcessAllowed(runmode.Default, 1, 2, p.FromRequest(runmode.Default, req), rec, req)
+		assert.Exactly(t, "/", req.URL.Path)
+
+		req = httptest.NewRequest("GET", "http://cs.io/checkout", nil)
+		assert.Exactly(t, "", p.FromRequest(runmode.Default, req))
+		p.ProcessAllowed(runmode.Default, 1, 1, "", rec, req)
+		assert.Exactly(t, "/checkout", req.URL.Path)
+	})
+
+	t.Run("use store code", func(t *testing.T) {
+		p := &runmode.ProcessStoreCodePath{
+			ConfigSource: runmode.ConfigSource{
+				Finder: &storemock.Find{
+					StoreIDbyCodeFn: func(_ scope.TypeID, code string) (int64, int64, error) {
+						if code == "uk" {
+							return 3, 2, nil
+						}
+						return 0, 0, errors.NewNotFoundf("Store %q not found", code)
+					},
+				},
+			},
+			UseStoreCode: true,
+		}
+		assert.Exactly(t, "uk", p.FromRequest(runmode.Default, httptest.NewRequest("GET", "http://cs.io/uk/account", nil)))
+		assert.Exactly(t, "", p.FromRequest(runmode.Default, httptest.NewRequest("GET", "http://cs.io/account/uk", nil)))
+	})
+}
+
+func TestProcessStoreCodeAcceptLanguage_FromRequest(t *testing.T) {
+	p := &runmode.ProcessStoreCodeAcceptLanguage{
+		Mapping: map[string]string{"de-ch": "ch_de", "de": "de", "fr": "fr", "en": "en"},
+	}
+	tests := []struct {
+		header   string
+		wantCode string
+	}{
+		{"", ""},
+		{"de-CH", "ch_de"},
+		{"de-AT,de;q=0.9", "de"},
+		{"it, fr;q=0.8, en;q=0.9", "en"},
+		{"fr;q=0, en;q=0.1", "en"},
+		{"*", ""},
+		{"nl, it", ""},
+		{"en;q=invalid, fr;q=0.5", "fr"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "http://cs.io/", nil)
+		req.Header.Set("Accept-Language", test.header)
+		assert.Exactly(t, test.wantCode, p.FromRequest(runmode.Default, req), "Header %q", test.header)
+	}
+}
+
+func TestWithRunMode_CodeProcessors(t *testing.T) {
+	cps := runmode.CodeProcessors{
+		&runmode.ProcessStoreCodePath{Mapping: map[string]string{"fr": "fr"}},
+		&runmode.ProcessStoreCodeHost{Mapping: map[string]string{"www.example.de": "de"}},
+	}
+	sf := storemock.NewDefaultStoreID(1, 1, nil, storemock.NewStoreIDbyCode(5, 2, nil))
+
+	var havePath string
+	h := runmode.WithRunMode(sf, runmode.Options{CodeProcessor: cps})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		havePath = r.URL.Path
+		websiteID, storeID, ok := scope.FromContext(r.Context())
+		assert.True(t, ok)
+		assert.Exactly(t, int64(5), storeID)
+		assert.Exactly(t, int64(2), websiteID)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "http://www.example.de/fr/catalog", nil))
+	assert.Exactly(t, http.StatusAccepted, rec.Code)
+	assert.Exactly(t, "/catalog", havePath)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "http://www.example.de/catalog", nil))
+	assert.Exactly(t, http.StatusAccepted, rec.Code)
+	assert.Exactly(t, "/catalog", havePath)
+	assert.Empty(t, rec.Header().Get("Set-Cookie"))
+}
+
+func TestCodeProcessors_ProcessAllowed_AllMatching(t *testing.T) {
+	cps := runmode.CodeProcessors{
+		&runmode.ProcessStoreCodeHost{Mapping: map[string]string{"www.example.de": "de"}},
+		&runmode.ProcessStoreCodePath{Mapping: map[string]string{"de": "de", "fr": "fr"}},
+	}
+	sf := storemock.NewDefaultStoreID(1, 1, nil, storemock.NewStoreIDbyCode(5, 2, nil))
+
+	var havePath string
+	h := runmode.WithRunMode(sf, runmode.Options{CodeProcessor: cps})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		havePath = r.URL.Path
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	tests := []struct {
+		url      string
+		wantPath string
+	}{
+		{"http://www.example.de/de/checkout", "/checkout"}, // host and path resolve the same store
+		{"http://www.example.de/fr/checkout", "/fr/checkout"},
+		{"http://www.example.fr/fr/checkout", "/checkout"},
+		{"http://www.example.de/checkout", "/checkout"},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", test.url, nil))
+		assert.Exactly(t, http.StatusAccepted, rec.Code, "URL %q", test.url)
+		assert.Exactly(t, test.wantPath, havePath, "URL %q", test.url)
+	}
+}
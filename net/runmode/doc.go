@@ -19,4 +19,9 @@ Sniperkit-Bot
 
 // Package runmode defines store specific middleware to initialize the scope and
 // its ID per request.
+//
+// StoreSwitch moves a client from one store to another. The store switcher
+// links to a signed switch URL of the target store which runs the registered
+// SwitchHandler, for example to carry the cart, currency or locale over, and
+// redirects to the equivalent URL in the target store.
 package runmode
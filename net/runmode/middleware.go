@@ -112,7 +112,9 @@ type Options struct {
 	// store. To use the admin area enable scope.Store and ID 0.
 	Calculater
 	// StoreCodeProcessor extracts the store code from an HTTP requests.
-	// Optional. Defaults to type ProcessStoreCodeCookie.
+	// Optional. Defaults to type ProcessStoreCodeCookie. Use CodeProcessors
+	// to combine it with ProcessStoreCodeHost, ProcessStoreCodePath or
+	// ProcessStoreCodeAcceptLanguage.
 	store.CodeProcessor
 	// DisableStoreCodeProcessor set to true and set StoreCodeProcessor to nil
 	// to disable store code handling
@@ -129,8 +131,6 @@ type Options struct {
 //	4. Check if the website/store ID
 func WithRunMode(sf store.Finder, o Options) mw.Middleware {
 
-	// todo: code/Magento/Store/App/Request/PathInfoProcessor.php => req.isDirectAccessFrontendName()
-
 	lg := o.Log
 	if lg == nil {
 		lg = log.BlackHole{} // disabled debug and info logging
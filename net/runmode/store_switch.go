@@ -0,0 +1,275 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runmode
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/corestoreio/errors"
+	"github.com/corestoreio/log"
+	loghttp "github.com/corestoreio/log/http"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/mw"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/signed"
+	csurl "github.com/sniperkit/snk.fork.corestoreio-pkg/net/url"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// URL query parameters of the store switch endpoint. The target store code
+// gets transported in store.CodeURLFieldName.
+const (
+	// SwitchParamFromStore contains the code of the store the client leaves.
+	SwitchParamFromStore = "___from_store"
+	// SwitchParamTarget contains the absolute URL in the target store to
+	// which the client gets redirected after the switch.
+	SwitchParamTarget = "___target"
+)
+
+// DefaultSwitchPath defines the path of the store switch endpoint relative to
+// the web base URL of the target store.
+const DefaultSwitchPath = "stores/store/switch/"
+
+// SwitchHandler carries state like the currency, the cart or the locale from
+// one store to another when a client switches the store. The request
+// contains already the scope of the target store, see scope.FromContext. A
+// returned error aborts the switch.
+type SwitchHandler interface {
+	SwitchStore(runMode scope.TypeID, from, to store.Store, w http.ResponseWriter, r *http.Request) error
+}
+
+// SwitchHandlerFunc type is an adapter to allow the use of ordinary functions
+// as SwitchHandler.
+type SwitchHandlerFunc func(runMode scope.TypeID, from, to store.Store, w http.ResponseWriter, r *http.Request) error
+
+// SwitchStore calls f(runMode, from, to, w, r).
+func (f SwitchHandlerFunc) SwitchStore(runMode scope.TypeID, from, to store.Store, w http.ResponseWriter, r *http.Request) error {
+	return f(runMode, from, to, w, r)
+}
+
+// AllowedStoresFinder returns the active stores to which a client can switch
+// depending on the run mode. Implemented by store.Service.
+type AllowedStoresFinder interface {
+	AllowedStores(runMode scope.TypeID) (store.StoreSlice, error)
+}
+
+// SwitchTargetFunc maps the current URL of the store from to the equivalent
+// URL in the store to.
+type SwitchTargetFunc func(from, to store.Store, current *url.URL, secure bool) (*url.URL, error)
+
+// StoreSwitch switches a client from one store to another. RedirectURL
+// creates the signed URL of the switch endpoint which gets served by
+// ServeHTTP. The endpoint invokes all registered SwitchHandler, sets the store
+// cookie and redirects to the equivalent URL in the target store. The
+// signature prevents tampering with the store codes and the redirect target.
+type StoreSwitch struct {
+	// Stores required, restricts the switch to the allowed stores of the run
+	// mode.
+	Stores AllowedStoresFinder
+	// Signer required, signs and verifies the query parameters of the switch
+	// endpoint. Set a TTL to limit the validity of a switch URL.
+	Signer *signed.URLValues
+	// Calculater optional custom runMode otherwise falls back to
+	// scope.Default.
+	Calculater
+	// TargetURL optional, defaults to SwitchTargetURL.
+	TargetURL SwitchTargetFunc
+	// SwitchPath optional path of the switch endpoint, defaults to
+	// DefaultSwitchPath.
+	SwitchPath string
+	// Cookie optional, sets the store code cookie for the target store.
+	// Defaults to an empty ProcessStoreCodeCookie.
+	Cookie *ProcessStoreCodeCookie
+	// ErrorHandler optional custom error handler. Defaults to sending an HTTP
+	// status code 500 and exposing the real error including full paths.
+	mw.ErrorHandler
+	// UnauthorizedHandler gets called when the signature is invalid or a store
+	// is not allowed. Defaults to sending an HTTP status code
+	// StatusUnauthorized and exposing the real error including full paths.
+	UnauthorizedHandler mw.ErrorHandler
+	// Log can be nil, defaults to black hole.
+	Log log.Logger
+
+	mu       sync.RWMutex
+	handlers []SwitchHandler
+}
+
+// Register appends switch handlers. They get invoked in the order of their
+// registration.
+func (ss *StoreSwitch) Register(handlers ...SwitchHandler) {
+	ss.mu.Lock()
+	ss.handlers = append(ss.handlers, handlers...)
+	ss.mu.Unlock()
+}
+
+// stores returns the allowed stores for the codes from and to. Returns a
+// NotFound error behaviour if one of the stores is not allowed.
+func (ss *StoreSwitch) stores(runMode scope.TypeID, fromCode, toCode string) (from, to store.Store, _ error) {
+	allowed, err := ss.Stores.AllowedStores(runMode)
+	if err != nil {
+		return from, to, errors.Wrapf(err, "[runmode] StoreSwitch.AllowedStores: %s", runMode)
+	}
+	var ok bool
+	if from, ok = allowed.FindOne(func(s store.Store) bool { return s.Code() == fromCode }); !ok {
+		return from, to, errors.NewNotFoundf("[runmode] StoreSwitch: store %q not allowed in run mode %s", fromCode, runMode)
+	}
+	if to, ok = allowed.FindOne(func(s store.Store) bool { return s.Code() == toCode }); !ok {
+		return from, to, errors.NewNotFoundf("[runmode] StoreSwitch: store %q not allowed in run mode %s", toCode, runMode)
+	}
+	return from, to, nil
+}
+
+// RedirectURL returns the signed URL of the switch endpoint on the web base
+// URL of the target store. Argument current contains the URL the client
+// currently visits in the store fromCode. Use this URL in the store switcher.
+func (ss *StoreSwitch) RedirectURL(runMode scope.TypeID, fromCode, toCode string, current *url.URL, secure bool) (*url.URL, error) {
+	from, to, err := ss.stores(runMode, fromCode, toCode)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	targetFn := ss.TargetURL
+	if targetFn == nil {
+		targetFn = SwitchTargetURL
+	}
+	target, err := targetFn(from, to, current, secure)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[runmode] StoreSwitch.TargetURL from %q to %q", fromCode, toCode)
+	}
+
+	base, err := csurl.NewBuilder(to.Config, to.Code()).BaseURL(csurl.TypeWeb, secure)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[runmode] StoreSwitch.BaseURL of store %q", toCode)
+	}
+	sp := ss.SwitchPath
+	if sp == "" {
+		sp = DefaultSwitchPath
+	}
+	u := base.ResolveReference(&url.URL{Path: strings.TrimLeft(sp, "/")})
+	u.RawQuery = ss.Signer.Sign(url.Values{
+		store.CodeURLFieldName: {toCode},
+		SwitchParamFromStore:   {fromCode},
+		SwitchParamTarget:      {target.String()},
+	}).Encode()
+	return u, nil
+}
+
+// ServeHTTP serves the switch endpoint. It verifies the signature, checks that
+// both stores are allowed in the run mode, invokes the switch handlers, sets
+// the store cookie and redirects to the target URL.
+func (ss *StoreSwitch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	lg := ss.Log
+	if lg == nil {
+		lg = log.BlackHole{} // disabled debug and info logging
+	}
+	errH := ss.ErrorHandler
+	if errH == nil {
+		errH = mw.ErrorWithStatusCode(http.StatusInternalServerError)
+	}
+	unAuthH := ss.UnauthorizedHandler
+	if unAuthH == nil {
+		unAuthH = mw.ErrorWithStatusCode(http.StatusUnauthorized)
+	}
+
+	q := r.URL.Query()
+	if err := ss.Signer.Verify(q); err != nil {
+		if lg.IsDebug() {
+			lg.Debug("runmode.StoreSwitch.ServeHTTP.Verify", log.Err(err), loghttp.Request("request", r))
+		}
+		unAuthH(errors.Wrap(err, "[runmode] StoreSwitch.Verify")).ServeHTTP(w, r)
+		return
+	}
+
+	runMode := Default
+	if ss.Calculater != nil {
+		runMode = ss.CalculateRunMode(r)
+	}
+	from, to, err := ss.stores(runMode, q.Get(SwitchParamFromStore), q.Get(store.CodeURLFieldName))
+	switch {
+	case errors.IsNotFound(err):
+		if lg.IsDebug() {
+			lg.Debug("runmode.StoreSwitch.ServeHTTP.StoreNotAllowed", log.Err(err), log.Stringer("run_mode", runMode), loghttp.Request("request", r))
+		}
+		unAuthH(errors.Wrap(err, "[runmode] StoreSwitch.stores")).ServeHTTP(w, r)
+		return
+	case err != nil:
+		errH(errors.Wrap(err, "[runmode] StoreSwitch.stores")).ServeHTTP(w, r)
+		return
+	}
+
+	r = r.WithContext(scope.WithContext(r.Context(), to.WebsiteID(), to.ID()))
+
+	ss.mu.RLock()
+	handlers := ss.handlers
+	ss.mu.RUnlock()
+	for _, h := range handlers {
+		if err := h.SwitchStore(runMode, from, to, w, r); err != nil {
+			if lg.IsDebug() {
+				lg.Debug("runmode.StoreSwitch.ServeHTTP.SwitchStore", log.Err(err), log.String("from_store", from.Code()),
+					log.String("to_store", to.Code()), log.Stringer("run_mode", runMode), loghttp.Request("request", r))
+			}
+			errH(errors.Wrapf(err, "[runmode] StoreSwitch.SwitchStore from %q to %q", from.Code(), to.Code())).ServeHTTP(w, r)
+			return
+		}
+	}
+
+	cookie := ss.Cookie
+	if cookie == nil {
+		cookie = &ProcessStoreCodeCookie{}
+	}
+	cookie.setStoreCookie(to.Code(), w, r)
+
+	if lg.IsDebug() {
+		lg.Debug("runmode.StoreSwitch.ServeHTTP.Redirect", log.String("from_store", from.Code()), log.String("to_store", to.Code()),
+			log.String("target", q.Get(SwitchParamTarget)), log.Stringer("run_mode", runMode))
+	}
+	http.Redirect(w, r, q.Get(SwitchParamTarget), http.StatusFound)
+}
+
+// SwitchTargetURL maps the current URL to the same path below the link base
+// URL of the target store. The path of the link base URL of store from gets
+// replaced, for example with enabled route web/url/use_store:
+// http://example.com/en/shoes.html?p=2 becomes
+// http://example.ch/de/shoes.html?p=2. The store code query parameters get
+// removed.
+func SwitchTargetURL(from, to store.Store, current *url.URL, secure bool) (*url.URL, error) {
+	fromBase, err := csurl.NewBuilder(from.Config, from.Code()).BaseURL(csurl.TypeLink, secure)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[runmode] SwitchTargetURL.BaseURL of store %q", from.Code())
+	}
+	toBase, err := csurl.NewBuilder(to.Config, to.Code()).BaseURL(csurl.TypeLink, secure)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[runmode] SwitchTargetURL.BaseURL of store %q", to.Code())
+	}
+
+	p := current.Path
+	if strings.HasPrefix(p, fromBase.Path) {
+		p = p[len(fromBase.Path):]
+	}
+	q := current.Query()
+	q.Del(store.CodeURLFieldName)
+	q.Del(SwitchParamFromStore)
+
+	u := toBase.ResolveReference(&url.URL{Path: strings.TrimLeft(p, "/")})
+	u.RawQuery = q.Encode()
+	return u, nil
+}
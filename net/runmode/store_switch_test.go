@@ -0,0 +1,155 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runmode_test
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/corestoreio/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/runmode"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/signed"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/storemock"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/hashpool"
+)
+
+func init() {
+	if err := hashpool.Register("sha256", crypto.SHA256.New); err != nil {
+		panic(fmt.Sprintf("%+v", err))
+	}
+}
+
+var _ http.Handler = (*runmode.StoreSwitch)(nil)
+
+func newTestStoreSwitch(t *testing.T) *runmode.StoreSwitch {
+	srv, _, err := storemock.NewFixture().
+		Website("euro").Group("dach").Store("de").Store("at").
+		Website("uk").Group("gb").Store("gb").
+		WithConfig("default/0/web/url/use_store", "1").
+		Build()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	sig, err := signed.NewURLValues("sha256", []byte("s3cr3t"))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return &runmode.StoreSwitch{
+		Stores: srv,
+		Signer: sig,
+	}
+}
+
+func mustParseURL(raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func TestSwitchTargetURL(t *testing.T) {
+	ss := newTestStoreSwitch(t)
+	u, err := ss.RedirectURL(runmode.Default, "de", "at", mustParseURL("http://euro.example.com/de/shoes.html?p=2&___store=de"), false)
+	if !assert.NoError(t, err, "%+v", err) {
+		t.FailNow()
+	}
+	assert.Exactly(t, "euro.example.com", u.Host)
+	assert.Exactly(t, "/"+runmode.DefaultSwitchPath, u.Path)
+	q := u.Query()
+	assert.Exactly(t, "at", q.Get(store.CodeURLFieldName))
+	assert.Exactly(t, "de", q.Get(runmode.SwitchParamFromStore))
+	assert.Exactly(t, "http://euro.example.com/at/shoes.html?p=2", q.Get(runmode.SwitchParamTarget))
+	assert.NoError(t, ss.Signer.Verify(q))
+
+	_, err = ss.RedirectURL(runmode.Default, "de", "gb", mustParseURL("http://euro.example.com/de/"), false)
+	assert.True(t, errors.IsNotFound(err), "store gb is not allowed in the default run mode: %+v", err)
+
+	u, err = ss.RedirectURL(scope.Store.WithID(0), "de", "gb", mustParseURL("http://euro.example.com/de/"), false)
+	assert.NoError(t, err, "%+v", err)
+	assert.Exactly(t, "uk.example.com", u.Host)
+	assert.Exactly(t, "http://uk.example.com/gb/", u.Query().Get(runmode.SwitchParamTarget))
+}
+
+func TestStoreSwitch_ServeHTTP(t *testing.T) {
+	ss := newTestStoreSwitch(t)
+
+	var switched []string
+	ss.Register(runmode.SwitchHandlerFunc(func(runMode scope.TypeID, from, to store.Store, w http.ResponseWriter, r *http.Request) error {
+		websiteID, storeID, _ := scope.FromContext(r.Context())
+		switched = append(switched, fmt.Sprintf("%s=>%s %d/%d", from.Code(), to.Code(), websiteID, storeID))
+		return nil
+	}))
+	switchURL, err := ss.RedirectURL(runmode.Default, "de", "at", mustParseURL("http://euro.example.com/de/cart/?x=1"), false)
+	if !assert.NoError(t, err, "%+v", err) {
+		t.FailNow()
+	}
+
+	t.Run("switch", func(t *testing.T) {
+		switched = nil
+		rec := httptest.NewRecorder()
+		ss.ServeHTTP(rec, httptest.NewRequest("GET", switchURL.String(), nil))
+		assert.Exactly(t, http.StatusFound, rec.Code)
+		assert.Exactly(t, "http://euro.example.com/at/cart/?x=1", rec.Header().Get("Location"))
+		assert.Contains(t, rec.Header().Get("Set-Cookie"), store.CodeFieldName+"=at")
+		assert.Exactly(t, []string{"de=>at 1/2"}, switched)
+	})
+	t.Run("tampered target", func(t *testing.T) {
+		switched = nil
+		u := *switchURL
+		u.RawQuery = strings.Replace(u.RawQuery, "euro.example.com", "evil.com", 1)
+		rec := httptest.NewRecorder()
+		ss.ServeHTTP(rec, httptest.NewRequest("GET", u.String(), nil))
+		assert.Exactly(t, http.StatusUnauthorized, rec.Code)
+		assert.Empty(t, switched)
+	})
+	t.Run("store not allowed", func(t *testing.T) {
+		switched = nil
+		q := ss.Signer.Sign(url.Values{
+			store.CodeURLFieldName:       {"gb"},
+			runmode.SwitchParamFromStore: {"de"},
+			runmode.SwitchParamTarget:    {"http://uk.example.com/gb/"},
+		})
+		rec := httptest.NewRecorder()
+		ss.ServeHTTP(rec, httptest.NewRequest("GET", "http://uk.example.com/stores/store/switch/?"+q.Encode(), nil))
+		assert.Exactly(t, http.StatusUnauthorized, rec.Code)
+		assert.Empty(t, switched)
+	})
+	t.Run("handler error", func(t *testing.T) {
+		ss.Register(runmode.SwitchHandlerFunc(func(_ scope.TypeID, _, _ store.Store, _ http.ResponseWriter, _ *http.Request) error {
+			return errors.NewNotValidf("cart cannot be transferred")
+		}))
+		rec := httptest.NewRecorder()
+		ss.ServeHTTP(rec, httptest.NewRequest("GET", switchURL.String(), nil))
+		assert.Exactly(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), "cart cannot be transferred")
+		assert.Empty(t, rec.Header().Get("Location"))
+	})
+}
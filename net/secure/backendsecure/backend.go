@@ -0,0 +1,161 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backendsecure
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/secure"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// Routes to the configuration values.
+const (
+	PathDisabled              = `net/secure/disabled`
+	PathAllowedHosts          = `net/secure/allowed_hosts`
+	PathSSLRedirect           = `net/secure/ssl_redirect`
+	PathSSLHost               = `net/secure/ssl_host`
+	PathSSLProxyHeaders       = `net/secure/ssl_proxy_headers`
+	PathHSTSMaxAge            = `net/secure/hsts_max_age`
+	PathHSTSIncludeSubdomains = `net/secure/hsts_include_subdomains`
+	PathHSTSPreload           = `net/secure/hsts_preload`
+	PathContentSecurityPolicy = `net/secure/content_security_policy`
+	PathCSPReportOnly         = `net/secure/csp_report_only`
+	PathFrameOptions          = `net/secure/frame_options`
+	PathContentTypeNosniff    = `net/secure/content_type_nosniff`
+	PathReferrerPolicy        = `net/secure/referrer_policy`
+	PathPermissionsPolicy     = `net/secure/permissions_policy`
+)
+
+var allPaths = [...]string{
+	PathDisabled, PathAllowedHosts, PathSSLRedirect, PathSSLHost,
+	PathSSLProxyHeaders, PathHSTSMaxAge, PathHSTSIncludeSubdomains,
+	PathHSTSPreload, PathContentSecurityPolicy, PathCSPReportOnly,
+	PathFrameOptions, PathContentTypeNosniff, PathReferrerPolicy,
+	PathPermissionsPolicy,
+}
+
+// Configuration just exported for the sake of documentation. See fields for
+// more information. The Configuration handles the reading of configuration
+// values within this package.
+type Configuration struct {
+	*secure.OptionFactories
+
+	// defaults contains the default value of each field, used when no value
+	// can be found in the configuration service.
+	defaults map[string]string
+}
+
+// New creates a new backend configuration. The sections must contain all
+// fields defined in NewConfigStructure, see the Path* constants.
+func New(sections config.Sections) (*Configuration, error) {
+	be := &Configuration{
+		OptionFactories: secure.NewOptionFactories(),
+		defaults:        make(map[string]string, len(allPaths)),
+	}
+	for _, route := range allPaths {
+		f, idx := sections.FindField(route)
+		if idx < 0 {
+			return nil, errors.NotFound.Newf("[backendsecure] Field %q not found in sections", route)
+		}
+		be.defaults[route] = f.Default
+	}
+	return be, nil
+}
+
+// MustNew same as New but panics on error.
+func MustNew(sections config.Sections) *Configuration {
+	be, err := New(sections)
+	if err != nil {
+		panic(err)
+	}
+	return be
+}
+
+func (be *Configuration) str(sg config.Scoped, route string) (string, error) {
+	v, ok, err := sg.Get(scope.Store, route).Str()
+	if err != nil {
+		return "", errors.Wrapf(err, "[backendsecure] Get %q", route)
+	}
+	if !ok {
+		return be.defaults[route], nil
+	}
+	return v, nil
+}
+
+func (be *Configuration) bool(sg config.Scoped, route string) (bool, error) {
+	v, err := be.str(sg, route)
+	if err != nil || v == "" {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.NotValid.New(err, "[backendsecure] Invalid bool %q in %q", v, route)
+	}
+	return b, nil
+}
+
+func (be *Configuration) duration(sg config.Scoped, route string) (time.Duration, error) {
+	v, err := be.str(sg, route)
+	if err != nil || v == "" {
+		return 0, err
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, errors.NotValid.New(err, "[backendsecure] Invalid duration %q in %q", v, route)
+	}
+	return d, nil
+}
+
+func (be *Configuration) strs(sg config.Scoped, route string) ([]string, error) {
+	v, err := be.str(sg, route)
+	if err != nil || v == "" {
+		return nil, err
+	}
+	var ret []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			ret = append(ret, s)
+		}
+	}
+	return ret, nil
+}
+
+// proxyHeaders parses the Header:Value pairs.
+func (be *Configuration) proxyHeaders(sg config.Scoped) (map[string]string, error) {
+	pairs, err := be.strs(sg, PathSSLProxyHeaders)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		i := strings.IndexByte(p, ':')
+		if i < 1 || i == len(p)-1 {
+			return nil, errors.NotValid.Newf("[backendsecure] Invalid Header:Value pair %q in %q", p, PathSSLProxyHeaders)
+		}
+		ret[strings.TrimSpace(p[:i])] = strings.TrimSpace(p[i+1:])
+	}
+	return ret, nil
+}
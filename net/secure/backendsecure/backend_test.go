@@ -0,0 +1,116 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backendsecure_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corestoreio/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/secure"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/secure/backendsecure"
+)
+
+func configByScope(t *testing.T, kv ...string) (secure.ScopedConfig, error) {
+	cfgStruct, err := backendsecure.NewConfigStructure()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	be := backendsecure.MustNew(cfgStruct)
+	srv, err := secure.New(config.NewFakeService(storage.NewMap(kv...)), secure.WithOptionFactory(be.PrepareOptionFactory()))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return srv.ConfigByScope(1, 2)
+}
+
+func TestNew_FieldNotFound(t *testing.T) {
+	be, err := backendsecure.New(config.MustMakeSectionsValidate())
+	assert.Nil(t, be)
+	assert.True(t, errors.NotFound.Match(err), "%+v", err)
+}
+
+func TestConfiguration_PrepareOptionFactory(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		sc, err := configByScope(t)
+		assert.NoError(t, err, "%+v", err)
+		assert.False(t, sc.Disabled)
+		assert.Empty(t, sc.AllowedHosts)
+		assert.False(t, sc.SSLRedirect)
+		assert.Empty(t, sc.SSLProxyHeaders, "proxy headers must not be trusted by default")
+		assert.Exactly(t, time.Duration(0), sc.HSTSMaxAge)
+		assert.Empty(t, sc.ContentSecurityPolicy)
+		assert.Exactly(t, secure.FrameOptionsSameOrigin, sc.FrameOptions)
+		assert.True(t, sc.ContentTypeNosniff)
+		assert.Exactly(t, secure.DefaultReferrerPolicy, sc.ReferrerPolicy)
+		assert.Empty(t, sc.PermissionsPolicy)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		sc, err := configByScope(t, "stores/2/"+backendsecure.PathDisabled, "1")
+		assert.NoError(t, err, "%+v", err)
+		assert.True(t, sc.Disabled)
+	})
+	t.Run("website and store scope", func(t *testing.T) {
+		sc, err := configByScope(t,
+			"websites/1/"+backendsecure.PathAllowedHosts, "corestore.io, www.corestore.io",
+			"websites/1/"+backendsecure.PathSSLRedirect, "1",
+			"stores/2/"+backendsecure.PathSSLHost, "secure.corestore.io",
+			"default/0/"+backendsecure.PathSSLProxyHeaders, "X-Forwarded-Ssl:on,X-Forwarded-Proto : https",
+			"websites/1/"+backendsecure.PathHSTSMaxAge, "8760h",
+			"websites/1/"+backendsecure.PathHSTSIncludeSubdomains, "1",
+			"stores/2/"+backendsecure.PathContentSecurityPolicy, "script-src 'self' $NONCE",
+			"stores/2/"+backendsecure.PathCSPReportOnly, "1",
+			"stores/2/"+backendsecure.PathFrameOptions, secure.FrameOptionsDeny,
+			"stores/2/"+backendsecure.PathContentTypeNosniff, "0",
+			"stores/2/"+backendsecure.PathReferrerPolicy, "no-referrer",
+			"stores/2/"+backendsecure.PathPermissionsPolicy, "geolocation=(), camera=()",
+		)
+		assert.NoError(t, err, "%+v", err)
+		assert.Exactly(t, []string{"corestore.io", "www.corestore.io"}, sc.AllowedHosts)
+		assert.True(t, sc.SSLRedirect)
+		assert.Exactly(t, "secure.corestore.io", sc.SSLHost)
+		assert.Exactly(t, map[string]string{"X-Forwarded-Ssl": "on", "X-Forwarded-Proto": "https"}, sc.SSLProxyHeaders)
+		assert.Exactly(t, 8760*time.Hour, sc.HSTSMaxAge)
+		assert.True(t, sc.HSTSIncludeSubdomains)
+		assert.False(t, sc.HSTSPreload)
+		assert.Exactly(t, "script-src 'self' $NONCE", sc.ContentSecurityPolicy)
+		assert.True(t, sc.CSPReportOnly)
+		assert.Exactly(t, secure.FrameOptionsDeny, sc.FrameOptions)
+		assert.False(t, sc.ContentTypeNosniff)
+		assert.Exactly(t, "no-referrer", sc.ReferrerPolicy)
+		assert.Exactly(t, "geolocation=(), camera=()", sc.PermissionsPolicy)
+	})
+	t.Run("invalid proxy header", func(t *testing.T) {
+		_, err := configByScope(t, "stores/2/"+backendsecure.PathSSLProxyHeaders, "X-Forwarded-Proto")
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+	t.Run("invalid frame options", func(t *testing.T) {
+		_, err := configByScope(t, "stores/2/"+backendsecure.PathFrameOptions, "ALLOW-FROM")
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+	t.Run("invalid HSTS max age", func(t *testing.T) {
+		_, err := configByScope(t, "stores/2/"+backendsecure.PathHSTSMaxAge, "one year")
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+}
@@ -0,0 +1,131 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backendsecure
+
+import (
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/secure"
+)
+
+// PrepareOptionFactory creates a closure around the type Configuration. The
+// closure will be used during a scoped request to figure out the
+// configuration depending on the incoming scope. An option array will be
+// returned by the closure.
+func (be *Configuration) PrepareOptionFactory() secure.OptionFactoryFunc {
+	return func(sg config.Scoped) []secure.Option {
+		var (
+			opts [10]secure.Option
+			i    int // used as index in opts
+		)
+
+		disabled, err := be.bool(sg, PathDisabled)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] Disabled.Get"))
+		}
+		opts[i] = secure.WithDisable(disabled, sg.ScopeIDs()...)
+		i++
+		if disabled {
+			return opts[:i]
+		}
+
+		hosts, err := be.strs(sg, PathAllowedHosts)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] AllowedHosts.Get"))
+		}
+		opts[i] = secure.WithAllowedHosts(hosts, sg.ScopeIDs()...)
+		i++
+
+		sslRedirect, err := be.bool(sg, PathSSLRedirect)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] SSLRedirect.Get"))
+		}
+		sslHost, err := be.str(sg, PathSSLHost)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] SSLHost.Get"))
+		}
+		opts[i] = secure.WithSSLRedirect(sslRedirect, sslHost, sg.ScopeIDs()...)
+		i++
+
+		proxyHeaders, err := be.proxyHeaders(sg)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] SSLProxyHeaders.Get"))
+		}
+		opts[i] = secure.WithSSLProxyHeaders(proxyHeaders, sg.ScopeIDs()...)
+		i++
+
+		maxAge, err := be.duration(sg, PathHSTSMaxAge)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] HSTSMaxAge.Get"))
+		}
+		subdomains, err := be.bool(sg, PathHSTSIncludeSubdomains)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] HSTSIncludeSubdomains.Get"))
+		}
+		preload, err := be.bool(sg, PathHSTSPreload)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] HSTSPreload.Get"))
+		}
+		opts[i] = secure.WithHSTS(maxAge, subdomains, preload, sg.ScopeIDs()...)
+		i++
+
+		csp, err := be.str(sg, PathContentSecurityPolicy)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] ContentSecurityPolicy.Get"))
+		}
+		reportOnly, err := be.bool(sg, PathCSPReportOnly)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] CSPReportOnly.Get"))
+		}
+		opts[i] = secure.WithContentSecurityPolicy(csp, reportOnly, sg.ScopeIDs()...)
+		i++
+
+		frameOptions, err := be.str(sg, PathFrameOptions)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] FrameOptions.Get"))
+		}
+		opts[i] = secure.WithFrameOptions(frameOptions, sg.ScopeIDs()...)
+		i++
+
+		nosniff, err := be.bool(sg, PathContentTypeNosniff)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] ContentTypeNosniff.Get"))
+		}
+		opts[i] = secure.WithContentTypeNosniff(nosniff, sg.ScopeIDs()...)
+		i++
+
+		referrer, err := be.str(sg, PathReferrerPolicy)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] ReferrerPolicy.Get"))
+		}
+		opts[i] = secure.WithReferrerPolicy(referrer, sg.ScopeIDs()...)
+		i++
+
+		permissions, err := be.str(sg, PathPermissionsPolicy)
+		if err != nil {
+			return secure.OptionsError(errors.Wrap(err, "[backendsecure] PermissionsPolicy.Get"))
+		}
+		opts[i] = secure.WithPermissionsPolicy(permissions, sg.ScopeIDs()...)
+		i++
+
+		return opts[:i]
+	}
+}
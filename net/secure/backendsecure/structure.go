@@ -0,0 +1,204 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backendsecure
+
+import (
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/secure"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// NewConfigStructure global configuration structure for this package. Used in
+// frontend (to display the user all the settings) and in backend (scope checks
+// and default values). See the source code of this function for the overall
+// available sections, groups and fields.
+func NewConfigStructure() (config.Sections, error) {
+	sortIdx := 10
+	var iter = func() int {
+		sortIdx += 10
+		return sortIdx
+	}
+	return config.MakeSectionsValidated(
+		&config.Section{
+			ID: "net",
+			Groups: config.MakeGroups(
+				&config.Group{
+					ID:        "secure",
+					Label:     `Security Headers`,
+					SortOrder: 150,
+					Scopes:    scope.PermStore,
+					Fields: config.MakeFields(
+						&config.Field{
+							// Path: net/secure/disabled
+							ID:        "disabled",
+							Label:     `Disabled`,
+							Comment:   `Set to true to disable the security headers middleware.`,
+							Type:      config.TypeSelect,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   `0`,
+						},
+						&config.Field{
+							// Path: net/secure/allowed_hosts
+							ID:        "allowed_hosts",
+							Label:     `Allowed Hosts`,
+							Comment:   `Comma separated list of fully qualified domain names which are allowed in the Host header. Empty allows all hosts.`,
+							Type:      config.TypeTextarea,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+						},
+						&config.Field{
+							// Path: net/secure/ssl_redirect
+							ID:        "ssl_redirect",
+							Label:     `Redirect to HTTPS`,
+							Comment:   `Set to true to redirect all requests without TLS to HTTPS.`,
+							Type:      config.TypeSelect,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   `0`,
+						},
+						&config.Field{
+							// Path: net/secure/ssl_host
+							ID:        "ssl_host",
+							Label:     `HTTPS Host`,
+							Comment:   `Host name to which the HTTPS redirect points. Empty uses the host of the request.`,
+							Type:      config.TypeText,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+						},
+						&config.Field{
+							// Path: net/secure/ssl_proxy_headers
+							ID:        "ssl_proxy_headers",
+							Label:     `HTTPS Proxy Headers`,
+							Comment:   `Comma separated list of Header:Value pairs, like X-Forwarded-Proto:https, which mark a request as HTTPS when the TLS connection terminates at a proxy. Set only if a trusted proxy overwrites these headers because clients can send them.`,
+							Type:      config.TypeTextarea,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+						},
+						&config.Field{
+							// Path: net/secure/hsts_max_age
+							ID:        "hsts_max_age",
+							Label:     `HSTS Max Age`,
+							Comment:   `Duration like 8760h of the Strict-Transport-Security header. Empty disables the header.`,
+							Type:      config.TypeText,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+						},
+						&config.Field{
+							// Path: net/secure/hsts_include_subdomains
+							ID:        "hsts_include_subdomains",
+							Label:     `HSTS Include Subdomains`,
+							Type:      config.TypeSelect,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   `0`,
+						},
+						&config.Field{
+							// Path: net/secure/hsts_preload
+							ID:        "hsts_preload",
+							Label:     `HSTS Preload`,
+							Type:      config.TypeSelect,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   `0`,
+						},
+						&config.Field{
+							// Path: net/secure/content_security_policy
+							ID:        "content_security_policy",
+							Label:     `Content Security Policy`,
+							Comment:   `Value of the Content-Security-Policy header. $NONCE gets replaced with a random nonce per request. Empty disables the header.`,
+							Type:      config.TypeTextarea,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+						},
+						&config.Field{
+							// Path: net/secure/csp_report_only
+							ID:        "csp_report_only",
+							Label:     `Content Security Policy Report Only`,
+							Comment:   `Set to true to send the header Content-Security-Policy-Report-Only instead.`,
+							Type:      config.TypeSelect,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   `0`,
+						},
+						&config.Field{
+							// Path: net/secure/frame_options
+							ID:         "frame_options",
+							Label:      `X-Frame-Options`,
+							Type:       config.TypeSelect,
+							SortOrder:  iter(),
+							Visible:    true,
+							CanBeEmpty: true,
+							Scopes:     scope.PermStore,
+							Default:    secure.FrameOptionsSameOrigin,
+							Options:    []string{"", secure.FrameOptionsDeny, secure.FrameOptionsSameOrigin},
+						},
+						&config.Field{
+							// Path: net/secure/content_type_nosniff
+							ID:        "content_type_nosniff",
+							Label:     `X-Content-Type-Options nosniff`,
+							Type:      config.TypeSelect,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+							Default:   `1`,
+						},
+						&config.Field{
+							// Path: net/secure/referrer_policy
+							ID:         "referrer_policy",
+							Label:      `Referrer-Policy`,
+							Type:       config.TypeSelect,
+							SortOrder:  iter(),
+							Visible:    true,
+							CanBeEmpty: true,
+							Scopes:     scope.PermStore,
+							Default:    secure.DefaultReferrerPolicy,
+							Options: []string{
+								"", "no-referrer", "no-referrer-when-downgrade", "origin",
+								"origin-when-cross-origin", "same-origin", "strict-origin",
+								"strict-origin-when-cross-origin", "unsafe-url",
+							},
+						},
+						&config.Field{
+							// Path: net/secure/permissions_policy
+							ID:        "permissions_policy",
+							Label:     `Permissions-Policy`,
+							Comment:   `Value of the Permissions-Policy header, e.g. geolocation=(), camera=(). Empty disables the header.`,
+							Type:      config.TypeTextarea,
+							SortOrder: iter(),
+							Visible:   true,
+							Scopes:    scope.PermStore,
+						},
+					),
+				},
+			),
+		},
+	)
+}
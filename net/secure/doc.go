@@ -3,7 +3,34 @@ Sniperkit-Bot
 - Status: analyzed
 */
 
-// Package secure adds a middleware for quick security wins to response HTTP headers
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
 //
-// TODO(cs) https://github.com/unrolled/secure/blob/v1/secure.go
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secure adds a middleware for quick security wins to response HTTP
+// headers.
+//
+// The middleware Service.WithSecure sets per website or store scope the
+// headers Strict-Transport-Security, Content-Security-Policy,
+// X-Frame-Options, X-Content-Type-Options, Referrer-Policy and
+// Permissions-Policy. Additionally it can reject requests whose Host header is
+// not allowed and redirect requests without TLS to HTTPS.
+//
+// A Content-Security-Policy can contain the NoncePlaceholder which gets
+// replaced with a new random nonce for each request. Use FromContextNonce to
+// add the nonce to inline script and style tags.
+//
+// Sub-package `backendsecure` implements the external configuration loading.
+//
+// Inspired by https://github.com/unrolled/secure
 package secure
@@ -0,0 +1,26 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secure
+
+const (
+	errScopedConfigFrameOptionsNotValid = `[secure] ScopedConfig %s has an invalid X-Frame-Options value: %q`
+	errScopedConfigHSTSNotValid         = `[secure] ScopedConfig %s has a negative HSTS max age: %s`
+	errHostNotAllowed                   = `[secure] Host %q not allowed`
+)
@@ -0,0 +1,151 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secure
+
+import (
+	"time"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/mw"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// WithDefaultConfig applies the default configuration settings for a specific
+// scope.
+//
+// Default values are:
+//		- all hosts allowed, no SSL redirect and no HSTS
+//		- no trusted SSL proxy headers
+//		- X-Frame-Options: SAMEORIGIN
+//		- X-Content-Type-Options: nosniff
+//		- Referrer-Policy: strict-origin-when-cross-origin
+//		- no Content-Security-Policy and no Permissions-Policy
+func WithDefaultConfig(scopeIDs ...scope.TypeID) Option {
+	return withDefaultConfig(scopeIDs...)
+}
+
+// WithAllowedHosts sets the fully qualified domain names which are allowed in
+// the Host header. An empty slice allows all hosts.
+func WithAllowedHosts(hosts []string, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.AllowedHosts = append([]string(nil), hosts...)
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithBadHostHandler sets the handler which gets called when the host is not
+// allowed.
+func WithBadHostHandler(eh mw.ErrorHandler, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.BadHostHandler = eh
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithSSLRedirect enables or disables the redirect of requests without TLS to
+// HTTPS. An empty sslHost redirects to the host of the request.
+func WithSSLRedirect(isEnabled bool, sslHost string, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.SSLRedirect = isEnabled
+		sc.SSLHost = sslHost
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithSSLProxyHeaders sets the header keys with their values which mark a
+// request as HTTPS when the TLS connection terminates at a proxy. Use it only
+// behind a trusted proxy which overwrites the headers sent by the clients.
+func WithSSLProxyHeaders(headers map[string]string, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.SSLProxyHeaders = make(map[string]string, len(headers))
+		for k, v := range headers {
+			sc.SSLProxyHeaders[k] = v
+		}
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithHSTS sets the Strict-Transport-Security header. A maxAge of zero
+// disables the header.
+func WithHSTS(maxAge time.Duration, includeSubdomains, preload bool, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.HSTSMaxAge = maxAge
+		sc.HSTSIncludeSubdomains = includeSubdomains
+		sc.HSTSPreload = preload
+		sc.buildHSTS()
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithContentSecurityPolicy sets the Content-Security-Policy header. The policy
+// can contain the NoncePlaceholder. If reportOnly is true the header
+// Content-Security-Policy-Report-Only gets sent.
+func WithContentSecurityPolicy(policy string, reportOnly bool, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.ContentSecurityPolicy = policy
+		sc.CSPReportOnly = reportOnly
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithFrameOptions sets the X-Frame-Options header to FrameOptionsDeny or
+// FrameOptionsSameOrigin. An empty value disables the header.
+func WithFrameOptions(value string, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.FrameOptions = value
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithContentTypeNosniff enables or disables the header
+// X-Content-Type-Options: nosniff.
+func WithContentTypeNosniff(isEnabled bool, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.ContentTypeNosniff = isEnabled
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithReferrerPolicy sets the Referrer-Policy header. An empty value disables
+// the header.
+func WithReferrerPolicy(policy string, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.ReferrerPolicy = policy
+		return s.updateScopedConfig(sc)
+	}
+}
+
+// WithPermissionsPolicy sets the Permissions-Policy header. An empty value
+// disables the header.
+func WithPermissionsPolicy(policy string, scopeIDs ...scope.TypeID) Option {
+	return func(s *Service) error {
+		sc := s.findScopedConfig(scopeIDs...)
+		sc.PermissionsPolicy = policy
+		return s.updateScopedConfig(sc)
+	}
+}
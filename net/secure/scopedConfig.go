@@ -0,0 +1,266 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secure
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/mw"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// HTTP response header names set by the Service.
+const (
+	HeaderStrictTransportSecurity         = "Strict-Transport-Security"
+	HeaderContentSecurityPolicy           = "Content-Security-Policy"
+	HeaderContentSecurityPolicyReportOnly = "Content-Security-Policy-Report-Only"
+	HeaderFrameOptions                    = "X-Frame-Options"
+	HeaderContentTypeOptions              = "X-Content-Type-Options"
+	HeaderReferrerPolicy                  = "Referrer-Policy"
+	HeaderPermissionsPolicy               = "Permissions-Policy"
+)
+
+// Values for the X-Frame-Options header.
+const (
+	FrameOptionsDeny       = "DENY"
+	FrameOptionsSameOrigin = "SAMEORIGIN"
+)
+
+// DefaultReferrerPolicy applied in the default configuration.
+const DefaultReferrerPolicy = "strict-origin-when-cross-origin"
+
+// NoncePlaceholder gets replaced in the Content-Security-Policy with a new
+// random nonce for each request, e.g. "script-src 'self' $NONCE" becomes
+// "script-src 'self' 'nonce-a1B2c3'". Retrieve the nonce with
+// FromContextNonce to add it to the script and style tags.
+const NoncePlaceholder = "$NONCE"
+
+var defaultBadHostHandler = mw.ErrorWithStatusCode(http.StatusBadRequest)
+
+// ScopedConfig contains the configuration for a specific scope.
+type ScopedConfig struct {
+	scopedConfigGeneric
+
+	// AllowedHosts contains the fully qualified domain names which are
+	// allowed in the Host header of a request. The port of the Host header
+	// gets ignored. An empty slice allows all hosts.
+	AllowedHosts []string
+	// BadHostHandler gets called when the host is not allowed. Defaults to
+	// status 400 Bad Request.
+	BadHostHandler mw.ErrorHandler
+
+	// SSLRedirect redirects all requests without TLS to HTTPS.
+	SSLRedirect bool
+	// SSLHost host name to which the redirect points. Empty uses the host of
+	// the request.
+	SSLHost string
+	// SSLProxyHeaders header keys with their values which mark a request as
+	// HTTPS when the TLS connection terminates at a proxy, e.g.
+	// X-Forwarded-Proto: https. Empty by default because any client can send
+	// these headers, set them only if a trusted proxy overwrites them.
+	SSLProxyHeaders map[string]string
+
+	// HSTSMaxAge sets the max-age of the Strict-Transport-Security header.
+	// Zero disables the header. The header gets only sent via HTTPS.
+	HSTSMaxAge time.Duration
+	// HSTSIncludeSubdomains adds the includeSubDomains directive.
+	HSTSIncludeSubdomains bool
+	// HSTSPreload adds the preload directive.
+	HSTSPreload bool
+
+	// ContentSecurityPolicy value of the header. Empty disables the header.
+	// Can contain NoncePlaceholder.
+	ContentSecurityPolicy string
+	// CSPReportOnly sends the header Content-Security-Policy-Report-Only
+	// instead of Content-Security-Policy.
+	CSPReportOnly bool
+	// FrameOptions either FrameOptionsDeny or FrameOptionsSameOrigin. Empty
+	// disables the header.
+	FrameOptions string
+	// ContentTypeNosniff sets X-Content-Type-Options to nosniff.
+	ContentTypeNosniff bool
+	// ReferrerPolicy value of the header. Empty disables the header.
+	ReferrerPolicy string
+	// PermissionsPolicy value of the header, e.g.
+	// "geolocation=(), camera=()". Empty disables the header.
+	PermissionsPolicy string
+
+	// hstsValue gets built once when applying the configuration.
+	hstsValue string
+}
+
+// isValid a configuration for a scope is only then valid when the frame
+// options contain a known value and the HSTS max age is not negative.
+func (sc *ScopedConfig) isValid() error {
+	if err := sc.isValidPreCheck(); err != nil {
+		return errors.Wrap(err, "[secure] ScopedConfig.isValid as an lastErr")
+	}
+	if sc.Disabled {
+		return nil
+	}
+	switch sc.FrameOptions {
+	case "", FrameOptionsDeny, FrameOptionsSameOrigin:
+	default:
+		return errors.NotValid.Newf(errScopedConfigFrameOptionsNotValid, sc.ScopeID, sc.FrameOptions)
+	}
+	if sc.HSTSMaxAge < 0 {
+		return errors.NotValid.Newf(errScopedConfigHSTSNotValid, sc.ScopeID, sc.HSTSMaxAge)
+	}
+	return nil
+}
+
+func newScopedConfig(target, parent scope.TypeID) *ScopedConfig {
+	return &ScopedConfig{
+		scopedConfigGeneric: newScopedConfigGeneric(target, parent),
+		BadHostHandler:      defaultBadHostHandler,
+		FrameOptions:        FrameOptionsSameOrigin,
+		ContentTypeNosniff:  true,
+		ReferrerPolicy:      DefaultReferrerPolicy,
+	}
+}
+
+// buildHSTS creates the value of the Strict-Transport-Security header.
+func (sc *ScopedConfig) buildHSTS() {
+	sc.hstsValue = ""
+	if sc.HSTSMaxAge <= 0 {
+		return
+	}
+	v := "max-age=" + strconv.FormatInt(int64(sc.HSTSMaxAge/time.Second), 10)
+	if sc.HSTSIncludeSubdomains {
+		v += "; includeSubDomains"
+	}
+	if sc.HSTSPreload {
+		v += "; preload"
+	}
+	sc.hstsValue = v
+}
+
+// isHostAllowed checks the Host of the request, without the port, against
+// AllowedHosts. The comparison is case insensitive.
+func (sc ScopedConfig) isHostAllowed(r *http.Request) bool {
+	if len(sc.AllowedHosts) == 0 {
+		return true
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, h := range sc.AllowedHosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSSL reports whether the request has been sent via HTTPS, either directly
+// or via a proxy.
+func (sc ScopedConfig) isSSL(r *http.Request) bool {
+	if r.TLS != nil || strings.EqualFold(r.URL.Scheme, "https") {
+		return true
+	}
+	for k, v := range sc.SSLProxyHeaders {
+		if hv := r.Header.Get(k); hv != "" && strings.EqualFold(hv, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// sslRedirectURL returns the HTTPS URL of the request.
+func (sc ScopedConfig) sslRedirectURL(r *http.Request) string {
+	host := r.Host
+	if sc.SSLHost != "" {
+		host = sc.SSLHost
+	}
+	return "https://" + host + r.URL.RequestURI()
+}
+
+// setHeaders writes all configured security headers into the response.
+// Returns the context with the CSP nonce, if the policy contains the
+// NoncePlaceholder.
+func (sc ScopedConfig) setHeaders(ctx context.Context, h http.Header, isSSL bool) (context.Context, error) {
+	if isSSL && sc.hstsValue != "" {
+		h.Set(HeaderStrictTransportSecurity, sc.hstsValue)
+	}
+	if sc.FrameOptions != "" {
+		h.Set(HeaderFrameOptions, sc.FrameOptions)
+	}
+	if sc.ContentTypeNosniff {
+		h.Set(HeaderContentTypeOptions, "nosniff")
+	}
+	if sc.ReferrerPolicy != "" {
+		h.Set(HeaderReferrerPolicy, sc.ReferrerPolicy)
+	}
+	if sc.PermissionsPolicy != "" {
+		h.Set(HeaderPermissionsPolicy, sc.PermissionsPolicy)
+	}
+	if sc.ContentSecurityPolicy == "" {
+		return ctx, nil
+	}
+
+	csp := sc.ContentSecurityPolicy
+	if strings.Contains(csp, NoncePlaceholder) {
+		nonce, err := newNonce()
+		if err != nil {
+			return ctx, errors.WithStack(err)
+		}
+		csp = strings.Replace(csp, NoncePlaceholder, "'nonce-"+nonce+"'", -1)
+		ctx = withContextNonce(ctx, nonce)
+	}
+	if sc.CSPReportOnly {
+		h.Set(HeaderContentSecurityPolicyReportOnly, csp)
+	} else {
+		h.Set(HeaderContentSecurityPolicy, csp)
+	}
+	return ctx, nil
+}
+
+// newNonce creates a random base64 encoded nonce with 128 bits.
+func newNonce() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", errors.ReadFailed.New(err, "[secure] Failed to read from crypto/rand")
+	}
+	return base64.StdEncoding.EncodeToString(buf[:]), nil
+}
+
+type ctxNonceKey struct{}
+
+func withContextNonce(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, ctxNonceKey{}, nonce)
+}
+
+// FromContextNonce returns the Content-Security-Policy nonce of the current
+// request. The nonce must be added to inline script and style tags, e.g.
+// <script nonce="{{.Nonce}}">.
+func FromContextNonce(ctx context.Context) (string, bool) {
+	n, ok := ctx.Value(ctxNonceKey{}).(string)
+	return n, ok && n != ""
+}
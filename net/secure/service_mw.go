@@ -0,0 +1,76 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secure
+
+import (
+	"net/http"
+
+	"github.com/corestoreio/errors"
+	"github.com/corestoreio/log"
+	loghttp "github.com/corestoreio/log/http"
+)
+
+// WithSecure to be used as a middleware. This middleware expects to find a
+// scope.FromContext(). It rejects requests with a not allowed host, redirects
+// to HTTPS if configured and sets the security headers of the current scope.
+func (s *Service) WithSecure(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scpCfg, err := s.configByContext(r.Context())
+		if err != nil {
+			if s.Log.IsDebug() {
+				s.Log.Debug("secure.Service.WithSecure.configByContext", log.Err(err), loghttp.Request("request", r))
+			}
+			s.ErrorHandler(errors.Wrap(err, "[secure] Service.WithSecure.configByContext")).ServeHTTP(w, r)
+			return
+		}
+		if scpCfg.Disabled {
+			if s.Log.IsDebug() {
+				s.Log.Debug("secure.Service.WithSecure.Disabled", log.Stringer("scope", scpCfg.ScopeID), loghttp.Request("request", r))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !scpCfg.isHostAllowed(r) {
+			if s.Log.IsDebug() {
+				s.Log.Debug("secure.Service.WithSecure.HostNotAllowed", log.Stringer("scope", scpCfg.ScopeID), loghttp.Request("request", r))
+			}
+			scpCfg.BadHostHandler(errors.NotAllowed.Newf(errHostNotAllowed, r.Host)).ServeHTTP(w, r)
+			return
+		}
+
+		isSSL := scpCfg.isSSL(r)
+		if scpCfg.SSLRedirect && !isSSL {
+			code := http.StatusMovedPermanently
+			if r.Method != "GET" && r.Method != "HEAD" {
+				code = http.StatusPermanentRedirect // keeps method and body
+			}
+			http.Redirect(w, r, scpCfg.sslRedirectURL(r), code)
+			return
+		}
+
+		ctx, err := scpCfg.setHeaders(r.Context(), w.Header(), isSSL)
+		if err != nil {
+			scpCfg.ErrorHandler(errors.Wrap(err, "[secure] Service.WithSecure.setHeaders")).ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,207 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secure_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/mw"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/secure"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+var finalHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	nonce, _ := secure.FromContextNonce(r.Context())
+	w.Header().Set("X-Test-Nonce", nonce)
+	w.WriteHeader(http.StatusTeapot)
+})
+
+func newRequest(method, target string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	return r.WithContext(scope.WithContext(r.Context(), 1, 2))
+}
+
+func newService(t *testing.T, opts ...secure.Option) *secure.Service {
+	srv, err := secure.New(config.NewFakeService(storage.NewMap()), opts...)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return srv
+}
+
+func TestService_WithSecure_MWAdapter(t *testing.T) {
+	// checks if the middleware conforms to the mw.Middleware definition
+	srv := secure.MustNew(config.NewFakeService(storage.NewMap()))
+	_ = mw.Chain(finalHandler, srv.WithSecure)
+}
+
+func TestService_WithSecure_Defaults(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newService(t).WithSecure(finalHandler).ServeHTTP(rec, newRequest("GET", "https://corestore.io/"))
+	assert.Exactly(t, http.StatusTeapot, rec.Code)
+	assert.Exactly(t, secure.FrameOptionsSameOrigin, rec.Header().Get(secure.HeaderFrameOptions))
+	assert.Exactly(t, "nosniff", rec.Header().Get(secure.HeaderContentTypeOptions))
+	assert.Exactly(t, secure.DefaultReferrerPolicy, rec.Header().Get(secure.HeaderReferrerPolicy))
+	assert.Empty(t, rec.Header().Get(secure.HeaderStrictTransportSecurity))
+	assert.Empty(t, rec.Header().Get(secure.HeaderContentSecurityPolicy))
+	assert.Empty(t, rec.Header().Get(secure.HeaderPermissionsPolicy))
+	assert.Empty(t, rec.Header().Get("X-Test-Nonce"))
+}
+
+func TestService_WithSecure_Headers(t *testing.T) {
+	s2 := scope.MakeTypeID(scope.Store, 2)
+	h := newService(t,
+		secure.WithHSTS(365*24*time.Hour, true, true, s2),
+		secure.WithContentSecurityPolicy("default-src 'self'; script-src 'self' "+secure.NoncePlaceholder, false, s2),
+		secure.WithFrameOptions(secure.FrameOptionsDeny, s2),
+		secure.WithContentTypeNosniff(false, s2),
+		secure.WithReferrerPolicy("no-referrer", s2),
+		secure.WithPermissionsPolicy("geolocation=(), camera=()", s2),
+		secure.WithSSLProxyHeaders(map[string]string{"X-Forwarded-Proto": "https"}, s2),
+	).WithSecure(finalHandler)
+
+	t.Run("HTTPS via TLS", func(t *testing.T) {
+		req := newRequest("GET", "http://corestore.io/")
+		req.TLS = &tls.ConnectionState{}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Exactly(t, http.StatusTeapot, rec.Code)
+		assert.Exactly(t, "max-age=31536000; includeSubDomains; preload", rec.Header().Get(secure.HeaderStrictTransportSecurity))
+		assert.Exactly(t, secure.FrameOptionsDeny, rec.Header().Get(secure.HeaderFrameOptions))
+		assert.Empty(t, rec.Header().Get(secure.HeaderContentTypeOptions))
+		assert.Exactly(t, "no-referrer", rec.Header().Get(secure.HeaderReferrerPolicy))
+		assert.Exactly(t, "geolocation=(), camera=()", rec.Header().Get(secure.HeaderPermissionsPolicy))
+
+		nonce := rec.Header().Get("X-Test-Nonce")
+		assert.NotEmpty(t, nonce)
+		assert.Exactly(t, "default-src 'self'; script-src 'self' 'nonce-"+nonce+"'", rec.Header().Get(secure.HeaderContentSecurityPolicy))
+
+		rec2 := httptest.NewRecorder()
+		h.ServeHTTP(rec2, req)
+		assert.NotEqual(t, nonce, rec2.Header().Get("X-Test-Nonce"), "Nonce must change per request")
+	})
+	t.Run("HTTPS via proxy header", func(t *testing.T) {
+		req := newRequest("GET", "http://corestore.io/")
+		req.Header.Set("X-Forwarded-Proto", "HTTPS")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.NotEmpty(t, rec.Header().Get(secure.HeaderStrictTransportSecurity))
+	})
+	t.Run("no HSTS via HTTP", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRequest("GET", "http://corestore.io/"))
+		assert.Exactly(t, http.StatusTeapot, rec.Code)
+		assert.Empty(t, rec.Header().Get(secure.HeaderStrictTransportSecurity))
+	})
+}
+
+func TestService_WithSecure_CSPReportOnly(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newService(t, secure.WithContentSecurityPolicy("default-src 'self'", true)).
+		WithSecure(finalHandler).ServeHTTP(rec, newRequest("GET", "http://corestore.io/"))
+	assert.Exactly(t, "default-src 'self'", rec.Header().Get(secure.HeaderContentSecurityPolicyReportOnly))
+	assert.Empty(t, rec.Header().Get(secure.HeaderContentSecurityPolicy))
+	assert.Empty(t, rec.Header().Get("X-Test-Nonce"))
+}
+
+func TestService_WithSecure_AllowedHosts(t *testing.T) {
+	h := newService(t, secure.WithAllowedHosts([]string{"www.corestore.io", "corestore.io"}, scope.MakeTypeID(scope.Website, 1))).WithSecure(finalHandler)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest("GET", "http://WWW.corestore.io/"))
+	assert.Exactly(t, http.StatusTeapot, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest("GET", "http://corestore.io:8443/"))
+	assert.Exactly(t, http.StatusTeapot, rec.Code, "port must be ignored")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest("GET", "http://evil.com/"))
+	assert.Exactly(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, rec.Header().Get(secure.HeaderFrameOptions))
+}
+
+func TestService_WithSecure_SSLRedirect(t *testing.T) {
+	tests := []struct {
+		sslHost  string
+		method   string
+		target   string
+		wantCode int
+		wantLoc  string
+	}{
+		{"", "GET", "http://corestore.io/catalog?id=1", http.StatusMovedPermanently, "https://corestore.io/catalog?id=1"},
+		{"secure.corestore.io", "GET", "http://corestore.io/catalog", http.StatusMovedPermanently, "https://secure.corestore.io/catalog"},
+		{"", "POST", "http://corestore.io/checkout", http.StatusPermanentRedirect, "https://corestore.io/checkout"},
+		{"", "GET", "https://corestore.io/catalog", http.StatusTeapot, ""},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		newService(t, secure.WithSSLRedirect(true, test.sslHost)).WithSecure(finalHandler).ServeHTTP(rec, newRequest(test.method, test.target))
+		assert.Exactly(t, test.wantCode, rec.Code, "%s %s", test.method, test.target)
+		assert.Exactly(t, test.wantLoc, rec.Header().Get("Location"), "%s %s", test.method, test.target)
+	}
+}
+
+func TestService_WithSecure_UntrustedProxyHeader(t *testing.T) {
+	h := newService(t,
+		secure.WithSSLRedirect(true, ""),
+		secure.WithHSTS(time.Hour, false, false),
+	).WithSecure(finalHandler)
+
+	req := newRequest("GET", "http://corestore.io/catalog")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Exactly(t, http.StatusMovedPermanently, rec.Code, "proxy header must not be trusted by default")
+	assert.Exactly(t, "https://corestore.io/catalog", rec.Header().Get("Location"))
+	assert.Empty(t, rec.Header().Get(secure.HeaderStrictTransportSecurity))
+}
+
+func TestService_WithSecure_Disabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newService(t,
+		secure.WithSSLRedirect(true, ""),
+		secure.WithDisable(true, scope.MakeTypeID(scope.Store, 2)),
+	).WithSecure(finalHandler).ServeHTTP(rec, newRequest("GET", "http://corestore.io/"))
+	assert.Exactly(t, http.StatusTeapot, rec.Code)
+	assert.Empty(t, rec.Header().Get(secure.HeaderFrameOptions))
+}
+
+func TestService_WithSecure_InvalidConfig(t *testing.T) {
+	tests := []secure.Option{
+		secure.WithFrameOptions("ALLOW-FROM https://corestore.io"),
+		secure.WithHSTS(-time.Second, false, false),
+	}
+	for i, opt := range tests {
+		rec := httptest.NewRecorder()
+		newService(t, opt).WithSecure(finalHandler).ServeHTTP(rec, newRequest("GET", "http://corestore.io/"))
+		assert.Exactly(t, http.StatusServiceUnavailable, rec.Code, "Index %d", i)
+		assert.True(t, strings.Contains(rec.Body.String(), "[secure]"), "Index %d: %s", i, rec.Body.String())
+	}
+}
@@ -31,4 +31,7 @@ const (
 	errHMACParseNotFound            = `[signed] Signature not found or empty`
 	errHMACParseNotValid            = `[signed] Signature %q not valid in header %q`
 	errHMACParseInvalidAlg          = `[signed] Unknown algorithm %q in Header %q with signature %q`
+	errURLValuesNotFound            = `[signed] URL parameter %q not found or empty`
+	errURLValuesExpired             = `[signed] URL signature expired at %s`
+	errURLValuesNoMatch             = `[signed] URL signature %q does not match`
 )
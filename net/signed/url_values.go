@@ -0,0 +1,127 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signed
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/hashpool"
+)
+
+// URL query parameter names written by URLValues.Sign.
+const (
+	URLParamSignature = "___sig"
+	URLParamExpires   = "___exp"
+)
+
+// URLValues signs URL query parameters with an HMAC and verifies them to
+// prevent tampering, for example with the parameters of a redirect. The
+// signature covers all parameters in their sorted and encoded form.
+type URLValues struct {
+	tank hashpool.Tank
+	// TTL (optional) defines how long a signature is valid. Zero means the
+	// signature never expires.
+	TTL time.Duration
+	// EncodeFn (optional) defines the byte to string encoding function.
+	// Defaults to base64.RawURLEncoding.EncodeToString.
+	EncodeFn
+	// DecodeFn (optional) defines the string to byte decoding function.
+	// Defaults to base64.RawURLEncoding.DecodeString.
+	DecodeFn
+	// Now (optional) returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewURLValues creates a new URL signer with a HMAC of the hash algorithm
+// name and the key. The hash name must be registered via hashpool.Register.
+func NewURLValues(hashName string, key []byte) (*URLValues, error) {
+	tnk, err := hashpool.FromRegistryHMAC(hashName, key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[signed] NewURLValues with hash %q", hashName)
+	}
+	return &URLValues{tank: tnk}, nil
+}
+
+func (uv *URLValues) now() time.Time {
+	if uv.Now != nil {
+		return uv.Now()
+	}
+	return time.Now()
+}
+
+// Sign adds the signature and, if TTL has been set, the expiration time to v.
+// An already existing signature gets replaced. Returns v.
+func (uv *URLValues) Sign(v url.Values) url.Values {
+	v.Del(URLParamSignature)
+	if uv.TTL > 0 {
+		v.Set(URLParamExpires, strconv.FormatInt(uv.now().Add(uv.TTL).Unix(), 10))
+	}
+	encFn := uv.EncodeFn
+	if encFn == nil {
+		encFn = base64.RawURLEncoding.EncodeToString
+	}
+	v.Set(URLParamSignature, encFn(uv.tank.Sum([]byte(v.Encode()), nil)))
+	return v
+}
+
+// Verify checks the signature of v and its expiration time. v does not get
+// modified. Errors can have the behaviour: NotFound or NotValid.
+func (uv *URLValues) Verify(v url.Values) error {
+	sig := v.Get(URLParamSignature)
+	if sig == "" {
+		return errors.NewNotFoundf(errURLValuesNotFound, URLParamSignature)
+	}
+	decFn := uv.DecodeFn
+	if decFn == nil {
+		decFn = base64.RawURLEncoding.DecodeString
+	}
+	mac, err := decFn(sig)
+	if err != nil {
+		return errors.NewNotValidf("[signed] URL signature failed to decode: %q. Error: %s", sig, err)
+	}
+
+	data := make(url.Values, len(v))
+	for k, vals := range v {
+		if k != URLParamSignature {
+			data[k] = vals
+		}
+	}
+	if !uv.tank.Equal([]byte(data.Encode()), mac) {
+		return errors.NewNotValidf(errURLValuesNoMatch, sig)
+	}
+
+	if exp := v.Get(URLParamExpires); exp != "" {
+		unix, err := strconv.ParseInt(exp, 10, 64)
+		if err != nil {
+			return errors.NewNotValidf("[signed] URL parameter %q not valid: %q", URLParamExpires, exp)
+		}
+		if expires := time.Unix(unix, 0); uv.now().After(expires) {
+			return errors.NewNotValidf(errURLValuesExpired, expires)
+		}
+	} else if uv.TTL > 0 {
+		return errors.NewNotFoundf(errURLValuesNotFound, URLParamExpires)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signed_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/corestoreio/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/signed"
+)
+
+func TestURLValues(t *testing.T) {
+	uv, err := signed.NewURLValues("sha256", []byte("pa$$w0rd")) // "sha256" registered via init() func
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	t.Run("sign and verify", func(t *testing.T) {
+		v := uv.Sign(url.Values{"___store": {"de"}, "___from_store": {"en"}})
+		assert.NotEmpty(t, v.Get(signed.URLParamSignature))
+		assert.Empty(t, v.Get(signed.URLParamExpires))
+		assert.NoError(t, uv.Verify(v))
+
+		// round trip through a query string
+		v2, err := url.ParseQuery(v.Encode())
+		assert.NoError(t, err)
+		assert.NoError(t, uv.Verify(v2))
+	})
+	t.Run("tampered", func(t *testing.T) {
+		v := uv.Sign(url.Values{"___store": {"de"}})
+		v.Set("___store", "ch")
+		err := uv.Verify(v)
+		assert.True(t, errors.IsNotValid(err), "%+v", err)
+
+		v = uv.Sign(url.Values{"___store": {"de"}})
+		v.Add("___referer", "http://evil.com")
+		err = uv.Verify(v)
+		assert.True(t, errors.IsNotValid(err), "%+v", err)
+	})
+	t.Run("missing or broken signature", func(t *testing.T) {
+		err := uv.Verify(url.Values{"___store": {"de"}})
+		assert.True(t, errors.IsNotFound(err), "%+v", err)
+		err = uv.Verify(url.Values{"___store": {"de"}, signed.URLParamSignature: {"!!"}})
+		assert.True(t, errors.IsNotValid(err), "%+v", err)
+	})
+	t.Run("other key", func(t *testing.T) {
+		uv2, err := signed.NewURLValues("sha256", []byte("other"))
+		assert.NoError(t, err)
+		err = uv2.Verify(uv.Sign(url.Values{"___store": {"de"}}))
+		assert.True(t, errors.IsNotValid(err), "%+v", err)
+	})
+	t.Run("expiration", func(t *testing.T) {
+		now := time.Unix(1500000000, 0)
+		uvTTL, err := signed.NewURLValues("sha256", []byte("pa$$w0rd"))
+		assert.NoError(t, err)
+		uvTTL.TTL = time.Minute
+		uvTTL.Now = func() time.Time { return now }
+
+		v := uvTTL.Sign(url.Values{"___store": {"de"}})
+		assert.Exactly(t, "1500000060", v.Get(signed.URLParamExpires))
+		assert.NoError(t, uvTTL.Verify(v))
+
+		now = now.Add(2 * time.Minute)
+		err = uvTTL.Verify(v)
+		assert.True(t, errors.IsNotValid(err), "%+v", err)
+
+		err = uvTTL.Verify(uv.Sign(url.Values{"___store": {"de"}}))
+		assert.True(t, errors.IsNotFound(err), "TTL requires the expiration parameter: %+v", err)
+	})
+	t.Run("unregistered hash", func(t *testing.T) {
+		_, err := signed.NewURLValues("md4711", []byte("x"))
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+	})
+}
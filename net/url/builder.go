@@ -0,0 +1,330 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	csnet "github.com/sniperkit/snk.fork.corestoreio-pkg/net"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// Configuration routes used by the Builder. Their names and default values
+// follow the Magento core_config_data layout.
+const (
+	PathWebUnsecureBaseURL       = "web/unsecure/base_url"
+	PathWebUnsecureBaseLinkURL   = "web/unsecure/base_link_url"
+	PathWebUnsecureBaseStaticURL = "web/unsecure/base_static_url"
+	PathWebUnsecureBaseMediaURL  = "web/unsecure/base_media_url"
+
+	PathWebSecureBaseURL       = "web/secure/base_url"
+	PathWebSecureBaseLinkURL   = "web/secure/base_link_url"
+	PathWebSecureBaseStaticURL = "web/secure/base_static_url"
+	PathWebSecureBaseMediaURL  = "web/secure/base_media_url"
+	// PathWebSecureUseInFrontend enables the secure routes for the store
+	// front.
+	PathWebSecureUseInFrontend = "web/secure/use_in_frontend"
+
+	// PathWebURLUseStore adds the store code as first path segment to all link
+	// URLs.
+	PathWebURLUseStore = "web/url/use_store"
+
+	PathCatalogSeoProductURLSuffix  = "catalog/seo/product_url_suffix"
+	PathCatalogSeoCategoryURLSuffix = "catalog/seo/category_url_suffix"
+)
+
+// Placeholders which can be used in the values of the base URL routes.
+const (
+	// PlaceholderBaseURL gets replaced by Builder.DistroBaseURL.
+	PlaceholderBaseURL = "{{base_url}}"
+	// PlaceholderUnsecureBaseURL gets replaced by the value of route
+	// web/unsecure/base_url.
+	PlaceholderUnsecureBaseURL = "{{unsecure_base_url}}"
+	// PlaceholderSecureBaseURL gets replaced by the value of route
+	// web/secure/base_url.
+	PlaceholderSecureBaseURL = "{{secure_base_url}}"
+)
+
+// DefaultURLSuffix gets used for products and categories if the suffix
+// routes have no value.
+const DefaultURLSuffix = ".html"
+
+// Type defines the kind of a base URL.
+type Type uint8
+
+// Available base URL types.
+const (
+	TypeWeb Type = iota
+	TypeLink
+	TypeStatic
+	TypeMedia
+	typeMax
+)
+
+var typeNames = [...]string{"Web", "Link", "Static", "Media"}
+
+func (t Type) String() string {
+	if t >= typeMax {
+		return "Type(" + strconv.Itoa(int(t)) + ")"
+	}
+	return typeNames[t]
+}
+
+// typeRoutes maps a Type to its unsecure and secure route and their default
+// values if the route has not been set.
+var typeRoutes = [typeMax][2]struct {
+	route string
+	def   string
+}{
+	TypeWeb: {
+		{PathWebUnsecureBaseURL, PlaceholderBaseURL},
+		{PathWebSecureBaseURL, PlaceholderUnsecureBaseURL},
+	},
+	TypeLink: {
+		{PathWebUnsecureBaseLinkURL, PlaceholderUnsecureBaseURL},
+		{PathWebSecureBaseLinkURL, PlaceholderSecureBaseURL},
+	},
+	TypeStatic: {
+		{PathWebUnsecureBaseStaticURL, PlaceholderUnsecureBaseURL + "static/"},
+		{PathWebSecureBaseStaticURL, PlaceholderSecureBaseURL + "static/"},
+	},
+	TypeMedia: {
+		{PathWebUnsecureBaseMediaURL, PlaceholderUnsecureBaseURL + "media/"},
+		{PathWebSecureBaseMediaURL, PlaceholderSecureBaseURL + "media/"},
+	},
+}
+
+// Builder creates absolute URLs for a store. It reads the base URL routes from
+// the scoped configuration on each call, so configuration changes take effect
+// immediately. A Builder is safe for concurrent use as long as its fields
+// don't get modified.
+type Builder struct {
+	// Config required, the configuration of the store for which the URLs get
+	// built.
+	Config config.Scoped
+	// StoreCode optional, gets added as first path segment to link URLs if
+	// route web/url/use_store is enabled and Config has a store scope.
+	StoreCode string
+	// DistroBaseURL optional, replaces the placeholder {{base_url}}. For
+	// example: "http://localhost:3000/".
+	DistroBaseURL string
+}
+
+// NewBuilder creates a new URL builder for the scoped configuration and the
+// store code.
+func NewBuilder(cfg config.Scoped, storeCode string) *Builder {
+	return &Builder{
+		Config:    cfg,
+		StoreCode: storeCode,
+	}
+}
+
+// IsSecureRequest reports whether the request has been sent via HTTPS,
+// directly or via a proxy setting the header X-Forwarded-Proto.
+func IsSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get(csnet.XForwardedProto), "https")
+}
+
+// UseSecure reports whether secure URLs should be generated. Argument
+// secure, e.g. the result of IsSecureRequest, gets only honored if route
+// web/secure/use_in_frontend has been enabled.
+func (b *Builder) UseSecure(secure bool) bool {
+	if !secure || !b.Config.IsValid() {
+		return false
+	}
+	use, ok, err := b.Config.Get(scope.Store, PathWebSecureUseInFrontend).Bool()
+	return err == nil && ok && use
+}
+
+// ForRequest returns UseSecure(IsSecureRequest(r)).
+func (b *Builder) ForRequest(r *http.Request) bool {
+	return b.UseSecure(IsSecureRequest(r))
+}
+
+func (b *Builder) routeValue(route, def string) (string, error) {
+	if !b.Config.IsValid() {
+		return "", errors.NewNotValidf("[url] Builder.Config is not valid. Route %q", route)
+	}
+	v, ok, err := b.Config.Get(scope.Store, route).Str()
+	if err != nil {
+		return "", errors.Wrapf(err, "[url] Failed to read route %q", route)
+	}
+	if !ok || v == "" {
+		v = def
+	}
+	return v, nil
+}
+
+// replacePlaceholders resolves the placeholders in raw. The base URL routes
+// get only loaded when their placeholder occurs.
+func (b *Builder) replacePlaceholders(raw string) (string, error) {
+	if strings.Contains(raw, PlaceholderUnsecureBaseURL) {
+		u, err := b.rawBaseURL(TypeWeb, false)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		raw = strings.Replace(raw, PlaceholderUnsecureBaseURL, u, -1)
+	}
+	if strings.Contains(raw, PlaceholderSecureBaseURL) {
+		u, err := b.rawBaseURL(TypeWeb, true)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		raw = strings.Replace(raw, PlaceholderSecureBaseURL, u, -1)
+	}
+	if strings.Contains(raw, PlaceholderBaseURL) {
+		if b.DistroBaseURL == "" {
+			return "", errors.NewNotFoundf("[url] Builder.DistroBaseURL is empty but required by placeholder %q", PlaceholderBaseURL)
+		}
+		raw = strings.Replace(raw, PlaceholderBaseURL, withSlash(b.DistroBaseURL), -1)
+	}
+	return raw, nil
+}
+
+// rawBaseURL returns the resolved base URL with a trailing slash. The
+// secure web base URL may only refer to the unsecure one, and the unsecure
+// web base URL only to {{base_url}}, which prevents endless recursion.
+func (b *Builder) rawBaseURL(t Type, secure bool) (string, error) {
+	if t >= typeMax {
+		return "", errors.NewNotSupportedf("[url] Unsupported URL type %s", t)
+	}
+	idx := 0
+	if secure {
+		idx = 1
+	}
+	tr := typeRoutes[t][idx]
+	raw, err := b.routeValue(tr.route, tr.def)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if t == TypeWeb {
+		if strings.Contains(raw, PlaceholderSecureBaseURL) || (!secure && strings.Contains(raw, PlaceholderUnsecureBaseURL)) {
+			return "", errors.NewNotValidf("[url] Route %q contains a circular placeholder: %q", tr.route, raw)
+		}
+	}
+	if raw, err = b.replacePlaceholders(raw); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return withSlash(raw), nil
+}
+
+// BaseURL returns the parsed absolute base URL of type t. The path always ends
+// with a slash. For TypeLink the store code gets appended if route
+// web/url/use_store has been enabled. Argument secure should be the result of
+// UseSecure or ForRequest.
+func (b *Builder) BaseURL(t Type, secure bool) (*url.URL, error) {
+	raw, err := b.rawBaseURL(t, secure)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if t == TypeLink && b.useStoreCode() {
+		raw += b.StoreCode + "/"
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, errors.NewNotValidf("[url] Failed to parse %s base URL %q: %s", t, raw, err)
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return nil, errors.NewNotValidf("[url] %s base URL %q must be absolute", t, raw)
+	}
+	return u, nil
+}
+
+func (b *Builder) useStoreCode() bool {
+	if b.StoreCode == "" || b.Config.ScopeID().Type() != scope.Store {
+		return false
+	}
+	use, ok, err := b.Config.Get(scope.Store, PathWebURLUseStore).Bool()
+	return err == nil && ok && use
+}
+
+// URL returns the absolute URL for the path relative to the base URL of type
+// t. Query parameters in path are preserved.
+func (b *Builder) URL(t Type, secure bool, path string) (string, error) {
+	base, err := b.BaseURL(t, secure)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	ref, err := url.Parse(strings.TrimLeft(path, "/"))
+	if err != nil {
+		return "", errors.NewNotValidf("[url] Failed to parse path %q: %s", path, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// ProductURL returns the URL of a product. If urlKey is empty, the URL falls
+// back to the catalog/product/view route with the product ID. The suffix gets
+// read from route catalog/seo/product_url_suffix, an empty value disables it.
+func (b *Builder) ProductURL(secure bool, id int64, urlKey string) (string, error) {
+	if urlKey == "" {
+		return b.URL(TypeLink, secure, "catalog/product/view/id/"+strconv.FormatInt(id, 10))
+	}
+	return b.seoURL(secure, PathCatalogSeoProductURLSuffix, urlKey)
+}
+
+// CategoryURL returns the URL of a category. Argument urlPath contains the
+// full slug path, for example "women/tops". If urlPath is empty, the URL
+// falls back to the catalog/category/view route with the category ID. The
+// suffix gets read from route catalog/seo/category_url_suffix.
+func (b *Builder) CategoryURL(secure bool, id int64, urlPath string) (string, error) {
+	if urlPath == "" {
+		return b.URL(TypeLink, secure, "catalog/category/view/id/"+strconv.FormatInt(id, 10))
+	}
+	return b.seoURL(secure, PathCatalogSeoCategoryURLSuffix, urlPath)
+}
+
+func (b *Builder) seoURL(secure bool, suffixRoute, slug string) (string, error) {
+	if !b.Config.IsValid() {
+		return "", errors.NewNotValidf("[url] Builder.Config is not valid. Route %q", suffixRoute)
+	}
+	suffix, ok, err := b.Config.Get(scope.Store, suffixRoute).Str()
+	if err != nil {
+		return "", errors.Wrapf(err, "[url] Failed to read route %q", suffixRoute)
+	}
+	if !ok {
+		suffix = DefaultURLSuffix
+	}
+	slug = strings.Trim(slug, "/")
+	if !strings.HasSuffix(slug, suffix) {
+		slug += suffix
+	}
+	return b.URL(TypeLink, secure, slug)
+}
+
+// MediaURL returns the URL of a media file, for example
+// "catalog/product/a/b/ab.jpg".
+func (b *Builder) MediaURL(secure bool, file string) (string, error) {
+	return b.URL(TypeMedia, secure, file)
+}
+
+// StaticURL returns the URL of a static file, for example a theme asset.
+func (b *Builder) StaticURL(secure bool, file string) (string, error) {
+	return b.URL(TypeStatic, secure, file)
+}
+
+func withSlash(s string) string {
+	return strings.TrimRight(s, "/") + "/"
+}
@@ -0,0 +1,179 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url_test
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/corestoreio/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/net/url"
+)
+
+func newTestBuilder(storeCode string, fqPathValue ...string) *url.Builder {
+	return url.NewBuilder(config.NewFakeService(storage.NewMap(fqPathValue...)).Scoped(1, 2), storeCode)
+}
+
+func TestBuilder_BaseURL(t *testing.T) {
+	b := newTestBuilder("de",
+		"default/0/"+url.PathWebUnsecureBaseURL, "http://example.com",
+		"stores/2/"+url.PathWebUnsecureBaseURL, "http://example.de/",
+		"default/0/"+url.PathWebSecureBaseURL, "https://example.com/",
+		"websites/1/"+url.PathWebSecureBaseMediaURL, "https://cdn.example.de/m/",
+	)
+	tests := []struct {
+		typ    url.Type
+		secure bool
+		want   string
+	}{
+		{url.TypeWeb, false, "http://example.de/"},
+		{url.TypeWeb, true, "https://example.com/"},
+		{url.TypeLink, false, "http://example.de/"},
+		{url.TypeLink, true, "https://example.com/"},
+		{url.TypeStatic, false, "http://example.de/static/"},
+		{url.TypeStatic, true, "https://example.com/static/"},
+		{url.TypeMedia, false, "http://example.de/media/"},
+		{url.TypeMedia, true, "https://cdn.example.de/m/"},
+	}
+	for _, test := range tests {
+		u, err := b.BaseURL(test.typ, test.secure)
+		if !assert.NoError(t, err, "%s secure %t", test.typ, test.secure) {
+			continue
+		}
+		assert.Exactly(t, test.want, u.String(), "%s secure %t", test.typ, test.secure)
+	}
+}
+
+func TestBuilder_Placeholder(t *testing.T) {
+	t.Run("distro base URL", func(t *testing.T) {
+		b := newTestBuilder("de",
+			"default/0/"+url.PathWebSecureBaseURL, "{{unsecure_base_url}}",
+			"default/0/"+url.PathWebUnsecureBaseLinkURL, "{{unsecure_base_url}}shop",
+		)
+		b.DistroBaseURL = "http://localhost:3000"
+		u, err := b.BaseURL(url.TypeWeb, true)
+		assert.NoError(t, err)
+		assert.Exactly(t, "http://localhost:3000/", u.String())
+
+		u, err = b.BaseURL(url.TypeLink, false)
+		assert.NoError(t, err)
+		assert.Exactly(t, "http://localhost:3000/shop/", u.String())
+	})
+	t.Run("distro base URL empty", func(t *testing.T) {
+		b := newTestBuilder("de")
+		u, err := b.BaseURL(url.TypeMedia, false)
+		assert.Nil(t, u)
+		assert.True(t, errors.IsNotFound(err), "%+v", err)
+	})
+	t.Run("circular", func(t *testing.T) {
+		b := newTestBuilder("de",
+			"default/0/"+url.PathWebUnsecureBaseURL, "{{secure_base_url}}",
+		)
+		_, err := b.BaseURL(url.TypeWeb, true)
+		assert.True(t, errors.IsNotValid(err), "%+v", err)
+	})
+	t.Run("not absolute", func(t *testing.T) {
+		b := newTestBuilder("de",
+			"default/0/"+url.PathWebUnsecureBaseURL, "/shop/",
+		)
+		_, err := b.BaseURL(url.TypeWeb, false)
+		assert.True(t, errors.IsNotValid(err), "%+v", err)
+	})
+	t.Run("invalid config", func(t *testing.T) {
+		b := url.NewBuilder(config.Scoped{}, "de")
+		_, err := b.BaseURL(url.TypeWeb, false)
+		assert.True(t, errors.IsNotValid(err), "%+v", err)
+		_, err = b.ProductURL(false, 1, "shirt")
+		assert.True(t, errors.IsNotValid(err), "%+v", err)
+		assert.False(t, b.UseSecure(true))
+	})
+}
+
+func TestBuilder_UseSecure(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	assert.False(t, url.IsSecureRequest(req))
+	req.Header.Set("X-Forwarded-Proto", "HTTPS")
+	assert.True(t, url.IsSecureRequest(req))
+	req = httptest.NewRequest("GET", "https://example.com/", nil)
+	req.TLS = &tls.ConnectionState{}
+	assert.True(t, url.IsSecureRequest(req))
+
+	b := newTestBuilder("de")
+	assert.False(t, b.ForRequest(req), "use_in_frontend not set")
+	b = newTestBuilder("de", "stores/2/"+url.PathWebSecureUseInFrontend, "1")
+	assert.True(t, b.ForRequest(req))
+	assert.False(t, b.UseSecure(false))
+}
+
+func TestBuilder_StoreCodeInURL(t *testing.T) {
+	b := newTestBuilder("de",
+		"default/0/"+url.PathWebUnsecureBaseURL, "http://example.com/",
+		"default/0/"+url.PathWebURLUseStore, "1",
+	)
+	u, err := b.BaseURL(url.TypeLink, false)
+	assert.NoError(t, err)
+	assert.Exactly(t, "http://example.com/de/", u.String())
+
+	u, err = b.BaseURL(url.TypeMedia, false)
+	assert.NoError(t, err)
+	assert.Exactly(t, "http://example.com/media/", u.String(), "media URLs must not contain the store code")
+
+	s, err := b.URL(url.TypeLink, false, "/checkout/cart?a=b")
+	assert.NoError(t, err)
+	assert.Exactly(t, "http://example.com/de/checkout/cart?a=b", s)
+
+	// website scope has no store, so no store code
+	b.Config = config.NewFakeService(storage.NewMap(
+		"default/0/"+url.PathWebUnsecureBaseURL, "http://example.com/",
+		"default/0/"+url.PathWebURLUseStore, "1",
+	)).Scoped(1, 0)
+	u, err = b.BaseURL(url.TypeLink, false)
+	assert.NoError(t, err)
+	assert.Exactly(t, "http://example.com/", u.String())
+}
+
+func TestBuilder_CatalogURLs(t *testing.T) {
+	b := newTestBuilder("de",
+		"default/0/"+url.PathWebUnsecureBaseURL, "http://example.com/",
+		"stores/2/"+url.PathCatalogSeoCategoryURLSuffix, "",
+	)
+	tests := []struct {
+		fn   func() (string, error)
+		want string
+	}{
+		{func() (string, error) { return b.ProductURL(false, 33, "blue-shirt") }, "http://example.com/blue-shirt.html"},
+		{func() (string, error) { return b.ProductURL(false, 33, "blue-shirt.html") }, "http://example.com/blue-shirt.html"},
+		{func() (string, error) { return b.ProductURL(false, 33, "") }, "http://example.com/catalog/product/view/id/33"},
+		{func() (string, error) { return b.CategoryURL(false, 7, "/women/tops/") }, "http://example.com/women/tops"},
+		{func() (string, error) { return b.CategoryURL(false, 7, "") }, "http://example.com/catalog/category/view/id/7"},
+		{func() (string, error) { return b.MediaURL(false, "/catalog/product/a/b/ab.jpg") }, "http://example.com/media/catalog/product/a/b/ab.jpg"},
+		{func() (string, error) { return b.StaticURL(false, "frontend/luma/styles.css") }, "http://example.com/static/frontend/luma/styles.css"},
+	}
+	for i, test := range tests {
+		have, err := test.fn()
+		assert.NoError(t, err, "Index %d", i)
+		assert.Exactly(t, test.want, have, "Index %d", i)
+	}
+}
@@ -18,4 +18,11 @@ Sniperkit-Bot
 // limitations under the License.
 
 // Package url parses program specific URLs and provides helper functions.
+//
+// The Builder creates absolute store front URLs from the base URL routes
+// web/unsecure/* and web/secure/* of a scoped configuration. It resolves the
+// placeholders {{base_url}}, {{unsecure_base_url}} and {{secure_base_url}},
+// switches between secure and unsecure URLs, adds the store code to link URLs
+// if web/url/use_store has been enabled and generates product, category,
+// media and static URLs.
 package url
@@ -0,0 +1,919 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/storage/null"
+)
+
+// ParseCreateTablesFromFiles reads the files and parses all CREATE TABLE
+// statements. See ParseCreateTables.
+func ParseCreateTablesFromFiles(files ...string) (tables map[string]Columns, fks map[string]KeyColumnUsageCollection, err error) {
+	tables = make(map[string]Columns)
+	fks = make(map[string]KeyColumnUsageCollection)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, nil, errors.NotFound.New(err, "[ddl] ParseCreateTablesFromFiles.Open")
+		}
+		tbls, tfks, err := ParseCreateTables(f)
+		if errC := f.Close(); err == nil && errC != nil {
+			err = errC
+		}
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "[ddl] ParseCreateTablesFromFiles with file %q", file)
+		}
+		for tn, cols := range tbls {
+			if _, ok := tables[tn]; ok {
+				return nil, nil, errors.AlreadyExists.Newf("[ddl] ParseCreateTablesFromFiles: Table %q in file %q has already been defined", tn, file)
+			}
+			tables[tn] = cols
+		}
+		for key, kcuc := range tfks {
+			c, ok := fks[key]
+			if !ok {
+				c = MakeKeyColumnUsageCollection()
+			}
+			c.Data = append(c.Data, kcuc.Data...)
+			fks[key] = c
+		}
+	}
+	return tables, fks, nil
+}
+
+// ParseCreateTables parses the CREATE TABLE statements from `r` without
+// connecting to a database. All other statements get ignored. The returned
+// columns contain the same data as the columns loaded with function LoadColumns
+// from a MariaDB server, hence the table name is the map key. The returned
+// foreign keys have the same format as LoadKeyColumnUsage, the map key contains
+// REFERENCED_TABLE_NAME.REFERENCED_COLUMN_NAME.
+func ParseCreateTables(r io.Reader) (tables map[string]Columns, fks map[string]KeyColumnUsageCollection, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	toks, err := tokenizeSQL(string(data))
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	tables = make(map[string]Columns)
+	fks = make(map[string]KeyColumnUsageCollection)
+	for _, stmt := range splitSQLTokens(toks, ";") {
+		ct, ok, err := parseCreateTable(stmt)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		if !ok {
+			continue
+		}
+		if _, ok := tables[ct.name]; ok {
+			return nil, nil, errors.AlreadyExists.Newf("[ddl] ParseCreateTables: Table %q has already been defined", ct.name)
+		}
+		tables[ct.name] = ct.columns
+		for _, kcu := range ct.fks {
+			key := fmt.Sprintf("%s.%s", kcu.ReferencedTableName.String, kcu.ReferencedColumnName.String)
+			c, ok := fks[key]
+			if !ok {
+				c = MakeKeyColumnUsageCollection()
+			}
+			c.Data = append(c.Data, kcu)
+			fks[key] = c
+		}
+	}
+	return tables, fks, nil
+}
+
+const (
+	tokWord   = iota + 1 // unquoted identifier, keyword or number
+	tokIdent             // back tick quoted identifier
+	tokString            // single or double quoted string, unescaped
+	tokPunct             // ( ) , ; .
+)
+
+type sqlToken struct {
+	kind int
+	val  string
+}
+
+// is reports whether the token is the unquoted keyword or punctuation `kw`.
+func (t sqlToken) is(kw string) bool {
+	return (t.kind == tokWord || t.kind == tokPunct) && strings.EqualFold(t.val, kw)
+}
+
+// tokenizeSQL splits the SQL string into tokens and removes all comments.
+func tokenizeSQL(s string) ([]sqlToken, error) {
+	toks := make([]sqlToken, 0, len(s)/4)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || (c == '-' && strings.HasPrefix(s[i:], "-- ")) || (c == '-' && strings.HasPrefix(s[i:], "--\n")):
+			if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(s)
+			}
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return nil, errors.NotValid.Newf("[ddl] Unterminated comment at position %d", i)
+			}
+			i += end + 4
+		case c == '(' || c == ')' || c == ',' || c == ';' || c == '.':
+			toks = append(toks, sqlToken{kind: tokPunct, val: s[i : i+1]})
+			i++
+		case c == '`' || c == '\'' || c == '"':
+			val, n, err := unquoteSQL(s[i:])
+			if err != nil {
+				return nil, errors.Wrapf(err, "[ddl] At position %d", i)
+			}
+			kind := tokString
+			if c == '`' {
+				kind = tokIdent
+			}
+			toks = append(toks, sqlToken{kind: kind, val: val})
+			i += n
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\n\r(),;`'\"", rune(s[i])) {
+				if s[i] == '.' && !isSQLNumber(s[start:i]) {
+					break // qualified identifier like db.table
+				}
+				i++
+			}
+			toks = append(toks, sqlToken{kind: tokWord, val: s[start:i]})
+		}
+	}
+	return toks, nil
+}
+
+// unquoteSQL unquotes the quoted string at the beginning of `s` and returns
+// the number of consumed bytes. Doubled quote characters and backslash
+// escapes, except for identifiers, get resolved.
+func unquoteSQL(s string) (string, int, error) {
+	q := s[0]
+	var buf strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == q && i+1 < len(s) && s[i+1] == q:
+			buf.WriteByte(q)
+			i++
+		case c == q:
+			return buf.String(), i + 1, nil
+		case c == '\\' && q != '`' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				buf.WriteByte('\n')
+			case 't':
+				buf.WriteByte('\t')
+			case 'r':
+				buf.WriteByte('\r')
+			case '0':
+				buf.WriteByte(0)
+			default:
+				buf.WriteByte(s[i])
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	if len(s) > 20 {
+		s = s[:20]
+	}
+	return "", 0, errors.NotValid.Newf("[ddl] Unterminated quoted string %q", s)
+}
+
+func isSQLNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// splitSQLTokens splits the tokens at the top level punctuation `sep`. Empty
+// parts get dropped.
+func splitSQLTokens(toks []sqlToken, sep string) [][]sqlToken {
+	var ret [][]sqlToken
+	depth, start := 0, 0
+	for i, t := range toks {
+		switch {
+		case t.kind != tokPunct:
+		case t.val == "(":
+			depth++
+		case t.val == ")":
+			depth--
+		case t.val == sep && depth == 0:
+			if i > start {
+				ret = append(ret, toks[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(toks) {
+		ret = append(ret, toks[start:])
+	}
+	return ret
+}
+
+// parenBlock returns the tokens between the opening parenthesis at toks[0] and
+// its matching closing parenthesis and the index after the closing one.
+func parenBlock(toks []sqlToken) ([]sqlToken, int, error) {
+	if len(toks) == 0 || !toks[0].is("(") {
+		return nil, 0, errors.NotValid.Newf("[ddl] Expecting an opening parenthesis")
+	}
+	depth := 0
+	for i, t := range toks {
+		switch {
+		case t.is("("):
+			depth++
+		case t.is(")"):
+			depth--
+			if depth == 0 {
+				return toks[1:i], i + 1, nil
+			}
+		}
+	}
+	return nil, 0, errors.NotValid.Newf("[ddl] Missing closing parenthesis")
+}
+
+// qualifiedName parses `schema`.`name` or `name` and returns the index after
+// the name.
+func qualifiedName(toks []sqlToken) (schema, name string, next int) {
+	if len(toks) == 0 || (toks[0].kind != tokIdent && toks[0].kind != tokWord) {
+		return "", "", 0
+	}
+	if len(toks) > 2 && toks[1].is(".") {
+		return toks[0].val, toks[2].val, 3
+	}
+	return "", toks[0].val, 1
+}
+
+// identList parses a parenthesized list of column names. Index prefix
+// lengths, like `name(10)`, and sort orders get dropped.
+func identList(toks []sqlToken) ([]string, int, error) {
+	inner, next, err := parenBlock(toks)
+	if err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+	var ret []string
+	for _, part := range splitSQLTokens(inner, ",") {
+		if part[0].kind != tokPunct { // functional index parts are not columns
+			ret = append(ret, part[0].val)
+		}
+	}
+	return ret, next, nil
+}
+
+// createTable contains a parsed CREATE TABLE statement.
+type createTable struct {
+	name    string
+	columns Columns
+	fks     []*KeyColumnUsage
+	// autoFKs counts the foreign keys without a constraint name.
+	autoFKs int
+	// defs contains additional parsing information for each column
+	defs []*columnDef
+}
+
+type columnDef struct {
+	col        *Column
+	notNull    bool
+	hasDefault bool
+	isUnique   bool
+}
+
+// indexDef defines an index in the order of appearance.
+type indexDef struct {
+	unique  bool
+	columns []string
+}
+
+func parseCreateTable(toks []sqlToken) (*createTable, bool, error) {
+	tok := func(i int) sqlToken {
+		if i < len(toks) {
+			return toks[i]
+		}
+		return sqlToken{}
+	}
+	i := 0
+	if !tok(i).is("CREATE") {
+		return nil, false, nil
+	}
+	i++
+	if tok(i).is("OR") && tok(i+1).is("REPLACE") {
+		i += 2
+	}
+	if tok(i).is("TEMPORARY") {
+		i++
+	}
+	if !tok(i).is("TABLE") {
+		return nil, false, nil
+	}
+	i++
+	if tok(i).is("IF") && tok(i+1).is("NOT") && tok(i+2).is("EXISTS") {
+		i += 3
+	}
+	_, name, n := qualifiedName(toks[i:])
+	if n == 0 {
+		return nil, false, errors.NotValid.Newf("[ddl] CREATE TABLE: Missing table name")
+	}
+	i += n
+	if i < len(toks) && toks[i].is("LIKE") {
+		return nil, false, errors.NotSupported.Newf("[ddl] CREATE TABLE %q LIKE is not supported", name)
+	}
+	body, _, err := parenBlock(toks[i:])
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "[ddl] CREATE TABLE %q", name)
+	}
+
+	ct := &createTable{name: name}
+	var pk []string
+	var indexes []indexDef
+	for _, def := range splitSQLTokens(body, ",") {
+		constraintName := ""
+		if def[0].is("CONSTRAINT") {
+			def = def[1:]
+			if len(def) > 0 && !def[0].is("PRIMARY") && !def[0].is("UNIQUE") && !def[0].is("FOREIGN") && !def[0].is("CHECK") {
+				constraintName = def[0].val
+				def = def[1:]
+			}
+			if len(def) == 0 {
+				return nil, false, errors.NotValid.Newf("[ddl] CREATE TABLE %q: Incomplete CONSTRAINT", name)
+			}
+		}
+		switch first := def[0]; {
+		case first.is("PRIMARY"):
+			if pk, err = indexColumns(def[1:]); err != nil {
+				return nil, false, errors.Wrapf(err, "[ddl] CREATE TABLE %q PRIMARY KEY", name)
+			}
+		case first.is("UNIQUE"), first.is("KEY"), first.is("INDEX"), first.is("FULLTEXT"), first.is("SPATIAL"):
+			cols, err := indexColumns(def[1:])
+			if err != nil {
+				return nil, false, errors.Wrapf(err, "[ddl] CREATE TABLE %q INDEX", name)
+			}
+			indexes = append(indexes, indexDef{unique: first.is("UNIQUE"), columns: cols})
+		case first.is("FOREIGN"):
+			if constraintName == "" {
+				ct.autoFKs++
+				// same naming as InnoDB
+				constraintName = fmt.Sprintf("%s_ibfk_%d", name, ct.autoFKs)
+			}
+			fks, err := parseForeignKey(name, constraintName, def[1:])
+			if err != nil {
+				return nil, false, errors.Wrapf(err, "[ddl] CREATE TABLE %q FOREIGN KEY", name)
+			}
+			ct.fks = append(ct.fks, fks...)
+			// A foreign key creates an implicit index.
+			indexes = append(indexes, indexDef{columns: []string{fks[0].ColumnName}})
+		case first.is("CHECK"), first.is("PERIOD"):
+			// ignored
+		default:
+			cd, err := parseColumnDef(def)
+			if err != nil {
+				return nil, false, errors.Wrapf(err, "[ddl] CREATE TABLE %q", name)
+			}
+			cd.col.Pos = uint64(len(ct.defs) + 1)
+			if cd.col.Key == columnPrimary {
+				pk = []string{cd.col.Field}
+			}
+			if cd.isUnique {
+				indexes = append(indexes, indexDef{unique: true, columns: []string{cd.col.Field}})
+			}
+			ct.defs = append(ct.defs, cd)
+		}
+	}
+	if len(ct.defs) == 0 {
+		return nil, false, errors.Empty.Newf("[ddl] CREATE TABLE %q: No columns found", name)
+	}
+	if err := ct.applyKeys(pk, indexes); err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	ct.columns = make(Columns, 0, len(ct.defs))
+	for _, cd := range ct.defs {
+		cd.finalize()
+		ct.columns = append(ct.columns, cd.col)
+	}
+	return ct, true, nil
+}
+
+// indexColumns skips the optional keywords and the index name until the
+// column list starts.
+func indexColumns(toks []sqlToken) ([]string, error) {
+	for i, t := range toks {
+		if t.is("(") {
+			cols, _, err := identList(toks[i:])
+			return cols, err
+		}
+	}
+	return nil, errors.NotValid.Newf("[ddl] Missing index columns")
+}
+
+func parseForeignKey(tableName, constraintName string, toks []sqlToken) ([]*KeyColumnUsage, error) {
+	i := 0
+	for i < len(toks) && !toks[i].is("(") {
+		i++ // skip KEY and the optional index name
+	}
+	cols, n, err := identList(toks[i:])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(cols) == 0 {
+		return nil, errors.Empty.Newf("[ddl] Missing foreign key columns")
+	}
+	i += n
+	if i >= len(toks) || !toks[i].is("REFERENCES") {
+		return nil, errors.NotValid.Newf("[ddl] Missing REFERENCES")
+	}
+	i++
+	refSchema, refTable, n := qualifiedName(toks[i:])
+	if n == 0 {
+		return nil, errors.NotValid.Newf("[ddl] Missing referenced table name")
+	}
+	i += n
+	refCols, _, err := identList(toks[i:])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(cols) != len(refCols) {
+		return nil, errors.Mismatch.Newf("[ddl] Column count %v does not match referenced column count %v", cols, refCols)
+	}
+	ret := make([]*KeyColumnUsage, 0, len(cols))
+	for j, c := range cols {
+		kcu := NewKeyColumnUsage()
+		kcu.ConstraintCatalog = "def"
+		kcu.ConstraintName = constraintName
+		kcu.TableCatalog = "def"
+		kcu.TableName = tableName
+		kcu.ColumnName = c
+		kcu.OrdinalPosition = int64(j + 1)
+		kcu.PositionInUniqueConstraint = null.MakeInt64(int64(j + 1))
+		if refSchema != "" {
+			kcu.ReferencedTableSchema = null.MakeString(refSchema)
+		}
+		kcu.ReferencedTableName = null.MakeString(refTable)
+		kcu.ReferencedColumnName = null.MakeString(refCols[j])
+		ret = append(ret, kcu)
+	}
+	return ret, nil
+}
+
+// applyKeys sets the COLUMN_KEY field in the same way as MySQL does: PRI for
+// all primary key columns, UNI for the column of a single column unique index
+// and MUL for the first column of all other indexes. A single column NOT NULL
+// unique index becomes the primary key when no primary key has been defined.
+func (ct *createTable) applyKeys(pk []string, indexes []indexDef) error {
+	byField := func(f string) *columnDef {
+		for _, cd := range ct.defs {
+			if strings.EqualFold(cd.col.Field, f) {
+				return cd
+			}
+		}
+		return nil
+	}
+	for _, f := range pk {
+		cd := byField(f)
+		if cd == nil {
+			return errors.NotFound.Newf("[ddl] CREATE TABLE %q: Primary key column %q not found", ct.name, f)
+		}
+		cd.col.Key = columnPrimary
+		cd.notNull = true
+	}
+	for _, idx := range indexes {
+		if len(idx.columns) == 0 {
+			continue
+		}
+		cd := byField(idx.columns[0])
+		if cd == nil {
+			return errors.NotFound.Newf("[ddl] CREATE TABLE %q: Index column %q not found", ct.name, idx.columns[0])
+		}
+		switch {
+		case cd.col.Key == columnPrimary:
+		case idx.unique && len(idx.columns) == 1 && cd.notNull && len(pk) == 0:
+			cd.col.Key = columnPrimary
+			pk = idx.columns
+		case idx.unique && len(idx.columns) == 1:
+			cd.col.Key = columnUnique
+		case cd.col.Key == "":
+			cd.col.Key = "MUL"
+		}
+	}
+	return nil
+}
+
+// finalize sets the NULL and DEFAULT fields after all keys have been applied.
+func (cd *columnDef) finalize() {
+	cd.col.Null = columnNull
+	if cd.notNull {
+		cd.col.Null = "NO"
+	}
+	switch {
+	case !cd.hasDefault && !cd.notNull:
+		cd.col.Default = null.MakeString("NULL")
+	case cd.hasDefault && cd.notNull && cd.col.Default.String == "NULL":
+		cd.col.Default = null.String{} // invalid definition anyway
+	}
+}
+
+// Default precision and display width of the integer types: signed precision,
+// signed width, unsigned width. The unsigned precision of bigint is 20.
+var intTypes = map[string][3]int64{
+	"tinyint":   {3, 4, 3},
+	"smallint":  {5, 6, 5},
+	"mediumint": {7, 9, 8},
+	"int":       {10, 11, 10},
+	"bigint":    {19, 20, 20},
+}
+
+var textLengths = map[string]int64{
+	"tinytext":   255,
+	"tinyblob":   255,
+	"text":       65535,
+	"blob":       65535,
+	"mediumtext": 16777215,
+	"mediumblob": 16777215,
+	"longtext":   4294967295,
+	"longblob":   4294967295,
+}
+
+var typeAliases = map[string]string{
+	"integer":   "int",
+	"int1":      "tinyint",
+	"int2":      "smallint",
+	"int3":      "mediumint",
+	"int4":      "int",
+	"int8":      "bigint",
+	"middleint": "mediumint",
+	"dec":       "decimal",
+	"numeric":   "decimal",
+	"fixed":     "decimal",
+	"real":      "double",
+	"float8":    "double",
+	"float4":    "float",
+	"character": "char",
+}
+
+// parseColumnDef parses a column definition.
+func parseColumnDef(toks []sqlToken) (*columnDef, error) {
+	if len(toks) < 2 || (toks[0].kind != tokIdent && toks[0].kind != tokWord) {
+		return nil, errors.NotValid.Newf("[ddl] Invalid column definition %v", toks)
+	}
+	c := &Column{Field: toks[0].val}
+	cd := &columnDef{col: c}
+
+	c.DataType = strings.ToLower(toks[1].val)
+	i := 2
+	if a, ok := typeAliases[c.DataType]; ok {
+		c.DataType = a
+	}
+	switch {
+	case c.DataType == "double" && i < len(toks) && toks[i].is("PRECISION"):
+		i++
+	case c.DataType == "bool" || c.DataType == "boolean":
+		c.DataType = "tinyint"
+		c.ColumnType = "tinyint(1)"
+	}
+
+	var args []string
+	var argToks []sqlToken
+	if i < len(toks) && toks[i].is("(") {
+		inner, n, err := parenBlock(toks[i:])
+		if err != nil {
+			return nil, errors.Wrapf(err, "[ddl] Column %q", c.Field)
+		}
+		i += n
+		for _, t := range inner {
+			if t.kind != tokPunct {
+				args = append(args, t.val)
+				argToks = append(argToks, t)
+			}
+		}
+	}
+	unsigned, zerofill := false, false
+modifiers:
+	for ; i < len(toks); i++ {
+		switch t := toks[i]; {
+		case t.is("UNSIGNED"):
+			unsigned = true
+		case t.is("ZEROFILL"):
+			unsigned, zerofill = true, true
+		case t.is("SIGNED"), t.is("BINARY"), t.is("ASCII"), t.is("UNICODE"), t.is("BYTE"):
+		case t.is("CHARACTER") || t.is("CHARSET") || t.is("COLLATE"):
+			if t.is("CHARACTER") {
+				i++ // SET
+			}
+			i++ // name
+		default:
+			break modifiers
+		}
+	}
+	if err := c.setTypeInfo(args, argToks, unsigned, zerofill); err != nil {
+		return nil, errors.Wrapf(err, "[ddl] Column %q", c.Field)
+	}
+
+	var extras []string
+	for ; i < len(toks); i++ {
+		t := toks[i]
+		switch {
+		case t.is("NOT") && i+1 < len(toks) && toks[i+1].is("NULL"):
+			cd.notNull = true
+			i++
+		case t.is("NULL"):
+			cd.notNull = false
+		case t.is("DEFAULT"):
+			if i+1 >= len(toks) {
+				return nil, errors.NotValid.Newf("[ddl] Column %q: Missing DEFAULT value", c.Field)
+			}
+			val, n := defaultValue(c, toks[i+1:])
+			c.Default = null.MakeString(val)
+			cd.hasDefault = true
+			i += n
+		case t.is("ON") && i+2 < len(toks) && toks[i+1].is("UPDATE"):
+			val, n := defaultValue(c, toks[i+2:])
+			extras = append(extras, "on update "+val)
+			i += n + 1
+		case t.is("AUTO_INCREMENT"):
+			extras = append(extras, columnAutoIncrement)
+		case t.is("PRIMARY"), t.is("KEY"):
+			c.Key = columnPrimary
+			if t.is("PRIMARY") && i+1 < len(toks) && toks[i+1].is("KEY") {
+				i++
+			}
+		case t.is("UNIQUE"):
+			cd.isUnique = true
+			if i+1 < len(toks) && toks[i+1].is("KEY") {
+				i++
+			}
+		case t.is("COMMENT"):
+			if i+1 < len(toks) {
+				c.Comment = toks[i+1].val
+				i++
+			}
+		case t.is("GENERATED") || t.is("AS"):
+			for i < len(toks) && !toks[i].is("(") {
+				i++
+			}
+			_, n, err := parenBlock(toks[i:])
+			if err != nil {
+				return nil, errors.Wrapf(err, "[ddl] Column %q generated expression", c.Field)
+			}
+			i += n - 1
+			kind := "VIRTUAL"
+			if i+1 < len(toks) && (toks[i+1].is("STORED") || toks[i+1].is("PERSISTENT")) {
+				kind = "STORED"
+			}
+			extras = append(extras, kind+" GENERATED")
+		case t.is("REFERENCES"), t.is("CHECK"):
+			// inline references are ignored by InnoDB, checks are not needed.
+			for i+1 < len(toks) && !toks[i+1].is("COMMENT") {
+				i++
+			}
+		case t.is("COLUMN_FORMAT"), t.is("STORAGE"), t.is("COLLATE"):
+			i++
+		}
+	}
+	c.Extra = strings.Join(extras, " ")
+	return cd, nil
+}
+
+// setTypeInfo sets the fields ColumnType, CharMaxLength, Precision and Scale
+// like information_schema.COLUMNS from a MariaDB server.
+func (c *Column) setTypeInfo(args []string, argToks []sqlToken, unsigned, zerofill bool) error {
+	argInt := func(idx int) (int64, error) {
+		v, err := strconv.ParseInt(args[idx], 10, 64)
+		if err != nil {
+			return 0, errors.NotValid.New(err, "[ddl] Invalid type argument")
+		}
+		return v, nil
+	}
+	suffix := ""
+	if unsigned {
+		suffix += " " + columnUnsigned
+	}
+	if zerofill {
+		suffix += " zerofill"
+	}
+
+	dt := c.DataType
+	switch {
+	case intTypes[dt] != [3]int64{}:
+		it := intTypes[dt]
+		width := it[1]
+		if unsigned {
+			width = it[2]
+		}
+		if len(args) > 0 {
+			var err error
+			if width, err = argInt(0); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		prec := it[0]
+		if dt == "bigint" && unsigned {
+			prec = 20
+		}
+		c.Precision = null.MakeInt64(prec)
+		c.Scale = null.MakeInt64(0)
+		if c.ColumnType == "" {
+			c.ColumnType = fmt.Sprintf("%s(%d)%s", dt, width, suffix)
+		}
+	case dt == "decimal":
+		var p, s int64 = 10, 0
+		var err error
+		if len(args) > 0 {
+			if p, err = argInt(0); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if len(args) > 1 {
+			if s, err = argInt(1); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		c.Precision = null.MakeInt64(p)
+		c.Scale = null.MakeInt64(s)
+		c.ColumnType = fmt.Sprintf("decimal(%d,%d)%s", p, s, suffix)
+	case dt == "float" || dt == "double":
+		c.Precision = null.MakeInt64(12)
+		if dt == "double" {
+			c.Precision = null.MakeInt64(22)
+		}
+		c.ColumnType = dt + suffix
+		if len(args) == 2 {
+			p, err := argInt(0)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			s, err := argInt(1)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			c.Precision = null.MakeInt64(p)
+			c.Scale = null.MakeInt64(s)
+			c.ColumnType = fmt.Sprintf("%s(%d,%d)%s", dt, p, s, suffix)
+		}
+	case dt == "bit":
+		var p int64 = 1
+		if len(args) > 0 {
+			var err error
+			if p, err = argInt(0); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		c.Precision = null.MakeInt64(p)
+		c.ColumnType = fmt.Sprintf("bit(%d)", p)
+	case dt == "char" || dt == "varchar" || dt == "binary" || dt == "varbinary":
+		var l int64 = 1
+		if len(args) > 0 {
+			var err error
+			if l, err = argInt(0); err != nil {
+				return errors.WithStack(err)
+			}
+		} else if dt == "varchar" || dt == "varbinary" {
+			return errors.NotValid.Newf("[ddl] Type %q requires a length", dt)
+		}
+		c.CharMaxLength = null.MakeInt64(l)
+		c.ColumnType = fmt.Sprintf("%s(%d)", dt, l)
+	case textLengths[dt] > 0:
+		c.CharMaxLength = null.MakeInt64(textLengths[dt])
+		c.ColumnType = dt
+	case dt == "enum" || dt == "set":
+		var maxLen, sumLen int64
+		quoted := make([]string, 0, len(argToks))
+		for _, t := range argToks {
+			l := int64(utf8.RuneCountInString(t.val))
+			if l > maxLen {
+				maxLen = l
+			}
+			sumLen += l
+			quoted = append(quoted, "'"+strings.Replace(t.val, "'", "''", -1)+"'")
+		}
+		c.CharMaxLength = null.MakeInt64(maxLen)
+		if dt == "set" && len(argToks) > 0 {
+			c.CharMaxLength = null.MakeInt64(sumLen + int64(len(argToks)-1))
+		}
+		c.ColumnType = dt + "(" + strings.Join(quoted, ",") + ")"
+	case dt == "time" || dt == "datetime" || dt == "timestamp":
+		c.ColumnType = dt
+		if len(args) > 0 && args[0] != "0" {
+			c.ColumnType = fmt.Sprintf("%s(%s)", dt, args[0])
+		}
+	case dt == "year":
+		c.ColumnType = "year(4)"
+	case dt == "json":
+		// MariaDB treats json as an alias for longtext.
+		c.DataType = "longtext"
+		c.CharMaxLength = null.MakeInt64(textLengths["longtext"])
+		c.ColumnType = "longtext"
+	default:
+		c.ColumnType = dt
+		if len(args) > 0 {
+			c.ColumnType = fmt.Sprintf("%s(%s)", dt, strings.Join(args, ","))
+		}
+	}
+	return nil
+}
+
+// defaultValue returns the DEFAULT value as shown in information_schema of
+// MariaDB and the number of consumed tokens. Strings are quoted, numbers get
+// formatted according to the scale of a decimal and functions get lower cased
+// with parenthesis.
+func defaultValue(c *Column, toks []sqlToken) (string, int) {
+	t := toks[0]
+	switch {
+	case t.kind == tokString && c.Precision.Valid:
+		return formatDecimalDefault(c, t.val), 1
+	case t.kind == tokWord && len(toks) > 1 && toks[1].kind == tokString && (t.is("b") || t.is("x")):
+		return strings.ToLower(t.val) + "'" + toks[1].val + "'", 2
+	case t.kind == tokString:
+		return "'" + strings.Replace(t.val, "'", "''", -1) + "'", 1
+	case t.is("NULL"):
+		return "NULL", 1
+	case t.is("("):
+		inner, n, err := parenBlock(toks)
+		if err != nil || len(inner) == 0 {
+			return "", 1
+		}
+		val, _ := defaultValue(c, inner)
+		return val, n
+	case isSQLNumber(t.val):
+		return formatDecimalDefault(c, t.val), 1
+	case t.is("TRUE"):
+		return "1", 1
+	case t.is("FALSE"):
+		return "0", 1
+	}
+	// function call like CURRENT_TIMESTAMP or now()
+	name := strings.ToLower(t.val)
+	n := 1
+	args := ""
+	if len(toks) > 1 && toks[1].is("(") {
+		inner, m, err := parenBlock(toks[1:])
+		if err == nil {
+			n += m
+			for _, a := range inner {
+				args += a.val
+			}
+		}
+	}
+	switch name {
+	case "current_timestamp", "now", "localtime", "localtimestamp":
+		name = "current_timestamp"
+	}
+	return name + "(" + args + ")", n
+}
+
+// formatDecimalDefault formats a numeric default value with the scale of a
+// decimal column.
+func formatDecimalDefault(c *Column, v string) string {
+	if c.DataType != "decimal" || !c.Scale.Valid || !isSQLNumber(v) {
+		return v
+	}
+	intPart, frac := v, ""
+	if dot := strings.IndexByte(v, '.'); dot >= 0 {
+		intPart, frac = v[:dot], v[dot+1:]
+	}
+	if intPart == "" || intPart == "-" || intPart == "+" {
+		intPart += "0"
+	}
+	intPart = strings.TrimPrefix(intPart, "+")
+	scale := int(c.Scale.Int64)
+	if len(frac) > scale {
+		frac = frac[:scale]
+	}
+	frac += strings.Repeat("0", scale-len(frac))
+	if scale == 0 {
+		return intPart
+	}
+	return intPart + "." + frac
+}
@@ -0,0 +1,113 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/sql/ddl"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/storage/null"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+func TestParseCreateTables(t *testing.T) {
+	t.Parallel()
+
+	t.Run("columns and keys", func(t *testing.T) {
+		tables, fks, err := ddl.ParseCreateTables(strings.NewReader(`-- dump
+/*!40101 SET NAMES utf8 */;
+DROP TABLE IF EXISTS customer_entity;
+CREATE TABLE IF NOT EXISTS ` + "`customer_entity`" + ` (
+  ` + "`entity_id`" + ` int(10) unsigned NOT NULL AUTO_INCREMENT COMMENT 'Entity Id',
+  email varchar(255) DEFAULT NULL COMMENT 'It''s a mail',
+  website_id smallint UNSIGNED,
+  status enum('a','bcd') NOT NULL DEFAULT 'a',
+  is_active boolean NOT NULL DEFAULT TRUE,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  grand_total decimal(12,4) DEFAULT '1.5',
+  PRIMARY KEY (entity_id),
+  UNIQUE KEY UNQ_EMAIL (email),
+  KEY IDX_WEBSITE (website_id, status)
+) ENGINE=InnoDB AUTO_INCREMENT=5 DEFAULT CHARSET=utf8 COMMENT='Customer Entity';`))
+		assert.NoError(t, err)
+		assert.Len(t, fks, 0)
+		assert.Exactly(t, ddl.Columns{
+			&ddl.Column{Field: "entity_id", Pos: 1, Null: "NO", DataType: "int", Precision: null.MakeInt64(10), Scale: null.MakeInt64(0), ColumnType: "int(10) unsigned", Key: "PRI", Extra: "auto_increment", Comment: "Entity Id"},
+			&ddl.Column{Field: "email", Pos: 2, Default: null.MakeString("NULL"), Null: "YES", DataType: "varchar", CharMaxLength: null.MakeInt64(255), ColumnType: "varchar(255)", Key: "UNI", Comment: "It's a mail"},
+			&ddl.Column{Field: "website_id", Pos: 3, Default: null.MakeString("NULL"), Null: "YES", DataType: "smallint", Precision: null.MakeInt64(5), Scale: null.MakeInt64(0), ColumnType: "smallint(5) unsigned", Key: "MUL"},
+			&ddl.Column{Field: "status", Pos: 4, Default: null.MakeString("'a'"), Null: "NO", DataType: "enum", CharMaxLength: null.MakeInt64(3), ColumnType: "enum('a','bcd')"},
+			&ddl.Column{Field: "is_active", Pos: 5, Default: null.MakeString("1"), Null: "NO", DataType: "tinyint", Precision: null.MakeInt64(3), Scale: null.MakeInt64(0), ColumnType: "tinyint(1)"},
+			&ddl.Column{Field: "updated_at", Pos: 6, Default: null.MakeString("current_timestamp()"), Null: "NO", DataType: "timestamp", ColumnType: "timestamp", Extra: "on update current_timestamp()"},
+			&ddl.Column{Field: "grand_total", Pos: 7, Default: null.MakeString("1.5000"), Null: "YES", DataType: "decimal", Precision: null.MakeInt64(12), Scale: null.MakeInt64(4), ColumnType: "decimal(12,4)"},
+		}, tables["customer_entity"])
+	})
+
+	t.Run("foreign keys", func(t *testing.T) {
+		tables, fks, err := ddl.ParseCreateTables(strings.NewReader(`
+CREATE TABLE sales_order (
+  entity_id int unsigned NOT NULL PRIMARY KEY,
+  customer_id int(10) unsigned DEFAULT NULL,
+  store_id smallint(5) unsigned NOT NULL,
+  CONSTRAINT FK_CUSTOMER FOREIGN KEY (customer_id) REFERENCES customer_entity (entity_id) ON DELETE SET NULL,
+  FOREIGN KEY (store_id) REFERENCES ` + "`shop`.`store`" + ` (store_id)
+);`))
+		assert.NoError(t, err)
+		assert.Len(t, tables, 1)
+		assert.Exactly(t, "MUL", tables["sales_order"].ByField("customer_id").Key)
+
+		kcu := fks["customer_entity.entity_id"].Data
+		assert.Len(t, kcu, 1)
+		assert.Exactly(t, "FK_CUSTOMER", kcu[0].ConstraintName)
+		assert.Exactly(t, "sales_order", kcu[0].TableName)
+		assert.Exactly(t, "customer_id", kcu[0].ColumnName)
+
+		kcu = fks["store.store_id"].Data
+		assert.Len(t, kcu, 1)
+		assert.Exactly(t, "sales_order_ibfk_1", kcu[0].ConstraintName)
+		assert.Exactly(t, null.MakeString("shop"), kcu[0].ReferencedTableSchema)
+	})
+
+	t.Run("all types from file", func(t *testing.T) {
+		tables, _, err := ddl.ParseCreateTablesFromFiles("../dmlgen/testdata/table_all_types.sql")
+		assert.NoError(t, err)
+		cols := tables["dmlgen_types"]
+		assert.Len(t, cols, 42)
+		assert.Exactly(t, &ddl.Column{Field: "col_decimal_24_12", Pos: 17, Default: null.MakeString("0.000000000000"), Null: "NO", DataType: "decimal", Precision: null.MakeInt64(24), Scale: null.MakeInt64(12), ColumnType: "decimal(24,12)"}, cols.ByField("col_decimal_24_12"))
+		assert.Exactly(t, &ddl.Column{Field: "col_bigint_3", Pos: 4, Default: null.MakeString("NULL"), Null: "YES", DataType: "bigint", Precision: null.MakeInt64(20), Scale: null.MakeInt64(0), ColumnType: "bigint(20) unsigned"}, cols.ByField("col_bigint_3"))
+		assert.Exactly(t, &ddl.Column{Field: "col_char_2", Pos: 42, Default: null.MakeString("'xchar'"), Null: "NO", DataType: "char", CharMaxLength: null.MakeInt64(17), ColumnType: "char(17)"}, cols.ByField("col_char_2"))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := ddl.ParseCreateTables(strings.NewReader(`CREATE TABLE a (id int, PRIMARY KEY (idx));`))
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+
+		_, _, err = ddl.ParseCreateTables(strings.NewReader(`CREATE TABLE a (id int); CREATE TABLE a (id int);`))
+		assert.True(t, errors.AlreadyExists.Match(err), "%+v", err)
+
+		_, _, err = ddl.ParseCreateTables(strings.NewReader(`CREATE TABLE a (id varchar(10) DEFAULT 'x);`))
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+
+		_, _, err = ddl.ParseCreateTablesFromFiles("testdata/not_found.sql")
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+	})
+}
@@ -0,0 +1,725 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/corestoreio/errors"
+	"github.com/corestoreio/log"
+	"github.com/gogo/protobuf/types"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/sql/dml"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/storage/null"
+)
+
+// StreamFormat defines the file format for Table.Export and Table.Import.
+type StreamFormat uint8
+
+// Supported stream formats.
+const (
+	// StreamCSV writes a header line with the column names and then one line
+	// per row. NULL values are written as `\N`.
+	StreamCSV StreamFormat = iota + 1
+	// StreamJSONLines writes one JSON object per line. The object keys are the
+	// column names.
+	StreamJSONLines
+	// StreamProtobuf writes varint length-delimited messages of type
+	// google.protobuf.ListValue. The first message contains the column names.
+	StreamProtobuf
+)
+
+// streamNull represents a NULL value in CSV files, as MySQL does.
+const streamNull = `\N`
+
+// defaultStreamProgressInterval reports after each 1000 rows the progress.
+const defaultStreamProgressInterval = 1000
+
+// ExportOptions provides options for the function Export.
+type ExportOptions struct {
+	Format StreamFormat
+	// Comma sets the CSV field delimiter. Defaults to comma.
+	Comma rune
+	// SkipHeader omits the CSV header line.
+	SkipHeader bool
+	// Progress optional callback gets called after each ProgressInterval rows
+	// and after the last row.
+	Progress func(rows uint64)
+	// ProgressInterval defaults to 1000 rows.
+	ProgressInterval uint64
+	// Log optional logger for debugging purposes
+	Log log.Logger
+}
+
+// ImportOptions provides options for the function Import.
+type ImportOptions struct {
+	Format StreamFormat
+	// Comma sets the CSV field delimiter. Defaults to comma.
+	Comma rune
+	// Columns optional column names if the CSV file has no header line or to
+	// define the column order of the JSON lines. Must match the table columns.
+	Columns []string
+	// BatchSize number of rows written with one INSERT statement. Defaults to
+	// 100.
+	BatchSize int
+	// DisableOnDuplicateKey writes a plain INSERT statement instead of INSERT
+	// ... ON DUPLICATE KEY UPDATE for all non primary key columns.
+	DisableOnDuplicateKey bool
+	// Progress optional callback gets called after each ProgressInterval rows
+	// and after the last row.
+	Progress func(rows uint64)
+	// ProgressInterval defaults to 1000 rows.
+	ProgressInterval uint64
+	// Log optional logger for debugging purposes
+	Log log.Logger
+}
+
+// streamKind defines how a column value gets serialized. It gets determined
+// from the Column metadata.
+type streamKind uint8
+
+const (
+	streamKindString streamKind = iota
+	streamKindInt
+	streamKindUint
+	streamKindFloat
+	streamKindDecimal
+	streamKindBool
+	streamKindDateTime
+	streamKindDate
+	streamKindBinary
+)
+
+func (k streamKind) isNumber() bool {
+	switch k {
+	case streamKindInt, streamKindUint, streamKindFloat, streamKindDecimal:
+		return true
+	}
+	return false
+}
+
+func columnStreamKind(c *Column) streamKind {
+	if c.IsBool() {
+		return streamKindBool
+	}
+	switch c.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "bigint":
+		if c.IsUnsigned() {
+			return streamKindUint
+		}
+		return streamKindInt
+	case "float", "double":
+		return streamKindFloat
+	case "decimal":
+		return streamKindDecimal
+	case "datetime", "timestamp":
+		return streamKindDateTime
+	case "date":
+		return streamKindDate
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return streamKindBinary
+	}
+	return streamKindString
+}
+
+// streamValue contains the text representation of a column value.
+type streamValue struct {
+	valid bool
+	data  string
+}
+
+type progress struct {
+	fn       func(rows uint64)
+	interval uint64
+	rows     uint64
+}
+
+func (p *progress) inc() {
+	p.rows++
+	if p.fn != nil && p.rows%p.interval == 0 {
+		p.fn(p.rows)
+	}
+}
+
+func (p *progress) done() {
+	if p.fn != nil && p.rows%p.interval != 0 {
+		p.fn(p.rows)
+	}
+}
+
+func newProgress(fn func(rows uint64), interval uint64) *progress {
+	if interval == 0 {
+		interval = defaultStreamProgressInterval
+	}
+	return &progress{fn: fn, interval: interval}
+}
+
+// readStreamValue reads the current column of the ColumnMap with the
+// appropriate type conversion.
+func readStreamValue(cm *dml.ColumnMap, k streamKind) (sv streamValue, err error) {
+	switch k {
+	case streamKindInt:
+		var v null.Int64
+		cm.NullInt64(&v)
+		sv = streamValue{valid: v.Valid, data: strconv.FormatInt(v.Int64, 10)}
+	case streamKindFloat:
+		var v null.Float64
+		cm.NullFloat64(&v)
+		sv = streamValue{valid: v.Valid, data: strconv.FormatFloat(v.Float64, 'g', -1, 64)}
+	case streamKindBool:
+		var v null.Bool
+		cm.NullBool(&v)
+		sv = streamValue{valid: v.Valid, data: strconv.FormatBool(v.Bool)}
+	case streamKindDateTime, streamKindDate:
+		var v null.Time
+		cm.NullTime(&v)
+		layout := "2006-01-02 15:04:05.999999"
+		if k == streamKindDate {
+			layout = "2006-01-02"
+		}
+		sv = streamValue{valid: v.Valid, data: v.Time.Format(layout)}
+	case streamKindBinary:
+		var v []byte
+		cm.Byte(&v)
+		sv = streamValue{valid: v != nil, data: base64.StdEncoding.EncodeToString(v)}
+	default: // unsigned ints and decimals are kept as text to not lose precision
+		var v null.String
+		cm.NullString(&v)
+		sv = streamValue{valid: v.Valid, data: v.String}
+	}
+	return sv, errors.WithStack(cm.Err())
+}
+
+// streamWriter writes the rows in a specific format.
+type streamWriter interface {
+	header(cols []string) error
+	row(cols []string, kinds []streamKind, vals []streamValue) error
+	flush() error
+}
+
+type csvStreamWriter struct {
+	w          *csv.Writer
+	skipHeader bool
+	rec        []string
+}
+
+func (sw *csvStreamWriter) header(cols []string) error {
+	if sw.skipHeader {
+		return nil
+	}
+	return sw.w.Write(cols)
+}
+
+func (sw *csvStreamWriter) row(_ []string, _ []streamKind, vals []streamValue) error {
+	sw.rec = sw.rec[:0]
+	for _, v := range vals {
+		if v.valid {
+			sw.rec = append(sw.rec, v.data)
+		} else {
+			sw.rec = append(sw.rec, streamNull)
+		}
+	}
+	return sw.w.Write(sw.rec)
+}
+
+func (sw *csvStreamWriter) flush() error {
+	sw.w.Flush()
+	return sw.w.Error()
+}
+
+type jsonStreamWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func (sw *jsonStreamWriter) header(_ []string) error { return nil }
+
+func (sw *jsonStreamWriter) row(cols []string, kinds []streamKind, vals []streamValue) error {
+	sw.buf = append(sw.buf[:0], '{')
+	for i, v := range vals {
+		if i > 0 {
+			sw.buf = append(sw.buf, ',')
+		}
+		sw.buf = strconv.AppendQuote(sw.buf, cols[i])
+		sw.buf = append(sw.buf, ':')
+		switch {
+		case !v.valid:
+			sw.buf = append(sw.buf, "null"...)
+		case kinds[i].isNumber(), kinds[i] == streamKindBool:
+			sw.buf = append(sw.buf, v.data...)
+		default:
+			qs, err := json.Marshal(v.data)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			sw.buf = append(sw.buf, qs...)
+		}
+	}
+	sw.buf = append(sw.buf, '}', '\n')
+	_, err := sw.w.Write(sw.buf)
+	return err
+}
+
+func (sw *jsonStreamWriter) flush() error { return sw.w.Flush() }
+
+type protoStreamWriter struct {
+	w      *bufio.Writer
+	lenBuf [binary.MaxVarintLen64]byte
+}
+
+func (sw *protoStreamWriter) write(lv *types.ListValue) error {
+	data, err := lv.Marshal()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	n := binary.PutUvarint(sw.lenBuf[:], uint64(len(data)))
+	if _, err := sw.w.Write(sw.lenBuf[:n]); err != nil {
+		return err
+	}
+	_, err = sw.w.Write(data)
+	return err
+}
+
+func (sw *protoStreamWriter) header(cols []string) error {
+	lv := &types.ListValue{Values: make([]*types.Value, len(cols))}
+	for i, c := range cols {
+		lv.Values[i] = &types.Value{Kind: &types.Value_StringValue{StringValue: c}}
+	}
+	return sw.write(lv)
+}
+
+func (sw *protoStreamWriter) row(_ []string, kinds []streamKind, vals []streamValue) error {
+	lv := &types.ListValue{Values: make([]*types.Value, len(vals))}
+	for i, v := range vals {
+		switch {
+		case !v.valid:
+			lv.Values[i] = &types.Value{Kind: &types.Value_NullValue{}}
+		case kinds[i] == streamKindBool:
+			lv.Values[i] = &types.Value{Kind: &types.Value_BoolValue{BoolValue: v.data == "true"}}
+		case kinds[i] == streamKindFloat:
+			f, err := strconv.ParseFloat(v.data, 64)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			lv.Values[i] = &types.Value{Kind: &types.Value_NumberValue{NumberValue: f}}
+		default: // integers are strings because a double has only 53 bits.
+			lv.Values[i] = &types.Value{Kind: &types.Value_StringValue{StringValue: v.data}}
+		}
+	}
+	return sw.write(lv)
+}
+
+func (sw *protoStreamWriter) flush() error { return sw.w.Flush() }
+
+// Export streams the result of the Select query into the writer with the
+// format defined in the options. If argument `sel` is nil, all columns of the
+// table get selected. The Table Columns must be loaded to convert the values
+// into the correct types, otherwise all values are treated as strings. It
+// runs on the client side with Artisan.IterateSerial and hence does not
+// require the FILE privilege like SELECT ... INTO OUTFILE. Returns the number
+// of written rows.
+func (t *Table) Export(ctx context.Context, w io.Writer, sel *dml.Select, o ExportOptions) (rows uint64, err error) {
+	if o.Log == nil {
+		o.Log = log.BlackHole{}
+	}
+	if sel == nil {
+		sel = t.SelectAll()
+	}
+
+	var sw streamWriter
+	switch o.Format {
+	case StreamCSV:
+		cw := csv.NewWriter(w)
+		if o.Comma > 0 {
+			cw.Comma = o.Comma
+		}
+		sw = &csvStreamWriter{w: cw, skipHeader: o.SkipHeader}
+	case StreamJSONLines:
+		sw = &jsonStreamWriter{w: bufio.NewWriter(w)}
+	case StreamProtobuf:
+		sw = &protoStreamWriter{w: bufio.NewWriter(w)}
+	default:
+		return 0, errors.NotSupported.Newf("[ddl] Table.Export: Format %d not supported", o.Format)
+	}
+
+	prgs := newProgress(o.Progress, o.ProgressInterval)
+	var cols []string
+	var kinds []streamKind
+	var vals []streamValue
+	err = sel.WithArgs().IterateSerial(ctx, func(cm *dml.ColumnMap) error {
+		if cm.Count == 0 {
+			cols, kinds, vals = cols[:0], kinds[:0], vals[:0]
+			for cm.Next() {
+				c := cm.Column()
+				cols = append(cols, c)
+				kinds = append(kinds, columnStreamKind(t.Columns.ByField(c)))
+			}
+			vals = make([]streamValue, len(cols))
+			if err := sw.header(cols); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		for i := 0; cm.Next(); i++ {
+			sv, err := readStreamValue(cm, kinds[i])
+			if err != nil {
+				return errors.Wrapf(err, "[ddl] Table.Export: Column %q at row %d", cols[i], prgs.rows)
+			}
+			vals[i] = sv
+		}
+		if err := sw.row(cols, kinds, vals); err != nil {
+			return errors.WithStack(err)
+		}
+		prgs.inc()
+		return nil
+	})
+	if err != nil {
+		return prgs.rows, errors.WithStack(err)
+	}
+	if err = sw.flush(); err != nil {
+		return prgs.rows, errors.WithStack(err)
+	}
+	prgs.done()
+	if o.Log.IsDebug() {
+		o.Log.Debug("ddl.Table.Export", log.String("table", t.Name), log.Uint64("rows", prgs.rows))
+	}
+	return prgs.rows, nil
+}
+
+// streamReader reads the rows in a specific format. It returns io.EOF after
+// the last row.
+type streamReader interface {
+	// columns returns the column names. Might read the first row.
+	columns() ([]string, error)
+	row(vals []streamValue) error
+}
+
+type csvStreamReader struct {
+	r    *csv.Reader
+	cols []string
+}
+
+func (sr *csvStreamReader) columns() ([]string, error) {
+	if len(sr.cols) > 0 {
+		return sr.cols, nil
+	}
+	cols, err := sr.r.Read()
+	if err == io.EOF {
+		return nil, errors.Empty.Newf("[ddl] Table.Import: CSV file contains no header")
+	}
+	sr.cols = append([]string(nil), cols...)
+	return sr.cols, errors.WithStack(err)
+}
+
+func (sr *csvStreamReader) row(vals []streamValue) error {
+	rec, err := sr.r.Read()
+	if err != nil {
+		return err
+	}
+	if len(rec) != len(vals) {
+		return errors.Mismatch.Newf("[ddl] Table.Import: CSV record has %d fields but expected %d", len(rec), len(vals))
+	}
+	for i, f := range rec {
+		vals[i] = streamValue{valid: f != streamNull, data: f}
+	}
+	return nil
+}
+
+type jsonStreamReader struct {
+	dec   *json.Decoder
+	cols  []string
+	table *Table
+	// first contains the already decoded first line, when the columns have
+	// been determined from it.
+	first map[string]interface{}
+}
+
+func (sr *jsonStreamReader) decode() (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := sr.dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (sr *jsonStreamReader) columns() ([]string, error) {
+	if len(sr.cols) > 0 {
+		return sr.cols, nil
+	}
+	m, err := sr.decode()
+	if err == io.EOF {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sr.first = m
+	// use the order of the table columns and append unknown keys sorted.
+	for _, c := range sr.table.Columns {
+		if _, ok := m[c.Field]; ok {
+			sr.cols = append(sr.cols, c.Field)
+		}
+	}
+	var unknown []string
+	for k := range m {
+		if !sr.table.Columns.Contains(k) {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	sr.cols = append(sr.cols, unknown...)
+	return sr.cols, nil
+}
+
+func (sr *jsonStreamReader) row(vals []streamValue) error {
+	m := sr.first
+	sr.first = nil
+	if m == nil {
+		var err error
+		if m, err = sr.decode(); err != nil {
+			return err
+		}
+	}
+	for i, c := range sr.cols {
+		switch v := m[c].(type) {
+		case nil:
+			vals[i] = streamValue{}
+		case json.Number:
+			vals[i] = streamValue{valid: true, data: v.String()}
+		case bool:
+			vals[i] = streamValue{valid: true, data: strconv.FormatBool(v)}
+		case string:
+			vals[i] = streamValue{valid: true, data: v}
+		default:
+			return errors.NotSupported.Newf("[ddl] Table.Import: JSON type %T of column %q not supported", v, c)
+		}
+	}
+	return nil
+}
+
+type protoStreamReader struct {
+	r    *bufio.Reader
+	cols []string
+	buf  []byte
+}
+
+func (sr *protoStreamReader) read() (*types.ListValue, error) {
+	l, err := binary.ReadUvarint(sr.r)
+	if err != nil {
+		return nil, err // io.EOF must not be wrapped
+	}
+	if uint64(cap(sr.buf)) < l {
+		sr.buf = make([]byte, l)
+	}
+	sr.buf = sr.buf[:l]
+	if _, err := io.ReadFull(sr.r, sr.buf); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	lv := new(types.ListValue)
+	return lv, errors.WithStack(lv.Unmarshal(sr.buf))
+}
+
+func (sr *protoStreamReader) columns() ([]string, error) {
+	if len(sr.cols) > 0 {
+		return sr.cols, nil
+	}
+	lv, err := sr.read()
+	if err == io.EOF {
+		return nil, errors.Empty.Newf("[ddl] Table.Import: Protobuf file contains no header")
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, v := range lv.Values {
+		sr.cols = append(sr.cols, v.GetStringValue())
+	}
+	return sr.cols, nil
+}
+
+func (sr *protoStreamReader) row(vals []streamValue) error {
+	lv, err := sr.read()
+	if err != nil {
+		return err
+	}
+	if len(lv.Values) != len(vals) {
+		return errors.Mismatch.Newf("[ddl] Table.Import: Protobuf message has %d values but expected %d", len(lv.Values), len(vals))
+	}
+	for i, v := range lv.Values {
+		switch k := v.Kind.(type) {
+		case *types.Value_NullValue:
+			vals[i] = streamValue{}
+		case *types.Value_BoolValue:
+			vals[i] = streamValue{valid: true, data: strconv.FormatBool(k.BoolValue)}
+		case *types.Value_NumberValue:
+			vals[i] = streamValue{valid: true, data: strconv.FormatFloat(k.NumberValue, 'g', -1, 64)}
+		case *types.Value_StringValue:
+			vals[i] = streamValue{valid: true, data: k.StringValue}
+		default:
+			return errors.NotSupported.Newf("[ddl] Table.Import: Protobuf type %T not supported", k)
+		}
+	}
+	return nil
+}
+
+// convertStreamValue converts the text representation into the argument type
+// of the column.
+func convertStreamValue(sv streamValue, k streamKind) (interface{}, error) {
+	if !sv.valid {
+		return nil, nil
+	}
+	switch k {
+	case streamKindInt:
+		return strconv.ParseInt(sv.data, 10, 64)
+	case streamKindUint:
+		return strconv.ParseUint(sv.data, 10, 64)
+	case streamKindFloat:
+		return strconv.ParseFloat(sv.data, 64)
+	case streamKindBool:
+		return strconv.ParseBool(sv.data)
+	case streamKindBinary:
+		return base64.StdEncoding.DecodeString(sv.data)
+	}
+	// decimals, date and time and strings are sent as text to the server.
+	return sv.data, nil
+}
+
+// Import reads the rows from the reader with the format defined in the
+// options and writes them in batches with INSERT ... ON DUPLICATE KEY UPDATE
+// into the table. It is the client side counter part of LoadDataInfile and
+// can read the files created with Export. The Table Columns must be loaded to
+// convert the values into the correct types. Returns the number of imported
+// rows.
+func (t *Table) Import(ctx context.Context, db dml.Execer, r io.Reader, o ImportOptions) (rows uint64, err error) {
+	if t.IsView {
+		return 0, nil
+	}
+	if o.Log == nil {
+		o.Log = log.BlackHole{}
+	}
+	if o.BatchSize < 1 {
+		o.BatchSize = 100
+	}
+
+	var sr streamReader
+	switch o.Format {
+	case StreamCSV:
+		cr := csv.NewReader(r)
+		if o.Comma > 0 {
+			cr.Comma = o.Comma
+		}
+		sr = &csvStreamReader{r: cr, cols: o.Columns}
+	case StreamJSONLines:
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		sr = &jsonStreamReader{dec: dec, cols: o.Columns, table: t}
+	case StreamProtobuf:
+		sr = &protoStreamReader{r: bufio.NewReader(r), cols: o.Columns}
+	default:
+		return 0, errors.NotSupported.Newf("[ddl] Table.Import: Format %d not supported", o.Format)
+	}
+
+	cols, err := sr.columns()
+	if err == io.EOF {
+		return 0, nil // empty JSON file
+	}
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if len(cols) == 0 {
+		return 0, errors.Empty.Newf("[ddl] Table.Import: No columns found for table %q", t.Name)
+	}
+	kinds := make([]streamKind, len(cols))
+	var pks []string
+	for i, c := range cols {
+		col := t.Columns.ByField(c)
+		if len(t.Columns) > 0 && col.Field == "" {
+			return 0, errors.NotFound.Newf("[ddl] Table.Import: Column %q not found in table %q", c, t.Name)
+		}
+		kinds[i] = columnStreamKind(col)
+		if col.IsPK() {
+			pks = append(pks, c)
+		}
+	}
+
+	// insertSQL caches the SQL string per row count, mostly two entries.
+	insertSQL := map[int]string{}
+	args := make([]interface{}, 0, o.BatchSize*len(cols))
+	prgs := newProgress(o.Progress, o.ProgressInterval)
+	flush := func() error {
+		rowCount := len(args) / len(cols)
+		if rowCount == 0 {
+			return nil
+		}
+		sqlStr, ok := insertSQL[rowCount]
+		if !ok {
+			ins := dml.NewInsert(t.Name).AddColumns(cols...).BuildValues().SetRowCount(rowCount)
+			if !o.DisableOnDuplicateKey {
+				ins.OnDuplicateKey().AddOnDuplicateKeyExclude(pks...)
+			}
+			var errSQL error
+			if sqlStr, _, errSQL = ins.ToSQL(); errSQL != nil {
+				return errors.WithStack(errSQL)
+			}
+			insertSQL[rowCount] = sqlStr
+		}
+		if _, err := db.ExecContext(ctx, sqlStr, args...); err != nil {
+			return errors.Wrapf(err, "[ddl] Table.Import: Failed to insert batch of %d rows into table %q", rowCount, t.Name)
+		}
+		args = args[:0]
+		return nil
+	}
+
+	vals := make([]streamValue, len(cols))
+	for {
+		if err := sr.row(vals); err == io.EOF {
+			break
+		} else if err != nil {
+			return prgs.rows, errors.Wrapf(err, "[ddl] Table.Import: Failed to read row %d", prgs.rows+1)
+		}
+		for i, sv := range vals {
+			arg, err := convertStreamValue(sv, kinds[i])
+			if err != nil {
+				return prgs.rows, errors.NotValid.New(err, "[ddl] Table.Import: Column %q at row %d", cols[i], prgs.rows+1)
+			}
+			args = append(args, arg)
+		}
+		prgs.inc()
+		if len(args) == cap(args) {
+			if err := flush(); err != nil {
+				return prgs.rows, errors.WithStack(err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return prgs.rows, errors.WithStack(err)
+	}
+	prgs.done()
+	if o.Log.IsDebug() {
+		o.Log.Debug("ddl.Table.Import", log.String("table", t.Name), log.Uint64("rows", prgs.rows))
+	}
+	return prgs.rows, nil
+}
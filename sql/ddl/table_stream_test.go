@@ -0,0 +1,148 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/sql/ddl"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/sql/dmltest"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/storage/null"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+func newStreamTable() *ddl.Table {
+	return ddl.NewTable("core_config_data",
+		&ddl.Column{Field: "config_id", Pos: 1, DataType: "int", ColumnType: "int(10) unsigned", Key: "PRI", Extra: "auto_increment"},
+		&ddl.Column{Field: "scope", Pos: 2, DataType: "varchar", ColumnType: "varchar(8)", Default: null.MakeString("default")},
+		&ddl.Column{Field: "scope_id", Pos: 3, DataType: "int", ColumnType: "int(11)"},
+		&ddl.Column{Field: "path", Pos: 4, DataType: "varchar", ColumnType: "varchar(255)"},
+		&ddl.Column{Field: "value", Pos: 5, DataType: "text", ColumnType: "text", Null: "YES"},
+	)
+}
+
+func streamMockRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"config_id", "scope", "scope_id", "path", "value"}).
+		AddRow([]byte("1"), []byte("default"), []byte("0"), []byte("web/url"), []byte("http://x.local/")).
+		AddRow([]byte("2"), []byte("stores"), []byte("2"), []byte("web/cookie"), nil)
+}
+
+func TestTable_Export(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		format ddl.StreamFormat
+		want   string
+	}{
+		{ddl.StreamCSV, "config_id,scope,scope_id,path,value\n1,default,0,web/url,http://x.local/\n2,stores,2,web/cookie,\\N\n"},
+		{ddl.StreamJSONLines, "{\"config_id\":1,\"scope\":\"default\",\"scope_id\":0,\"path\":\"web/url\",\"value\":\"http://x.local/\"}\n{\"config_id\":2,\"scope\":\"stores\",\"scope_id\":2,\"path\":\"web/cookie\",\"value\":null}\n"},
+	}
+	for _, test := range tests {
+		dbc, dbMock := dmltest.MockDB(t)
+		dbMock.ExpectQuery("SELECT (.+) FROM `core_config_data`").WillReturnRows(streamMockRows())
+
+		tbl := newStreamTable()
+		tbl.DB = dbc.DB
+		var buf bytes.Buffer
+		var progress []uint64
+		rows, err := tbl.Export(context.TODO(), &buf, nil, ddl.ExportOptions{
+			Format:           test.format,
+			ProgressInterval: 1,
+			Progress:         func(rows uint64) { progress = append(progress, rows) },
+		})
+		assert.NoError(t, err)
+		assert.Exactly(t, uint64(2), rows)
+		assert.Exactly(t, []uint64{1, 2}, progress)
+		assert.Exactly(t, test.want, buf.String())
+		dmltest.MockClose(t, dbc, dbMock)
+	}
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := newStreamTable().Export(context.TODO(), nil, nil, ddl.ExportOptions{})
+		assert.True(t, errors.NotSupported.Match(err), "%+v", err)
+	})
+}
+
+func TestTable_Import(t *testing.T) {
+	t.Parallel()
+
+	const insertSQL = "INSERT INTO `core_config_data` (`config_id`,`scope`,`scope_id`,`path`,`value`) VALUES (?,?,?,?,?)"
+
+	runImport := func(t *testing.T, format ddl.StreamFormat, data []byte) {
+		dbc, dbMock := dmltest.MockDB(t)
+		defer dmltest.MockClose(t, dbc, dbMock)
+
+		dbMock.ExpectExec(dmltest.SQLMockQuoteMeta(insertSQL+",(?,?,?,?,?) ON DUPLICATE KEY UPDATE")).
+			WithArgs(sqlmock.AnyArg(), "default", int64(0), "web/url", "http://x.local/", sqlmock.AnyArg(), "stores", int64(2), "web/cookie", nil).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		rows, err := newStreamTable().Import(context.TODO(), dbc.DB, bytes.NewReader(data), ddl.ImportOptions{
+			Format: format,
+		})
+		assert.NoError(t, err)
+		assert.Exactly(t, uint64(2), rows)
+	}
+
+	for _, format := range []ddl.StreamFormat{ddl.StreamCSV, ddl.StreamJSONLines, ddl.StreamProtobuf} {
+		dbc, dbMock := dmltest.MockDB(t)
+		dbMock.ExpectQuery("SELECT (.+) FROM `core_config_data`").WillReturnRows(streamMockRows())
+		tbl := newStreamTable()
+		tbl.DB = dbc.DB
+		var buf bytes.Buffer
+		_, err := tbl.Export(context.TODO(), &buf, nil, ddl.ExportOptions{Format: format})
+		assert.NoError(t, err)
+		dmltest.MockClose(t, dbc, dbMock)
+
+		runImport(t, format, buf.Bytes())
+	}
+
+	t.Run("batches without ON DUPLICATE KEY", func(t *testing.T) {
+		dbc, dbMock := dmltest.MockDB(t)
+		defer dmltest.MockClose(t, dbc, dbMock)
+
+		dbMock.ExpectExec(dmltest.SQLMockQuoteMeta("INSERT INTO `core_config_data` (`path`,`value`) VALUES (?,?),(?,?)")).
+			WithArgs("a", "1", "b", "2").WillReturnResult(sqlmock.NewResult(0, 2))
+		dbMock.ExpectExec(dmltest.SQLMockQuoteMeta("INSERT INTO `core_config_data` (`path`,`value`) VALUES (?,?)")).
+			WithArgs("c", nil).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		rows, err := newStreamTable().Import(context.TODO(), dbc.DB, strings.NewReader("a|1\nb|2\nc|\\N\n"), ddl.ImportOptions{
+			Format:                ddl.StreamCSV,
+			Comma:                 '|',
+			Columns:               []string{"path", "value"},
+			BatchSize:             2,
+			DisableOnDuplicateKey: true,
+		})
+		assert.NoError(t, err)
+		assert.Exactly(t, uint64(3), rows)
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		_, err := newStreamTable().Import(context.TODO(), nil, strings.NewReader("path,xvalue\na,b\n"), ddl.ImportOptions{
+			Format: ddl.StreamCSV,
+		})
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+	})
+}
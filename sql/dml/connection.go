@@ -56,6 +56,10 @@ type connCommon struct {
 	makeUniqueID uniqueIDFn
 	mapTableName func(oldName string) (newName string)
 	runOnClose   []ConnPoolOption
+	// stmtCacheSize maximum number of cached prepared statements. Zero
+	// disables the cache.
+	stmtCacheSize int
+	stmtCache     *stmtCache
 }
 
 // ConnPool at a connection to the database with an EventReceiver to send
@@ -366,6 +370,11 @@ func (c *ConnPool) Close() error {
 			return errors.WithStack(err)
 		}
 	}
+	if c.stmtCache != nil {
+		if err := c.stmtCache.close(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
 	return c.DB.Close() // no stack wrap otherwise error is hard to compare
 }
 
@@ -472,15 +481,20 @@ func (c *ConnPool) Conn(ctx context.Context) (*Conn, error) {
 	if l != nil {
 		l = c.Log.With(log.String("conn_id", c.makeUniqueID()))
 	}
-	return &Conn{
+	cc := &Conn{
 		connCommon: connCommon{
-			start:        now(),
-			Log:          l,
-			makeUniqueID: c.makeUniqueID,
-			mapTableName: c.mapTableName,
+			start:         now(),
+			Log:           l,
+			makeUniqueID:  c.makeUniqueID,
+			mapTableName:  c.mapTableName,
+			stmtCacheSize: c.stmtCacheSize,
 		},
 		DB: dbc,
-	}, errors.WithStack(err)
+	}
+	if err == nil && c.stmtCacheSize > 0 {
+		cc.stmtCache = newStmtCache(dbc, c.stmtCacheSize, l)
+	}
+	return cc, errors.WithStack(err)
 }
 
 // WithRawSQL creates a new Artisan for the given SQL string.
@@ -580,6 +594,11 @@ func (c *Conn) Close() error {
 	if c.Log != nil && c.Log.IsDebug() {
 		defer c.Log.Debug("Close", log.Duration("duration", now().Sub(c.start)))
 	}
+	if c.stmtCache != nil {
+		if err := c.stmtCache.close(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
 	return c.DB.Close() // no stack wrap otherwise error is hard to compare
 }
 
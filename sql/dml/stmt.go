@@ -69,6 +69,9 @@ func (sw stmtWrapper) QueryRowContext(ctx context.Context, sql string, args ...i
 type Stmt struct {
 	base builderCommon
 	Stmt *sql.Stmt
+	// cached gets set when the Stmt has been created by the prepared statement
+	// cache. The cache owns the statement.
+	cached *cachedStmt
 }
 
 // WithArgs creates a new argument handler.
@@ -80,8 +83,17 @@ func (st *Stmt) WithArgs() *Artisan {
 		isPrepared: true,
 	}
 	a.base.DB = stmtWrapper{stmt: st.Stmt}
+	if st.cached != nil {
+		a.base.DB = stmtWrapper{stmt: st.cached}
+	}
 	return a
 }
 
-// Close closes the statement in the database and frees its resources.
-func (st *Stmt) Close() error { return st.Stmt.Close() }
+// Close closes the statement in the database and frees its resources. A
+// statement retrieved from the prepared statement cache gets not closed.
+func (st *Stmt) Close() error {
+	if st.cached != nil {
+		return nil
+	}
+	return st.Stmt.Close()
+}
@@ -0,0 +1,340 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dml
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/corestoreio/errors"
+	"github.com/corestoreio/log"
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/storage/lru"
+)
+
+// WithPreparedStmtCache enables the LRU cache for prepared statements with a
+// maximum of `maxEntries` statements. The least recently used statement gets
+// closed when the cache is full. Each Conn created from the ConnPool gets its
+// own cache with the same size, because prepared statements are bound to a
+// database session. All cached statements get closed when calling Close on
+// the ConnPool or Conn. Sort Order 30.
+func WithPreparedStmtCache(maxEntries int) ConnPoolOption {
+	return ConnPoolOption{
+		sortOrder: 30,
+		fn: func(c *ConnPool) error {
+			if maxEntries < 1 {
+				return errors.NotValid.Newf("[dml] WithPreparedStmtCache: maxEntries must be greater than zero, have %d", maxEntries)
+			}
+			c.stmtCacheSize = maxEntries
+			c.stmtCache = newStmtCache(c.DB, maxEntries, c.Log)
+			return nil
+		},
+	}
+}
+
+// StmtCacheStats contains the statistics of the prepared statement cache.
+type StmtCacheStats struct {
+	// Len current number of cached prepared statements.
+	Len int
+	// Hits number of successful cache lookups.
+	Hits uint64
+	// Misses number of lookups which required a prepare.
+	Misses uint64
+	// Evictions number of statements closed because the cache was full.
+	Evictions uint64
+	// Reprepares number of statements prepared again after a lost connection
+	// or a closed statement.
+	Reprepares uint64
+}
+
+// stmtCache caches prepared statements keyed by their SQL string.
+type stmtCache struct {
+	db  Preparer
+	log log.Logger
+	// mu protects the miss path, so a SQL string gets only prepared once.
+	mu  sync.Mutex
+	lru *lru.Cache
+
+	hits       uint64
+	misses     uint64
+	evictions  uint64
+	reprepares uint64
+	closed     int32
+}
+
+func newStmtCache(db Preparer, maxEntries int, l log.Logger) *stmtCache {
+	sc := &stmtCache{
+		db:  db,
+		log: l,
+		lru: lru.New(maxEntries),
+	}
+	sc.lru.OnEvicted = func(_ lru.Key, value interface{}) {
+		cs := value.(*cachedStmt)
+		if atomic.LoadInt32(&sc.closed) == 0 {
+			atomic.AddUint64(&sc.evictions, 1)
+		}
+		if err := cs.evict(); err != nil && sc.log != nil && sc.log.IsInfo() {
+			sc.log.Info("stmtCache.OnEvicted.Close", log.Err(err), log.String("sql", cs.query))
+		}
+	}
+	return sc
+}
+
+// get returns the cached statement for the SQL string or prepares it.
+func (sc *stmtCache) get(ctx context.Context, query string) (*cachedStmt, error) {
+	if atomic.LoadInt32(&sc.closed) == 1 {
+		return nil, errors.AlreadyClosed.Newf("[dml] Prepared statement cache already closed")
+	}
+	if v, ok := sc.lru.Get(query); ok {
+		atomic.AddUint64(&sc.hits, 1)
+		return v.(*cachedStmt), nil
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if v, ok := sc.lru.Get(query); ok { // double check, another goroutine might have been faster
+		atomic.AddUint64(&sc.hits, 1)
+		return v.(*cachedStmt), nil
+	}
+	atomic.AddUint64(&sc.misses, 1)
+	stmt, err := sc.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[dml] stmtCache.PrepareContext with query %q", query)
+	}
+	cs := &cachedStmt{cache: sc, query: query, stmt: stmt}
+	sc.lru.Add(query, cs)
+	return cs, nil
+}
+
+// stmt returns a Stmt for a query builder. The Stmt uses the cached prepared
+// statement.
+func (sc *stmtCache) stmt(ctx context.Context, qb QueryBuilder) (*Stmt, error) {
+	var bb *BuilderBase
+	var source rune
+	switch b := qb.(type) {
+	case *Select:
+		bb, source = &b.BuilderBase, dmlSourceSelect
+	case *Insert:
+		bb, source = &b.BuilderBase, dmlSourceInsert
+	case *Update:
+		bb, source = &b.BuilderBase, dmlSourceUpdate
+	case *Delete:
+		bb, source = &b.BuilderBase, dmlSourceDelete
+	case *Union:
+		bb, source = &b.BuilderBase, dmlSourceUnion
+	case *With:
+		bb, source = &b.BuilderBase, dmlSourceWith
+	case *Show:
+		bb, source = &b.BuilderBase, dmlSourceShow
+	}
+
+	var base builderCommon
+	var rawQuery []byte
+	if bb != nil {
+		var err error
+		if rawQuery, err = bb.buildToSQL(qb.(queryBuilder)); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		base = bb.builderCommon
+	} else {
+		sqlStr, _, err := qb.ToSQL()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		rawQuery = []byte(sqlStr)
+		base.Log = sc.log
+	}
+
+	cs, err := sc.get(ctx, string(rawQuery))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	stmt := &Stmt{
+		base:   base,
+		Stmt:   cs.current(),
+		cached: cs,
+	}
+	stmt.base.cachedSQL = rawQuery
+	stmt.base.DB = stmtWrapper{stmt: cs}
+	stmt.base.source = source
+	return stmt, nil
+}
+
+func (sc *stmtCache) stats() StmtCacheStats {
+	return StmtCacheStats{
+		Len:        sc.lru.Len(),
+		Hits:       atomic.LoadUint64(&sc.hits),
+		Misses:     atomic.LoadUint64(&sc.misses),
+		Evictions:  atomic.LoadUint64(&sc.evictions),
+		Reprepares: atomic.LoadUint64(&sc.reprepares),
+	}
+}
+
+// close closes all cached statements. Subsequent calls to get return an
+// error.
+func (sc *stmtCache) close() error {
+	if !atomic.CompareAndSwapInt32(&sc.closed, 0, 1) {
+		return nil
+	}
+	sc.mu.Lock()
+	sc.lru.Clear()
+	sc.mu.Unlock()
+	return nil
+}
+
+// cachedStmt wraps a *sql.Stmt which can be replaced when the statement got
+// lost.
+type cachedStmt struct {
+	cache *stmtCache
+	query string
+
+	mu      sync.RWMutex
+	stmt    *sql.Stmt
+	evicted bool
+}
+
+func (cs *cachedStmt) current() *sql.Stmt {
+	cs.mu.RLock()
+	st := cs.stmt
+	cs.mu.RUnlock()
+	return st
+}
+
+func (cs *cachedStmt) evict() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.evicted = true
+	return cs.stmt.Close()
+}
+
+// reprepare creates a new prepared statement if `old` is still the current
+// one. An evicted statement gets requested again from the cache, to not leak
+// statements which are not owned by the cache.
+func (cs *cachedStmt) reprepare(ctx context.Context, old *sql.Stmt) (*sql.Stmt, error) {
+	cs.mu.Lock()
+	if cs.evicted {
+		cs.mu.Unlock()
+		ncs, err := cs.cache.get(ctx, cs.query)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return ncs.current(), nil
+	}
+	defer cs.mu.Unlock()
+	if cs.stmt != old {
+		return cs.stmt, nil // another goroutine has been faster
+	}
+	stmt, err := cs.cache.db.PrepareContext(ctx, cs.query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[dml] stmtCache.Reprepare with query %q", cs.query)
+	}
+	atomic.AddUint64(&cs.cache.reprepares, 1)
+	_ = old.Close() // the old statement is already unusable
+	cs.stmt = stmt
+	return stmt, nil
+}
+
+// isStmtLost reports whether the error has been caused by a closed statement
+// or a lost connection, so that preparing the statement again might help.
+func isStmtLost(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case err == driver.ErrBadConn, err == mysql.ErrInvalidConn:
+		return true
+	}
+	return strings.Contains(err.Error(), "statement is closed")
+}
+
+func (cs *cachedStmt) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	st := cs.current()
+	res, err := st.ExecContext(ctx, args...)
+	if isStmtLost(err) {
+		if st, err = cs.reprepare(ctx, st); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return st.ExecContext(ctx, args...)
+	}
+	return res, err
+}
+
+func (cs *cachedStmt) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	st := cs.current()
+	rows, err := st.QueryContext(ctx, args...)
+	if isStmtLost(err) {
+		if st, err = cs.reprepare(ctx, st); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return st.QueryContext(ctx, args...)
+	}
+	return rows, err
+}
+
+// QueryRowContext cannot detect a lost statement because *sql.Row defers the
+// error to Scan.
+func (cs *cachedStmt) QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row {
+	return cs.current().QueryRowContext(ctx, args...)
+}
+
+// PrepareCached returns a prepared statement for the query builder from the
+// LRU cache or prepares and caches it. The generated SQL string gets used as
+// cache key. The cache must be enabled with option WithPreparedStmtCache. The
+// returned Stmt is owned by the cache, calling Close on it does nothing. It
+// gets transparently prepared again after a lost connection or after it has
+// been evicted from the cache.
+func (c *ConnPool) PrepareCached(ctx context.Context, qb QueryBuilder) (*Stmt, error) {
+	if c.stmtCache == nil {
+		return nil, errors.NotSupported.Newf("[dml] ConnPool.PrepareCached: Prepared statement cache not enabled. Please use option WithPreparedStmtCache.")
+	}
+	return c.stmtCache.stmt(ctx, qb)
+}
+
+// StmtCacheStats returns the statistics of the prepared statement cache. The
+// zero value gets returned if the cache has not been enabled.
+func (c *ConnPool) StmtCacheStats() StmtCacheStats {
+	if c.stmtCache == nil {
+		return StmtCacheStats{}
+	}
+	return c.stmtCache.stats()
+}
+
+// PrepareCached returns a prepared statement for the query builder from the
+// LRU cache of the current connection or prepares and caches it. See
+// ConnPool.PrepareCached for more details.
+func (c *Conn) PrepareCached(ctx context.Context, qb QueryBuilder) (*Stmt, error) {
+	if c.stmtCache == nil {
+		return nil, errors.NotSupported.Newf("[dml] Conn.PrepareCached: Prepared statement cache not enabled. Please use option WithPreparedStmtCache.")
+	}
+	return c.stmtCache.stmt(ctx, qb)
+}
+
+// StmtCacheStats returns the statistics of the prepared statement cache of
+// the current connection.
+func (c *Conn) StmtCacheStats() StmtCacheStats {
+	if c.stmtCache == nil {
+		return StmtCacheStats{}
+	}
+	return c.stmtCache.stats()
+}
@@ -0,0 +1,98 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dml_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/sql/dml"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/sql/dmltest"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+func TestConnPool_PrepareCached(t *testing.T) {
+	t.Parallel()
+
+	t.Run("not enabled", func(t *testing.T) {
+		dbc, dbMock := dmltest.MockDB(t)
+		defer dmltest.MockClose(t, dbc, dbMock)
+
+		stmt, err := dbc.PrepareCached(context.TODO(), dbc.SelectFrom("core_config_data").AddColumns("value"))
+		assert.Nil(t, stmt)
+		assert.True(t, errors.NotSupported.Match(err), "%+v", err)
+		assert.Exactly(t, dml.StmtCacheStats{}, dbc.StmtCacheStats())
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		dbc, dbMock := dmltest.MockDB(t)
+		defer dmltest.MockClose(t, dbc, dbMock)
+		err := dbc.Options(dml.WithPreparedStmtCache(0))
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+
+	t.Run("hit and miss", func(t *testing.T) {
+		dbc, dbMock := dmltest.MockDB(t, dml.WithPreparedStmtCache(5))
+		defer dmltest.MockClose(t, dbc, dbMock)
+
+		prep := dbMock.ExpectPrepare(dmltest.SQLMockQuoteMeta("SELECT `value` FROM `core_config_data` WHERE (`path` = ?)")).WillBeClosed()
+		prep.ExpectQuery().WithArgs("web/url").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("a"))
+		prep.ExpectQuery().WithArgs("web/cookie").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("b"))
+
+		for _, path := range []string{"web/url", "web/cookie"} {
+			stmt, err := dbc.PrepareCached(context.TODO(),
+				dbc.SelectFrom("core_config_data").AddColumns("value").Where(dml.Column("path").PlaceHolder()),
+			)
+			assert.NoError(t, err)
+			_, found, err := stmt.WithArgs().String(path).LoadNullString(context.TODO())
+			assert.NoError(t, err)
+			assert.True(t, found)
+			assert.NoError(t, stmt.Close(), "Close must not close the cached statement")
+		}
+		assert.Exactly(t, dml.StmtCacheStats{Len: 1, Hits: 1, Misses: 1}, dbc.StmtCacheStats())
+	})
+
+	t.Run("eviction and reprepare", func(t *testing.T) {
+		dbc, dbMock := dmltest.MockDB(t, dml.WithPreparedStmtCache(1))
+		defer dmltest.MockClose(t, dbc, dbMock)
+
+		dbMock.ExpectPrepare(dmltest.SQLMockQuoteMeta("DELETE FROM `a` WHERE (`id` = ?)")).WillBeClosed()
+		dbMock.ExpectPrepare(dmltest.SQLMockQuoteMeta("DELETE FROM `b` WHERE (`id` = ?)")).WillBeClosed()
+		prepA2 := dbMock.ExpectPrepare(dmltest.SQLMockQuoteMeta("DELETE FROM `a` WHERE (`id` = ?)")).WillBeClosed()
+		prepA2.ExpectExec().WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		stmtA, err := dbc.PrepareCached(context.TODO(), dbc.DeleteFrom("a").Where(dml.Column("id").PlaceHolder()))
+		assert.NoError(t, err)
+		_, err = dbc.PrepareCached(context.TODO(), dbc.DeleteFrom("b").Where(dml.Column("id").PlaceHolder()))
+		assert.NoError(t, err)
+
+		// stmtA has been evicted and closed, so it must be prepared again.
+		res, err := stmtA.WithArgs().Int(3).ExecContext(context.TODO())
+		assert.NoError(t, err)
+		ra, err := res.RowsAffected()
+		assert.NoError(t, err)
+		assert.Exactly(t, int64(1), ra)
+
+		assert.Exactly(t, dml.StmtCacheStats{Len: 1, Misses: 3, Evictions: 2}, dbc.StmtCacheStats())
+	})
+}
@@ -0,0 +1,70 @@
+// grpcDefaultPageSize default maximum number of rows returned by the List
+// method of a gRPC server. Auto generated.
+const grpcDefaultPageSize = 100
+
+// grpcFieldMaskColumns returns the column names of the field mask or all
+// columns if the field mask is empty. Auto generated.
+func grpcFieldMaskColumns(tbl *ddl.Table, fm *types.FieldMask) ([]string, error) {
+	if fm == nil || len(fm.Paths) == 0 {
+		return tbl.Columns.FieldNames(), nil
+	}
+	for _, p := range fm.Paths {
+		if !tbl.Columns.Contains(p) {
+			return nil, errors.NotFound.Newf("[{{.Package}}] Column %q of the field mask not found in table %q", p, tbl.Name)
+		}
+	}
+	return fm.Paths, nil
+}
+
+// grpcPagination returns the offset from the page token and the page size
+// limited by maxPageSize. Auto generated.
+func grpcPagination(pageToken string, pageSize, maxPageSize uint64) (offset, size uint64, err error) {
+	if pageToken != "" {
+		if offset, err = strconv.ParseUint(pageToken, 10, 64); err != nil {
+			return 0, 0, errors.NotValid.New(err, "[{{.Package}}] Invalid page token %q", pageToken)
+		}
+	}
+	if maxPageSize == 0 {
+		maxPageSize = grpcDefaultPageSize
+	}
+	size = pageSize
+	if size == 0 || size > maxPageSize {
+		size = maxPageSize
+	}
+	return offset, size, nil
+}
+
+func grpcContains(sl []string, s string) bool {
+	for _, v := range sl {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// grpcError converts an error into a gRPC status error. Auto generated.
+func grpcError(err error) error {
+	var c codes.Code
+	switch {
+	case err == nil:
+		return nil
+	case err == context.Canceled:
+		c = codes.Canceled
+	case err == context.DeadlineExceeded:
+		c = codes.DeadlineExceeded
+	case errors.NotFound.Match(err):
+		c = codes.NotFound
+	case errors.NotValid.Match(err), errors.Empty.Match(err):
+		c = codes.InvalidArgument
+	case errors.AlreadyExists.Match(err):
+		c = codes.AlreadyExists
+	case errors.NotSupported.Match(err), errors.NotImplemented.Match(err):
+		c = codes.Unimplemented
+	case errors.NotAllowed.Match(err), errors.Unauthorized.Match(err):
+		c = codes.PermissionDenied
+	default:
+		c = codes.Internal
+	}
+	return status.Error(c, err.Error())
+}
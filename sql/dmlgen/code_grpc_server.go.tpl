@@ -0,0 +1,140 @@
+{{- $pk := index .Columns.PrimaryKeys 0 -}}
+// {{.Entity}}Server implements the gRPC interface {{.Entity}}ServiceServer for
+// the DB table `{{.TableName}}`. Auto generated.
+type {{.Entity}}Server struct {
+	// Tables must contain the table `{{.TableName}}` with a database connection.
+	Tables *ddl.Tables
+	// MaxPageSize limits the number of rows returned by List.
+	MaxPageSize uint64
+}
+
+// New{{.Entity}}Server creates a new gRPC server for the table
+// `{{.TableName}}`. Auto generated.
+func New{{.Entity}}Server(tbls *ddl.Tables) *{{.Entity}}Server {
+	return &{{.Entity}}Server{
+		Tables:      tbls,
+		MaxPageSize: grpcDefaultPageSize,
+	}
+}
+
+func (s *{{.Entity}}Server) table() (*ddl.Table, error) {
+	return s.Tables.Table("{{.TableName}}")
+}
+
+// Get loads a row by its primary key. Auto generated.
+func (s *{{.Entity}}Server) Get(ctx context.Context, req *Get{{.Entity}}Request) (*{{.Entity}}, error) {
+	tbl, err := s.table()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	cols, err := grpcFieldMaskColumns(tbl, req.FieldMask)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	e := New{{.Entity}}()
+	rowCount, err := tbl.Select(cols...).Where(dml.Column("{{$pk.Field}}").Equal().PlaceHolder()).
+		WithArgs().{{GoFunc $pk}}(req.{{ToGoCamelCase $pk.Field}}).Load(ctx, e)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if rowCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "[{{.Package}}] {{.Entity}} %v not found", req.{{ToGoCamelCase $pk.Field}})
+	}
+	return e, nil
+}
+
+// List loads a page of rows ordered by the primary key. Auto generated.
+func (s *{{.Entity}}Server) List(ctx context.Context, req *List{{.Entity}}Request) (*List{{.Entity}}Response, error) {
+	tbl, err := s.table()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	cols, err := grpcFieldMaskColumns(tbl, req.FieldMask)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	offset, pageSize, err := grpcPagination(req.PageToken, req.PageSize, s.MaxPageSize)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &List{{.Entity}}Response{
+		Data: make([]*{{.Entity}}, 0, pageSize),
+	}
+	// Loads one more row to detect the last page.
+	err = tbl.Select(cols...).OrderBy("{{$pk.Field}}").Limit(offset, pageSize+1).WithArgs().
+		IterateSerial(ctx, func(cm *dml.ColumnMap) error {
+			e := New{{.Entity}}()
+			if err := e.MapColumns(cm); err != nil {
+				return errors.WithStack(err)
+			}
+			resp.Data = append(resp.Data, e)
+			return nil
+		})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if uint64(len(resp.Data)) > pageSize {
+		resp.Data = resp.Data[:pageSize]
+		resp.NextPageToken = strconv.FormatUint(offset+pageSize, 10)
+	}
+	return resp, nil
+}
+
+// Upsert inserts a row or updates the columns of the update mask of an
+// existing row. Returns the stored row. Auto generated.
+func (s *{{.Entity}}Server) Upsert(ctx context.Context, req *Upsert{{.Entity}}Request) (*{{.Entity}}, error) {
+	if req.Data == nil {
+		return nil, status.Error(codes.InvalidArgument, "[{{.Package}}] {{.Entity}} Upsert: Data cannot be empty")
+	}
+	tbl, err := s.table()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	updCols, err := grpcFieldMaskColumns(tbl, req.UpdateMask)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	excluded := []string{"{{$pk.Field}}"}
+	for _, c := range tbl.Columns.NonPrimaryColumns().FieldNames() {
+		if !grpcContains(updCols, c) {
+			excluded = append(excluded, c)
+		}
+	}
+	if len(excluded) == len(tbl.Columns) {
+		return nil, status.Error(codes.InvalidArgument, "[{{.Package}}] {{.Entity}} Upsert: update_mask must contain at least one non primary key column")
+	}
+
+	ins := dml.NewInsert(tbl.Name).AddColumns(tbl.Columns.FieldNames()...).WithDB(tbl.DB)
+	ins.Listeners = ins.Listeners.Merge(tbl.Listeners.Insert)
+	ins.OnDuplicateKey().AddOnDuplicateKeyExclude(excluded...)
+
+	pk := req.Data.{{ToGoCamelCase $pk.Field}}
+	if _, err := ins.WithArgs().Record("", req.Data).ExecContext(ctx); err != nil {
+		return nil, grpcError(err)
+	}
+	var zero {{GoType $pk}}
+	if pk != zero {
+		// An update does not return a reliable last insert ID.
+		req.Data.{{ToGoCamelCase $pk.Field}} = pk
+	}
+	return s.Get(ctx, &Get{{.Entity}}Request{ {{- ToGoCamelCase $pk.Field}}: req.Data.{{ToGoCamelCase $pk.Field -}} })
+}
+
+// Delete deletes a row by its primary key. Auto generated.
+func (s *{{.Entity}}Server) Delete(ctx context.Context, req *Delete{{.Entity}}Request) (*types.Empty, error) {
+	tbl, err := s.table()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	del := dml.NewDelete(tbl.Name).Where(dml.Column("{{$pk.Field}}").Equal().PlaceHolder()).WithDB(tbl.DB)
+	del.Listeners = del.Listeners.Merge(tbl.Listeners.Delete)
+	res, err := del.WithArgs().{{GoFunc $pk}}(req.{{ToGoCamelCase $pk.Field}}).ExecContext(ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, status.Errorf(codes.NotFound, "[{{.Package}}] {{.Entity}} %v not found", req.{{ToGoCamelCase $pk.Field}})
+	}
+	return &types.Empty{}, nil
+}
@@ -0,0 +1,48 @@
+{{- $pk := index .Columns.PrimaryKeys 0 -}}
+// {{.Entity}}Service exposes the DB table `{{.TableName}}`. Auto generated.
+service {{.Entity}}Service {
+	rpc Get (Get{{.Entity}}Request) returns ({{.Entity}});
+	rpc List (List{{.Entity}}Request) returns (List{{.Entity}}Response);
+	rpc Upsert (Upsert{{.Entity}}Request) returns ({{.Entity}});
+	rpc Delete (Delete{{.Entity}}Request) returns (google.protobuf.Empty);
+}
+
+// Get{{.Entity}}Request loads a row by its primary key. An empty field_mask
+// loads all columns. Auto generated.
+message Get{{.Entity}}Request {
+	option (gogoproto.typedecl) = true;
+	{{ProtoType $pk}} {{$pk.Field}} = 1 [(gogoproto.customname)="{{ToGoCamelCase $pk.Field}}" {{- ProtoCustomType $pk}}];
+	google.protobuf.FieldMask field_mask = 2;
+}
+
+// List{{.Entity}}Request loads a page of rows ordered by the primary key. The
+// page_token must be empty or contain the next_page_token of the previous
+// response. Auto generated.
+message List{{.Entity}}Request {
+	option (gogoproto.typedecl) = true;
+	uint64 page_size = 1;
+	string page_token = 2;
+	google.protobuf.FieldMask field_mask = 3;
+}
+
+// List{{.Entity}}Response contains a page of rows. An empty next_page_token
+// signals the last page. Auto generated.
+message List{{.Entity}}Response {
+	option (gogoproto.typedecl) = true;
+	repeated {{.Entity}} Data = 1;
+	string next_page_token = 2;
+}
+
+// Upsert{{.Entity}}Request inserts or updates a row. An empty update_mask
+// updates all columns of an existing row. Auto generated.
+message Upsert{{.Entity}}Request {
+	option (gogoproto.typedecl) = true;
+	{{.Entity}} Data = 1;
+	google.protobuf.FieldMask update_mask = 2;
+}
+
+// Delete{{.Entity}}Request deletes a row by its primary key. Auto generated.
+message Delete{{.Entity}}Request {
+	option (gogoproto.typedecl) = true;
+	{{ProtoType $pk}} {{$pk.Field}} = 1 [(gogoproto.customname)="{{ToGoCamelCase $pk.Field}}" {{- ProtoCustomType $pk}}];
+}
@@ -63,6 +63,7 @@ type Tables struct {
 	// goTpl contains a parsed template to render a single table.
 	tpls       *template.Template
 	writeProto bool
+	writeGRPC  bool
 	lastError  error
 }
 
@@ -107,7 +108,13 @@ type TableOption struct {
 	// but should have a dedicated function to extract their unique primitive
 	// values as a slice.
 	UniquifiedColumns []string
-	lastErr           error
+	// GRPCService generates a gRPC service with the methods Get, List, Upsert
+	// and Delete into the proto file and its server implementation, backed by
+	// the generated types, into the Go file. Field masks select the columns
+	// for reading and updating. Requires the protobuf encoder and exactly one
+	// primary key column.
+	GRPCService bool
+	lastErr     error
 }
 
 func (to *TableOption) applyEncoders(ts *Tables, t *table) {
@@ -198,6 +205,31 @@ func (to *TableOption) applyCustomStructTags(t *table) {
 	}
 }
 
+func (to *TableOption) applyGRPCService(ts *Tables, t *table) {
+	if !to.GRPCService || to.lastErr != nil {
+		return
+	}
+	if !t.Protobuf {
+		to.lastErr = errors.NotValid.Newf("[dmlgen] WithTableOption:GRPCService: Table %q requires the protobuf encoder", t.TableName)
+		return
+	}
+	if pks := t.Columns.PrimaryKeys(); len(pks) != 1 {
+		to.lastErr = errors.NotSupported.Newf("[dmlgen] WithTableOption:GRPCService: Table %q must have exactly one primary key column, have %v", t.TableName, pks.FieldNames())
+		return
+	}
+	t.GRPCService = true
+	if !ts.writeGRPC {
+		ts.writeGRPC = true
+		ts.ImportPaths = append(ts.ImportPaths,
+			"context",
+			"strconv",
+			"github.com/gogo/protobuf/types",
+			"google.golang.org/grpc/codes",
+			"google.golang.org/grpc/status",
+		)
+	}
+}
+
 func (to *TableOption) applyComments(t *table) {
 	var buf strings.Builder
 	lines := strings.Split(to.Comment, "\n")
@@ -267,6 +299,7 @@ func WithTableOption(tableName string, opt *TableOption) (o Option) {
 			return errors.NotFound.Newf("[dmlgen] WithTableOption: Table %q not found.", tableName)
 		}
 		opt.applyEncoders(ts, t)
+		opt.applyGRPCService(ts, t)
 		opt.applyStructTags(t)
 		opt.applyCustomStructTags(t)
 		opt.applyComments(t)
@@ -293,30 +326,56 @@ func WithColumnAliasesFromForeignKeys(ctx context.Context, db dml.Querier) (opt
 		if err != nil {
 			return errors.WithStack(err)
 		}
-		for tblPkCol, kcuc := range tblFks {
-			// tblPkCol == REFERENCED_TABLE_NAME.REFERENCED_COLUMN_NAME
-			// REFERENCED_TABLE_NAME is contained in sortedTableNames()
-			dotPos := strings.IndexByte(tblPkCol, '.')
-			refTable := tblPkCol[:dotPos]
-			refColumn := tblPkCol[dotPos+1:]
-
-			t := ts.Tables[refTable]
-			for _, c := range t.Columns {
-				// TODO: optimize this and rethink method receivers like Each, on the collection.
-				if c.Field == refColumn {
-					unique := map[string]bool{refColumn: true} // refColumn already seen because field name
-					for _, kcu := range kcuc.Data {
-						if kcu.ReferencedColumnName.String == refColumn && !unique[kcu.ColumnName] {
-							c.Aliases = append(c.Aliases, kcu.ColumnName)
-							unique[kcu.ColumnName] = true
-						}
+		ts.applyForeignKeyAliases(tblFks)
+		return nil
+	}
+	return
+}
+
+// WithColumnAliasesFromCreateTableFiles same as
+// WithColumnAliasesFromForeignKeys but parses the foreign keys from the CREATE
+// TABLE statements in the provided SQL files. No database connection is
+// required. The files can contain more tables than the ones used for code
+// generation.
+func WithColumnAliasesFromCreateTableFiles(files ...string) (opt Option) {
+	opt.sortOrder = 200 // must run at the end or where the end is near ;-)
+	opt.fn = func(ts *Tables) error {
+		_, tblFks, err := ddl.ParseCreateTablesFromFiles(files...)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		ts.applyForeignKeyAliases(tblFks)
+		return nil
+	}
+	return
+}
+
+// applyForeignKeyAliases uses the column names of the foreign keys as aliases
+// for the referenced columns. Map key of tblFks is
+// REFERENCED_TABLE_NAME.REFERENCED_COLUMN_NAME.
+func (ts *Tables) applyForeignKeyAliases(tblFks map[string]ddl.KeyColumnUsageCollection) {
+	for tblPkCol, kcuc := range tblFks {
+		dotPos := strings.IndexByte(tblPkCol, '.')
+		refTable := tblPkCol[:dotPos]
+		refColumn := tblPkCol[dotPos+1:]
+
+		t, ok := ts.Tables[refTable]
+		if !ok {
+			continue // REFERENCED_TABLE_NAME not used for code generation
+		}
+		for _, c := range t.Columns {
+			// TODO: optimize this and rethink method receivers like Each, on the collection.
+			if c.Field == refColumn {
+				unique := map[string]bool{refColumn: true} // refColumn already seen because field name
+				for _, kcu := range kcuc.Data {
+					if kcu.ReferencedColumnName.String == refColumn && !unique[kcu.ColumnName] {
+						c.Aliases = append(c.Aliases, kcu.ColumnName)
+						unique[kcu.ColumnName] = true
 					}
 				}
 			}
 		}
-		return nil
 	}
-	return
 }
 
 // WithTable sets a table and its columns. Allows to overwrite a table fetched
@@ -353,6 +412,38 @@ func WithLoadColumns(ctx context.Context, db dml.Querier, tables ...string) (opt
 	return
 }
 
+// WithCreateTableFiles parses the CREATE TABLE statements in the provided SQL
+// files and loads the column definitions of all found tables. The columns are
+// equal to the ones loaded with WithLoadColumns, hence no database connection
+// is required. If `tables` has been provided, only those tables get loaded.
+func WithCreateTableFiles(files []string, tables ...string) (opt Option) {
+	opt.sortOrder = 1
+	opt.fn = func(ts *Tables) error {
+		tblCols, _, err := ddl.ParseCreateTablesFromFiles(files...)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		tblNames := tables
+		if len(tblNames) == 0 {
+			for tblName := range tblCols {
+				tblNames = append(tblNames, tblName)
+			}
+		}
+		for _, tblName := range tblNames {
+			cols, ok := tblCols[tblName]
+			if !ok {
+				return errors.NotFound.Newf("[dmlgen] WithCreateTableFiles: Table %q not found in files %v", tblName, files)
+			}
+			ts.Tables[tblName] = &table{
+				TableName: tblName,
+				Columns:   cols,
+			}
+		}
+		return nil
+	}
+	return
+}
+
 func (ts *Tables) sortedTableNames() []string {
 	sortedKeys := make(slices.String, 0, len(ts.Tables))
 	for k := range ts.Tables {
@@ -456,6 +547,9 @@ func (ts *Tables) WriteProto(w io.Writer) error {
 		if err := ts.tpls.Funcs(ts.FuncMap).ExecuteTemplate(buf, "code_proto_header.go.tpl", ts); err != nil {
 			return errors.WriteFailed.New(err, "[dmlgen] For file header")
 		}
+		if ts.writeGRPC {
+			buf.WriteString("import \"google/protobuf/empty.proto\";\nimport \"google/protobuf/field_mask.proto\";\n")
+		}
 	}
 
 	for _, tblName := range ts.sortedTableNames() {
@@ -463,6 +557,12 @@ func (ts *Tables) WriteProto(w io.Writer) error {
 		if err := t.writeTo(buf, ts.tpls.Lookup("code_proto.go.tpl").Funcs(ts.FuncMap)); err != nil {
 			return errors.WriteFailed.New(err, "[dmlgen] For Table %q", t.TableName)
 		}
+		if !t.GRPCService {
+			continue
+		}
+		if err := t.writeTo(buf, ts.tpls.Lookup("code_proto_service.go.tpl").Funcs(ts.FuncMap)); err != nil {
+			return errors.WriteFailed.New(err, "[dmlgen] For gRPC service of Table %q", t.TableName)
+		}
 	}
 	_, err := buf.WriteTo(w)
 	return err
@@ -501,6 +601,11 @@ func (ts *Tables) WriteGo(w io.Writer) error {
 			return errors.WriteFailed.New(err, "[dmlgen] For Tables %v", tables)
 		}
 	}
+	if ts.writeGRPC {
+		if err := ts.tpls.ExecuteTemplate(buf, "code_grpc_helper.go.tpl", ts); err != nil {
+			return errors.WriteFailed.New(err, "[dmlgen] For gRPC helper functions")
+		}
+	}
 
 	// deal with random map to guarantee the persistent code generation.
 	for _, tblname := range sortedTableNames {
@@ -517,6 +622,9 @@ func (ts *Tables) WriteGo(w io.Writer) error {
 		if t.BinaryMarshaler {
 			ts.execTpl(buf, t, "code_binary.go.tpl")
 		}
+		if t.GRPCService {
+			ts.execTpl(buf, t, "code_grpc_server.go.tpl")
+		}
 		if ts.lastError != nil {
 			return ts.lastError
 		}
@@ -554,6 +662,7 @@ type table struct {
 	TextMarshaler            bool
 	BinaryMarshaler          bool
 	Protobuf                 bool // writes the .proto file if true
+	GRPCService              bool // writes the gRPC service and server if true
 	DisableCollectionMethods bool
 }
 
@@ -574,7 +683,8 @@ func (t *table) writeTo(w io.Writer, tpl *template.Template) error {
 }
 
 // GenerateProto searches all *.proto files in the given path and calls protoc
-// to generate the Go source code.
+// to generate the Go source code. The gRPC plugin generates the client and
+// server interfaces for the services.
 func GenerateProto(path string) error {
 
 	path = filepath.Clean(path)
@@ -590,7 +700,10 @@ func GenerateProto(path string) error {
 	// To generate PHP Code replace `gogo_out` with `php_out`.
 	// Java bit similar. Java has ~15k LOC, Go ~3.7k
 	args := []string{
-		"--gogo_out", "Mgoogle/protobuf/timestamp.proto=github.com/gogo/protobuf/types:.",
+		"--gogo_out", "plugins=grpc," +
+			"Mgoogle/protobuf/timestamp.proto=github.com/gogo/protobuf/types," +
+			"Mgoogle/protobuf/field_mask.proto=github.com/gogo/protobuf/types," +
+			"Mgoogle/protobuf/empty.proto=github.com/gogo/protobuf/types:.",
 		"--proto_path", fmt.Sprintf("%s/src/:%s/src/github.com/gogo/protobuf/protobuf/:.", build.Default.GOPATH, build.Default.GOPATH),
 	}
 	args = append(args, protoFiles...)
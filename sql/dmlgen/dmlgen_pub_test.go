@@ -20,6 +20,7 @@ Sniperkit-Bot
 package dmlgen_test
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"os"
@@ -132,6 +133,139 @@ func TestNewTables(t *testing.T) {
 	require.NoError(t, dmlgen.GenerateProto("./testdata"))
 }
 
+// TestWithCreateTableFiles generates the code from a CREATE TABLE file and
+// compares it with the code generated from the information_schema.
+func TestWithCreateTableFiles(t *testing.T) {
+	t.Parallel()
+
+	t.Run("equal to information_schema", func(t *testing.T) {
+		db, mock := dmltest.MockDB(t)
+		defer dmltest.MockClose(t, db, mock)
+
+		mock.ExpectQuery("SELECT.+information_schema.COLUMNS.+").WillReturnRows(dmltest.MustMockRows(
+			dmltest.WithFile("testdata/INFORMATION_SCHEMA.COLUMNS.csv"),
+		))
+
+		tblOpt := func() dmlgen.Option {
+			return dmlgen.WithTableOption(
+				"dmlgen_types", &dmlgen.TableOption{
+					Encoders:          []string{"text", "binary", "protobuf"},
+					StructTags:        []string{"json", "protobuf"},
+					UniquifiedColumns: []string{"col_longtext_2", "col_int_1", "col_int_2", "has_smallint_5", "col_date_2", "col_blob"},
+				})
+		}
+
+		tsDB, err := dmlgen.NewTables("testdata",
+			tblOpt(),
+			dmlgen.WithLoadColumns(context.Background(), db.DB, "dmlgen_types"),
+		)
+		require.NoError(t, err)
+		tsFile, err := dmlgen.NewTables("testdata",
+			tblOpt(),
+			dmlgen.WithCreateTableFiles([]string{"testdata/table_all_types.sql"}),
+		)
+		require.NoError(t, err)
+
+		var bufDB, bufFile bytes.Buffer
+		require.NoError(t, tsDB.WriteGo(&bufDB))
+		require.NoError(t, tsFile.WriteGo(&bufFile))
+		assert.Exactly(t, bufDB.String(), bufFile.String())
+
+		bufDB.Reset()
+		bufFile.Reset()
+		require.NoError(t, tsDB.WriteProto(&bufDB))
+		require.NoError(t, tsFile.WriteProto(&bufFile))
+		assert.Exactly(t, bufDB.String(), bufFile.String())
+	})
+
+	t.Run("aliases from foreign keys", func(t *testing.T) {
+		ts, err := dmlgen.NewTables("testdata",
+			dmlgen.WithCreateTableFiles([]string{"testdata/table_foreign_keys.sql"}, "dmlgen_customer"),
+			dmlgen.WithColumnAliasesFromCreateTableFiles("testdata/table_foreign_keys.sql"),
+		)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, ts.WriteGo(&buf))
+		assert.Contains(t, buf.String(), `case "entity_id", "customer_id", "owner_id":`)
+		assert.NotContains(t, buf.String(), "DmlgenOrder")
+	})
+
+	t.Run("table not found", func(t *testing.T) {
+		ts, err := dmlgen.NewTables("testdata",
+			dmlgen.WithCreateTableFiles([]string{"testdata/table_foreign_keys.sql"}, "dmlgen_invoice"),
+		)
+		assert.Nil(t, ts)
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+	})
+}
+
+func TestWithTableOption_GRPCService(t *testing.T) {
+	t.Parallel()
+
+	coreConfigData := func() dmlgen.Option {
+		return dmlgen.WithTable("core_config_data", ddl.Columns{
+			&ddl.Column{Field: "config_id", Pos: 1, Null: "NO", DataType: "int", Precision: null.MakeInt64(10), Scale: null.MakeInt64(0), ColumnType: "int(10) unsigned", Key: "PRI", Extra: "auto_increment", Comment: "Config Id"},
+			&ddl.Column{Field: "scope", Pos: 2, Default: null.MakeString("'default'"), Null: "NO", DataType: "varchar", CharMaxLength: null.MakeInt64(8), ColumnType: "varchar(8)", Key: "MUL", Comment: "Config Scope"},
+			&ddl.Column{Field: "path", Pos: 3, Default: null.MakeString("'general'"), Null: "NO", DataType: "varchar", CharMaxLength: null.MakeInt64(255), ColumnType: "varchar(255)", Comment: "Config Path"},
+		})
+	}
+
+	t.Run("proto and Go server", func(t *testing.T) {
+		ts, err := dmlgen.NewTables("testdata",
+			coreConfigData(),
+			dmlgen.WithTableOption("core_config_data", &dmlgen.TableOption{
+				Encoders:    []string{"protobuf"},
+				GRPCService: true,
+			}),
+		)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, ts.WriteProto(&buf))
+		proto := buf.String()
+		assert.Contains(t, proto, `import "google/protobuf/field_mask.proto";`)
+		assert.Contains(t, proto, "service CoreConfigDataService {")
+		assert.Contains(t, proto, "rpc Delete (DeleteCoreConfigDataRequest) returns (google.protobuf.Empty);")
+		assert.Contains(t, proto, `uint64 config_id = 1 [(gogoproto.customname)="ConfigID"];`)
+
+		buf.Reset()
+		require.NoError(t, ts.WriteGo(&buf))
+		goSrc := buf.String()
+		assert.Contains(t, goSrc, `"google.golang.org/grpc/codes"`)
+		assert.Contains(t, goSrc, "func NewCoreConfigDataServer(tbls *ddl.Tables) *CoreConfigDataServer {")
+		assert.Contains(t, goSrc, "func (s *CoreConfigDataServer) Get(ctx context.Context, req *GetCoreConfigDataRequest) (*CoreConfigData, error) {")
+		assert.Contains(t, goSrc, "WithArgs().Uint64(req.ConfigID).Load(ctx, e)")
+		assert.Contains(t, goSrc, "func grpcFieldMaskColumns(tbl *ddl.Table, fm *types.FieldMask) ([]string, error) {")
+	})
+
+	t.Run("requires protobuf encoder", func(t *testing.T) {
+		ts, err := dmlgen.NewTables("testdata",
+			coreConfigData(),
+			dmlgen.WithTableOption("core_config_data", &dmlgen.TableOption{
+				GRPCService: true,
+			}),
+		)
+		assert.Nil(t, ts)
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+
+	t.Run("composite primary key not supported", func(t *testing.T) {
+		ts, err := dmlgen.NewTables("testdata",
+			dmlgen.WithTable("catalog_product_website", ddl.Columns{
+				&ddl.Column{Field: "product_id", Pos: 1, Null: "NO", DataType: "int", ColumnType: "int(10) unsigned", Key: "PRI"},
+				&ddl.Column{Field: "website_id", Pos: 2, Null: "NO", DataType: "smallint", ColumnType: "smallint(5) unsigned", Key: "PRI"},
+			}),
+			dmlgen.WithTableOption("catalog_product_website", &dmlgen.TableOption{
+				Encoders:    []string{"protobuf"},
+				GRPCService: true,
+			}),
+		)
+		assert.Nil(t, ts)
+		assert.True(t, errors.NotSupported.Match(err), "%+v", err)
+	})
+}
+
 func TestInfoSchemaForeignKeys(t *testing.T) {
 
 	t.Skip("One time test. Use when needed to regenerate the code")
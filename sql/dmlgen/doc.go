@@ -23,5 +23,9 @@ Sniperkit-Bot
 // To generated the protocol buffer file
 // $ protoc --gogo_out=Mgoogle/protobuf/timestamp.proto=github.com/gogo/protobuf/types:. --proto_path=/Users/kiri/GoPro/src/:/Users/kiri/GoPro/src/github.com/gogo/protobuf/protobuf/:. *.proto
 //
+// Tables with the TableOption GRPCService additionally need the gRPC plugin
+// and the mappings for the well known types empty and field_mask, see function
+// GenerateProto.
+//
 // TODO: Generate also protobuf code for https://github.com/twitchtv/twirp/wiki
 package dmlgen
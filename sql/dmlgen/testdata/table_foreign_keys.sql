@@ -0,0 +1,25 @@
+DROP TABLE IF EXISTS `dmlgen_customer`;
+CREATE TABLE `dmlgen_customer` (
+  `entity_id`  INT(10) UNSIGNED     NOT NULL AUTO_INCREMENT COMMENT 'Entity ID',
+  `email`      VARCHAR(255)                  DEFAULT NULL COMMENT 'Email',
+  `created_at` TIMESTAMP            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Created At',
+  PRIMARY KEY (`entity_id`),
+  UNIQUE KEY `DMLGEN_CUSTOMER_EMAIL` (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `dmlgen_order`;
+CREATE TABLE `dmlgen_order` (
+  `entity_id`   INT(10) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'Entity ID',
+  `customer_id` INT(10) UNSIGNED          DEFAULT NULL COMMENT 'Customer ID',
+  `grand_total` DECIMAL(20, 4)            DEFAULT NULL COMMENT 'Grand Total',
+  PRIMARY KEY (`entity_id`),
+  CONSTRAINT `DMLGEN_ORDER_CUSTOMER_ID_DMLGEN_CUSTOMER_ENTITY_ID` FOREIGN KEY (`customer_id`) REFERENCES `dmlgen_customer` (`entity_id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `dmlgen_wishlist`;
+CREATE TABLE `dmlgen_wishlist` (
+  `wishlist_id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+  `owner_id`    INT(10) UNSIGNED NOT NULL DEFAULT 0,
+  PRIMARY KEY (`wishlist_id`),
+  FOREIGN KEY (`owner_id`) REFERENCES `dmlgen_customer` (`entity_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,625 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/corestoreio/errors"
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/sql/dml"
+)
+
+// EnvRecord is the name of the environment variable which switches the
+// Recorder into record mode. Any non-empty value enables recording.
+const EnvRecord = "CS_DML_RECORD"
+
+// RecorderOption applies options to the Recorder.
+type RecorderOption func(*Recorder)
+
+// WithRecordMode forces the Recorder into record mode, regardless of the
+// environment variable EnvRecord.
+func WithRecordMode() RecorderOption {
+	return func(r *Recorder) { r.record = true }
+}
+
+// WithRecordDriver sets the parent driver and its data source name which gets
+// used in record mode. Defaults to the MySQL driver with the DSN from the
+// environment variable EnvDSN.
+func WithRecordDriver(drv driver.Driver, dsn string) RecorderOption {
+	return func(r *Recorder) {
+		r.parentDriver = drv
+		r.parentDSN = dsn
+	}
+}
+
+// WithRecordConnPoolOptions applies additional options to the ConnPool created
+// by the Recorder.
+func WithRecordConnPoolOptions(opts ...dml.ConnPoolOption) RecorderOption {
+	return func(r *Recorder) { r.poolOpts = append(r.poolOpts, opts...) }
+}
+
+// Recorder captures in record mode every SQL statement with its arguments and
+// results, which a ConnPool sends to the database, and writes them into a
+// golden file on Close. In replay mode, the default, the Recorder serves the
+// results from the golden file without any database. A recorded statement gets
+// identified by its interpolated SQL string, so it does not matter whether
+// the query has been interpolated on the client side or not.
+//
+// Use it in a test like:
+//		dbc, rec := dmltest.NewRecorder(t, filepath.Join("testdata", "my_test.golden.json"))
+//		defer dmltest.Close(t, rec)
+//
+// To refresh the golden files run the tests with the environment variables
+// EnvDSN and EnvRecord set.
+type Recorder struct {
+	// ConnPool uses the Recorder as its driver.
+	ConnPool *dml.ConnPool
+
+	goldenFile   string
+	record       bool
+	parentDriver driver.Driver
+	parentDSN    string
+	poolOpts     []dml.ConnPoolOption
+
+	mu sync.Mutex
+	// records contains in record mode the captured queries in the executed
+	// order.
+	records []*GoldenQuery
+	// replay contains in replay mode per interpolated SQL string the not yet
+	// served queries.
+	replay map[string][]*GoldenQuery
+}
+
+// NewRecorder creates a new Recorder and its ConnPool. In replay mode the
+// golden file must exist. In record mode the test gets skipped if the DSN
+// environment variable has not been set.
+func NewRecorder(t testing.TB, goldenFile string, opts ...RecorderOption) (*dml.ConnPool, *Recorder) {
+	if t != nil { // t can be nil in Example functions
+		t.Helper()
+	}
+	r := &Recorder{
+		goldenFile: goldenFile,
+		record:     os.Getenv(EnvRecord) != "",
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.record && r.parentDriver == nil {
+		r.parentDriver = mysql.MySQLDriver{}
+		r.parentDSN = MustGetDSN(t)
+	}
+	if !r.record {
+		FatalIfError(t, r.loadGolden())
+	}
+
+	dbc, err := dml.NewConnPool(append([]dml.ConnPoolOption{dml.WithDB(sql.OpenDB(r))}, r.poolOpts...)...)
+	FatalIfError(t, err)
+	r.ConnPool = dbc
+	return dbc, r
+}
+
+// IsRecording returns true if the Recorder runs in record mode.
+func (r *Recorder) IsRecording() bool { return r.record }
+
+// Close closes the ConnPool. In record mode it writes the golden file. In
+// replay mode it returns an error if some recorded queries have not been
+// requested.
+func (r *Recorder) Close() error {
+	if err := r.ConnPool.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.record {
+		data, err := json.MarshalIndent(r.records, "", "\t")
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return errors.WithStack(ioutil.WriteFile(r.goldenFile, append(data, '\n'), 0644))
+	}
+
+	var unused []string
+	for q, gqs := range r.replay {
+		for range gqs {
+			unused = append(unused, q)
+		}
+	}
+	if len(unused) > 0 {
+		return errors.Mismatch.Newf("[dmltest] Recorder: %d queries of golden file %q have not been requested: %q", len(unused), r.goldenFile, unused)
+	}
+	return nil
+}
+
+func (r *Recorder) loadGolden() error {
+	data, err := ioutil.ReadFile(r.goldenFile)
+	if err != nil {
+		return errors.NotFound.New(err, "[dmltest] Recorder: Cannot read golden file. Run the test with env var %q set to create it.", EnvRecord)
+	}
+	var gqs []*GoldenQuery
+	if err := json.Unmarshal(data, &gqs); err != nil {
+		return errors.BadEncoding.New(err, "[dmltest] Recorder: Cannot decode golden file %q", r.goldenFile)
+	}
+	r.replay = make(map[string][]*GoldenQuery, len(gqs))
+	for _, gq := range gqs {
+		r.replay[gq.Query] = append(r.replay[gq.Query], gq)
+	}
+	return nil
+}
+
+func (r *Recorder) add(gq *GoldenQuery) {
+	r.mu.Lock()
+	r.records = append(r.records, gq)
+	r.mu.Unlock()
+}
+
+// next returns the next recorded query for the interpolated SQL string.
+func (r *Recorder) next(kind, query string) (*GoldenQuery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	gqs := r.replay[query]
+	if len(gqs) == 0 {
+		return nil, errors.NotFound.Newf("[dmltest] Recorder: %s %q not found in golden file %q. Run the test with env var %q set to record it.", kind, query, r.goldenFile, EnvRecord)
+	}
+	gq := gqs[0]
+	if len(gqs) == 1 {
+		delete(r.replay, query)
+	} else {
+		r.replay[query] = gqs[1:]
+	}
+	if gq.Kind != kind {
+		return nil, errors.Mismatch.Newf("[dmltest] Recorder: Query %q has been recorded as %q but requested as %q", query, gq.Kind, kind)
+	}
+	return gq, nil
+}
+
+// Connect implements driver.Connector.
+func (r *Recorder) Connect(_ context.Context) (driver.Conn, error) {
+	rc := &recordConn{rec: r}
+	if r.record {
+		c, err := r.parentDriver.Open(r.parentDSN)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		rc.parent = c
+	}
+	return rc, nil
+}
+
+// Driver implements driver.Connector.
+func (r *Recorder) Driver() driver.Driver { return recordDriver{r} }
+
+type recordDriver struct {
+	rec *Recorder
+}
+
+func (d recordDriver) Open(_ string) (driver.Conn, error) {
+	return d.rec.Connect(context.Background())
+}
+
+// GoldenQuery defines a single recorded SQL statement including its result.
+// It gets serialized into the golden file.
+type GoldenQuery struct {
+	// Kind can be "exec" or "query".
+	Kind string `json:"kind"`
+	// Query contains the SQL string with all arguments interpolated.
+	Query        string          `json:"query"`
+	Columns      []string        `json:"columns,omitempty"`
+	Rows         [][]GoldenValue `json:"rows,omitempty"`
+	LastInsertID int64           `json:"last_insert_id,omitempty"`
+	RowsAffected int64           `json:"rows_affected,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// GoldenValue represents a driver.Value with its type, to restore the exact
+// Go type when replaying.
+type GoldenValue struct {
+	// Type can be: null, int64, float64, bool, bytes, base64, string or time.
+	Type  string `json:"t"`
+	Value string `json:"v,omitempty"`
+}
+
+func newGoldenValue(v driver.Value) (GoldenValue, error) {
+	switch vt := v.(type) {
+	case nil:
+		return GoldenValue{Type: "null"}, nil
+	case int64:
+		return GoldenValue{Type: "int64", Value: strconv.FormatInt(vt, 10)}, nil
+	case float64:
+		return GoldenValue{Type: "float64", Value: strconv.FormatFloat(vt, 'g', -1, 64)}, nil
+	case bool:
+		return GoldenValue{Type: "bool", Value: strconv.FormatBool(vt)}, nil
+	case []byte:
+		if utf8.Valid(vt) {
+			return GoldenValue{Type: "bytes", Value: string(vt)}, nil
+		}
+		return GoldenValue{Type: "base64", Value: base64.StdEncoding.EncodeToString(vt)}, nil
+	case string:
+		return GoldenValue{Type: "string", Value: vt}, nil
+	case time.Time:
+		return GoldenValue{Type: "time", Value: vt.Format(time.RFC3339Nano)}, nil
+	}
+	return GoldenValue{}, errors.NotSupported.Newf("[dmltest] Recorder: Type %T not supported", v)
+}
+
+// DriverValue returns the driver.Value with the recorded type.
+func (gv GoldenValue) DriverValue() (driver.Value, error) {
+	switch gv.Type {
+	case "null":
+		return nil, nil
+	case "int64":
+		return strconv.ParseInt(gv.Value, 10, 64)
+	case "float64":
+		return strconv.ParseFloat(gv.Value, 64)
+	case "bool":
+		return strconv.ParseBool(gv.Value)
+	case "bytes":
+		return []byte(gv.Value), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(gv.Value)
+	case "string":
+		return gv.Value, nil
+	case "time":
+		return time.Parse(time.RFC3339Nano, gv.Value)
+	}
+	return nil, errors.NotSupported.Newf("[dmltest] Recorder: Type %q not supported", gv.Type)
+}
+
+// interpolate creates the identifying SQL string for a query and its
+// arguments.
+func interpolate(query string, args []driver.NamedValue) (string, error) {
+	if len(args) == 0 {
+		return query, nil
+	}
+	ip := dml.Interpolate(query)
+	for _, a := range args {
+		switch at := a.Value.(type) {
+		case nil:
+			ip.Null()
+		case int64:
+			ip.Int64(at)
+		case float64:
+			ip.Float64(at)
+		case bool:
+			ip.Bool(at)
+		case []byte:
+			ip.Bytes(at)
+		case string:
+			ip.Str(at)
+		case time.Time:
+			ip.Time(at)
+		default:
+			ip.Unsafe(at)
+		}
+	}
+	s, _, err := ip.ToSQL()
+	return s, errors.WithStack(err)
+}
+
+// recordConn records in record mode all queries and forwards them to the
+// parent connection. In replay mode parent is nil.
+type recordConn struct {
+	rec    *Recorder
+	parent driver.Conn
+}
+
+func (c *recordConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *recordConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	rs := &recordStmt{conn: c, query: query}
+	if c.parent == nil {
+		return rs, nil
+	}
+	var err error
+	if pc, ok := c.parent.(driver.ConnPrepareContext); ok {
+		rs.parent, err = pc.PrepareContext(ctx, query)
+	} else {
+		rs.parent, err = c.parent.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+func (c *recordConn) Close() error {
+	if c.parent == nil {
+		return nil
+	}
+	return c.parent.Close()
+}
+
+func (c *recordConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recordConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.parent == nil {
+		return replayTx{}, nil
+	}
+	if pc, ok := c.parent.(driver.ConnBeginTx); ok {
+		return pc.BeginTx(ctx, opts)
+	}
+	return c.parent.Begin() // nolint: staticcheck
+}
+
+func (c *recordConn) Ping(ctx context.Context) error {
+	if p, ok := c.parent.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *recordConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.parent == nil {
+		return c.replayExec(query, args)
+	}
+	pe, ok := c.parent.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	res, err := pe.ExecContext(ctx, query, args)
+	return c.recordExec(query, args, res, err)
+}
+
+func (c *recordConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.parent == nil {
+		return c.replayQuery(query, args)
+	}
+	pq, ok := c.parent.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rows, err := pq.QueryContext(ctx, query, args)
+	return c.recordQuery(query, args, rows, err)
+}
+
+func (c *recordConn) recordExec(query string, args []driver.NamedValue, res driver.Result, err error) (driver.Result, error) {
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	iq, ipErr := interpolate(query, args)
+	if ipErr != nil {
+		return nil, ipErr
+	}
+	gq := &GoldenQuery{Kind: "exec", Query: iq}
+	if err != nil {
+		gq.Error = err.Error()
+		c.rec.add(gq)
+		return nil, err
+	}
+	// Some drivers return errors for LastInsertId on UPDATE or DELETE
+	// statements, those get ignored.
+	gq.LastInsertID, _ = res.LastInsertId()
+	gq.RowsAffected, _ = res.RowsAffected()
+	c.rec.add(gq)
+	return res, nil
+}
+
+func (c *recordConn) recordQuery(query string, args []driver.NamedValue, rows driver.Rows, err error) (driver.Rows, error) {
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	iq, ipErr := interpolate(query, args)
+	if ipErr != nil {
+		return nil, ipErr
+	}
+	gq := &GoldenQuery{Kind: "query", Query: iq}
+	if err != nil {
+		gq.Error = err.Error()
+		c.rec.add(gq)
+		return nil, err
+	}
+	defer rows.Close()
+
+	gq.Columns = rows.Columns()
+	mr := &memRows{columns: gq.Columns}
+	for {
+		dest := make([]driver.Value, len(gq.Columns))
+		if err := rows.Next(dest); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		gvs := make([]GoldenValue, len(dest))
+		for i, v := range dest {
+			if b, ok := v.([]byte); ok {
+				// The driver may reuse the byte slice in the next call to Next.
+				dest[i] = append([]byte(nil), b...)
+			}
+			gv, err := newGoldenValue(dest[i])
+			if err != nil {
+				return nil, err
+			}
+			gvs[i] = gv
+		}
+		gq.Rows = append(gq.Rows, gvs)
+		mr.rows = append(mr.rows, dest)
+	}
+	c.rec.add(gq)
+	return mr, nil
+}
+
+func (c *recordConn) replayExec(query string, args []driver.NamedValue) (driver.Result, error) {
+	iq, err := interpolate(query, args)
+	if err != nil {
+		return nil, err
+	}
+	gq, err := c.rec.next("exec", iq)
+	if err != nil {
+		return nil, err
+	}
+	if gq.Error != "" {
+		return nil, errors.New(gq.Error)
+	}
+	return replayResult{lastInsertID: gq.LastInsertID, rowsAffected: gq.RowsAffected}, nil
+}
+
+func (c *recordConn) replayQuery(query string, args []driver.NamedValue) (driver.Rows, error) {
+	iq, err := interpolate(query, args)
+	if err != nil {
+		return nil, err
+	}
+	gq, err := c.rec.next("query", iq)
+	if err != nil {
+		return nil, err
+	}
+	if gq.Error != "" {
+		return nil, errors.New(gq.Error)
+	}
+	mr := &memRows{
+		columns: gq.Columns,
+		rows:    make([][]driver.Value, len(gq.Rows)),
+	}
+	for i, gvs := range gq.Rows {
+		row := make([]driver.Value, len(gvs))
+		for j, gv := range gvs {
+			if row[j], err = gv.DriverValue(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		mr.rows[i] = row
+	}
+	return mr, nil
+}
+
+// recordStmt forwards in record mode to the parent statement. In replay mode
+// parent is nil.
+type recordStmt struct {
+	conn   *recordConn
+	parent driver.Stmt
+	query  string
+}
+
+func (s *recordStmt) Close() error {
+	if s.parent == nil {
+		return nil
+	}
+	return s.parent.Close()
+}
+
+func (s *recordStmt) NumInput() int {
+	if s.parent == nil {
+		return -1
+	}
+	return s.parent.NumInput()
+}
+
+func (s *recordStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamed(args))
+}
+
+func (s *recordStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamed(args))
+}
+
+func (s *recordStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if s.parent == nil {
+		return s.conn.replayExec(s.query, args)
+	}
+	var res driver.Result
+	var err error
+	if pe, ok := s.parent.(driver.StmtExecContext); ok {
+		res, err = pe.ExecContext(ctx, args)
+	} else {
+		res, err = s.parent.Exec(namedToValues(args)) // nolint: staticcheck
+	}
+	return s.conn.recordExec(s.query, args, res, err)
+}
+
+func (s *recordStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if s.parent == nil {
+		return s.conn.replayQuery(s.query, args)
+	}
+	var rows driver.Rows
+	var err error
+	if pq, ok := s.parent.(driver.StmtQueryContext); ok {
+		rows, err = pq.QueryContext(ctx, args)
+	} else {
+		rows, err = s.parent.Query(namedToValues(args)) // nolint: staticcheck
+	}
+	return s.conn.recordQuery(s.query, args, rows, err)
+}
+
+func valuesToNamed(args []driver.Value) []driver.NamedValue {
+	ret := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		ret[i].Ordinal = i + 1
+		ret[i].Value = a
+	}
+	return ret
+}
+
+func namedToValues(args []driver.NamedValue) []driver.Value {
+	ret := make([]driver.Value, len(args))
+	for i, a := range args {
+		ret[i] = a.Value
+	}
+	return ret
+}
+
+type replayTx struct{}
+
+func (replayTx) Commit() error   { return nil }
+func (replayTx) Rollback() error { return nil }
+
+type replayResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r replayResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r replayResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// memRows implements driver.Rows for an in-memory result set.
+type memRows struct {
+	columns []string
+	rows    [][]driver.Value
+	pos     int
+}
+
+func (m *memRows) Columns() []string { return m.columns }
+func (m *memRows) Close() error      { return nil }
+
+func (m *memRows) Next(dest []driver.Value) error {
+	if m.pos >= len(m.rows) {
+		return io.EOF
+	}
+	copy(dest, m.rows[m.pos])
+	m.pos++
+	return nil
+}
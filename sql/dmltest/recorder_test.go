@@ -0,0 +1,123 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dmltest_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/sql/dmltest"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+func TestRecorder_Replay(t *testing.T) {
+	if os.Getenv(dmltest.EnvRecord) != "" {
+		t.Skip("Golden file gets only replayed.")
+	}
+	dbc, rec := dmltest.NewRecorder(t, filepath.Join("testdata", "recorder_replay.golden.json"))
+	assert.False(t, rec.IsRecording())
+
+	ctx := context.Background()
+	rows, err := dbc.WithRawSQL("SELECT `path`, `value` FROM `core_config_data` WHERE (`scope_id` = ?)").Int(2).QueryContext(ctx)
+	assert.NoError(t, err)
+	var paths []string
+	var values []*string
+	for rows.Next() {
+		var p string
+		var v *string
+		assert.NoError(t, rows.Scan(&p, &v))
+		paths = append(paths, p)
+		values = append(values, v)
+	}
+	assert.NoError(t, rows.Close())
+	assert.Exactly(t, []string{"general/region/state_required", "web/default/front"}, paths)
+	assert.Exactly(t, "AT", *values[0])
+	assert.Nil(t, values[1])
+
+	// Interpolated on the client side results in the same golden query.
+	res, err := dbc.WithRawSQL("UPDATE `core_config_data` SET `value`=? WHERE (`config_id` = ?)").Interpolate().String("DE").Int(3).ExecContext(ctx)
+	assert.NoError(t, err)
+	ra, err := res.RowsAffected()
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(1), ra)
+
+	_, err = dbc.WithRawSQL("DELETE FROM `core_config_data`").ExecContext(ctx)
+	assert.True(t, errors.NotFound.Match(err), "%+v", err)
+
+	assert.NoError(t, rec.Close())
+}
+
+func TestRecorder_Unused(t *testing.T) {
+	_, rec := dmltest.NewRecorder(t, filepath.Join("testdata", "recorder_replay.golden.json"))
+	err := rec.Close()
+	assert.True(t, errors.Mismatch.Match(err), "%+v", err)
+}
+
+func TestRecorder_RecordAndReplay(t *testing.T) {
+	db, mock, err := sqlmock.NewWithDSN("dmltest_recorder")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	dir, err := ioutil.TempDir("", "dmltest_recorder")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	goldenFile := filepath.Join(dir, "record.golden.json")
+
+	mock.ExpectQuery(dmltest.SQLMockQuoteMeta("SELECT `config_id` FROM `core_config_data` WHERE (`path` = ?)")).
+		WithArgs("web/unsecure/base_url").
+		WillReturnRows(sqlmock.NewRows([]string{"config_id"}).
+			AddRow(int64(7)).
+			AddRow([]byte("8")))
+	mock.ExpectExec(dmltest.SQLMockQuoteMeta("INSERT INTO `core_config_data` (`path`,`value`) VALUES (?,?)")).
+		WithArgs("web/secure/base_url", "https://x.local/").
+		WillReturnResult(sqlmock.NewResult(21, 1))
+
+	ctx := context.Background()
+	run := func(t *testing.T, opts ...dmltest.RecorderOption) {
+		dbc, rec := dmltest.NewRecorder(t, goldenFile, opts...)
+		ids, err := dbc.WithRawSQL("SELECT `config_id` FROM `core_config_data` WHERE (`path` = ?)").String("web/unsecure/base_url").LoadInt64s(ctx, nil)
+		assert.NoError(t, err)
+		assert.Exactly(t, []int64{7, 8}, ids)
+
+		res, err := dbc.WithRawSQL("INSERT INTO `core_config_data` (`path`,`value`) VALUES (?,?)").String("web/secure/base_url").String("https://x.local/").ExecContext(ctx)
+		assert.NoError(t, err)
+		lid, err := res.LastInsertId()
+		assert.NoError(t, err)
+		assert.Exactly(t, int64(21), lid)
+		assert.NoError(t, rec.Close())
+	}
+
+	t.Run("record", func(t *testing.T) {
+		run(t, dmltest.WithRecordMode(), dmltest.WithRecordDriver(db.Driver(), "dmltest_recorder"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+		data, err := ioutil.ReadFile(goldenFile)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "SELECT `config_id` FROM `core_config_data` WHERE (`path` = 'web/unsecure/base_url')")
+	})
+	t.Run("replay", func(t *testing.T) {
+		run(t)
+	})
+}
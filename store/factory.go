@@ -39,6 +39,15 @@ type factory struct {
 	websites   TableWebsiteSlice
 	groups     TableGroupSlice
 	stores     TableStoreSlice
+
+	// optional resourcers to persist changes made via the mutation API of the
+	// Service.
+	twr TableWebsitesResourcer
+	tgr TableGroupsResourcer
+	tsr TableStoresResourcer
+	// beginTx optional, starts a transaction for each mutation. Replaces the
+	// resourcers above.
+	beginTx func() (TableResourcersTx, error)
 }
 
 // newFactory creates a new object which handles the raw data from the three
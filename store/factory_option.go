@@ -45,3 +45,39 @@ func WithTableStores(tss ...*TableStore) Option {
 		return nil
 	}
 }
+
+// WithTableResourcers sets the resourcers which persist the changes made via
+// the Service functions Create*, Update* and Delete*. A nil resourcer disables
+// persisting for that table.
+func WithTableResourcers(twr TableWebsitesResourcer, tgr TableGroupsResourcer, tsr TableStoresResourcer) Option {
+	return func(s *factory) error {
+		s.twr = twr
+		s.tgr = tgr
+		s.tsr = tsr
+		return nil
+	}
+}
+
+// TableResourcersTx writes the changes of one mutation within a database
+// transaction. An implementation can wrap for example a *dml.Tx and create the
+// resourcers from it.
+type TableResourcersTx interface {
+	// Resourcers returns the resourcers which write within the transaction.
+	// A nil resourcer disables persisting for that table.
+	Resourcers() (TableWebsitesResourcer, TableGroupsResourcer, TableStoresResourcer)
+	Commit() error
+	Rollback() error
+}
+
+// WithTableResourcersTx sets a function which begins a new transaction for
+// each call to the Service functions Create*, Update* and Delete*. All changes
+// of a mutation, like the cascading deletes of DeleteWebsite, get written with
+// the resourcers of the transaction, which gets committed after all changes
+// have been written and rolled back on error. If set, the resourcers of
+// WithTableResourcers get ignored for persisting.
+func WithTableResourcersTx(beginTx func() (TableResourcersTx, error)) Option {
+	return func(s *factory) error {
+		s.beginTx = beginTx
+		return nil
+	}
+}
@@ -43,4 +43,9 @@ Sniperkit-Bot
 //
 // A group scope does not make sense in the above schema but is supported by
 // other Go types in this package.
+//
+// Additional scopes, for example a customer group or a price list between
+// website and store, can be inserted into the hierarchy with RegisterType.
+// Type.Chain, Type.IsParentOf, Perm and config.Scoped follow the registered
+// hierarchy.
 package scope
@@ -0,0 +1,234 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scope
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/corestoreio/errors"
+)
+
+// The Types from firstCustomType to maxCustomType-1 can be registered with
+// RegisterType. Types 5 to 7 are reserved for future built-in types. The upper
+// limit is given by Perm, which is an uint16 bit set.
+const (
+	firstCustomType Type = 8
+	maxCustomType   Type = 16
+)
+
+type typeInfo struct {
+	name   string
+	str    TypeStr
+	bStr   []byte
+	parent Type
+	// chain contains the type itself and all its parents up to Default.
+	chain []Type
+}
+
+// hierarchy gets never modified after it has been stored in hierarchyV.
+type hierarchy struct {
+	types  [maxCustomType]typeInfo
+	next   Type
+	byStr  map[string]Type
+	byName map[string]Type
+}
+
+var (
+	hierarchyMu sync.Mutex   // protects writers of hierarchyV
+	hierarchyV  atomic.Value // *hierarchy
+)
+
+func init() {
+	h := &hierarchy{next: firstCustomType}
+	h.types[Absent] = typeInfo{name: "Absent"}
+	h.types[Default] = typeInfo{name: "Default", str: StrDefault, parent: Absent}
+	h.types[Website] = typeInfo{name: "Website", str: StrWebsites, parent: Default}
+	h.types[Group] = typeInfo{name: "Group", parent: Website}
+	h.types[Store] = typeInfo{name: "Store", str: StrStores, parent: Group}
+	h.build()
+	hierarchyV.Store(h)
+}
+
+func loadHierarchy() *hierarchy {
+	return hierarchyV.Load().(*hierarchy)
+}
+
+// build creates the lookup maps and the parent chains.
+func (h *hierarchy) build() {
+	h.byStr = make(map[string]Type, len(h.types))
+	h.byName = make(map[string]Type, len(h.types))
+	for i := range h.types {
+		ti := &h.types[i]
+		if ti.name == "" || Type(i) == Absent {
+			continue
+		}
+		h.byName[ti.name] = Type(i)
+		if ti.str != "" {
+			h.byStr[string(ti.str)] = Type(i)
+			ti.bStr = []byte(ti.str)
+		}
+		ti.chain = ti.chain[:0]
+		for t := Type(i); t != Absent; t = h.types[t].parent {
+			ti.chain = append(ti.chain, t)
+		}
+	}
+}
+
+func (h *hierarchy) clone() *hierarchy {
+	h2 := &hierarchy{
+		types: h.types,
+		next:  h.next,
+	}
+	for i := range h2.types {
+		h2.types[i].chain = nil // build allocates new chains
+	}
+	return h2
+}
+
+func (h *hierarchy) isRegistered(t Type) bool {
+	return t < maxCustomType && h.types[t].name != ""
+}
+
+// RegisterType registers a custom scope Type with its human readable name,
+// for example "CustomerGroup", and its TypeStr, for example
+// "customer_groups", used in the fully qualified configuration paths. The
+// new Type gets inserted directly below parent into the hierarchy, the
+// previous child of parent becomes the child of the new Type. Registering
+// "CustomerGroup" with parent Website results in the hierarchy:
+//		Default -> Website -> CustomerGroup -> Group -> Store
+// At most eight custom types can be registered. RegisterType should be called
+// during the initialization of a program because the hierarchy is global.
+func RegisterType(name string, str TypeStr, parent Type) (Type, error) {
+	hierarchyMu.Lock()
+	defer hierarchyMu.Unlock()
+
+	h := loadHierarchy()
+	switch {
+	case name == "" || str == "":
+		return Absent, errors.Empty.Newf("[scope] RegisterType: name %q and TypeStr %q cannot be empty", name, str)
+	case !validTypeStr(str):
+		return Absent, errors.NotValid.Newf("[scope] RegisterType: TypeStr %q can only contain the characters a-z, 0-9 and _", str)
+	case h.byName[name] > Absent || h.byStr[string(str)] > Absent:
+		return Absent, errors.AlreadyExists.Newf("[scope] RegisterType: name %q or TypeStr %q already registered", name, str)
+	case parent == Absent || !h.isRegistered(parent):
+		return Absent, errors.NotFound.Newf("[scope] RegisterType: parent %s not found", parent)
+	case h.next >= maxCustomType:
+		return Absent, errors.NotSupported.Newf("[scope] RegisterType: maximum of %d custom types reached", maxCustomType-firstCustomType)
+	}
+
+	h2 := h.clone()
+	t := h2.next
+	h2.next++
+	for i := range h2.types {
+		if h2.isRegistered(Type(i)) && h2.types[i].parent == parent {
+			h2.types[i].parent = t
+		}
+	}
+	h2.types[t] = typeInfo{name: name, str: str, parent: parent}
+	h2.build()
+	hierarchyV.Store(h2)
+	return t, nil
+}
+
+func validTypeStr(s TypeStr) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsCustom returns true if the type has been registered with RegisterType.
+func (s Type) IsCustom() bool {
+	return s >= firstCustomType && loadHierarchy().isRegistered(s)
+}
+
+// IsConfigScope returns true if values can be stored for the type in table
+// core_config_data, which applies to Default, Website, Store and the custom
+// types.
+func (s Type) IsConfigScope() bool {
+	h := loadHierarchy()
+	return h.isRegistered(s) && h.types[s].str != ""
+}
+
+// Parent returns the parent of the type in the hierarchy. Default and
+// unknown types return Absent.
+func (s Type) Parent() Type {
+	h := loadHierarchy()
+	if !h.isRegistered(s) {
+		return Absent
+	}
+	return h.types[s].parent
+}
+
+// Chain returns the type itself and all its parents up to Default, for
+// example Store, Group, Website, Default. Unknown types return nil. The
+// returned slice must not be modified.
+func (s Type) Chain() []Type {
+	h := loadHierarchy()
+	if !h.isRegistered(s) {
+		return nil
+	}
+	return h.types[s].chain
+}
+
+// IsParentOf returns true if s is a direct or indirect parent of child.
+func (s Type) IsParentOf(child Type) bool {
+	if s == child || s == Absent {
+		return false
+	}
+	for _, t := range child.Chain() {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Perm returns the permission containing the type and all its parents
+// which are configuration scopes. Store returns PermStore.
+func (s Type) Perm() Perm {
+	var p Perm
+	for _, t := range s.Chain() {
+		if t.IsConfigScope() {
+			p = p.Set(t)
+		}
+	}
+	return p
+}
+
+// configParent returns the nearest parent which is a configuration scope.
+func (s Type) configParent() Type {
+	chain := s.Chain()
+	for i := 1; i < len(chain); i++ {
+		if chain[i].IsConfigScope() {
+			return chain[i]
+		}
+	}
+	return Absent
+}
+
+// depth returns the level of the type in the hierarchy, Default has level
+// one, unknown types zero.
+func (s Type) depth() int {
+	return len(s.Chain())
+}
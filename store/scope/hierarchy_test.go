@@ -0,0 +1,160 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scope_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/corestoreio/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+var (
+	registerOnce  sync.Once
+	customerGroup scope.Type
+	priceList     scope.Type
+)
+
+// registerCustomTypes creates the hierarchy:
+// Default -> Website -> CustomerGroup -> PriceList -> Group -> Store
+func registerCustomTypes(t *testing.T) {
+	registerOnce.Do(func() {
+		var err error
+		customerGroup, err = scope.RegisterType("CustomerGroup", "customer_groups", scope.Website)
+		assert.NoError(t, err)
+		priceList, err = scope.RegisterType("PriceList", "price_lists", customerGroup)
+		assert.NoError(t, err)
+	})
+}
+
+func TestRegisterType(t *testing.T) {
+	registerCustomTypes(t)
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := scope.RegisterType("", "x", scope.Website)
+		assert.True(t, errors.Empty.Match(err), "%+v", err)
+		_, err = scope.RegisterType("X", "X-y", scope.Website)
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+		_, err = scope.RegisterType("CustomerGroup", "customer_groups2", scope.Website)
+		assert.True(t, errors.AlreadyExists.Match(err), "%+v", err)
+		_, err = scope.RegisterType("Website2", "websites", scope.Default)
+		assert.True(t, errors.AlreadyExists.Match(err), "%+v", err)
+		_, err = scope.RegisterType("Unknown", "unknowns", scope.Type(7))
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+		_, err = scope.RegisterType("Unknown", "unknowns", scope.Absent)
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+	})
+
+	t.Run("hierarchy", func(t *testing.T) {
+		assert.Exactly(t, []scope.Type{scope.Store, scope.Group, priceList, customerGroup, scope.Website, scope.Default}, scope.Store.Chain())
+		assert.Exactly(t, []scope.Type{customerGroup, scope.Website, scope.Default}, customerGroup.Chain())
+		assert.Exactly(t, []scope.Type(nil), scope.Type(6).Chain())
+		assert.Exactly(t, customerGroup, priceList.Parent())
+		assert.Exactly(t, priceList, scope.Group.Parent())
+		assert.Exactly(t, scope.Absent, scope.Default.Parent())
+
+		assert.True(t, scope.Website.IsParentOf(priceList))
+		assert.True(t, customerGroup.IsParentOf(scope.Store))
+		assert.False(t, scope.Store.IsParentOf(customerGroup))
+		assert.False(t, customerGroup.IsParentOf(customerGroup))
+	})
+
+	t.Run("type", func(t *testing.T) {
+		assert.Exactly(t, "CustomerGroup", customerGroup.String())
+		assert.Exactly(t, "price_lists", priceList.StrType())
+		assert.Exactly(t, []byte("customer_groups"), customerGroup.StrBytes())
+		assert.Exactly(t, customerGroup, scope.FromString("customer_groups"))
+		assert.Exactly(t, priceList, scope.TypeStr("price_lists").Type())
+		assert.Exactly(t, scope.Default, scope.FromString("unknowns"))
+		assert.True(t, scope.Valid("customer_groups"))
+		assert.True(t, scope.ValidBytes([]byte("price_lists")))
+		assert.False(t, scope.Valid("CustomerGroup"))
+		assert.NoError(t, priceList.IsValid())
+		assert.True(t, errors.NotValid.Match(scope.Type(9+7).IsValid()))
+
+		assert.True(t, customerGroup.IsCustom())
+		assert.False(t, scope.Store.IsCustom())
+		assert.True(t, customerGroup.IsConfigScope())
+		assert.True(t, scope.Store.IsConfigScope())
+		assert.False(t, scope.Group.IsConfigScope())
+		assert.False(t, scope.Absent.IsConfigScope())
+
+		assert.True(t, scope.ValidParent(scope.Store, priceList))
+		assert.True(t, scope.ValidParent(priceList, customerGroup))
+		assert.True(t, scope.ValidParent(customerGroup, scope.Website))
+		assert.True(t, scope.ValidParent(scope.Store, scope.Website))
+		assert.False(t, scope.ValidParent(customerGroup, scope.Store))
+		assert.False(t, scope.ValidParent(priceList, scope.Website))
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		data, err := json.Marshal(struct{ T scope.Type }{priceList})
+		assert.NoError(t, err)
+		assert.Exactly(t, `{"T":"PriceList"}`, string(data))
+
+		var v struct{ T scope.Type }
+		assert.NoError(t, json.Unmarshal(data, &v))
+		assert.Exactly(t, priceList, v.T)
+	})
+
+	t.Run("perm", func(t *testing.T) {
+		assert.Exactly(t, scope.PermStore, scope.Website.Perm().Set(scope.Store))
+		assert.Exactly(t, scope.PermWebsite.Set(customerGroup), customerGroup.Perm())
+		assert.Exactly(t, scope.PermWebsite.Set(customerGroup, priceList, scope.Store), scope.Store.Perm())
+
+		p, err := scope.MakePerm("price_lists")
+		assert.NoError(t, err)
+		assert.Exactly(t, priceList.Perm(), p)
+		assert.Exactly(t, priceList, p.Top())
+		assert.Exactly(t, "price_lists", p.String())
+		assert.Exactly(t, []string{"Default", "Website", "CustomerGroup", "PriceList"}, p.Human())
+
+		assert.Exactly(t, scope.Store, scope.PermStore.Set(customerGroup).Top())
+		assert.Exactly(t, customerGroup, scope.PermWebsite.Set(customerGroup, scope.Group).Top())
+	})
+
+	t.Run("TypeID", func(t *testing.T) {
+		assert.Exactly(t, "customer_groups/3", string(customerGroup.WithID(3).AppendHuman(nil, '/')))
+		assert.Exactly(t, "Type(PriceList) ID(4)", priceList.WithID(4).String())
+
+		assert.True(t, scope.Store.WithID(1).ValidParent(priceList.WithID(4)))
+		assert.True(t, customerGroup.WithID(3).ValidParent(scope.Website.WithID(1)))
+		assert.False(t, customerGroup.WithID(3).ValidParent(priceList.WithID(4)))
+
+		target, parents := scope.TypeIDs{scope.Store.WithID(1), customerGroup.WithID(3), scope.Website.WithID(2)}.TargetAndParents()
+		assert.Exactly(t, scope.Store.WithID(1), target)
+		assert.Exactly(t, scope.TypeIDs{customerGroup.WithID(3), scope.Website.WithID(2), scope.DefaultTypeID}, parents)
+
+		target, parents = scope.TypeIDs{customerGroup.WithID(3), scope.Store.WithID(1)}.TargetAndParents()
+		assert.Exactly(t, customerGroup.WithID(3), target)
+		assert.Exactly(t, scope.TypeIDs{scope.DefaultTypeID}, parents)
+
+		low, err := scope.TypeIDs{scope.Website.WithID(1), priceList.WithID(4), customerGroup.WithID(3)}.Lowest()
+		assert.NoError(t, err)
+		assert.Exactly(t, priceList.WithID(4), low)
+
+		_, err = scope.TypeIDs{priceList.WithID(4), priceList.WithID(5)}.Lowest()
+		assert.True(t, errors.NotValid.Match(err), "%+v", err)
+	})
+}
@@ -51,8 +51,9 @@ const PermWebsiteReverse Perm = 1<<Store | 1<<Website
 
 // MakePerm creates a Perm type based on the input argument which can be either:
 // "default","d" or "" for PermDefault, "websites", "website" or "w" for
-// PermWebsite OR "stores", "store" or "s" for PermStore. Any other argument
-// triggers a NotSupported error.
+// PermWebsite OR "stores", "store" or "s" for PermStore. The TypeStr of a
+// custom type returns the Perm of that type. Any other argument triggers a
+// NotSupported error.
 func MakePerm(name string) (p Perm, err error) {
 	switch name {
 	case strDefault, "d", "":
@@ -62,6 +63,9 @@ func MakePerm(name string) (p Perm, err error) {
 	case strStores, "s":
 		p = PermStore
 	default:
+		if t := fromCustomStr(name); t.IsCustom() {
+			return t.Perm(), nil
+		}
 		err = errors.NotSupported.Newf("[scope] Permission Scope identifier %q not supported. Available: d,w,s", name)
 	}
 	return
@@ -82,15 +86,18 @@ func (bits Perm) Set(scopes ...Type) Perm {
 
 // Top returns the highest stored scope within a Perm. A Perm can consists of 3
 // scopes: 1. Default -> 2. Website -> 3. Store Highest scope for a Perm with
-// all scopes is: Store.
+// all scopes is: Store. Custom types are considered according to their
+// position in the hierarchy. Types which are not a configuration scope, like
+// Group, get ignored.
 func (bits Perm) Top() Type {
-	switch {
-	case bits.Has(Store):
-		return Store
-	case bits.Has(Website):
-		return Website
+	h := loadHierarchy()
+	top, depth := Default, 0
+	for t := Default; t < maxCustomType; t++ {
+		if ti := h.types[t]; bits.Has(t) && ti.str != "" && len(ti.chain) > depth {
+			top, depth = t, len(ti.chain)
+		}
 	}
-	return Default
+	return top
 }
 
 // Has checks if a given scope.Type exists within a Perm. Only the first argument
@@ -104,9 +111,9 @@ func (bits Perm) Human(ret ...string) []string {
 	if ret == nil {
 		ret = make([]string, 0, maxType)
 	}
-	for i := uint(0); i < uint(maxType); i++ {
+	for i := uint(0); i < uint(maxCustomType); i++ {
 		bit := (bits & (1 << i)) != 0
-		if bit {
+		if bit && (i < uint(maxType) || Type(i).IsCustom()) {
 			ret = append(ret, Type(i).String())
 		}
 	}
@@ -115,13 +122,7 @@ func (bits Perm) Human(ret ...string) []string {
 
 // String readable representation of the permissions
 func (bits Perm) String() string {
-	switch {
-	case bits.Has(Store):
-		return strStores
-	case bits.Has(Website):
-		return strWebsites
-	}
-	return strDefault
+	return FromType(bits.Top()).String()
 }
 
 // TODO for Go2 implement encoding.TextMarshaler and econding.BinaryMarshaler
@@ -36,7 +36,8 @@ type Type uint8
 
 // Those constants define the overall scopes. The hierarchical order is always:
 // 		Absent -> Default -> Website -> Group -> Store
-// These internal IDs may change without notice.
+// These internal IDs may change without notice. Custom types can be inserted
+// into the hierarchy with RegisterType.
 const (
 	Absent Type = iota // must start with 0
 	Default
@@ -64,6 +65,9 @@ var _TypeIndex = [...]uint8{0, 6, 13, 20, 25, 30}
 // String human readable name of a Type. For Marshaling see Perm.
 func (s Type) String() string {
 	if s+1 >= Type(len(_TypeIndex)) {
+		if h := loadHierarchy(); h.isRegistered(s) {
+			return h.types[s].name
+		}
 		return fmt.Sprintf("Type(%d)", s)
 	}
 	return _TypeName[_TypeIndex[s]:_TypeIndex[s+1]]
@@ -88,6 +92,9 @@ func (s Type) MarshalJSON() ([]byte, error) {
 		ret = jsonStore
 	default:
 		ret = jsonDefault
+		if s.IsCustom() {
+			ret = []byte(`"` + s.String() + `"`)
+		}
 	}
 	return ret, nil
 }
@@ -107,6 +114,9 @@ func (s Type) StrBytes() []byte {
 	case Store:
 		return bStores
 	}
+	if h := loadHierarchy(); s >= firstCustomType && h.isRegistered(s) {
+		return h.types[s].bStr
+	}
 	return bDefault
 }
 
@@ -121,10 +131,10 @@ func (s Type) WithID(id int64) TypeID {
 	return MakeTypeID(s, id)
 }
 
-// IsValid checks if the type is within the scope Default, Website, Group or
-// Store.
+// IsValid checks if the type is within the scope Default, Website, Group,
+// Store or a registered custom type.
 func (s Type) IsValid() error {
-	if s >= maxType {
+	if s >= maxType && !s.IsCustom() {
 		return errors.NotValid.Newf("[scope] Invalid Type: %s", s)
 	}
 	return nil
@@ -169,11 +179,11 @@ func (s TypeStr) Type() Type {
 	case StrStores:
 		return Store
 	}
-	return Default
+	return fromCustomStr(string(s))
 }
 
-// FromString returns the Type from a string: default, websites or stores.
-// Opposite of FromType.
+// FromString returns the Type from a string: default, websites, stores or the
+// TypeStr of a custom type. Opposite of FromType.
 func FromString(s string) Type {
 	switch TypeStr(s) {
 	case StrWebsites:
@@ -181,6 +191,13 @@ func FromString(s string) Type {
 	case StrStores:
 		return Store
 	}
+	return fromCustomStr(s)
+}
+
+func fromCustomStr(s string) Type {
+	if t, ok := loadHierarchy().byStr[s]; ok && t >= firstCustomType {
+		return t
+	}
 	return Default
 }
 
@@ -193,21 +210,27 @@ func FromType(scopeID Type) TypeStr {
 	case Store:
 		return StrStores
 	}
+	if h := loadHierarchy(); scopeID >= firstCustomType && h.isRegistered(scopeID) {
+		return h.types[scopeID].str
+	}
 	return StrDefault
 }
 
-// Valid checks if s is a valid StrScope of either StrDefault, StrWebsites or
-// StrStores. Case-sensitive. Input should all be lowercase.
+// Valid checks if s is a valid StrScope of either StrDefault, StrWebsites,
+// StrStores or the TypeStr of a custom type. Case-sensitive. Input should all
+// be lowercase.
 func Valid(s string) bool {
 	switch s {
 	case strWebsites, strStores, strDefault:
 		return true
 	}
-	return false
+	_, ok := loadHierarchy().byStr[s]
+	return ok
 }
 
 // FromBytes returns the Type from a byte slice. Supported values are
-// default, websites, stores, Default, Website, Group and store. Case sensitive.
+// default, websites, stores, Default, Website, Group and store. Custom types
+// get found by their TypeStr and their name. Case sensitive.
 func FromBytes(b []byte) Type {
 	switch {
 	case bytes.Equal(bWebsites, b):
@@ -229,19 +252,35 @@ func FromBytes(b []byte) Type {
 	case bytes.Equal(sbStore, b):
 		return Store
 	}
+	if len(b) > 0 {
+		h := loadHierarchy()
+		name := string(bytes.Trim(b, `"`))
+		if t, ok := h.byStr[name]; ok && t >= firstCustomType {
+			return t
+		}
+		if t, ok := h.byName[name]; ok && t >= firstCustomType {
+			return t
+		}
+	}
 	return Default
 }
 
 // ValidBytes checks if b is a valid byte Type of either StrDefault,
-// StrWebsites or StrStores. Case-sensitive.
+// StrWebsites, StrStores or the TypeStr of a custom type. Case-sensitive.
 func ValidBytes(b []byte) bool {
-	return bytes.Equal(bDefault, b) || bytes.Equal(bWebsites, b) || bytes.Equal(bStores, b)
+	if bytes.Equal(bDefault, b) || bytes.Equal(bWebsites, b) || bytes.Equal(bStores, b) {
+		return true
+	}
+	_, ok := loadHierarchy().byStr[string(b)]
+	return ok
 }
 
 // ValidParent validates if the parent scope is within the hierarchical chain:
-// default -> website -> store.
+// default -> website -> store. If current or parent is a custom type, parent
+// must be the nearest configuration scope above current.
 func ValidParent(current Type, parent Type) bool {
 	return (parent == Default && current == Default) ||
 		(parent == Default && current == Website) ||
-		(parent == Website && current == Store)
+		(parent == Website && current == Store) ||
+		((current.IsCustom() || parent.IsCustom()) && parent == current.configParent())
 }
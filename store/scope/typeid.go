@@ -92,9 +92,9 @@ func (t TypeID) AppendBytes(dst []byte) (text []byte) {
 	return strconv.AppendUint(dst, t.ToUint64(), 10)
 }
 
-// AppendHuman appends to dst the human textual representation of a Websites or
-// Stores scope and their IDs. Default, Group and invalid scopes won't get
-// appended. Will write:
+// AppendHuman appends to dst the human textual representation of a Websites,
+// Stores or custom scope and their IDs. Default, Group and invalid scopes
+// won't get appended. Will write:
 //		scope.Websites.WithID(1) => websites/1
 //		scope.Stores.WithID(2) => stores/2
 //		scope.DefaultTypeID => "" <- returns dst unchanged.
@@ -102,7 +102,7 @@ func (t TypeID) AppendBytes(dst []byte) (text []byte) {
 // This function gets used in the config package to write a path depending on
 // the paths scope.
 func (t TypeID) AppendHuman(dst []byte, separator byte) (text []byte) {
-	if s, id := t.Unpack(); s.IsWebSiteOrStore() {
+	if s, id := t.Unpack(); s.IsWebSiteOrStore() || s.IsCustom() {
 		dst = append(dst, s.StrBytes()...)
 		dst = append(dst, separator)
 		dst = strconv.AppendInt(dst, id, 10)
@@ -197,14 +197,17 @@ func (t TypeID) ID() int64 {
 }
 
 // ValidParent validates if the parent Type is within the hierarchical chain:
-// default -> website -> store. Returns also true when parent is zero.
+// default -> website -> store. Returns also true when parent is zero. If one
+// of both types is a custom type, parent must be the nearest configuration
+// scope above t.
 func (t TypeID) ValidParent(parent TypeID) bool {
 	p, pID := parent.Unpack()
 	c, cID := t.Unpack()
 	return (p == Absent && pID == 0) ||
 		(p == Default && pID == 0 && c == Default && cID == 0) ||
 		(p == Default && pID == 0 && c == Website && cID >= 0) ||
-		(p == Website && pID >= 0 && c == Store && cID >= 0)
+		(p == Website && pID >= 0 && c == Store && cID >= 0) ||
+		((c.IsCustom() || p.IsCustom()) && pID >= 0 && cID >= 0 && p == c.configParent())
 }
 
 // IsValid checks if the scope and its ID are valid.
@@ -265,10 +268,10 @@ type TypeIDs []TypeID
 // target contains either the DefaultTypeID or the desired TypeID. Parents
 // contains at least the DefaultTypeID (appended at the end) and all other
 // parents. But only those parents which are really a parent in the hierarchical
-// order Default->Website->Group->Store. No sorting will be performed on the
-// parents. This function gets mainly used to perform hierarchical look ups with
-// the parents slice in the net packages to create a new scoped configuration
-// for the target TypeID.
+// order Default->Website->Group->Store, including registered custom types. No
+// sorting will be performed on the parents. This function gets mainly used to
+// perform hierarchical look ups with the parents slice in the net packages to
+// create a new scoped configuration for the target TypeID.
 func (t TypeIDs) TargetAndParents() (target TypeID, parents TypeIDs) {
 	parents = make(TypeIDs, 0, len(t)+1) // +1 because DefaultTypeID gets appended
 	if len(t) == 0 {
@@ -281,7 +284,7 @@ func (t TypeIDs) TargetAndParents() (target TypeID, parents TypeIDs) {
 	// lookup the remaining parents if they contain the DefaultTypeID
 	containsDefault := false
 	for _, pID := range t {
-		if pID.Type() < target.Type() || (pID == DefaultTypeID && !containsDefault) {
+		if pID.Type().IsParentOf(target.Type()) || (pID == DefaultTypeID && !containsDefault) {
 			parents = append(parents, pID)
 			if pID == DefaultTypeID {
 				containsDefault = true
@@ -313,47 +316,24 @@ func (t TypeIDs) Less(i, j int) bool { return t[i] < t[j] }
 func (t TypeIDs) Lowest() (TypeID, error) {
 	sort.Stable(t)
 	var pick = DefaultTypeID
-	wIDs, gIDs, sIDs := float64(0), float64(0), float64(0)
-	wC, gC, sC := float64(0), float64(0), float64(0)
+	var ids, counts [maxCustomType]float64
 	for _, v := range t {
-
-		if v.Type() > pick.Type() {
+		typ := v.Type()
+		if typ > Default && typ.depth() == 0 {
+			return 0, errors.NotValid.Newf("[scope] Invalid TypeID: %s in slice.", v)
+		}
+		if typ.depth() > pick.Type().depth() {
 			pick = v
 		}
-
-		switch v.Type() {
-		case Website:
-			wC++
-			wIDs += float64(v.ID())
-		case Group:
-			gC++
-			gIDs += float64(v.ID())
-		case Store:
-			sC++
-			sIDs += float64(v.ID())
+		if typ > Default {
+			counts[typ]++
+			ids[typ] += float64(v.ID())
 		}
 	}
 
-	switch pick.Type() {
-	case Website:
-		if float64(pick.ID()) != wIDs/wC {
-			return 0, errors.NotValid.Newf("[scope] Invalid TypeID: %s in slice.", pick)
-		}
-	case Group:
-		if float64(pick.ID()) != gIDs/gC {
-			return 0, errors.NotValid.Newf("[scope] Invalid TypeID: %s in slice.", pick)
-		}
-	case Store:
-		if float64(pick.ID()) != sIDs/sC {
-			return 0, errors.NotValid.Newf("[scope] Invalid TypeID: %s in slice.", pick)
-		}
-	case Default, Absent:
-		// do nothing
-	default:
-		// todo implement scope independent solution ...
+	if typ := pick.Type(); typ > Default && float64(pick.ID()) != ids[typ]/counts[typ] {
 		return 0, errors.NotValid.Newf("[scope] Invalid TypeID: %s in slice.", pick)
 	}
-
 	return pick, nil
 }
 
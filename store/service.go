@@ -87,6 +87,13 @@ type Service struct {
 	cacheGroup       map[int64]Group
 	cacheStore       map[int64]Store
 	cacheSingleStore map[scope.TypeID]bool
+
+	// mutMu serializes the Create*, Update* and Delete* functions.
+	mutMu sync.Mutex
+	// subMu protects the subscribers of the change events.
+	subMu       sync.RWMutex
+	subAutoInc  int
+	subscribers map[int]ChangeFunc
 }
 
 func newService() *Service {
@@ -97,6 +104,7 @@ func newService() *Service {
 		cacheGroup:             make(map[int64]Group),
 		cacheStore:             make(map[int64]Store),
 		cacheSingleStore:       make(map[scope.TypeID]bool),
+		subscribers:            make(map[int]ChangeFunc),
 	}
 }
 
@@ -178,6 +186,8 @@ func (s *Service) loadFromOptions(cfg config.Getter, opts ...Option) error {
 // its default group and its default stores.
 func (s *Service) IsAllowedStoreID(runMode scope.TypeID, storeID int64) (isAllowed bool, storeCode string, _ error) {
 	scp, scpID := runMode.Unpack()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	switch scp {
 	case scope.Store:
@@ -257,8 +267,11 @@ func (s *Service) DefaultStoreID(runMode scope.TypeID) (storeID, websiteID int64
 			return 0, 0, errors.Wrapf(err, "[store] DefaultStoreID.Website Scope %s ID %d", scp, id)
 		}
 	} else {
+		s.mu.RLock()
+		ws := s.websites
+		s.mu.RUnlock()
 		var err error
-		w, err = s.websites.Default()
+		w, err = ws.Default()
 		if err != nil {
 			return 0, 0, errors.Wrapf(err, "[store] DefaultStoreID.Website.Default Scope %s ID %d", scp, id)
 		}
@@ -329,6 +342,8 @@ func (s *Service) StoreIDbyCode(runMode scope.TypeID, storeCode string) (storeID
 // callee.
 func (s *Service) AllowedStores(runMode scope.TypeID) (StoreSlice, error) {
 	scp, scpID := runMode.Unpack()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	switch scp {
 	case scope.Store:
@@ -498,10 +513,29 @@ func (s *Service) LoadFromResource(twr TableWebsitesResourcer, tgr TableGroupsRe
 		WithTableWebsites(s.backend.websites...),
 		WithTableGroups(s.backend.groups...),
 		WithTableStores(s.backend.stores...),
+		WithTableResourcers(twr, tgr, tsr),
 	)
 	return errors.Wrap(err, "[store] LoadFromDB.ApplyStorage")
 }
 
+// LoadFromTables replaces the website, store group and store view data with
+// the provided already loaded table rows. After reloading the internal cache
+// will be cleared. Useful when another package has already queried or written
+// the tables and a second round trip to the database should be avoided.
+func (s *Service) LoadFromTables(tws TableWebsiteSlice, tgs TableGroupSlice, tss TableStoreSlice) error {
+	be := s.backend
+	s.ClearCache()
+
+	err := s.loadFromOptions(
+		be.rootConfig,
+		WithTableWebsites(tws...),
+		WithTableGroups(tgs...),
+		WithTableStores(tss...),
+		WithTableResourcers(be.twr, be.tgr, be.tsr),
+	)
+	return errors.Wrap(err, "[store] LoadFromTables.ApplyStorage")
+}
+
 // ClearCache resets the internal caches which stores the pointers to Websites,
 // Groups or Stores. The ReInit() also uses this method to clear caches before
 // the Storage gets reloaded.
@@ -0,0 +1,725 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+)
+
+// ChangeAction defines the kind of modification of a website, group or
+// store.
+type ChangeAction uint8
+
+// ChangeAction constants used in a ChangeEvent.
+const (
+	ChangeCreated ChangeAction = iota + 1
+	ChangeUpdated
+	ChangeDeleted
+)
+
+func (a ChangeAction) String() string {
+	switch a {
+	case ChangeCreated:
+		return "created"
+	case ChangeUpdated:
+		return "updated"
+	case ChangeDeleted:
+		return "deleted"
+	}
+	return "ChangeAction(" + strconv.Itoa(int(a)) + ")"
+}
+
+// ChangeEvent describes a modification of the store structure. The events
+// get emitted after the internal caches of the Service have been swapped.
+type ChangeEvent struct {
+	Action ChangeAction
+	// Scope contains the type, scope.Website, scope.Group or scope.Store,
+	// and the ID of the modified entity.
+	Scope scope.TypeID
+	Code  string
+}
+
+// ChangeFunc receives the change events. It gets called synchronously, in
+// the order of the modifications, after all locks of the Service have been
+// released.
+type ChangeFunc func(ChangeEvent)
+
+// Subscribe adds a function which gets called for every ChangeEvent. Returns
+// a subscription ID which can be used to unsubscribe.
+func (s *Service) Subscribe(fn ChangeFunc) (subscriptionID int, err error) {
+	if fn == nil {
+		return 0, errors.NewEmptyf("[store] Subscribe: ChangeFunc cannot be nil")
+	}
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	s.subAutoInc++
+	s.subscribers[s.subAutoInc] = fn
+	return s.subAutoInc, nil
+}
+
+// Unsubscribe removes a subscriber. Returns a NotFound error if the ID does
+// not exists.
+func (s *Service) Unsubscribe(subscriptionID int) error {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	if _, ok := s.subscribers[subscriptionID]; !ok {
+		return errors.NewNotFoundf("[store] Unsubscribe: subscription ID %d not found", subscriptionID)
+	}
+	delete(s.subscribers, subscriptionID)
+	return nil
+}
+
+func (s *Service) notify(events []ChangeEvent) {
+	s.subMu.RLock()
+	ids := make([]int, 0, len(s.subscribers))
+	for id := range s.subscribers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	fns := make([]ChangeFunc, 0, len(ids))
+	for _, id := range ids {
+		fns = append(fns, s.subscribers[id])
+	}
+	s.subMu.RUnlock()
+
+	for _, ev := range events {
+		for _, fn := range fns {
+			fn(ev)
+		}
+	}
+}
+
+// CreateWebsite adds a new website. The WebsiteID of tw gets set to the new
+// ID. A website without groups must have a DefaultGroupID of zero. If the new
+// website is the default website, all other websites lose their default
+// flag.
+func (s *Service) CreateWebsite(tw *TableWebsite) error {
+	st, err := s.mutate(func(st *structure) error {
+		w := *tw
+		w.WebsiteID = st.nextWebsiteID()
+		st.websites = append(st.websites, &w)
+		st.emit(ChangeCreated, scope.Website, w.WebsiteID, w.Code.String)
+		if w.IsDefault.Bool {
+			st.unsetDefaultWebsites(w.WebsiteID)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "[store] CreateWebsite")
+	}
+	tw.WebsiteID = st.events[0].Scope.ID()
+	return nil
+}
+
+// UpdateWebsite replaces the website with the same WebsiteID.
+func (s *Service) UpdateWebsite(tw *TableWebsite) error {
+	_, err := s.mutate(func(st *structure) error {
+		for i, old := range st.websites {
+			if old.WebsiteID == tw.WebsiteID {
+				w := *tw
+				st.websites[i] = &w
+				st.emit(ChangeUpdated, scope.Website, w.WebsiteID, w.Code.String)
+				if w.IsDefault.Bool {
+					st.unsetDefaultWebsites(w.WebsiteID)
+				}
+				return nil
+			}
+		}
+		return errors.NewNotFoundf("[store] WebsiteID %d", tw.WebsiteID)
+	})
+	return errors.Wrap(err, "[store] UpdateWebsite")
+}
+
+// DeleteWebsite removes the website including all of its groups and stores.
+// The admin website with ID zero cannot be deleted. Set the option
+// WithTableResourcersTx to persist all deletes in one transaction.
+func (s *Service) DeleteWebsite(id int64) error {
+	_, err := s.mutate(func(st *structure) error {
+		if id == 0 {
+			return errors.NewNotSupportedf("[store] The admin website cannot be deleted")
+		}
+		if _, found := st.websites.FindByWebsiteID(id); !found {
+			return errors.NewNotFoundf("[store] WebsiteID %d", id)
+		}
+		st.deleteStores(func(ts *TableStore) bool { return ts.WebsiteID == id })
+		st.deleteGroups(func(tg *TableGroup) bool { return tg.WebsiteID == id })
+		st.websites = st.websites.FilterNot(func(tw *TableWebsite) bool {
+			if tw.WebsiteID == id {
+				st.deleted.websites = append(st.deleted.websites, tw)
+				st.emit(ChangeDeleted, scope.Website, tw.WebsiteID, tw.Code.String)
+				return true
+			}
+			return false
+		})
+		return nil
+	})
+	return errors.Wrap(err, "[store] DeleteWebsite")
+}
+
+// CreateGroup adds a new group. The GroupID of tg gets set to the new ID. The
+// first group of a website becomes the default group of the website.
+func (s *Service) CreateGroup(tg *TableGroup) error {
+	st, err := s.mutate(func(st *structure) error {
+		g := *tg
+		g.GroupID = st.nextGroupID()
+		st.groups = append(st.groups, &g)
+		st.emit(ChangeCreated, scope.Group, g.GroupID, g.Code.String)
+		st.fixWebsiteDefault(g.WebsiteID)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "[store] CreateGroup")
+	}
+	tg.GroupID = st.events[0].Scope.ID()
+	return nil
+}
+
+// UpdateGroup replaces the group with the same GroupID. If the group moves to
+// another website, all of its stores move too.
+func (s *Service) UpdateGroup(tg *TableGroup) error {
+	_, err := s.mutate(func(st *structure) error {
+		for i, old := range st.groups {
+			if old.GroupID == tg.GroupID {
+				g := *tg
+				st.groups[i] = &g
+				st.emit(ChangeUpdated, scope.Group, g.GroupID, g.Code.String)
+				if old.WebsiteID != g.WebsiteID {
+					for j, ts := range st.stores {
+						if ts.GroupID == g.GroupID {
+							c := *ts
+							c.WebsiteID = g.WebsiteID
+							st.stores[j] = &c
+							st.emit(ChangeUpdated, scope.Store, c.StoreID, c.Code.String)
+						}
+					}
+					st.fixWebsiteDefault(old.WebsiteID)
+					st.fixWebsiteDefault(g.WebsiteID)
+				}
+				return nil
+			}
+		}
+		return errors.NewNotFoundf("[store] GroupID %d", tg.GroupID)
+	})
+	return errors.Wrap(err, "[store] UpdateGroup")
+}
+
+// DeleteGroup removes the group including all of its stores. The admin group
+// with ID zero cannot be deleted. Set the option WithTableResourcersTx to
+// persist all deletes in one transaction.
+func (s *Service) DeleteGroup(id int64) error {
+	_, err := s.mutate(func(st *structure) error {
+		if id == 0 {
+			return errors.NewNotSupportedf("[store] The admin group cannot be deleted")
+		}
+		tg, found := st.groups.FindByGroupID(id)
+		if !found {
+			return errors.NewNotFoundf("[store] GroupID %d", id)
+		}
+		st.deleteStores(func(ts *TableStore) bool { return ts.GroupID == id })
+		st.deleteGroups(func(g *TableGroup) bool { return g.GroupID == id })
+		st.fixWebsiteDefault(tg.WebsiteID)
+		return nil
+	})
+	return errors.Wrap(err, "[store] DeleteGroup")
+}
+
+// CreateStore adds a new store. The StoreID of ts gets set to the new ID. The
+// first store of a group becomes the default store of the group.
+func (s *Service) CreateStore(ts *TableStore) error {
+	st, err := s.mutate(func(st *structure) error {
+		c := *ts
+		c.StoreID = st.nextStoreID()
+		st.stores = append(st.stores, &c)
+		st.emit(ChangeCreated, scope.Store, c.StoreID, c.Code.String)
+		st.fixGroupDefault(c.GroupID)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "[store] CreateStore")
+	}
+	ts.StoreID = st.events[0].Scope.ID()
+	return nil
+}
+
+// UpdateStore replaces the store with the same StoreID.
+func (s *Service) UpdateStore(ts *TableStore) error {
+	_, err := s.mutate(func(st *structure) error {
+		for i, old := range st.stores {
+			if old.StoreID == ts.StoreID {
+				c := *ts
+				st.stores[i] = &c
+				st.emit(ChangeUpdated, scope.Store, c.StoreID, c.Code.String)
+				if old.GroupID != c.GroupID {
+					st.fixGroupDefault(old.GroupID)
+					st.fixGroupDefault(c.GroupID)
+				}
+				return nil
+			}
+		}
+		return errors.NewNotFoundf("[store] StoreID %d", ts.StoreID)
+	})
+	return errors.Wrap(err, "[store] UpdateStore")
+}
+
+// DeleteStore removes a store. The admin store with ID zero cannot be
+// deleted. The default store of a group can only be deleted if it is the last
+// store of the group.
+func (s *Service) DeleteStore(id int64) error {
+	_, err := s.mutate(func(st *structure) error {
+		if id == 0 {
+			return errors.NewNotSupportedf("[store] The admin store cannot be deleted")
+		}
+		ts, found := st.stores.FindByStoreID(id)
+		if !found {
+			return errors.NewNotFoundf("[store] StoreID %d", id)
+		}
+		st.deleteStores(func(c *TableStore) bool { return c.StoreID == id })
+		st.fixGroupDefault(ts.GroupID)
+		return nil
+	})
+	return errors.Wrap(err, "[store] DeleteStore")
+}
+
+// mutate applies fn to a copy of the current table rows, validates the
+// result, persists the changes with the optional resourcers and swaps the
+// internal caches. The subscribers get notified at the end. If persisting
+// fails, the Service keeps its old state. The already written changes of
+// previous events get only rolled back if the option WithTableResourcersTx has
+// been set.
+func (s *Service) mutate(fn func(*structure) error) (*structure, error) {
+	s.mutMu.Lock()
+	defer s.mutMu.Unlock()
+
+	st := s.copyStructure()
+	if err := fn(st); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := st.validate(); err != nil {
+		return nil, errors.Wrap(err, "[store] Service.mutate.validate")
+	}
+	if err := st.persist(); err != nil {
+		return nil, errors.Wrap(err, "[store] Service.mutate.persist")
+	}
+	if err := s.swap(st); err != nil {
+		return nil, errors.Wrap(err, "[store] Service.mutate.swap")
+	}
+	s.notify(st.events)
+	return st, nil
+}
+
+// swap creates new caches from the structure and replaces the old caches
+// while holding the lock.
+func (s *Service) swap(st *structure) error {
+	ns := newService()
+	err := ns.loadFromOptions(
+		st.backend.rootConfig,
+		WithTableWebsites(st.websites...),
+		WithTableGroups(st.groups...),
+		WithTableStores(st.stores...),
+		WithTableResourcers(st.backend.twr, st.backend.tgr, st.backend.tsr),
+		WithTableResourcersTx(st.backend.beginTx),
+	)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backend = ns.backend
+	s.websites = ns.websites
+	s.groups = ns.groups
+	s.stores = ns.stores
+	s.cacheWebsite = ns.cacheWebsite
+	s.cacheGroup = ns.cacheGroup
+	s.cacheStore = ns.cacheStore
+	s.cacheSingleStore = make(map[scope.TypeID]bool)
+	atomic.StoreInt64(&s.defaultStoreID, -1)
+	return nil
+}
+
+// structure contains copies of the table rows which can be modified without
+// affecting the running Service.
+type structure struct {
+	backend  *factory
+	websites TableWebsiteSlice
+	groups   TableGroupSlice
+	stores   TableStoreSlice
+	deleted  struct {
+		websites TableWebsiteSlice
+		groups   TableGroupSlice
+		stores   TableStoreSlice
+	}
+	events []ChangeEvent
+}
+
+func (s *Service) copyStructure() *structure {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	be := s.backend
+	st := &structure{
+		backend:  be,
+		websites: make(TableWebsiteSlice, 0, len(be.websites)+1),
+		groups:   make(TableGroupSlice, 0, len(be.groups)+1),
+		stores:   make(TableStoreSlice, 0, len(be.stores)+1),
+	}
+	for _, w := range be.websites {
+		c := *w
+		st.websites = append(st.websites, &c)
+	}
+	for _, g := range be.groups {
+		c := *g
+		st.groups = append(st.groups, &c)
+	}
+	for _, ts := range be.stores {
+		c := *ts
+		st.stores = append(st.stores, &c)
+	}
+	return st
+}
+
+func (st *structure) emit(a ChangeAction, typ scope.Type, id int64, code string) {
+	st.events = append(st.events, ChangeEvent{Action: a, Scope: scope.MakeTypeID(typ, id), Code: code})
+}
+
+func (st *structure) nextWebsiteID() (id int64) {
+	for _, w := range st.websites {
+		if w.WebsiteID > id {
+			id = w.WebsiteID
+		}
+	}
+	return id + 1
+}
+
+func (st *structure) nextGroupID() (id int64) {
+	for _, g := range st.groups {
+		if g.GroupID > id {
+			id = g.GroupID
+		}
+	}
+	return id + 1
+}
+
+func (st *structure) nextStoreID() (id int64) {
+	for _, ts := range st.stores {
+		if ts.StoreID > id {
+			id = ts.StoreID
+		}
+	}
+	return id + 1
+}
+
+func (st *structure) unsetDefaultWebsites(exceptID int64) {
+	for i, w := range st.websites {
+		if w.WebsiteID != exceptID && w.IsDefault.Bool {
+			c := *w
+			c.IsDefault.Bool = false
+			st.websites[i] = &c
+			st.emit(ChangeUpdated, scope.Website, c.WebsiteID, c.Code.String)
+		}
+	}
+}
+
+func (st *structure) deleteStores(match func(*TableStore) bool) {
+	st.stores = st.stores.FilterNot(func(ts *TableStore) bool {
+		if match(ts) {
+			st.deleted.stores = append(st.deleted.stores, ts)
+			st.emit(ChangeDeleted, scope.Store, ts.StoreID, ts.Code.String)
+			return true
+		}
+		return false
+	})
+}
+
+func (st *structure) deleteGroups(match func(*TableGroup) bool) {
+	st.groups = st.groups.FilterNot(func(tg *TableGroup) bool {
+		if match(tg) {
+			st.deleted.groups = append(st.deleted.groups, tg)
+			st.emit(ChangeDeleted, scope.Group, tg.GroupID, tg.Code.String)
+			return true
+		}
+		return false
+	})
+}
+
+// fixWebsiteDefault sets the default group of a website to the first group if
+// the website has groups but no default group, or resets the default group to
+// zero if the website has no groups anymore.
+func (st *structure) fixWebsiteDefault(websiteID int64) {
+	for i, w := range st.websites {
+		if w.WebsiteID != websiteID {
+			continue
+		}
+		groups := st.groups.Filter(func(tg *TableGroup) bool { return tg.WebsiteID == websiteID })
+		_, isChild := groups.FindByGroupID(w.DefaultGroupID)
+		newID := w.DefaultGroupID
+		switch {
+		case len(groups) == 0:
+			newID = 0
+		case w.DefaultGroupID == 0 && !isChild:
+			newID = groups[0].GroupID
+		}
+		if newID != w.DefaultGroupID {
+			c := *w
+			c.DefaultGroupID = newID
+			st.websites[i] = &c
+			st.emit(ChangeUpdated, scope.Website, c.WebsiteID, c.Code.String)
+		}
+		return
+	}
+}
+
+// fixGroupDefault same as fixWebsiteDefault but for the default store of a
+// group.
+func (st *structure) fixGroupDefault(groupID int64) {
+	for i, g := range st.groups {
+		if g.GroupID != groupID {
+			continue
+		}
+		stores := st.stores.FilterByGroupID(groupID)
+		_, isChild := stores.FindByStoreID(g.DefaultStoreID)
+		newID := g.DefaultStoreID
+		switch {
+		case len(stores) == 0:
+			newID = 0
+		case g.DefaultStoreID == 0 && !isChild:
+			newID = stores[0].StoreID
+		}
+		if newID != g.DefaultStoreID {
+			c := *g
+			c.DefaultStoreID = newID
+			st.groups[i] = &c
+			st.emit(ChangeUpdated, scope.Group, c.GroupID, c.Code.String)
+		}
+		return
+	}
+}
+
+// validate checks the invariants: unique and valid codes, exactly one
+// default website, existing default groups and stores which belong to their
+// parent, existing parents and the admin store zero which must belong to the
+// admin website and group.
+func (st *structure) validate() error {
+	codes := make(map[string]bool, len(st.websites))
+	var defaultIDs []int64
+	for _, w := range st.websites {
+		if err := CodeIsValid(w.Code.String); err != nil {
+			return errors.Wrapf(err, "[store] WebsiteID %d", w.WebsiteID)
+		}
+		if codes[w.Code.String] {
+			return errors.NewAlreadyExistsf("[store] Website code %q already in use", w.Code.String)
+		}
+		codes[w.Code.String] = true
+		if w.IsDefault.Bool {
+			defaultIDs = append(defaultIDs, w.WebsiteID)
+		}
+		groups := st.groups.Filter(func(tg *TableGroup) bool { return tg.WebsiteID == w.WebsiteID })
+		if _, found := groups.FindByGroupID(w.DefaultGroupID); len(groups) > 0 && !found || len(groups) == 0 && w.DefaultGroupID != 0 {
+			return errors.NewNotValidf("[store] WebsiteID %d: default group %d does not belong to the website", w.WebsiteID, w.DefaultGroupID)
+		}
+	}
+	if len(st.websites) > 0 && len(defaultIDs) != 1 {
+		return errors.NewNotValidf("[store] Only one Website can be the default Website. Have: %v", defaultIDs)
+	}
+
+	codes = make(map[string]bool, len(st.groups))
+	for _, g := range st.groups {
+		if _, found := st.websites.FindByWebsiteID(g.WebsiteID); !found {
+			return errors.NewNotFoundf("[store] GroupID %d: website %d not found", g.GroupID, g.WebsiteID)
+		}
+		if c := g.Code.String; c != "" {
+			if err := CodeIsValid(c); err != nil {
+				return errors.Wrapf(err, "[store] GroupID %d", g.GroupID)
+			}
+			if codes[c] {
+				return errors.NewAlreadyExistsf("[store] Group code %q already in use", c)
+			}
+			codes[c] = true
+		}
+		stores := st.stores.FilterByGroupID(g.GroupID)
+		if _, found := stores.FindByStoreID(g.DefaultStoreID); len(stores) > 0 && !found || len(stores) == 0 && g.DefaultStoreID != 0 {
+			return errors.NewNotValidf(errGroupDefaultStoreNotFound+" in GroupID %d", g.DefaultStoreID, g.GroupID)
+		}
+	}
+
+	codes = make(map[string]bool, len(st.stores))
+	for _, ts := range st.stores {
+		if err := CodeIsValid(ts.Code.String); err != nil {
+			return errors.Wrapf(err, "[store] StoreID %d", ts.StoreID)
+		}
+		if codes[ts.Code.String] {
+			return errors.NewAlreadyExistsf("[store] Store code %q already in use", ts.Code.String)
+		}
+		codes[ts.Code.String] = true
+		g, found := st.groups.FindByGroupID(ts.GroupID)
+		if !found {
+			return errors.NewNotFoundf("[store] StoreID %d: group %d not found", ts.StoreID, ts.GroupID)
+		}
+		if g.WebsiteID != ts.WebsiteID {
+			return errors.NewNotValidf("[store] StoreID %d: website %d does not match website %d of group %d", ts.StoreID, ts.WebsiteID, g.WebsiteID, g.GroupID)
+		}
+		if ts.StoreID == 0 && (ts.WebsiteID != 0 || ts.GroupID != 0) {
+			return errors.NewNotValidf("[store] The admin store must belong to the admin website and group")
+		}
+	}
+	return nil
+}
+
+// persist writes the changes with the resourcers of a new transaction, if
+// available, otherwise with the resourcers of WithTableResourcers. The
+// transaction gets committed after all events have been written.
+func (st *structure) persist() (err error) {
+	be := st.backend
+	if be.beginTx == nil {
+		return st.write(be.twr, be.tgr, be.tsr)
+	}
+
+	tx, err := be.beginTx()
+	if err != nil {
+		return errors.Wrap(err, "[store] BeginTx")
+	}
+	defer func() {
+		if err == nil {
+			err = errors.Wrap(tx.Commit(), "[store] Commit")
+			return
+		}
+		if err2 := tx.Rollback(); err2 != nil {
+			err = errors.Wrapf(err, "[store] Rollback failed: %s", err2)
+		}
+	}()
+	err = st.write(tx.Resourcers())
+	return
+}
+
+// write writes the changes in the order of the events. New IDs returned by
+// the resourcers replace the temporary IDs.
+func (st *structure) write(twr TableWebsitesResourcer, tgr TableGroupsResourcer, tsr TableStoresResourcer) error {
+	for i := 0; i < len(st.events); i++ {
+		typ, id := st.events[i].Scope.Unpack()
+		action := st.events[i].Action
+		var lastID int
+		var err error
+		switch {
+		case typ == scope.Website && twr != nil:
+			switch action {
+			case ChangeCreated:
+				w, _ := st.websites.FindByWebsiteID(id)
+				lastID, err = twr.Insert(TableWebsiteSlice{w})
+			case ChangeUpdated:
+				w, _ := st.websites.FindByWebsiteID(id)
+				_, err = twr.Update(TableWebsiteSlice{w})
+			case ChangeDeleted:
+				w, _ := st.deleted.websites.FindByWebsiteID(id)
+				_, err = twr.Delete(TableWebsiteSlice{w})
+			}
+		case typ == scope.Group && tgr != nil:
+			switch action {
+			case ChangeCreated:
+				g, _ := st.groups.FindByGroupID(id)
+				lastID, err = tgr.Insert(TableGroupSlice{g})
+			case ChangeUpdated:
+				g, _ := st.groups.FindByGroupID(id)
+				_, err = tgr.Update(TableGroupSlice{g})
+			case ChangeDeleted:
+				g, _ := st.deleted.groups.FindByGroupID(id)
+				_, err = tgr.Delete(TableGroupSlice{g})
+			}
+		case typ == scope.Store && tsr != nil:
+			switch action {
+			case ChangeCreated:
+				ts, _ := st.stores.FindByStoreID(id)
+				lastID, err = tsr.Insert(TableStoreSlice{ts})
+			case ChangeUpdated:
+				ts, _ := st.stores.FindByStoreID(id)
+				_, err = tsr.Update(TableStoreSlice{ts})
+			case ChangeDeleted:
+				ts, _ := st.deleted.stores.FindByStoreID(id)
+				_, err = tsr.Delete(TableStoreSlice{ts})
+			}
+		}
+		if err != nil {
+			return errors.Wrapf(err, "[store] %s %s", st.events[i].Scope, action)
+		}
+		if action == ChangeCreated && lastID > 0 && int64(lastID) != id {
+			st.remap(typ, id, int64(lastID))
+		}
+	}
+	return nil
+}
+
+// remap replaces a temporary ID with the ID assigned by the database.
+func (st *structure) remap(typ scope.Type, oldID, newID int64) {
+	switch typ {
+	case scope.Website:
+		for _, w := range st.websites {
+			if w.WebsiteID == oldID {
+				w.WebsiteID = newID
+			}
+		}
+		for _, g := range st.groups {
+			if g.WebsiteID == oldID {
+				g.WebsiteID = newID
+			}
+		}
+		for _, ts := range st.stores {
+			if ts.WebsiteID == oldID {
+				ts.WebsiteID = newID
+			}
+		}
+	case scope.Group:
+		for _, w := range st.websites {
+			if w.DefaultGroupID == oldID {
+				w.DefaultGroupID = newID
+			}
+		}
+		for _, g := range st.groups {
+			if g.GroupID == oldID {
+				g.GroupID = newID
+			}
+		}
+		for _, ts := range st.stores {
+			if ts.GroupID == oldID {
+				ts.GroupID = newID
+			}
+		}
+	case scope.Store:
+		for _, g := range st.groups {
+			if g.DefaultStoreID == oldID {
+				g.DefaultStoreID = newID
+			}
+		}
+		for _, ts := range st.stores {
+			if ts.StoreID == oldID {
+				ts.StoreID = newID
+			}
+		}
+	}
+	oldTID, newTID := scope.MakeTypeID(typ, oldID), scope.MakeTypeID(typ, newID)
+	for i, ev := range st.events {
+		if ev.Scope == oldTID {
+			st.events[i].Scope = newTID
+		}
+	}
+}
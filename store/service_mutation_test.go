@@ -0,0 +1,287 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"testing"
+
+	"github.com/corestoreio/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/cfgmock"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/null"
+)
+
+func newMutationService(opts ...store.Option) *store.Service {
+	return store.MustNewService(cfgmock.NewService(), append([]store.Option{
+		store.WithTableWebsites(
+			&store.TableWebsite{WebsiteID: 0, Code: null.StringFrom("admin"), Name: null.StringFrom("Admin"), DefaultGroupID: 0, IsDefault: null.BoolFrom(false)},
+			&store.TableWebsite{WebsiteID: 1, Code: null.StringFrom("euro"), Name: null.StringFrom("Europe"), DefaultGroupID: 1, IsDefault: null.BoolFrom(true)},
+		),
+		store.WithTableGroups(
+			&store.TableGroup{GroupID: 0, WebsiteID: 0, Name: "Default", DefaultStoreID: 0},
+			&store.TableGroup{GroupID: 1, WebsiteID: 1, Code: null.StringFrom("dach"), Name: "DACH Group", RootCategoryID: 2, DefaultStoreID: 1},
+		),
+		store.WithTableStores(
+			&store.TableStore{StoreID: 0, Code: null.StringFrom("admin"), WebsiteID: 0, GroupID: 0, Name: "Admin", IsActive: true},
+			&store.TableStore{StoreID: 1, Code: null.StringFrom("de"), WebsiteID: 1, GroupID: 1, Name: "Germany", IsActive: true},
+		),
+	}, opts...)...)
+}
+
+type eventRecorder []store.ChangeEvent
+
+func (er *eventRecorder) record(ev store.ChangeEvent) { *er = append(*er, ev) }
+
+func (er *eventRecorder) reset() []store.ChangeEvent {
+	evs := *er
+	*er = nil
+	return evs
+}
+
+func TestService_Mutation(t *testing.T) {
+	srv := newMutationService()
+	var rec eventRecorder
+	subID, err := srv.Subscribe(rec.record)
+	assert.NoError(t, err)
+
+	tw := &store.TableWebsite{Code: null.StringFrom("asia"), Name: null.StringFrom("Asia")}
+	assert.NoError(t, srv.CreateWebsite(tw))
+	assert.Exactly(t, int64(2), tw.WebsiteID)
+	assert.Exactly(t, []store.ChangeEvent{
+		{Action: store.ChangeCreated, Scope: scope.MakeTypeID(scope.Website, 2), Code: "asia"},
+	}, rec.reset())
+
+	tg := &store.TableGroup{WebsiteID: 2, Code: null.StringFrom("apac"), Name: "APAC", RootCategoryID: 2}
+	assert.NoError(t, srv.CreateGroup(tg))
+	assert.Exactly(t, int64(2), tg.GroupID)
+	assert.Exactly(t, []store.ChangeEvent{
+		{Action: store.ChangeCreated, Scope: scope.MakeTypeID(scope.Group, 2), Code: "apac"},
+		{Action: store.ChangeUpdated, Scope: scope.MakeTypeID(scope.Website, 2), Code: "asia"},
+	}, rec.reset())
+
+	ts := &store.TableStore{Code: null.StringFrom("jp"), WebsiteID: 2, GroupID: 2, Name: "Japan", IsActive: true}
+	assert.NoError(t, srv.CreateStore(ts))
+	assert.Exactly(t, int64(2), ts.StoreID)
+	assert.Exactly(t, []store.ChangeEvent{
+		{Action: store.ChangeCreated, Scope: scope.MakeTypeID(scope.Store, 2), Code: "jp"},
+		{Action: store.ChangeUpdated, Scope: scope.MakeTypeID(scope.Group, 2), Code: "apac"},
+	}, rec.reset())
+
+	w, err := srv.Website(2)
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(2), w.Data.DefaultGroupID)
+	storeID, websiteID, err := srv.StoreIDbyCode(scope.MakeTypeID(scope.Store, 0), "jp")
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(2), storeID)
+	assert.Exactly(t, int64(2), websiteID)
+
+	asia := *w.Data
+	asia.IsDefault = null.BoolFrom(true)
+	assert.NoError(t, srv.UpdateWebsite(&asia))
+	assert.Exactly(t, []store.ChangeEvent{
+		{Action: store.ChangeUpdated, Scope: scope.MakeTypeID(scope.Website, 2), Code: "asia"},
+		{Action: store.ChangeUpdated, Scope: scope.MakeTypeID(scope.Website, 1), Code: "euro"},
+	}, rec.reset())
+	dsv, err := srv.DefaultStoreView()
+	assert.NoError(t, err)
+	assert.Exactly(t, "jp", dsv.Code())
+
+	err = srv.DeleteWebsite(2)
+	assert.True(t, errors.IsNotValid(err), "%+v", err) // default website cannot be deleted
+	assert.Empty(t, rec.reset())
+
+	ew, err := srv.Website(1)
+	assert.NoError(t, err)
+	euro := *ew.Data
+	euro.IsDefault = null.BoolFrom(true)
+	assert.NoError(t, srv.UpdateWebsite(&euro))
+	rec.reset()
+
+	assert.NoError(t, srv.DeleteWebsite(2))
+	assert.Exactly(t, []store.ChangeEvent{
+		{Action: store.ChangeDeleted, Scope: scope.MakeTypeID(scope.Store, 2), Code: "jp"},
+		{Action: store.ChangeDeleted, Scope: scope.MakeTypeID(scope.Group, 2), Code: "apac"},
+		{Action: store.ChangeDeleted, Scope: scope.MakeTypeID(scope.Website, 2), Code: "asia"},
+	}, rec.reset())
+	_, err = srv.Store(2)
+	assert.True(t, errors.IsNotFound(err), "%+v", err)
+
+	assert.NoError(t, srv.Unsubscribe(subID))
+	assert.True(t, errors.IsNotFound(srv.Unsubscribe(subID)))
+	assert.NoError(t, srv.CreateStore(&store.TableStore{Code: null.StringFrom("at"), WebsiteID: 1, GroupID: 1, Name: "Austria", IsActive: true}))
+	assert.Empty(t, rec.reset())
+}
+
+func TestService_Mutation_Errors(t *testing.T) {
+	srv := newMutationService()
+
+	t.Run("admin store", func(t *testing.T) {
+		err := srv.DeleteStore(0)
+		assert.True(t, errors.IsNotSupported(err), "%+v", err)
+	})
+	t.Run("admin website", func(t *testing.T) {
+		err := srv.DeleteWebsite(0)
+		assert.True(t, errors.IsNotSupported(err), "%+v", err)
+	})
+	t.Run("duplicate store code", func(t *testing.T) {
+		err := srv.CreateStore(&store.TableStore{Code: null.StringFrom("de"), WebsiteID: 1, GroupID: 1, Name: "Germany 2"})
+		assert.True(t, errors.IsAlreadyExists(err), "%+v", err)
+	})
+	t.Run("invalid store code", func(t *testing.T) {
+		err := srv.CreateStore(&store.TableStore{Code: null.StringFrom("1de"), WebsiteID: 1, GroupID: 1, Name: "Germany 2"})
+		assert.True(t, errors.IsNotValid(err), "%+v", err)
+	})
+	t.Run("group not found", func(t *testing.T) {
+		err := srv.CreateStore(&store.TableStore{Code: null.StringFrom("ch"), WebsiteID: 1, GroupID: 9, Name: "Swiss"})
+		assert.True(t, errors.IsNotFound(err), "%+v", err)
+	})
+	t.Run("website mismatch", func(t *testing.T) {
+		err := srv.CreateStore(&store.TableStore{Code: null.StringFrom("ch"), WebsiteID: 0, GroupID: 1, Name: "Swiss"})
+		assert.True(t, errors.IsNotValid(err), "%+v", err)
+	})
+	t.Run("update not found", func(t *testing.T) {
+		err := srv.UpdateGroup(&store.TableGroup{GroupID: 33, WebsiteID: 1})
+		assert.True(t, errors.IsNotFound(err), "%+v", err)
+	})
+	t.Run("delete default store", func(t *testing.T) {
+		assert.NoError(t, srv.CreateStore(&store.TableStore{Code: null.StringFrom("ch"), WebsiteID: 1, GroupID: 1, Name: "Swiss", IsActive: true}))
+		err := srv.DeleteStore(1)
+		assert.True(t, errors.IsNotValid(err), "%+v", err)
+		_, err = srv.Store(1)
+		assert.NoError(t, err, "Store 1 must still exist")
+	})
+}
+
+type websiteResourcer struct {
+	store.TableWebsitesResourcer
+	inserted store.TableWebsiteSlice
+	lastID   int
+}
+
+func (wr *websiteResourcer) Insert(tws store.TableWebsiteSlice) (int, error) {
+	wr.inserted = append(wr.inserted, tws...)
+	return wr.lastID, nil
+}
+
+func TestService_Mutation_Persist(t *testing.T) {
+	wr := &websiteResourcer{lastID: 10}
+	srv := newMutationService(store.WithTableResourcers(wr, nil, nil))
+
+	var rec eventRecorder
+	_, err := srv.Subscribe(rec.record)
+	assert.NoError(t, err)
+
+	tw := &store.TableWebsite{Code: null.StringFrom("asia"), Name: null.StringFrom("Asia")}
+	assert.NoError(t, srv.CreateWebsite(tw))
+	assert.Exactly(t, int64(10), tw.WebsiteID)
+	assert.Len(t, wr.inserted, 1)
+	assert.Exactly(t, []store.ChangeEvent{
+		{Action: store.ChangeCreated, Scope: scope.MakeTypeID(scope.Website, 10), Code: "asia"},
+	}, rec.reset())
+
+	_, err = srv.Website(10)
+	assert.NoError(t, err)
+	_, err = srv.Website(2)
+	assert.True(t, errors.IsNotFound(err), "%+v", err)
+}
+
+type groupResourcer struct {
+	store.TableGroupsResourcer
+	deleted store.TableGroupSlice
+}
+
+func (gr *groupResourcer) Delete(tgs store.TableGroupSlice) (int, error) {
+	gr.deleted = append(gr.deleted, tgs...)
+	return len(tgs), nil
+}
+
+type storeResourcer struct {
+	store.TableStoresResourcer
+	deleted   store.TableStoreSlice
+	deleteErr error
+}
+
+func (sr *storeResourcer) Delete(tss store.TableStoreSlice) (int, error) {
+	if sr.deleteErr != nil {
+		return 0, sr.deleteErr
+	}
+	sr.deleted = append(sr.deleted, tss...)
+	return len(tss), nil
+}
+
+type resourcersTx struct {
+	gr        *groupResourcer
+	sr        *storeResourcer
+	commits   int
+	rollbacks int
+}
+
+func (tx *resourcersTx) Resourcers() (store.TableWebsitesResourcer, store.TableGroupsResourcer, store.TableStoresResourcer) {
+	return nil, tx.gr, tx.sr
+}
+func (tx *resourcersTx) Commit() error   { tx.commits++; return nil }
+func (tx *resourcersTx) Rollback() error { tx.rollbacks++; return nil }
+
+func TestService_Mutation_PersistTx(t *testing.T) {
+
+	t.Run("cascading delete gets committed", func(t *testing.T) {
+		tx := &resourcersTx{gr: new(groupResourcer), sr: new(storeResourcer)}
+		srv := newMutationService(store.WithTableResourcersTx(func() (store.TableResourcersTx, error) {
+			return tx, nil
+		}))
+
+		assert.NoError(t, srv.DeleteGroup(1))
+		assert.Exactly(t, 1, tx.commits)
+		assert.Exactly(t, 0, tx.rollbacks)
+		assert.Len(t, tx.gr.deleted, 1)
+		assert.Len(t, tx.sr.deleted, 1)
+		_, err := srv.Group(1)
+		assert.True(t, errors.IsNotFound(err), "%+v", err)
+	})
+
+	t.Run("failed cascading delete gets rolled back", func(t *testing.T) {
+		tx := &resourcersTx{gr: new(groupResourcer), sr: &storeResourcer{deleteErr: errors.NewFatalf("DB gone")}}
+		srv := newMutationService(store.WithTableResourcersTx(func() (store.TableResourcersTx, error) {
+			return tx, nil
+		}))
+
+		err := srv.DeleteWebsite(1)
+		assert.True(t, errors.IsFatal(err), "%+v", err)
+		assert.Exactly(t, 0, tx.commits)
+		assert.Exactly(t, 1, tx.rollbacks)
+		_, err = srv.Website(1)
+		assert.NoError(t, err, "Website 1 must still exist")
+		_, err = srv.Store(1)
+		assert.NoError(t, err, "Store 1 must still exist")
+	})
+
+	t.Run("begin error", func(t *testing.T) {
+		srv := newMutationService(store.WithTableResourcersTx(func() (store.TableResourcersTx, error) {
+			return nil, errors.NewFatalf("DB gone")
+		}))
+		err := srv.DeleteStore(1)
+		assert.True(t, errors.IsFatal(err), "%+v", err)
+		_, err = srv.Store(1)
+		assert.NoError(t, err, "Store 1 must still exist")
+	})
+}
@@ -0,0 +1,199 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storeimport
+
+import (
+	"context"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/sql/dml"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/storage/null"
+)
+
+// Table names used by the Importer.
+const (
+	TableNameWebsite = "store_website"
+	TableNameGroup   = "store_group"
+	TableNameStore   = "store"
+)
+
+type websiteRow struct {
+	ID             int64
+	Code           string
+	Name           string
+	SortOrder      int64
+	DefaultGroupID int64
+	IsDefault      bool
+
+	defaultGroup *groupRow
+	isNew        bool
+	changes      []string
+}
+
+type groupRow struct {
+	ID             int64
+	WebsiteID      int64
+	Code           string
+	Name           string
+	RootCategoryID int64
+	DefaultStoreID int64
+
+	website      *websiteRow
+	defaultStore *storeRow
+	isNew        bool
+	changes      []string
+}
+
+type storeRow struct {
+	ID        int64
+	Code      string
+	WebsiteID int64
+	GroupID   int64
+	Name      string
+	SortOrder int64
+	IsActive  bool
+
+	website    *websiteRow
+	group      *groupRow
+	isNew      bool
+	deactivate bool
+	changes    []string
+}
+
+// tables contains the rows of the three store tables ordered by their primary
+// key. New rows get appended.
+type tables struct {
+	websites []*websiteRow
+	groups   []*groupRow
+	stores   []*storeRow
+}
+
+// selecter gets implemented by *dml.ConnPool and *dml.Tx.
+type selecter interface {
+	SelectFrom(fromAlias ...string) *dml.Select
+}
+
+func loadTables(ctx context.Context, db selecter) (*tables, error) {
+	t := new(tables)
+	var code, name null.String
+	var isDefault null.Bool
+
+	err := db.SelectFrom(TableNameWebsite).
+		AddColumns("website_id", "code", "name", "sort_order", "default_group_id", "is_default").
+		OrderBy("website_id").WithArgs().IterateSerial(ctx, func(cm *dml.ColumnMap) error {
+		code, name, isDefault = null.String{}, null.String{}, null.Bool{}
+		w := new(websiteRow)
+		if err := cm.Int64(&w.ID).NullString(&code).NullString(&name).Int64(&w.SortOrder).Int64(&w.DefaultGroupID).NullBool(&isDefault).Err(); err != nil {
+			return errors.WithStack(err)
+		}
+		w.Code, w.Name, w.IsDefault = code.String, name.String, isDefault.Bool
+		t.websites = append(t.websites, w)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "[storeimport] Load table %q", TableNameWebsite)
+	}
+
+	err = db.SelectFrom(TableNameGroup).
+		AddColumns("group_id", "website_id", "code", "name", "root_category_id", "default_store_id").
+		OrderBy("group_id").WithArgs().IterateSerial(ctx, func(cm *dml.ColumnMap) error {
+		code = null.String{}
+		g := new(groupRow)
+		if err := cm.Int64(&g.ID).Int64(&g.WebsiteID).NullString(&code).String(&g.Name).Int64(&g.RootCategoryID).Int64(&g.DefaultStoreID).Err(); err != nil {
+			return errors.WithStack(err)
+		}
+		g.Code = code.String
+		t.groups = append(t.groups, g)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "[storeimport] Load table %q", TableNameGroup)
+	}
+
+	err = db.SelectFrom(TableNameStore).
+		AddColumns("store_id", "code", "website_id", "group_id", "name", "sort_order", "is_active").
+		OrderBy("store_id").WithArgs().IterateSerial(ctx, func(cm *dml.ColumnMap) error {
+		code = null.String{}
+		s := new(storeRow)
+		if err := cm.Int64(&s.ID).NullString(&code).Int64(&s.WebsiteID).Int64(&s.GroupID).String(&s.Name).Int64(&s.SortOrder).Bool(&s.IsActive).Err(); err != nil {
+			return errors.WithStack(err)
+		}
+		s.Code = code.String
+		t.stores = append(t.stores, s)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "[storeimport] Load table %q", TableNameStore)
+	}
+	t.link()
+	return t, nil
+}
+
+// link connects the loaded rows via pointers.
+func (t *tables) link() {
+	ws := make(map[int64]*websiteRow, len(t.websites))
+	for _, w := range t.websites {
+		ws[w.ID] = w
+	}
+	gs := make(map[int64]*groupRow, len(t.groups))
+	for _, g := range t.groups {
+		gs[g.ID] = g
+		g.website = ws[g.WebsiteID]
+	}
+	ss := make(map[int64]*storeRow, len(t.stores))
+	for _, s := range t.stores {
+		ss[s.ID] = s
+		s.website = ws[s.WebsiteID]
+		s.group = gs[s.GroupID]
+	}
+	for _, w := range t.websites {
+		w.defaultGroup = gs[w.DefaultGroupID]
+	}
+	for _, g := range t.groups {
+		g.defaultStore = ss[g.DefaultStoreID]
+	}
+}
+
+// resolveIDs copies the IDs of the linked rows into the foreign key fields.
+// Must be called after new rows have received their auto increment IDs.
+func (t *tables) resolveIDs() {
+	for _, w := range t.websites {
+		if w.defaultGroup != nil {
+			w.DefaultGroupID = w.defaultGroup.ID
+		}
+	}
+	for _, g := range t.groups {
+		if g.website != nil {
+			g.WebsiteID = g.website.ID
+		}
+		if g.defaultStore != nil {
+			g.DefaultStoreID = g.defaultStore.ID
+		}
+	}
+	for _, s := range t.stores {
+		if s.website != nil {
+			s.WebsiteID = s.website.ID
+		}
+		if s.group != nil {
+			s.GroupID = s.group.ID
+		}
+	}
+}
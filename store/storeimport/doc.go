@@ -0,0 +1,50 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storeimport provisions websites, store groups and store views from a
+// declarative YAML file.
+//
+// The YAML file describes the desired state below the root key
+// "store-structure". Two layouts are supported; the Magento like layout which
+// calls groups "stores" and stores "store-views" and the CoreStore layout
+// which uses "groups" and "stores". See the files in the testdata directory.
+//
+//	store-structure:
+//	    websites:
+//	        en:
+//	            name: English
+//	            groups:
+//	                pos_us:
+//	                    name: POS United States
+//	                    root-category: POS Catalog
+//	                    stores:
+//	                        en_us:
+//	                            code: pos_en_us
+//	                            name: POS United States EN US
+//
+// An empty code falls back to the map key. All codes get validated with
+// store.CodeIsValid.
+//
+// An Importer compares the parsed Structure with the current content of the
+// tables store_website, store_group and store and calculates a Plan which
+// contains the steps to create, update or deactivate entities. Stores which
+// are not part of the file get deactivated, never deleted. Applying the Plan
+// runs within one database transaction and reloads the store.Service
+// afterwards.
+package storeimport
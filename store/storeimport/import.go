@@ -0,0 +1,544 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storeimport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/sql/dml"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/null"
+)
+
+// Reloader gets called after a successful import with the new content of the
+// tables. *store.Service implements this interface.
+type Reloader interface {
+	LoadFromTables(store.TableWebsiteSlice, store.TableGroupSlice, store.TableStoreSlice) error
+}
+
+// Options applied to an Importer.
+type Options struct {
+	// RootCategories maps the root category names used in the YAML file to
+	// the category IDs. A numeric name gets used as ID if not found in the
+	// map.
+	RootCategories map[string]int64
+	// KeepUnknownStores disables the deactivation of stores which are
+	// defined in the database but not in the YAML file.
+	KeepUnknownStores bool
+	// Reloader optional, gets called after the transaction has been
+	// committed.
+	Reloader Reloader
+}
+
+// Importer calculates and applies a Plan to provision websites, groups and
+// stores.
+type Importer struct {
+	db *dml.ConnPool
+	o  Options
+}
+
+// NewImporter creates a new Importer which uses the connection pool to query
+// and modify the tables.
+func NewImporter(db *dml.ConnPool, o Options) *Importer {
+	return &Importer{
+		db: db,
+		o:  o,
+	}
+}
+
+// Plan calculates the steps required to transform the current database state
+// into the Structure. The database does not get modified.
+func (im *Importer) Plan(ctx context.Context, s *Structure) (*Plan, error) {
+	cur, err := loadTables(ctx, im.db)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	p, err := newPlan(cur, s, im.o)
+	return p, errors.WithStack(err)
+}
+
+// Apply calculates the Plan and executes it within one transaction. The
+// current state gets read within the same transaction. On success the
+// Reloader, if set, receives the new table content.
+func (im *Importer) Apply(ctx context.Context, s *Structure) (*Plan, error) {
+	var p *Plan
+	err := im.db.Transaction(ctx, nil, func(tx *dml.Tx) error {
+		cur, err := loadTables(ctx, tx)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if p, err = newPlan(cur, s, im.o); err != nil {
+			return errors.WithStack(err)
+		}
+		return errors.WithStack(p.exec(ctx, tx))
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if im.o.Reloader != nil {
+		if err := im.o.Reloader.LoadFromTables(p.Tables()); err != nil {
+			return p, errors.Wrap(err, "[storeimport] Apply.Reloader")
+		}
+	}
+	return p, nil
+}
+
+// Action defines what happens with an entity.
+type Action uint8
+
+// Action constants
+const (
+	ActionCreate Action = iota + 1
+	ActionUpdate
+	ActionDeactivate
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionCreate:
+		return "create"
+	case ActionUpdate:
+		return "update"
+	case ActionDeactivate:
+		return "deactivate"
+	}
+	return "Action(" + strconv.Itoa(int(a)) + ")"
+}
+
+func (a Action) symbol() byte {
+	switch a {
+	case ActionCreate:
+		return '+'
+	case ActionDeactivate:
+		return '-'
+	}
+	return '~'
+}
+
+// Step a single modification of a website, group or store.
+type Step struct {
+	Action Action
+	// Scope one of scope.Website, scope.Group or scope.Store.
+	Scope scope.Type
+	Code  string
+	// Changes contains the modified columns in the format
+	// "column: old => new". Empty for ActionCreate.
+	Changes []string
+}
+
+func (s Step) String() string {
+	str := fmt.Sprintf("%c %s %s", s.Action.symbol(), s.Scope, s.Code)
+	if len(s.Changes) > 0 {
+		str += " (" + strings.Join(s.Changes, ", ") + ")"
+	}
+	return str
+}
+
+// Plan contains the steps to apply the Structure to the database.
+type Plan struct {
+	Steps []Step
+	t     *tables
+}
+
+// String returns one line per step.
+func (p *Plan) String() string {
+	var buf bytes.Buffer
+	for _, s := range p.Steps {
+		buf.WriteString(s.String())
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// Tables returns the table content after the plan has been applied.
+// Auto increment IDs of new rows are only available after a successful Apply.
+func (p *Plan) Tables() (store.TableWebsiteSlice, store.TableGroupSlice, store.TableStoreSlice) {
+	tws := make(store.TableWebsiteSlice, 0, len(p.t.websites))
+	for _, w := range p.t.websites {
+		tws = append(tws, &store.TableWebsite{
+			WebsiteID:      w.ID,
+			Code:           null.StringFrom(w.Code),
+			Name:           null.StringFrom(w.Name),
+			SortOrder:      w.SortOrder,
+			DefaultGroupID: w.DefaultGroupID,
+			IsDefault:      null.BoolFrom(w.IsDefault),
+		})
+	}
+	tgs := make(store.TableGroupSlice, 0, len(p.t.groups))
+	for _, g := range p.t.groups {
+		tgs = append(tgs, &store.TableGroup{
+			GroupID:        g.ID,
+			WebsiteID:      g.WebsiteID,
+			Code:           null.StringFrom(g.Code),
+			Name:           g.Name,
+			RootCategoryID: g.RootCategoryID,
+			DefaultStoreID: g.DefaultStoreID,
+		})
+	}
+	tss := make(store.TableStoreSlice, 0, len(p.t.stores))
+	for _, s := range p.t.stores {
+		tss = append(tss, &store.TableStore{
+			StoreID:   s.ID,
+			Code:      null.StringFrom(s.Code),
+			WebsiteID: s.WebsiteID,
+			GroupID:   s.GroupID,
+			Name:      s.Name,
+			SortOrder: s.SortOrder,
+			IsActive:  s.IsActive,
+		})
+	}
+	return tws, tgs, tss
+}
+
+func change(column string, old, new interface{}) string {
+	return fmt.Sprintf("%s: %v => %v", column, old, new)
+}
+
+// newPlan modifies the rows in cur to match the Structure and records the
+// differences as steps.
+func newPlan(cur *tables, s *Structure, o Options) (*Plan, error) {
+	websites := make(map[string]*websiteRow, len(cur.websites))
+	for _, w := range cur.websites {
+		websites[w.Code] = w
+	}
+	groups := make(map[string]*groupRow, len(cur.groups))
+	for _, g := range cur.groups {
+		if g.Code != "" {
+			groups[g.Code] = g
+		}
+	}
+	stores := make(map[string]*storeRow, len(cur.stores))
+	for _, st := range cur.stores {
+		stores[st.Code] = st
+	}
+
+	// Magento requires globally unique group codes.
+	groupCodeCount := make(map[string]int)
+	for _, w := range s.Websites {
+		for _, g := range w.Groups {
+			groupCodeCount[g.Code]++
+		}
+	}
+
+	var defaultWebsite, firstWebsite *websiteRow
+	seenStores := make(map[*storeRow]bool, len(cur.stores))
+	for _, w := range s.Websites {
+		wr := websites[w.Code]
+		switch {
+		case wr == nil:
+			wr = &websiteRow{Code: w.Code, Name: w.Name, SortOrder: w.SortOrder, isNew: true}
+			cur.websites = append(cur.websites, wr)
+		case wr.ID == 0:
+			return nil, errors.NotSupported.Newf("[storeimport] Website %q: the admin website cannot be modified", w.Code)
+		default:
+			if wr.Name != w.Name {
+				wr.changes = append(wr.changes, change("name", wr.Name, w.Name))
+				wr.Name = w.Name
+			}
+			if wr.SortOrder != w.SortOrder {
+				wr.changes = append(wr.changes, change("sort_order", wr.SortOrder, w.SortOrder))
+				wr.SortOrder = w.SortOrder
+			}
+		}
+		if firstWebsite == nil {
+			firstWebsite = wr
+		}
+		if w.Default {
+			defaultWebsite = wr
+		}
+
+		for gIdx, g := range w.Groups {
+			code := g.Code
+			if groupCodeCount[code] > 1 {
+				code = w.Code + "_" + g.Code
+				if err := store.CodeIsValid(code); err != nil {
+					return nil, errors.NotValid.New(err, "[storeimport] Website %q Group %q: prefixed group code %q", w.Code, g.Code, code)
+				}
+			}
+			gr := groups[code]
+			if gr == nil && !wr.isNew {
+				// Groups created before Magento 2.2 have no code.
+				for _, cg := range cur.groups {
+					if cg.Code == "" && cg.website == wr && cg.Name == g.Name {
+						gr = cg
+						break
+					}
+				}
+			}
+			if gr == nil {
+				rootID, err := o.rootCategoryID(g.RootCategory)
+				if err != nil {
+					return nil, errors.Wrapf(err, "[storeimport] Website %q Group %q", w.Code, g.Code)
+				}
+				gr = &groupRow{Code: code, Name: g.Name, RootCategoryID: rootID, website: wr, isNew: true}
+				cur.groups = append(cur.groups, gr)
+				groups[code] = gr
+			} else {
+				if gr.ID == 0 {
+					return nil, errors.NotSupported.Newf("[storeimport] Group %q: the admin group cannot be modified", code)
+				}
+				if gr.Code != code {
+					gr.changes = append(gr.changes, change("code", gr.Code, code))
+					gr.Code = code
+				}
+				if gr.website != wr {
+					gr.changes = append(gr.changes, change("website", gr.website.code(), wr.Code))
+					gr.website = wr
+				}
+				if gr.Name != g.Name {
+					gr.changes = append(gr.changes, change("name", gr.Name, g.Name))
+					gr.Name = g.Name
+				}
+				if g.RootCategory != "" {
+					rootID, err := o.rootCategoryID(g.RootCategory)
+					if err != nil {
+						return nil, errors.Wrapf(err, "[storeimport] Website %q Group %q", w.Code, g.Code)
+					}
+					if gr.RootCategoryID != rootID {
+						gr.changes = append(gr.changes, change("root_category_id", gr.RootCategoryID, rootID))
+						gr.RootCategoryID = rootID
+					}
+				}
+			}
+			if gIdx == 0 && wr.defaultGroup != gr {
+				if !wr.isNew {
+					wr.changes = append(wr.changes, change("default_group", wr.defaultGroup.code(), gr.Code))
+				}
+				wr.defaultGroup = gr
+			}
+
+			for sIdx, st := range g.Stores {
+				sr := stores[st.Code]
+				if sr == nil {
+					sr = &storeRow{Code: st.Code, Name: st.Name, SortOrder: st.SortOrder, IsActive: st.IsActive(), website: wr, group: gr, isNew: true}
+					cur.stores = append(cur.stores, sr)
+				} else {
+					if sr.ID == 0 {
+						return nil, errors.NotSupported.Newf("[storeimport] Store %q: the admin store cannot be modified", st.Code)
+					}
+					if sr.website != wr {
+						sr.changes = append(sr.changes, change("website", sr.website.code(), wr.Code))
+						sr.website = wr
+					}
+					if sr.group != gr {
+						sr.changes = append(sr.changes, change("group", sr.group.code(), gr.Code))
+						sr.group = gr
+					}
+					if sr.Name != st.Name {
+						sr.changes = append(sr.changes, change("name", sr.Name, st.Name))
+						sr.Name = st.Name
+					}
+					if sr.SortOrder != st.SortOrder {
+						sr.changes = append(sr.changes, change("sort_order", sr.SortOrder, st.SortOrder))
+						sr.SortOrder = st.SortOrder
+					}
+					if sr.IsActive != st.IsActive() {
+						sr.changes = append(sr.changes, change("is_active", sr.IsActive, st.IsActive()))
+						sr.IsActive = st.IsActive()
+					}
+				}
+				seenStores[sr] = true
+
+				if (g.DefaultStore == "" && sIdx == 0 || g.DefaultStore == st.Code) && gr.defaultStore != sr {
+					if !gr.isNew {
+						gr.changes = append(gr.changes, change("default_store", gr.defaultStore.code(), sr.Code))
+					}
+					gr.defaultStore = sr
+				}
+			}
+		}
+	}
+
+	if !o.KeepUnknownStores {
+		for _, sr := range cur.stores {
+			if sr.ID != 0 && !seenStores[sr] && sr.IsActive {
+				sr.IsActive = false
+				sr.deactivate = true
+			}
+		}
+	}
+
+	if defaultWebsite == nil {
+		hasDefault := false
+		for _, w := range cur.websites {
+			hasDefault = hasDefault || (w.ID != 0 && w.IsDefault)
+		}
+		if !hasDefault {
+			defaultWebsite = firstWebsite
+		}
+	}
+	if defaultWebsite != nil {
+		for _, w := range cur.websites {
+			if isDefault := w == defaultWebsite; w.IsDefault != isDefault {
+				if !w.isNew {
+					w.changes = append(w.changes, change("is_default", w.IsDefault, isDefault))
+				}
+				w.IsDefault = isDefault
+			}
+		}
+	}
+
+	p := &Plan{t: cur}
+	for _, w := range cur.websites {
+		p.addStep(scope.Website, w.Code, w.isNew, false, w.changes)
+	}
+	for _, g := range cur.groups {
+		p.addStep(scope.Group, g.Code, g.isNew, false, g.changes)
+	}
+	for _, st := range cur.stores {
+		p.addStep(scope.Store, st.Code, st.isNew, st.deactivate, st.changes)
+	}
+	return p, nil
+}
+
+func (p *Plan) addStep(typ scope.Type, code string, isNew, deactivate bool, changes []string) {
+	switch {
+	case isNew:
+		p.Steps = append(p.Steps, Step{Action: ActionCreate, Scope: typ, Code: code})
+	case deactivate:
+		p.Steps = append(p.Steps, Step{Action: ActionDeactivate, Scope: typ, Code: code})
+	case len(changes) > 0:
+		p.Steps = append(p.Steps, Step{Action: ActionUpdate, Scope: typ, Code: code, Changes: changes})
+	}
+}
+
+// exec inserts the new rows in the order websites, groups and stores and
+// updates afterwards all modified rows including the foreign keys of the new
+// rows.
+func (p *Plan) exec(ctx context.Context, tx *dml.Tx) error {
+	if len(p.Steps) == 0 {
+		return nil
+	}
+	t := p.t
+	insWebsite := tx.InsertInto(TableNameWebsite).AddColumns("code", "name", "sort_order", "default_group_id", "is_default").BuildValues()
+	for _, w := range t.websites {
+		if w.isNew {
+			res, err := insWebsite.WithArgs().ExecContext(ctx, w.Code, w.Name, w.SortOrder, int64(0), w.IsDefault)
+			if err != nil {
+				return errors.Wrapf(err, "[storeimport] Insert website %q", w.Code)
+			}
+			if w.ID, err = res.LastInsertId(); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	t.resolveIDs()
+
+	insGroup := tx.InsertInto(TableNameGroup).AddColumns("website_id", "code", "name", "root_category_id", "default_store_id").BuildValues()
+	for _, g := range t.groups {
+		if g.isNew {
+			res, err := insGroup.WithArgs().ExecContext(ctx, g.WebsiteID, g.Code, g.Name, g.RootCategoryID, int64(0))
+			if err != nil {
+				return errors.Wrapf(err, "[storeimport] Insert group %q", g.Code)
+			}
+			if g.ID, err = res.LastInsertId(); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	t.resolveIDs()
+
+	insStore := tx.InsertInto(TableNameStore).AddColumns("code", "website_id", "group_id", "name", "sort_order", "is_active").BuildValues()
+	for _, s := range t.stores {
+		if s.isNew {
+			res, err := insStore.WithArgs().ExecContext(ctx, s.Code, s.WebsiteID, s.GroupID, s.Name, s.SortOrder, s.IsActive)
+			if err != nil {
+				return errors.Wrapf(err, "[storeimport] Insert store %q", s.Code)
+			}
+			if s.ID, err = res.LastInsertId(); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	t.resolveIDs()
+
+	updWebsite := tx.Update(TableNameWebsite).AddColumns("name", "sort_order", "default_group_id", "is_default").
+		Where(dml.Column("website_id").PlaceHolder())
+	for _, w := range t.websites {
+		if w.isNew || len(w.changes) > 0 {
+			if _, err := updWebsite.WithArgs().ExecContext(ctx, w.Name, w.SortOrder, w.DefaultGroupID, w.IsDefault, w.ID); err != nil {
+				return errors.Wrapf(err, "[storeimport] Update website %q", w.Code)
+			}
+		}
+	}
+	updGroup := tx.Update(TableNameGroup).AddColumns("website_id", "code", "name", "root_category_id", "default_store_id").
+		Where(dml.Column("group_id").PlaceHolder())
+	for _, g := range t.groups {
+		if g.isNew || len(g.changes) > 0 {
+			if _, err := updGroup.WithArgs().ExecContext(ctx, g.WebsiteID, g.Code, g.Name, g.RootCategoryID, g.DefaultStoreID, g.ID); err != nil {
+				return errors.Wrapf(err, "[storeimport] Update group %q", g.Code)
+			}
+		}
+	}
+	updStore := tx.Update(TableNameStore).AddColumns("website_id", "group_id", "name", "sort_order", "is_active").
+		Where(dml.Column("store_id").PlaceHolder())
+	for _, s := range t.stores {
+		if !s.isNew && (s.deactivate || len(s.changes) > 0) {
+			if _, err := updStore.WithArgs().ExecContext(ctx, s.WebsiteID, s.GroupID, s.Name, s.SortOrder, s.IsActive, s.ID); err != nil {
+				return errors.Wrapf(err, "[storeimport] Update store %q", s.Code)
+			}
+		}
+	}
+	return nil
+}
+
+// rootCategoryID resolves the name via the RootCategories map or parses it as
+// an integer.
+func (o Options) rootCategoryID(name string) (int64, error) {
+	if name == "" {
+		return 0, errors.Empty.Newf("[storeimport] Root category is empty")
+	}
+	if id, ok := o.RootCategories[name]; ok {
+		return id, nil
+	}
+	id, err := strconv.ParseInt(name, 10, 64)
+	if err != nil {
+		return 0, errors.NotFound.Newf("[storeimport] Root category %q not found in Options.RootCategories", name)
+	}
+	return id, nil
+}
+
+func (w *websiteRow) code() string {
+	if w == nil {
+		return "<nil>"
+	}
+	return w.Code
+}
+
+func (g *groupRow) code() string {
+	if g == nil {
+		return "<nil>"
+	}
+	return g.Code
+}
+
+func (s *storeRow) code() string {
+	if s == nil {
+		return "<nil>"
+	}
+	return s.Code
+}
@@ -0,0 +1,195 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storeimport_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/sql/dmltest"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/storeimport"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+const testStructure = `store-structure:
+    websites:
+        en:
+            name: English
+            groups:
+                pos_us:
+                    name: POS United States
+                    root-category: 2
+                    stores:
+                        en_us:
+                            code: pos_en_us
+                            name: POS United States EN
+        de:
+            name: German
+            default: true
+            groups:
+                pos_de:
+                    name: POS Germany
+                    root-category: POS Catalog
+                    stores:
+                        de_de:
+                            code: pos_de_de
+                            name: POS Germany DE
+`
+
+type reloader struct {
+	tws store.TableWebsiteSlice
+	tgs store.TableGroupSlice
+	tss store.TableStoreSlice
+}
+
+func (r *reloader) LoadFromTables(tws store.TableWebsiteSlice, tgs store.TableGroupSlice, tss store.TableStoreSlice) error {
+	r.tws, r.tgs, r.tss = tws, tgs, tss
+	return nil
+}
+
+func expectCurrentTables(dbMock sqlmock.Sqlmock) {
+	dbMock.ExpectQuery("SELECT .+ FROM `store_website`").WillReturnRows(
+		sqlmock.NewRows([]string{"website_id", "code", "name", "sort_order", "default_group_id", "is_default"}).
+			AddRow(0, "admin", "Admin", 0, 0, 0).
+			AddRow(1, "en", "Englisch", 0, 1, 1),
+	)
+	dbMock.ExpectQuery("SELECT .+ FROM `store_group`").WillReturnRows(
+		sqlmock.NewRows([]string{"group_id", "website_id", "code", "name", "root_category_id", "default_store_id"}).
+			AddRow(0, 0, "default", "Default", 0, 0).
+			AddRow(1, 1, nil, "POS United States", 2, 1),
+	)
+	dbMock.ExpectQuery("SELECT .+ FROM `store`").WillReturnRows(
+		sqlmock.NewRows([]string{"store_id", "code", "website_id", "group_id", "name", "sort_order", "is_active"}).
+			AddRow(0, "admin", 0, 0, "Admin", 0, 1).
+			AddRow(1, "pos_en_us", 1, 1, "POS United States EN", 0, 1).
+			AddRow(2, "old", 1, 1, "Old Store", 0, 1),
+	)
+}
+
+func parseTestStructure(t *testing.T) *storeimport.Structure {
+	s, err := storeimport.Parse(strings.NewReader(testStructure))
+	assert.NoError(t, err, "%+v", err)
+	return s
+}
+
+var testOptions = storeimport.Options{
+	RootCategories: map[string]int64{"POS Catalog": 3},
+}
+
+func TestImporter_Plan(t *testing.T) {
+	dbc, dbMock := dmltest.MockDB(t)
+	defer dmltest.MockClose(t, dbc, dbMock)
+
+	expectCurrentTables(dbMock)
+
+	p, err := storeimport.NewImporter(dbc, testOptions).Plan(context.TODO(), parseTestStructure(t))
+	assert.NoError(t, err, "%+v", err)
+
+	assert.Exactly(t, []storeimport.Step{
+		{Action: storeimport.ActionUpdate, Scope: scope.Website, Code: "en", Changes: []string{"name: Englisch => English", "is_default: true => false"}},
+		{Action: storeimport.ActionCreate, Scope: scope.Website, Code: "de"},
+		{Action: storeimport.ActionUpdate, Scope: scope.Group, Code: "pos_us", Changes: []string{"code:  => pos_us"}},
+		{Action: storeimport.ActionCreate, Scope: scope.Group, Code: "pos_de"},
+		{Action: storeimport.ActionDeactivate, Scope: scope.Store, Code: "old"},
+		{Action: storeimport.ActionCreate, Scope: scope.Store, Code: "pos_de_de"},
+	}, p.Steps)
+	assert.Contains(t, p.String(), "+ Group pos_de\n")
+}
+
+func TestImporter_Apply(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dbc, dbMock := dmltest.MockDB(t)
+		defer dmltest.MockClose(t, dbc, dbMock)
+
+		dbMock.ExpectBegin()
+		expectCurrentTables(dbMock)
+		dbMock.ExpectExec("INSERT INTO `store_website`").WillReturnResult(sqlmock.NewResult(2, 1))
+		dbMock.ExpectExec("INSERT INTO `store_group`").WillReturnResult(sqlmock.NewResult(2, 1))
+		dbMock.ExpectExec("INSERT INTO `store`").WillReturnResult(sqlmock.NewResult(3, 1))
+		dbMock.ExpectExec("UPDATE `store_website`").WillReturnResult(sqlmock.NewResult(0, 1)) // en
+		dbMock.ExpectExec("UPDATE `store_website`").WillReturnResult(sqlmock.NewResult(0, 1)) // de
+		dbMock.ExpectExec("UPDATE `store_group`").WillReturnResult(sqlmock.NewResult(0, 1))   // pos_us
+		dbMock.ExpectExec("UPDATE `store_group`").WillReturnResult(sqlmock.NewResult(0, 1))   // pos_de
+		dbMock.ExpectExec("UPDATE `store`").WillReturnResult(sqlmock.NewResult(0, 1))         // old
+		dbMock.ExpectCommit()
+
+		rl := new(reloader)
+		o := testOptions
+		o.Reloader = rl
+		p, err := storeimport.NewImporter(dbc, o).Apply(context.TODO(), parseTestStructure(t))
+		assert.NoError(t, err, "%+v", err)
+		assert.Len(t, p.Steps, 6)
+
+		assert.Len(t, rl.tws, 3)
+		de := rl.tws[2]
+		assert.Exactly(t, int64(2), de.WebsiteID)
+		assert.Exactly(t, int64(2), de.DefaultGroupID)
+		assert.True(t, de.IsDefault.Bool)
+		assert.False(t, rl.tws[1].IsDefault.Bool)
+
+		assert.Len(t, rl.tgs, 3)
+		assert.Exactly(t, "pos_us", rl.tgs[1].Code.String)
+		assert.Exactly(t, int64(2), rl.tgs[2].WebsiteID)
+		assert.Exactly(t, int64(3), rl.tgs[2].RootCategoryID)
+		assert.Exactly(t, int64(3), rl.tgs[2].DefaultStoreID)
+
+		assert.Len(t, rl.tss, 4)
+		assert.False(t, rl.tss[2].IsActive, "Store old must be deactivated")
+		assert.Exactly(t, int64(3), rl.tss[3].StoreID)
+		assert.Exactly(t, int64(2), rl.tss[3].GroupID)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		dbc, dbMock := dmltest.MockDB(t)
+		defer dmltest.MockClose(t, dbc, dbMock)
+
+		dbMock.ExpectBegin()
+		expectCurrentTables(dbMock)
+		dbMock.ExpectExec("INSERT INTO `store_website`").WillReturnError(errors.AlreadyExists.Newf("Duplicate entry"))
+		dbMock.ExpectRollback()
+
+		rl := new(reloader)
+		o := testOptions
+		o.Reloader = rl
+		p, err := storeimport.NewImporter(dbc, o).Apply(context.TODO(), parseTestStructure(t))
+		assert.Nil(t, p)
+		assert.True(t, errors.AlreadyExists.Match(err), "%+v", err)
+		assert.Nil(t, rl.tws)
+	})
+
+	t.Run("unknown root category", func(t *testing.T) {
+		dbc, dbMock := dmltest.MockDB(t)
+		defer dmltest.MockClose(t, dbc, dbMock)
+
+		dbMock.ExpectBegin()
+		expectCurrentTables(dbMock)
+		dbMock.ExpectRollback()
+
+		p, err := storeimport.NewImporter(dbc, storeimport.Options{}).Apply(context.TODO(), parseTestStructure(t))
+		assert.Nil(t, p)
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+	})
+}
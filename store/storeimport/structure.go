@@ -0,0 +1,335 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storeimport
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/corestoreio/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store"
+)
+
+// Structure defines the desired websites, groups and stores.
+type Structure struct {
+	Websites Websites `yaml:"websites"`
+}
+
+// Websites an ordered list of websites. The order of the YAML file gets
+// preserved.
+type Websites []*Website
+
+// Website defines a website and its groups.
+type Website struct {
+	Code      string `yaml:"code"`
+	Name      string `yaml:"name"`
+	SortOrder int64  `yaml:"sort-order"`
+	// Default marks the website as the default website. If no website in the
+	// file and in the database is the default one, the first website of the
+	// file gets the default.
+	Default bool   `yaml:"default"`
+	Groups  Groups `yaml:"groups"`
+}
+
+// Groups an ordered list of store groups.
+type Groups []*Group
+
+// Group defines a store group and its stores.
+type Group struct {
+	// Code identifies a group within its website. The same group code can be
+	// used in different websites. Magento requires unique group codes, in
+	// that case the database code gets prefixed with the website code, see
+	// Importer.
+	Code string `yaml:"code"`
+	Name string `yaml:"name"`
+	// RootCategory the name or the ID of the root category. The name gets
+	// resolved via Options.RootCategories.
+	RootCategory string `yaml:"root-category"`
+	// DefaultStore code of the default store. If empty, the first store of
+	// the group gets used.
+	DefaultStore string `yaml:"default-store"`
+	Stores       Stores `yaml:"stores"`
+}
+
+// Stores an ordered list of store views.
+type Stores []*Store
+
+// Store defines a store view.
+type Store struct {
+	Code      string `yaml:"code"`
+	Name      string `yaml:"name"`
+	SortOrder int64  `yaml:"sort-order"`
+	// Active defaults to true if not set.
+	Active *bool `yaml:"active"`
+}
+
+// IsActive returns the active flag and applies the default value true.
+func (s *Store) IsActive() bool {
+	return s.Active == nil || *s.Active
+}
+
+// ParseFile parses the YAML file and validates the structure.
+func ParseFile(fileName string) (*Structure, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, errors.NotFound.New(err, "[storeimport] ParseFile.Open: %q", fileName)
+	}
+	defer f.Close()
+	s, err := Parse(f)
+	return s, errors.WithStack(err)
+}
+
+// Parse reads the YAML document from r and validates the structure.
+func Parse(r io.Reader) (*Structure, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var doc struct {
+		Structure *Structure `yaml:"store-structure"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.NotValid.New(err, "[storeimport] Parse.Unmarshal")
+	}
+	if doc.Structure == nil {
+		return nil, errors.NotFound.Newf("[storeimport] Parse: root key \"store-structure\" not found")
+	}
+	if err := doc.Structure.Validate(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return doc.Structure, nil
+}
+
+// Validate checks all codes with store.CodeIsValid and searches for
+// duplicates. Website and store codes must be unique, group codes must be
+// unique within a website.
+func (s *Structure) Validate() error {
+	if len(s.Websites) == 0 {
+		return errors.Empty.Newf("[storeimport] Structure contains no websites")
+	}
+	websites := make(map[string]bool, len(s.Websites))
+	stores := make(map[string]string)
+	var defaultWebsite string
+	for _, w := range s.Websites {
+		if err := store.CodeIsValid(w.Code); err != nil {
+			return errors.NotValid.New(err, "[storeimport] Website %q", w.Code)
+		}
+		if websites[w.Code] {
+			return errors.Duplicated.Newf("[storeimport] Website code %q already defined", w.Code)
+		}
+		websites[w.Code] = true
+		if w.Default {
+			if defaultWebsite != "" {
+				return errors.NotValid.Newf("[storeimport] Websites %q and %q are both marked as default", defaultWebsite, w.Code)
+			}
+			defaultWebsite = w.Code
+		}
+		if len(w.Groups) == 0 {
+			return errors.Empty.Newf("[storeimport] Website %q contains no groups", w.Code)
+		}
+
+		groups := make(map[string]bool, len(w.Groups))
+		for _, g := range w.Groups {
+			if err := store.CodeIsValid(g.Code); err != nil {
+				return errors.NotValid.New(err, "[storeimport] Website %q Group %q", w.Code, g.Code)
+			}
+			if groups[g.Code] {
+				return errors.Duplicated.Newf("[storeimport] Website %q: group code %q already defined", w.Code, g.Code)
+			}
+			groups[g.Code] = true
+			if len(g.Stores) == 0 {
+				return errors.Empty.Newf("[storeimport] Website %q Group %q contains no stores", w.Code, g.Code)
+			}
+
+			foundDefault := g.DefaultStore == ""
+			for _, st := range g.Stores {
+				if err := store.CodeIsValid(st.Code); err != nil {
+					return errors.NotValid.New(err, "[storeimport] Website %q Group %q Store %q", w.Code, g.Code, st.Code)
+				}
+				if prev, ok := stores[st.Code]; ok {
+					return errors.Duplicated.Newf("[storeimport] Store code %q already defined in %s", st.Code, prev)
+				}
+				stores[st.Code] = fmt.Sprintf("%s/%s", w.Code, g.Code)
+				foundDefault = foundDefault || g.DefaultStore == st.Code
+			}
+			if !foundDefault {
+				return errors.NotFound.Newf("[storeimport] Website %q Group %q: default store %q not found in group", w.Code, g.Code, g.DefaultStore)
+			}
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML preserves the order of the websites and applies the map key as
+// code if the code is empty.
+func (ws *Websites) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	keys, err := orderedKeys(unmarshal)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var m map[string]*Website
+	if err := unmarshal(&m); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, k := range keys {
+		w := m[k]
+		if w == nil {
+			w = &Website{}
+		}
+		if w.Code == "" {
+			w.Code = k
+		}
+		if w.Name == "" {
+			w.Name = w.Code
+		}
+		*ws = append(*ws, w)
+	}
+	return nil
+}
+
+// UnmarshalYAML supports both layouts "groups"/"stores" and
+// "stores"/"store-views".
+func (w *Website) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw struct {
+		Code      string `yaml:"code"`
+		Name      string `yaml:"name"`
+		SortOrder int64  `yaml:"sort-order"`
+		Default   bool   `yaml:"default"`
+		Groups    Groups `yaml:"groups"`
+		Stores    Groups `yaml:"stores"`
+	}
+	if err := unmarshal(&raw); err != nil {
+		return errors.WithStack(err)
+	}
+	if len(raw.Groups) > 0 && len(raw.Stores) > 0 {
+		return errors.NotValid.Newf("[storeimport] Website %q: keys \"groups\" and \"stores\" cannot be mixed", raw.Code)
+	}
+	*w = Website{
+		Code:      raw.Code,
+		Name:      raw.Name,
+		SortOrder: raw.SortOrder,
+		Default:   raw.Default,
+		Groups:    raw.Groups,
+	}
+	if len(raw.Stores) > 0 {
+		w.Groups = raw.Stores
+	}
+	return nil
+}
+
+// UnmarshalYAML preserves the order of the groups and applies the map key as
+// code if the code is empty.
+func (gs *Groups) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	keys, err := orderedKeys(unmarshal)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var m map[string]*Group
+	if err := unmarshal(&m); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, k := range keys {
+		g := m[k]
+		if g == nil {
+			g = &Group{}
+		}
+		if g.Code == "" {
+			g.Code = k
+		}
+		if g.Name == "" {
+			g.Name = g.Code
+		}
+		*gs = append(*gs, g)
+	}
+	return nil
+}
+
+// UnmarshalYAML supports the keys "stores" and "store-views".
+func (g *Group) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw struct {
+		Code         string `yaml:"code"`
+		Name         string `yaml:"name"`
+		RootCategory string `yaml:"root-category"`
+		DefaultStore string `yaml:"default-store"`
+		Stores       Stores `yaml:"stores"`
+		StoreViews   Stores `yaml:"store-views"`
+	}
+	if err := unmarshal(&raw); err != nil {
+		return errors.WithStack(err)
+	}
+	if len(raw.Stores) > 0 && len(raw.StoreViews) > 0 {
+		return errors.NotValid.Newf("[storeimport] Group %q: keys \"stores\" and \"store-views\" cannot be mixed", raw.Code)
+	}
+	*g = Group{
+		Code:         raw.Code,
+		Name:         raw.Name,
+		RootCategory: raw.RootCategory,
+		DefaultStore: raw.DefaultStore,
+		Stores:       raw.Stores,
+	}
+	if len(raw.StoreViews) > 0 {
+		g.Stores = raw.StoreViews
+	}
+	return nil
+}
+
+// UnmarshalYAML preserves the order of the stores and applies the map key as
+// code if the code is empty.
+func (ss *Stores) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	keys, err := orderedKeys(unmarshal)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var m map[string]*Store
+	if err := unmarshal(&m); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, k := range keys {
+		s := m[k]
+		if s == nil {
+			s = &Store{}
+		}
+		if s.Code == "" {
+			s.Code = k
+		}
+		if s.Name == "" {
+			s.Name = s.Code
+		}
+		*ss = append(*ss, s)
+	}
+	return nil
+}
+
+// orderedKeys returns the keys of a YAML mapping in document order.
+func orderedKeys(unmarshal func(interface{}) error) ([]string, error) {
+	var ms yaml.MapSlice
+	if err := unmarshal(&ms); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(ms))
+	for _, item := range ms {
+		keys = append(keys, fmt.Sprintf("%v", item.Key))
+	}
+	return keys, nil
+}
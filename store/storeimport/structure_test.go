@@ -0,0 +1,147 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storeimport_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/storeimport"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/assert"
+)
+
+func TestParseFile(t *testing.T) {
+	t.Run("store-views layout", func(t *testing.T) {
+		s, err := storeimport.ParseFile("testdata/store-structure1.yaml")
+		assert.NoError(t, err, "%+v", err)
+		assert.True(t, len(s.Websites) > 30, "Websites: %d", len(s.Websites))
+
+		al := s.Websites[0]
+		assert.Exactly(t, "al", al.Code)
+		assert.Exactly(t, "Albania", al.Name)
+		assert.Len(t, al.Groups, 1)
+		assert.Exactly(t, "pos_al", al.Groups[0].Code)
+		assert.Exactly(t, "POS Catalog", al.Groups[0].RootCategory)
+		assert.Len(t, al.Groups[0].Stores, 1)
+		assert.Exactly(t, "pos_al_en", al.Groups[0].Stores[0].Code)
+		assert.True(t, al.Groups[0].Stores[0].IsActive())
+
+		var found bool
+		for _, w := range s.Websites {
+			for _, g := range w.Groups {
+				for _, st := range g.Stores {
+					found = found || st.Code == "pos_ch_fr"
+				}
+			}
+		}
+		assert.True(t, found, "Store code pos_ch_fr must be used instead of the map key cf_fr")
+	})
+
+	t.Run("groups layout", func(t *testing.T) {
+		s, err := storeimport.ParseFile("testdata/store-structure2.yaml")
+		assert.NoError(t, err, "%+v", err)
+		assert.Exactly(t, "es", s.Websites[0].Code)
+		assert.Exactly(t, "en", s.Websites[1].Code)
+		// group pos_us gets used in both websites
+		assert.Exactly(t, "pos_us", s.Websites[0].Groups[0].Code)
+		assert.Exactly(t, "pos_us", s.Websites[1].Groups[0].Code)
+		assert.Exactly(t, "pos_es_ca", s.Websites[0].Groups[1].Stores[1].Code)
+	})
+
+	t.Run("file not found", func(t *testing.T) {
+		s, err := storeimport.ParseFile("testdata/not-found.yaml")
+		assert.Nil(t, s)
+		assert.True(t, errors.NotFound.Match(err), "%+v", err)
+	})
+}
+
+func TestParse_Errors(t *testing.T) {
+	runner := func(yaml string, kind errors.Kind) func(*testing.T) {
+		return func(t *testing.T) {
+			s, err := storeimport.Parse(strings.NewReader(yaml))
+			assert.Nil(t, s)
+			assert.True(t, kind.Match(err), "%+v", err)
+		}
+	}
+	t.Run("root key missing", runner("websites:\n  en:\n    name: English\n", errors.NotFound))
+	t.Run("no websites", runner("store-structure:\n  websites:\n", errors.Empty))
+	t.Run("invalid website code", runner(`store-structure:
+  websites:
+    1en:
+      groups:
+        g1:
+          stores:
+            s1:
+              name: Store
+`, errors.NotValid))
+	t.Run("duplicate store code", runner(`store-structure:
+  websites:
+    en:
+      groups:
+        g1:
+          stores:
+            s1:
+              code: en_us
+    de:
+      groups:
+        g1:
+          stores:
+            s1:
+              code: en_us
+`, errors.Duplicated))
+	t.Run("mixed layout", runner(`store-structure:
+  websites:
+    en:
+      groups:
+        g1:
+          stores:
+            s1:
+      stores:
+        g2:
+          store-views:
+            s2:
+`, errors.NotValid))
+	t.Run("default store not in group", runner(`store-structure:
+  websites:
+    en:
+      groups:
+        g1:
+          default-store: s2
+          stores:
+            s1:
+`, errors.NotFound))
+	t.Run("two default websites", runner(`store-structure:
+  websites:
+    en:
+      default: true
+      groups:
+        g1:
+          stores:
+            s1:
+    de:
+      default: true
+      groups:
+        g2:
+          stores:
+            s2:
+`, errors.NotValid))
+}
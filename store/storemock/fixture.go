@@ -0,0 +1,371 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storemock
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/corestoreio/errors"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/config/storage"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/storeimport"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/util/null"
+)
+
+// RouteSingleStoreModeEnabled configuration route which gets set by
+// Fixture.SingleStoreMode.
+const RouteSingleStoreModeEnabled = "general/single_store_mode/enabled"
+
+// FixtureRootCategoryID gets assigned to all groups created by a Fixture.
+const FixtureRootCategoryID = 2
+
+// Fixture builds an in-memory store topology for tests. The methods can be
+// chained. A Website, Group or Store gets appended to the previously added
+// parent. The first website, the first group of a website and the first active
+// store of a group become the defaults. The admin website, group and store with
+// ID 0 are always present. The first error stops the building and gets
+// returned by Build.
+//
+//	srv, cfg, err := storemock.NewFixture().
+//		Website("euro").Group("dach").Store("de").Store("at").InactiveStore("ch").
+//		Group("uk").Store("uk").
+//		Website("oz").Group("au").Store("au").Store("nz").
+//		Build()
+type Fixture struct {
+	websites store.TableWebsiteSlice
+	groups   store.TableGroupSlice
+	stores   store.TableStoreSlice
+	config   []string
+	// singleStoreMode nil means not set.
+	singleStoreMode *bool
+	website         *store.TableWebsite
+	group           *store.TableGroup
+	err             error
+}
+
+// NewFixture creates a new Fixture containing only the admin website, group
+// and store.
+func NewFixture() *Fixture {
+	return &Fixture{
+		websites: store.TableWebsiteSlice{
+			&store.TableWebsite{WebsiteID: 0, Code: null.StringFrom("admin"), Name: null.StringFrom("Admin"), IsDefault: null.BoolFrom(false)},
+		},
+		groups: store.TableGroupSlice{
+			&store.TableGroup{GroupID: 0, WebsiteID: 0, Name: "Default"},
+		},
+		stores: store.TableStoreSlice{
+			&store.TableStore{StoreID: 0, Code: null.StringFrom("admin"), WebsiteID: 0, GroupID: 0, Name: "Admin", IsActive: true},
+		},
+	}
+}
+
+func fixtureName(code string) string {
+	return strings.ToUpper(code[:1]) + code[1:]
+}
+
+// Website appends a new website. The first website becomes the default
+// website.
+func (f *Fixture) Website(code string) *Fixture {
+	if f.err != nil {
+		return f
+	}
+	if err := store.CodeIsValid(code); err != nil {
+		f.err = errors.Wrapf(err, "[storemock] Fixture.Website %q", code)
+		return f
+	}
+	for _, w := range f.websites {
+		if w.Code.String == code {
+			f.err = errors.NewAlreadyExistsf("[storemock] Fixture.Website %q already exists", code)
+			return f
+		}
+	}
+	w := &store.TableWebsite{
+		WebsiteID: int64(len(f.websites)),
+		Code:      null.StringFrom(code),
+		Name:      null.StringFrom(fixtureName(code)),
+		SortOrder: int64(len(f.websites) * 10),
+		IsDefault: null.BoolFrom(len(f.websites) == 1),
+	}
+	f.websites = append(f.websites, w)
+	f.website = w
+	f.group = nil
+	return f
+}
+
+// DefaultWebsite marks the current website as the default website.
+func (f *Fixture) DefaultWebsite() *Fixture {
+	if f.err != nil {
+		return f
+	}
+	if f.website == nil {
+		f.err = errors.NewNotValidf("[storemock] Fixture.DefaultWebsite requires a website")
+		return f
+	}
+	for _, w := range f.websites {
+		w.IsDefault = null.BoolFrom(w == f.website)
+	}
+	return f
+}
+
+// Group appends a new group to the current website.
+func (f *Fixture) Group(code string) *Fixture {
+	if f.err != nil {
+		return f
+	}
+	if f.website == nil {
+		f.err = errors.NewNotValidf("[storemock] Fixture.Group %q requires a website", code)
+		return f
+	}
+	if err := store.CodeIsValid(code); err != nil {
+		f.err = errors.Wrapf(err, "[storemock] Fixture.Group %q", code)
+		return f
+	}
+	for _, g := range f.groups {
+		if g.Code.String == code {
+			f.err = errors.NewAlreadyExistsf("[storemock] Fixture.Group %q already exists", code)
+			return f
+		}
+	}
+	g := &store.TableGroup{
+		GroupID:        int64(len(f.groups)),
+		WebsiteID:      f.website.WebsiteID,
+		Code:           null.StringFrom(code),
+		Name:           fixtureName(code),
+		RootCategoryID: FixtureRootCategoryID,
+	}
+	if f.website.DefaultGroupID == 0 {
+		f.website.DefaultGroupID = g.GroupID
+	}
+	f.groups = append(f.groups, g)
+	f.group = g
+	return f
+}
+
+// Store appends a new active store to the current group.
+func (f *Fixture) Store(code string) *Fixture {
+	return f.addStore(code, true)
+}
+
+// InactiveStore appends a new inactive store to the current group.
+func (f *Fixture) InactiveStore(code string) *Fixture {
+	return f.addStore(code, false)
+}
+
+func (f *Fixture) addStore(code string, active bool) *Fixture {
+	if f.err != nil {
+		return f
+	}
+	if f.group == nil {
+		f.err = errors.NewNotValidf("[storemock] Fixture.Store %q requires a group", code)
+		return f
+	}
+	if err := store.CodeIsValid(code); err != nil {
+		f.err = errors.Wrapf(err, "[storemock] Fixture.Store %q", code)
+		return f
+	}
+	for _, s := range f.stores {
+		if s.Code.String == code {
+			f.err = errors.NewAlreadyExistsf("[storemock] Fixture.Store %q already exists", code)
+			return f
+		}
+	}
+	s := &store.TableStore{
+		StoreID:   int64(len(f.stores)),
+		Code:      null.StringFrom(code),
+		WebsiteID: f.group.WebsiteID,
+		GroupID:   f.group.GroupID,
+		Name:      fixtureName(code),
+		SortOrder: int64(len(f.stores) * 10),
+		IsActive:  active,
+	}
+	if f.group.DefaultStoreID == 0 && active {
+		f.group.DefaultStoreID = s.StoreID
+	}
+	f.stores = append(f.stores, s)
+	return f
+}
+
+// Generate appends n websites each with one group and m active stores. The
+// codes follow the pattern: website w1, group w1_g1 and stores w1_s1 to
+// w1_sM. Numbering continues after the already generated websites.
+func (f *Fixture) Generate(websites, stores int) *Fixture {
+	offset := len(f.websites)
+	for i := 0; i < websites && f.err == nil; i++ {
+		wc := "w" + strconv.Itoa(offset+i)
+		f.Website(wc).Group(wc + "_g1")
+		for j := 1; j <= stores; j++ {
+			f.Store(wc + "_s" + strconv.Itoa(j))
+		}
+	}
+	return f
+}
+
+// SingleStoreMode sets the flag store.Service.SingleStoreModeEnabled and the
+// configuration route general/single_store_mode/enabled in the default scope.
+func (f *Fixture) SingleStoreMode(enabled bool) *Fixture {
+	f.singleStoreMode = &enabled
+	return f
+}
+
+// WithConfig adds fully qualified configuration paths and their values, for
+// example "stores/2/web/url/use_store", "1". The values overwrite the default
+// values of the fixture.
+func (f *Fixture) WithConfig(fqPathValue ...string) *Fixture {
+	if f.err != nil {
+		return f
+	}
+	if len(fqPathValue)%2 == 1 {
+		f.err = errors.NewNotValidf("[storemock] Fixture.WithConfig requires path/value pairs, got %d arguments", len(fqPathValue))
+		return f
+	}
+	f.config = append(f.config, fqPathValue...)
+	return f
+}
+
+// ImportStructure appends the websites, groups and stores of a storeimport
+// structure.
+func (f *Fixture) ImportStructure(s *storeimport.Structure) *Fixture {
+	if f.err != nil {
+		return f
+	}
+	if err := s.Validate(); err != nil {
+		f.err = errors.Wrap(err, "[storemock] Fixture.ImportStructure")
+		return f
+	}
+	for _, w := range s.Websites {
+		f.Website(w.Code)
+		if f.err != nil {
+			return f
+		}
+		if w.Name != "" {
+			f.website.Name = null.StringFrom(w.Name)
+		}
+		f.website.SortOrder = w.SortOrder
+		if w.Default {
+			f.DefaultWebsite()
+		}
+		for _, g := range w.Groups {
+			f.Group(g.Code)
+			if f.err != nil {
+				return f
+			}
+			if g.Name != "" {
+				f.group.Name = g.Name
+			}
+			for _, st := range g.Stores {
+				f.addStore(st.Code, st.IsActive())
+				if f.err != nil {
+					return f
+				}
+				last := f.stores[len(f.stores)-1]
+				if st.Name != "" {
+					last.Name = st.Name
+				}
+				last.SortOrder = st.SortOrder
+				if st.Code == g.DefaultStore {
+					f.group.DefaultStoreID = last.StoreID
+				}
+			}
+		}
+	}
+	return f
+}
+
+// ImportYAML parses the storeimport YAML format and appends its websites,
+// groups and stores.
+func (f *Fixture) ImportYAML(r io.Reader) *Fixture {
+	if f.err != nil {
+		return f
+	}
+	s, err := storeimport.Parse(r)
+	if err != nil {
+		f.err = errors.Wrap(err, "[storemock] Fixture.ImportYAML")
+		return f
+	}
+	return f.ImportStructure(s)
+}
+
+// Tables returns the generated table rows. The rows must not be modified.
+func (f *Fixture) Tables() (store.TableWebsiteSlice, store.TableGroupSlice, store.TableStoreSlice) {
+	return f.websites, f.groups, f.stores
+}
+
+// ConfigPathValues returns the fully qualified configuration paths and their
+// values. Each website gets the base URLs http://<code>.example.com/ and
+// https://<code>.example.com/, the default scope gets http://example.com/.
+func (f *Fixture) ConfigPathValues() []string {
+	pv := []string{
+		"default/0/web/unsecure/base_url", "http://example.com/",
+		"default/0/web/secure/base_url", "https://example.com/",
+	}
+	for _, w := range f.websites {
+		if w.WebsiteID == 0 {
+			continue
+		}
+		prefix := "websites/" + strconv.FormatInt(w.WebsiteID, 10) + "/web/"
+		host := strings.Replace(w.Code.String, "_", "-", -1) + ".example.com/"
+		pv = append(pv,
+			prefix+"unsecure/base_url", "http://"+host,
+			prefix+"secure/base_url", "https://"+host,
+		)
+	}
+	if f.singleStoreMode != nil {
+		v := "0"
+		if *f.singleStoreMode {
+			v = "1"
+		}
+		pv = append(pv, "default/0/"+RouteSingleStoreModeEnabled, v)
+	}
+	return append(pv, f.config...)
+}
+
+// Build creates the store.Service and the matching configuration service.
+// The options get applied after the table options of the fixture.
+func (f *Fixture) Build(opts ...store.Option) (*store.Service, *config.FakeService, error) {
+	if f.err != nil {
+		return nil, nil, errors.WithStack(f.err)
+	}
+	cfg := config.NewFakeService(storage.NewMap(f.ConfigPathValues()...))
+	srv, err := store.NewService(cfg, append([]store.Option{
+		store.WithTableWebsites(f.websites...),
+		store.WithTableGroups(f.groups...),
+		store.WithTableStores(f.stores...),
+	}, opts...)...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "[storemock] Fixture.Build")
+	}
+	if f.singleStoreMode != nil {
+		srv.SingleStoreModeEnabled = *f.singleStoreMode
+	}
+	return srv, cfg, nil
+}
+
+// MustBuild same as Build but panics on error.
+func (f *Fixture) MustBuild(opts ...store.Option) (*store.Service, *config.FakeService) {
+	srv, cfg, err := f.Build(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return srv, cfg
+}
@@ -0,0 +1,160 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Copyright 2015-present, Cyrill @ Schumacher.fm and the CoreStore contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storemock_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/corestoreio/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/scope"
+	"github.com/sniperkit/snk.fork.corestoreio-pkg/store/storemock"
+)
+
+func TestFixture_Build(t *testing.T) {
+	srv, cfg, err := storemock.NewFixture().
+		Website("euro").Group("dach").Store("de").Store("at").InactiveStore("ch").
+		Group("uk").Store("uk").
+		Website("oz").DefaultWebsite().Group("au").InactiveStore("nz").Store("au").
+		WithConfig("stores/4/web/url/use_store", "1").
+		Build()
+	if !assert.NoError(t, err, "%+v", err) {
+		t.FailNow()
+	}
+	assert.Exactly(t, 3, srv.Websites().Len())
+	assert.Exactly(t, 4, srv.Groups().Len())
+	assert.Exactly(t, 7, srv.Stores().Len())
+
+	tws, tgs, tss := storemock.NewFixture().
+		Website("euro").Group("dach").Store("de").Store("at").InactiveStore("ch").
+		Group("uk").Store("uk").
+		Website("oz").DefaultWebsite().Group("au").InactiveStore("nz").Store("au").
+		Tables()
+
+	assert.False(t, tws[1].IsDefault.Bool)
+	assert.True(t, tws[2].IsDefault.Bool)
+	assert.Exactly(t, int64(1), tws[1].DefaultGroupID)
+	assert.Exactly(t, int64(3), tws[2].DefaultGroupID)
+	assert.Exactly(t, int64(1), tgs[1].DefaultStoreID)
+	assert.Exactly(t, int64(6), tgs[3].DefaultStoreID, "first active store")
+	assert.Exactly(t, "Dach", tgs[1].Name)
+	assert.False(t, tss[3].IsActive)
+	assert.Exactly(t, int64(2), tss[6].WebsiteID)
+
+	v, ok, err := cfg.Scoped(2, 6).Get(scope.Store, "web/unsecure/base_url").Str()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Exactly(t, "http://oz.example.com/", v)
+	v, _, _ = cfg.Scoped(0, 0).Get(scope.Default, "web/secure/base_url").Str()
+	assert.Exactly(t, "https://example.com/", v)
+	b, ok, err := cfg.Scoped(1, 4).Get(scope.Store, "web/url/use_store").Bool()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, b)
+}
+
+func TestFixture_Generate(t *testing.T) {
+	f := storemock.NewFixture().Generate(3, 4).SingleStoreMode(false)
+	tws, tgs, tss := f.Tables()
+	assert.Len(t, tws, 4)
+	assert.Len(t, tgs, 4)
+	assert.Len(t, tss, 13)
+	assert.Exactly(t, "w3", tws[3].Code.String)
+	assert.Exactly(t, "w3_g1", tgs[3].Code.String)
+	assert.Exactly(t, "w3_s4", tss[12].Code.String)
+	assert.Exactly(t, int64(3), tss[12].WebsiteID)
+
+	srv, cfg, err := f.Build()
+	assert.NoError(t, err, "%+v", err)
+	assert.False(t, srv.SingleStoreModeEnabled)
+	b, ok, err := cfg.Scoped(1, 1).Get(scope.Store, storemock.RouteSingleStoreModeEnabled).Bool()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, b)
+
+	_, tgs, _ = f.Generate(1, 1).Tables()
+	assert.Exactly(t, "w4_g1", tgs[4].Code.String)
+}
+
+func TestFixture_ImportYAML(t *testing.T) {
+	const structure = `
+store-structure:
+    websites:
+      - code: ch
+        name: Switzerland
+        groups:
+          - code: pos_ch
+            name: POS Switzerland
+            default-store: pos_ch_de
+            stores:
+              - code: pos_ch_en
+                name: POS Switzerland EN
+              - code: pos_ch_de
+                name: POS Switzerland DE
+      - code: de
+        name: Germany
+        default: true
+        groups:
+          - code: pos_de
+            name: POS Germany
+            stores:
+              - code: pos_de_en
+                name: POS Germany EN
+`
+	f := storemock.NewFixture().ImportYAML(strings.NewReader(structure))
+	tws, tgs, tss := f.Tables()
+	assert.Len(t, tws, 3)
+	assert.Len(t, tgs, 3)
+	assert.Len(t, tss, 4)
+	assert.Exactly(t, "Switzerland", tws[1].Name.String)
+	assert.False(t, tws[1].IsDefault.Bool)
+	assert.True(t, tws[2].IsDefault.Bool)
+	assert.Exactly(t, int64(2), tgs[1].DefaultStoreID)
+	assert.Exactly(t, "POS Germany EN", tss[3].Name)
+
+	_, _, err := f.Build()
+	assert.NoError(t, err, "%+v", err)
+
+	_, _, err = storemock.NewFixture().ImportYAML(strings.NewReader("store-structure: [")).Build()
+	assert.Error(t, err)
+}
+
+func TestFixture_Errors(t *testing.T) {
+	tests := []struct {
+		f       *storemock.Fixture
+		errKind errors.BehaviourFunc
+	}{
+		{storemock.NewFixture().Group("g"), errors.IsNotValid},
+		{storemock.NewFixture().Website("w").Store("s"), errors.IsNotValid},
+		{storemock.NewFixture().Website("1w"), errors.IsNotValid},
+		{storemock.NewFixture().Website("w").Website("w"), errors.IsAlreadyExists},
+		{storemock.NewFixture().Website("w").Group("g").Website("v").Group("g"), errors.IsAlreadyExists},
+		{storemock.NewFixture().Website("w").Group("g").Store("s").Store("s"), errors.IsAlreadyExists},
+		{storemock.NewFixture().WithConfig("default/0/a/b/c"), errors.IsNotValid},
+	}
+	for i, test := range tests {
+		srv, cfg, err := test.f.Build()
+		assert.Nil(t, srv, "Index %d", i)
+		assert.Nil(t, cfg, "Index %d", i)
+		assert.True(t, test.errKind(err), "Index %d: %+v", i, err)
+	}
+}
@@ -453,11 +453,12 @@ type TableGroupSlice []*TableGroup
 // TableGroup represents a type for DB table store_group
 // Generated via tableToStruct.
 type TableGroup struct {
-	GroupID        int64  `db:"group_id" json:",omitempty"`         // group_id smallint(5) unsigned NOT NULL PRI  auto_increment
-	WebsiteID      int64  `db:"website_id" json:",omitempty"`       // website_id smallint(5) unsigned NOT NULL MUL DEFAULT '0'
-	Name           string `db:"name" json:",omitempty"`             // name varchar(255) NOT NULL
-	RootCategoryID int64  `db:"root_category_id" json:",omitempty"` // root_category_id int(10) unsigned NOT NULL  DEFAULT '0'
-	DefaultStoreID int64  `db:"default_store_id" json:",omitempty"` // default_store_id smallint(5) unsigned NOT NULL MUL DEFAULT '0'
+	GroupID        int64       `db:"group_id" json:",omitempty"`         // group_id smallint(5) unsigned NOT NULL PRI  auto_increment
+	WebsiteID      int64       `db:"website_id" json:",omitempty"`       // website_id smallint(5) unsigned NOT NULL MUL DEFAULT '0'
+	Code           null.String `db:"code" json:",omitempty"`             // code varchar(32) NULL UNI
+	Name           string      `db:"name" json:",omitempty"`             // name varchar(255) NOT NULL
+	RootCategoryID int64       `db:"root_category_id" json:",omitempty"` // root_category_id int(10) unsigned NOT NULL  DEFAULT '0'
+	DefaultStoreID int64       `db:"default_store_id" json:",omitempty"` // default_store_id smallint(5) unsigned NOT NULL MUL DEFAULT '0'
 }
 
 //
@@ -631,6 +632,7 @@ func (s *TableGroupSlice) Prepend(n *TableGroup) {
 type ExtractGroup struct {
 	GroupID        func() []int64
 	WebsiteID      func() []int64
+	Code           func() []string
 	Name           func() []string
 	RootCategoryID func() []int64
 	DefaultStoreID func() []int64
@@ -654,6 +656,13 @@ func (s TableGroupSlice) Extract() ExtractGroup {
 			}
 			return ext
 		},
+		Code: func() []string {
+			ext := make([]string, 0, len(s))
+			for _, v := range s {
+				ext = append(ext, v.Code.String)
+			}
+			return ext
+		},
 		Name: func() []string {
 			ext := make([]string, 0, len(s))
 			for _, v := range s {